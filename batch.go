@@ -43,6 +43,8 @@ type Batch struct {
 	// offset holds the information to build an EntryDetail record which
 	// balances the batch by debiting or crediting the sum of amounts in the batch.
 	offset *Offset
+	// offsetEntries are the offset records added by the last Create call
+	offsetEntries []*EntryDetail
 
 	// category defines if the entry is a Forward, Return, or NOC
 	category string
@@ -1075,8 +1077,10 @@ func (batch *Batch) addendaFieldInclusionReturn(entry *EntryDetail) error {
 		// Offset entries within a Return batch will not have an Addenda99 record as they might be
 		// used to zero accounting entries.
 		//
+		// Files which were read don't carry their Offset, so these are also allowed by their IndividualName.
+		//
 		// See: https://github.com/moov-io/ach/issues/1010
-		if entry.IndividualName == offsetIndividualName {
+		if batch.isOffsetEntry(entry) || entry.IndividualName == offsetIndividualName {
 			return nil
 		}
 		return batch.Error("Addenda99", ErrFieldInclusion)
@@ -1227,98 +1231,82 @@ func (b *Batch) upsertOffsets() error {
 	if b == nil || b.offset == nil {
 		return nil
 	}
-	if err := CheckRoutingNumber(b.offset.RoutingNumber); err != nil {
-		return fmt.Errorf("offset: invalid routing number %s: %v", b.offset.RoutingNumber, err)
+	debitAccount, creditAccount := b.offset.debitAccount(), b.offset.creditAccount()
+	if err := debitAccount.validate(); err != nil {
+		return err
+	}
+	if err := creditAccount.validate(); err != nil {
+		return err
 	}
 
 	// remove any Offset records already on the batch
+	existing := b.offsetRecords()
 	for i := 0; i < len(b.Entries); i++ {
-		// TODO(adam): Should we remove this based on checking the last element is
-		// debit/credit and sums to all the other elements (which are mutually exclusive to
-		// the last record being debit or credit)?
-		// See: https://github.com/moov-io/ach/issues/540
-		if strings.EqualFold(b.Entries[i].IndividualName, offsetIndividualName) {
-			// fixup BatchControl records for our conditional after this for loop
-			if b.Entries[i].TransactionCode == CheckingCredit || b.Entries[i].TransactionCode == SavingsCredit {
-				b.Control.TotalCreditEntryDollarAmount -= b.Entries[i].Amount
-			} else {
-				b.Control.TotalDebitEntryDollarAmount -= b.Entries[i].Amount
-			}
-			// remove the EntryDetail
-			b.Control.EntryAddendaCount -= 1
-			b.Entries = append(b.Entries[:i], b.Entries[i+1:]...)
-			i--
+		if !slices.Contains(existing, b.Entries[i]) {
+			continue
+		}
+		// fixup BatchControl records for our conditional after this for loop
+		if b.Entries[i].CreditOrDebit() == "C" {
+			b.Control.TotalCreditEntryDollarAmount -= b.Entries[i].Amount
+		} else {
+			b.Control.TotalDebitEntryDollarAmount -= b.Entries[i].Amount
+		}
+		// remove the EntryDetail
+		b.Control.EntryAddendaCount -= 1
+		b.Entries = append(b.Entries[:i], b.Entries[i+1:]...)
+		i--
+	}
+	b.offsetEntries = nil
+
+	// A debit offset balances the credits and a credit offset balances the debits
+	var offsets []*EntryDetail
+	if amount := b.Control.TotalCreditEntryDollarAmount; amount > 0 {
+		if err := debitAccount.validateDebit(); err != nil {
+			return err
 		}
+		offsets = append(offsets, debitAccount.entryDetail(true, amount, b.Entries))
 	}
-
-	// Make sure the offset account type is valid
-	if err := b.offset.AccountType.validate(); err != nil {
-		return err
-	}
-
-	offsetCount := 1
-
-	// Create our debit offset EntryDetail
-	debitED := createOffsetEntryDetail(b.offset, b)
-	debitED.TraceNumber = fmt.Sprintf("%15.15d", lastTraceNumber(b.Entries)+offsetCount)
-	debitED.Amount = b.Control.TotalCreditEntryDollarAmount
-	switch b.offset.AccountType {
-	case OffsetChecking:
-		debitED.TransactionCode = CheckingDebit
-	case OffsetSavings:
-		debitED.TransactionCode = SavingsDebit
-	}
-	if debitED.Amount == 0 {
-		debitED = nil // zero out so we don't add an empty OFFSET EntryDetail
-	} else {
-		offsetCount += 1
-	}
-
-	// Create our credit offset EntryDetail
-	creditED := createOffsetEntryDetail(b.offset, b)
-	creditED.TraceNumber = fmt.Sprintf("%15.15d", lastTraceNumber(b.Entries)+offsetCount)
-	creditED.Amount = b.Control.TotalDebitEntryDollarAmount
-	switch b.offset.AccountType {
-	case OffsetChecking:
-		creditED.TransactionCode = CheckingCredit
-	case OffsetSavings:
-		creditED.TransactionCode = SavingsCredit
+	if amount := b.Control.TotalDebitEntryDollarAmount; amount > 0 {
+		offsets = append(offsets, creditAccount.entryDetail(false, amount, b.Entries))
 	}
-	if creditED.Amount == 0 {
-		creditED = nil // zero out so we don't add an empty OFFSET EntryDetail
+	if len(offsets) == 0 {
+		return nil
 	}
 
-	// Add both EntryDetails to our Batch and recalculate some fields
-	if debitED != nil {
-		b.AddEntry(debitED)
-		b.Control.EntryAddendaCount += 1
-		b.Control.TotalDebitEntryDollarAmount += debitED.Amount
-	}
-	if creditED != nil {
-		b.AddEntry(creditED)
+	// Add the EntryDetails to our Batch and recalculate some fields
+	for _, ed := range offsets {
+		ed.TraceNumber = fmt.Sprintf("%15.15d", lastTraceNumber(b.Entries)+1)
+		b.AddEntry(ed)
 		b.Control.EntryAddendaCount += 1
-		b.Control.TotalCreditEntryDollarAmount += creditED.Amount
+		if ed.CreditOrDebit() == "C" {
+			b.Control.TotalCreditEntryDollarAmount += ed.Amount
+		} else {
+			b.Control.TotalDebitEntryDollarAmount += ed.Amount
+		}
 	}
-	b.Header.ServiceClassCode = MixedDebitsAndCredits
+	b.offsetEntries = offsets
 
+	b.Header.ServiceClassCode = MixedDebitsAndCredits
 	b.Control.ServiceClassCode = MixedDebitsAndCredits
 	b.Control.EntryHash = b.calculateEntryHash()
 
 	return nil
 }
 
-func createOffsetEntryDetail(off *Offset, batch *Batch) *EntryDetail {
-	ed := NewEntryDetail()
-	ed.RDFIIdentification = batch.offset.RoutingNumber[:8]
-	ed.CheckDigit = batch.offset.RoutingNumber[8:9]
-	ed.DFIAccountNumber = batch.offset.AccountNumber
-	ed.IdentificationNumber = "" // left empty
-	ed.IndividualName = offsetIndividualName
-	ed.DiscretionaryData = batch.offset.Description
-	if len(batch.Entries) > 0 {
-		ed.Category = batch.Entries[0].Category
-	}
-	return ed
+// offsetRecords returns the offset records of the batch. These are the records added by the last Create call,
+// or those identified by the batch's Offset, such as after reading a file. See Offset.records.
+func (b *Batch) offsetRecords() []*EntryDetail {
+	if b.offsetEntries != nil {
+		return slices.DeleteFunc(slices.Clone(b.offsetEntries), func(ed *EntryDetail) bool {
+			return !slices.Contains(b.Entries, ed)
+		})
+	}
+	return b.offset.records(b.Entries, 0, 0)
+}
+
+// isOffsetEntry returns if entry is an offset record of the batch
+func (b *Batch) isOffsetEntry(entry *EntryDetail) bool {
+	return entry != nil && slices.Contains(b.offsetRecords(), entry)
 }
 
 // aba8 returns the first 8 digits of an ABA routing number.
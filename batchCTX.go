@@ -86,7 +86,7 @@ func (batch *BatchCTX) InvalidEntries() []InvalidEntry {
 		if entry.Addenda98 != nil {
 			addendaCount += 1
 		}
-		if entry.Addenda99 != nil {
+		if entry.Addenda99 != nil || entry.Addenda99Dishonored != nil || entry.Addenda99Contested != nil {
 			addendaCount += 1
 		}
 
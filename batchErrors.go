@@ -57,6 +57,8 @@ var (
 	ErrBatchCompanyEntryDescriptionREDEPCHECK = errors.New("this batch type requires that the Company Entry Description is REDEPCHECK")
 	// ErrBatchAddendaCategory is the error given when the addenda isn't allowed for the batch's type and category
 	ErrBatchAddendaCategory = errors.New("this batch type does not allow this addenda for category")
+	// ErrBatchEntryNotFound is the error given when a batch has no entry with the requested trace number
+	ErrBatchEntryNotFound = errors.New("entry not found in batch")
 )
 
 // BatchError is an Error that describes batch validation issues
@@ -144,6 +146,10 @@ func (e ErrBatchHeaderControlEquality) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchHeaderControlEquality) Code() ErrorCode {
+	return CodeBatchHeaderControlMismatch
+}
+
 // ErrBatchCalculatedControlEquality is the error given when the control record does not match the calculated value
 type ErrBatchCalculatedControlEquality struct {
 	Message         string
@@ -164,6 +170,10 @@ func (e ErrBatchCalculatedControlEquality) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchCalculatedControlEquality) Code() ErrorCode {
+	return CodeBatchControlTotals
+}
+
 // ErrBatchAscending is the error given when the trace numbers in a batch are not in ascending order
 type ErrBatchAscending struct {
 	Message       string
@@ -184,6 +194,10 @@ func (e ErrBatchAscending) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchAscending) Code() ErrorCode {
+	return CodeBatchTraceNumberOrder
+}
+
 // ErrBatchCategory is the error given when a batch has entires with two different categories
 type ErrBatchCategory struct {
 	Message   string
@@ -204,6 +218,10 @@ func (e ErrBatchCategory) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchCategory) Code() ErrorCode {
+	return CodeBatchCategory
+}
+
 // ErrBatchTraceNumberNotODFI is the error given when a batch's ODFI does not match an entry's trace number
 type ErrBatchTraceNumberNotODFI struct {
 	Message     string
@@ -224,6 +242,10 @@ func (e ErrBatchTraceNumberNotODFI) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchTraceNumberNotODFI) Code() ErrorCode {
+	return CodeBatchTraceNumberODFI
+}
+
 // ErrBatchAddendaTraceNumber is the error given when the entry detail sequence number doesn't match the trace number
 type ErrBatchAddendaTraceNumber struct {
 	Message           string
@@ -244,6 +266,10 @@ func (e ErrBatchAddendaTraceNumber) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchAddendaTraceNumber) Code() ErrorCode {
+	return CodeBatchAddendaTraceNumber
+}
+
 // ErrBatchAddendaCount is the error given when there are too many addenda than allowed for the batch type
 type ErrBatchAddendaCount struct {
 	Message      string
@@ -264,6 +290,10 @@ func (e ErrBatchAddendaCount) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchAddendaCount) Code() ErrorCode {
+	return CodeBatchAddendaCount
+}
+
 // ErrBatchRequiredAddendaCount is the error given when the batch type requires a certain number of addenda, which is not met
 type ErrBatchRequiredAddendaCount struct {
 	Message       string
@@ -284,6 +314,10 @@ func (e ErrBatchRequiredAddendaCount) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchRequiredAddendaCount) Code() ErrorCode {
+	return CodeBatchAddendaRequired
+}
+
 // ErrBatchExpectedAddendaCount is the error given when the batch type has entries with a field
 // for the number of addenda, and a different number of addenda are foound
 type ErrBatchExpectedAddendaCount struct {
@@ -305,6 +339,10 @@ func (e ErrBatchExpectedAddendaCount) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchExpectedAddendaCount) Code() ErrorCode {
+	return CodeBatchAddendaExpected
+}
+
 // ErrBatchServiceClassTranCode is the error given when the transaction code is not valid for the batch's service class
 type ErrBatchServiceClassTranCode struct {
 	Message          string
@@ -325,6 +363,10 @@ func (e ErrBatchServiceClassTranCode) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchServiceClassTranCode) Code() ErrorCode {
+	return CodeBatchServiceClassTransactionCode
+}
+
 // ErrBatchAmount is the error given when the amount exceeds the batch type's limit
 type ErrBatchAmount struct {
 	Message string
@@ -346,6 +388,10 @@ func (e ErrBatchAmount) Error() string {
 	return e.Message
 }
 
+func (e ErrBatchAmount) Code() ErrorCode {
+	return CodeBatchAmountLimit
+}
+
 // ErrBatchIATNOC is the error given when an IAT batch has an NOC, and there are invalid values
 type ErrBatchIATNOC struct {
 	Message  string
@@ -366,3 +412,7 @@ func NewErrBatchIATNOC(found, expected interface{}) ErrBatchIATNOC {
 func (e ErrBatchIATNOC) Error() string {
 	return e.Message
 }
+
+func (e ErrBatchIATNOC) Code() ErrorCode {
+	return CodeBatchIATNOC
+}
@@ -1740,6 +1740,54 @@ func TestBatch_DeleteEntries(t *testing.T) {
 	require.Equal(t, "1", b.Entries[0].TraceNumber)
 }
 
+func TestBatch_GetEntry(t *testing.T) {
+	b := &Batch{}
+	b.SetHeader(mockBatchHeader())
+
+	ed1 := mockEntryDetail()
+	ed1.TraceNumber = "121042880000001"
+	ed2 := mockEntryDetail()
+	ed2.TraceNumber = "121042880000002"
+	b.AddEntry(ed1)
+	b.AddEntry(ed2)
+
+	require.Equal(t, ed2, GetEntry(b, "121042880000002"))
+	require.Equal(t, ed1, GetEntry(b, " 121042880000001 "))
+	require.Nil(t, GetEntry(b, "121042880000003"))
+}
+
+func TestBatch_UpdateEntry(t *testing.T) {
+	b := NewBatchPPD(mockBatchPPDHeader())
+
+	ed1 := mockPPDEntryDetail()
+	ed1.TraceNumber = "121042880000001"
+	ed2 := mockPPDEntryDetail()
+	ed2.TraceNumber = "121042880000002"
+	b.AddEntry(ed1)
+	b.AddEntry(ed2)
+	require.NoError(t, b.Create())
+	require.Equal(t, 200000000, b.GetControl().TotalCreditEntryDollarAmount)
+
+	updated := mockPPDEntryDetail()
+	updated.TraceNumber = "121042880000001"
+	updated.Amount = 500
+	require.NoError(t, UpdateEntry(b, "121042880000001", updated))
+
+	// the entry keeps its position
+	require.Len(t, b.Entries, 2)
+	require.Equal(t, updated, b.Entries[0])
+	require.Equal(t, ed2, b.Entries[1])
+
+	require.NoError(t, b.Create())
+	require.Equal(t, 100000500, b.GetControl().TotalCreditEntryDollarAmount)
+
+	err := UpdateEntry(b, "121042880000009", mockPPDEntryDetail())
+	require.ErrorIs(t, err, ErrBatchEntryNotFound)
+
+	err = UpdateEntry(b, "121042880000002", nil)
+	require.ErrorIs(t, err, ErrConstructor)
+}
+
 func TestBatch_DeleteADVEntries(t *testing.T) {
 	b := &Batch{}
 	b.SetHeader(mockBatchHeader())
@@ -1811,9 +1859,10 @@ func TestBatch_upsertOffsets_PanicRegression(t *testing.T) {
 	}
 
 	batch.offset = &Offset{
-		RoutingNumber: "987654320",
-		AccountNumber: "123456",
-		AccountType:   OffsetChecking,
+		RoutingNumber:       "987654320",
+		AccountNumber:       "123456",
+		AccountType:         OffsetChecking,
+		MatchIndividualName: true,
 	}
 
 	require.NotPanics(t, func() {
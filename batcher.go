@@ -18,7 +18,10 @@
 package ach
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"strings"
 )
 
 // Batcher abstract the different ACH batch types that can exist in a file.
@@ -55,12 +58,73 @@ type Batcher interface {
 	SetValidation(*ValidateOpts)
 }
 
+// GetEntry returns the first EntryDetail in b with traceNumber, or nil if there is none
+func GetEntry(b Batcher, traceNumber string) *EntryDetail {
+	if b == nil {
+		return nil
+	}
+	traceNumber = strings.TrimSpace(traceNumber)
+	for _, entry := range b.GetEntries() {
+		if entry != nil && strings.TrimSpace(entry.TraceNumber) == traceNumber {
+			return entry
+		}
+	}
+	return nil
+}
+
+// UpdateEntry replaces the EntryDetail of b with traceNumber, keeping its position in the batch.
+// Create should be called afterwards to recompute the BatchControl.
+func UpdateEntry(b Batcher, traceNumber string, entry *EntryDetail) error {
+	if b == nil {
+		return errors.New("nil Batcher")
+	}
+	if entry == nil {
+		return b.Error("EntryDetail", ErrConstructor)
+	}
+	traceNumber = strings.TrimSpace(traceNumber)
+	entries := b.GetEntries()
+	for i := range entries {
+		if entries[i] != nil && strings.TrimSpace(entries[i].TraceNumber) == traceNumber {
+			if bh := b.GetHeader(); bh != nil {
+				entry.secCode = cmp.Or(entry.secCode, strings.ToUpper(bh.StandardEntryClassCode))
+			}
+			entries[i] = entry
+			return nil
+		}
+	}
+	return b.Error("TraceNumber", ErrBatchEntryNotFound, traceNumber)
+}
+
 // Offset contains the associated information to append an 'Offset Record' on an ACH batch during Create.
+//
+// The debit offset, which balances credits, and the credit offset, which balances debits, are both sent to
+// RoutingNumber and AccountNumber unless DebitAccount or CreditAccount are set.
 type Offset struct {
 	RoutingNumber string            `json:"routingNumber"`
 	AccountNumber string            `json:"accountNumber"`
 	AccountType   OffsetAccountType `json:"accountType"`
 	Description   string            `json:"description"`
+
+	// DebitAccount receives the debit offset record instead of RoutingNumber and AccountNumber
+	DebitAccount *OffsetAccount `json:"debitAccount,omitempty"`
+	// CreditAccount receives the credit offset record instead of RoutingNumber and AccountNumber
+	CreditAccount *OffsetAccount `json:"creditAccount,omitempty"`
+
+	// Strategy determines if File.Balance offsets each batch or the entire File. Batch.WithOffset ignores it.
+	Strategy OffsetStrategy `json:"strategy,omitempty"`
+
+	// MatchIndividualName also identifies entries named OFFSET as offset records. Offset records are otherwise
+	// identified by their account and TransactionCode, which is enough for those created by this library. Set
+	// it to replace offset records created by other tools or with a different Offset.
+	MatchIndividualName bool `json:"matchIndividualName,omitempty"`
+}
+
+// OffsetAccount is an account which offset records are sent to. An empty Description defaults to the Offset's.
+type OffsetAccount struct {
+	RoutingNumber string            `json:"routingNumber"`
+	AccountNumber string            `json:"accountNumber"`
+	AccountType   OffsetAccountType `json:"accountType"`
+	Description   string            `json:"description"`
 }
 
 type OffsetAccountType string
@@ -68,11 +132,13 @@ type OffsetAccountType string
 const (
 	OffsetChecking OffsetAccountType = "checking"
 	OffsetSavings  OffsetAccountType = "savings"
+	OffsetGL       OffsetAccountType = "gl"
+	OffsetLoan     OffsetAccountType = "loan"
 )
 
 func (t OffsetAccountType) validate() error {
 	switch t {
-	case OffsetChecking, OffsetSavings:
+	case OffsetChecking, OffsetSavings, OffsetGL, OffsetLoan:
 		return nil
 	default:
 		return fmt.Errorf("unknown offset account type: %s", t)
@@ -18,8 +18,17 @@ func dumpFiles(paths []string, validateOpts *ach.ValidateOpts) error {
 	files := make([]*ach.File, len(paths))
 	for i := range paths {
 		f, err := readIncomingFile(paths[i], validateOpts)
+		if err == nil && f != nil && validateOpts != nil && validateOpts.CollectAllErrors {
+			err = f.ValidateAll(validateOpts)
+		}
 		if err != nil {
-			fmt.Printf("WARN: problem reading %s:\n %v\n\n", paths[i], err)
+			if validateOpts != nil && validateOpts.CollectAllErrors {
+				fmt.Printf("WARN: problems reading %s:\n", paths[i])
+				describe.Errors(os.Stdout, err)
+				fmt.Println("")
+			} else {
+				fmt.Printf("WARN: problem reading %s:\n %v\n\n", paths[i], err)
+			}
 		}
 		if f != nil {
 			files[i] = f
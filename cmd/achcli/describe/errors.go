@@ -0,0 +1,34 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package describe
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/moov-io/ach"
+)
+
+// Errors prints each error from reading or validating a file as a row in a table.
+// base.ParseError values include the line number and record the error was found on.
+func Errors(ww io.Writer, err error) {
+	if err == nil {
+		return
+	}
+
+	w := tabwriter.NewWriter(ww, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "  Line\tRecord\tCode\tError")
+	for _, detail := range ach.DescribeErrors(err) {
+		line := ""
+		if detail.Line > 0 {
+			line = strconv.Itoa(detail.Line)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", line, detail.Record, detail.Code, detail.Message)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package describe
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeErrors(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	file.Header.FileIDModifier = "?"
+	file.Control.EntryHash = 1
+
+	var buf bytes.Buffer
+	Errors(&buf, file.ValidateAll(nil))
+	if testing.Verbose() {
+		os.Stdout.Write(buf.Bytes())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], "Line")
+	require.Contains(t, lines[0], "Code")
+	require.Contains(t, lines[1], "1     FileHeader   ACH-FIELD-NOT-UPPERCASE")
+	require.Contains(t, lines[2], "5     FileControl  ACH-FILE-CONTROL-TOTALS")
+
+	t.Run("plain error", func(t *testing.T) {
+		var buf bytes.Buffer
+		Errors(&buf, errors.New("bad thing"))
+		require.Contains(t, buf.String(), "bad thing")
+
+		buf.Reset()
+		Errors(&buf, nil)
+		require.Empty(t, buf.String())
+	})
+}
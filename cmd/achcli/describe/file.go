@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/moov-io/ach"
+	"github.com/moov-io/ach/addenda"
 	"github.com/moov-io/ach/cmd/achcli/describe/mask"
 
 	"golang.org/x/text/language"
@@ -230,8 +232,48 @@ func dumpAddenda05(w *tabwriter.Writer, batch ach.Batcher, a *ach.Addenda05, opt
 		return
 	}
 
+	paymentInfo := a.PaymentRelatedInformationField()
+	txp := parseTXP(batch, a)
+	// Tax identification numbers are masked like the SSNs of DNE entries
+	if txp != nil && (opts.MaskNames || opts.MaskAccountNumbers || opts.MaskIdentification) {
+		masked := mask.Number(txp.TaxIdentificationNumber)
+		paymentInfo = strings.Replace(paymentInfo, txp.TaxIdentificationNumber, masked, 1)
+		txp.TaxIdentificationNumber = masked
+	}
+
 	fmt.Fprintln(w, "      PaymentRelatedInformation\tSequenceNumber\tEntryDetailSequenceNumber")
-	fmt.Fprintf(w, "      %s\t%s\t%s\n", a.PaymentRelatedInformationField(), a.SequenceNumberField(), a.EntryDetailSequenceNumberField())
+	fmt.Fprintf(w, "      %s\t%s\t%s\n", paymentInfo, a.SequenceNumberField(), a.EntryDetailSequenceNumberField())
+
+	if txp != nil {
+		dumpTXP(w, txp, opts)
+	}
+}
+
+// parseTXP returns the TXP addenda of CCD and CTX tax payments
+func parseTXP(batch ach.Batcher, a *ach.Addenda05) *addenda.TXP {
+	if batch == nil || batch.GetHeader() == nil {
+		return nil
+	}
+	switch batch.GetHeader().StandardEntryClassCode {
+	case ach.CCD, ach.CTX:
+		txp, _ := addenda.ParseTXP(strings.TrimSpace(a.PaymentRelatedInformation))
+		return txp
+	}
+	return nil
+}
+
+func dumpTXP(w *tabwriter.Writer, txp *addenda.TXP, opts *Opts) {
+	fmt.Fprintln(w, "\n      TXP TaxIdentificationNumber\tTaxPaymentTypeCode\tDate\tTaxpayerVerification")
+	fmt.Fprintf(w, "      %s\t%s\t%s\t%s\n", txp.TaxIdentificationNumber, txp.TaxPaymentTypeCode, txp.Date, txp.TaxpayerVerification)
+
+	fmt.Fprintln(w, "      AmountType\tAmount")
+	for _, amt := range txp.TaxAmounts {
+		amount := amt.AmountCents
+		if cents, err := strconv.Atoi(amt.AmountCents); err == nil {
+			amount = formatAmount(opts.PrettyAmounts, cents)
+		}
+		fmt.Fprintf(w, "      %s\t%s\n", amt.AmountType, amount)
+	}
 }
 
 func dumpAddenda98(w *tabwriter.Writer, opts *Opts, a *ach.Addenda98) {
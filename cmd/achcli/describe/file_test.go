@@ -89,3 +89,24 @@ func TestFormatAmount(t *testing.T) {
 	require.Equal(t, "123.45", formatAmount(true, 12345))
 	require.Equal(t, "1,234,567.89", formatAmount(true, 123456789))
 }
+
+func TestDescribeTXP(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "test", "testdata", "txp-credit.ach"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	File(&buf, file, &Opts{
+		Options: mask.Options{
+			MaskIdentification: true,
+		},
+		PrettyAmounts: true,
+	})
+	if testing.Verbose() {
+		os.Stdout.Write(buf.Bytes())
+	}
+	require.Contains(t, buf.String(), "TXP TaxIdentificationNumber")
+	require.Contains(t, buf.String(), "TXP******6789*941*250901")
+	require.Contains(t, buf.String(), "*****6789")
+	require.Contains(t, buf.String(), "10.00")
+	require.NotContains(t, buf.String(), "TXP*123456789")
+}
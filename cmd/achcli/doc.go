@@ -23,7 +23,9 @@ EXAMPLES
   achcli -diff first.ach second.ach    Show the difference between two ACH files
   achcli -mask file.ach                Print file details with personally identifiable information partially removed
   achcli -reformat=json first.ach      Convert an incoming ACH file into another format (options: ach, json)
+  achcli -return=TRACE:R01 file.ach    Create a return file for entries in a received ACH file
   achcli -validate opts.json file.ach  Read an ACH File with the provided ValidateOpts
+  achcli -validate.all file.ach        List every validation error in a file with its line number
   achcli -version                      Print the version of achcli (Example: %s)
   achcli 20060102.ach                  Summarize an ACH file for human readability
 
@@ -23,6 +23,7 @@ var (
 	flagFlatten  = flag.Bool("flatten", false, "Flatten batches in each file")
 	flagMerge    = flag.Bool("merge", false, "Merge files before describing")
 	flagReformat = flag.String("reformat", "", "Reformat an incoming ACH file to another format")
+	flagReturn   = flag.String("return", "", "Create a return file for entries formatted as TRACE:CODE[:YYMMDD],...")
 
 	flagMask              = flag.Bool("mask", false, "Mask/hide full account numbers and individual names")
 	flagMaskAccounts      = flag.Bool("mask.accounts", false, "Mask/hide full account numbers")
@@ -34,6 +35,7 @@ var (
 
 	flagSkipValidation = flag.Bool("skip-validation", false, "Skip all validation checks")
 	flagValidateOpts   = flag.String("validate", "", "Path to config file in json format to enable validation opts")
+	flagValidateAll    = flag.Bool("validate.all", false, "Report every validation error in a table instead of stopping at the first")
 
 	// Fix commands
 	flagFix       = flag.Bool("fix", false, "Trigger fix tasks")
@@ -98,6 +100,18 @@ func main() {
 		}
 		fmt.Printf("Fixed file: %s\n", newpath)
 
+	case *flagReturn != "":
+		if len(args) != 1 {
+			fmt.Printf("ERROR: unexpected %d arguments: %#v\n", len(args), args)
+			os.Exit(1)
+		}
+		newpath, err := returnFile(args[0], *flagReturn, flagValidateOpts, flagSkipValidation)
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Return file: %s\n", newpath)
+
 	case *flagReformat != "" && len(args) == 1:
 		if err := reformat(*flagReformat, args[0], validateOpts); err != nil {
 			fmt.Printf("ERROR: %v\n", err)
@@ -132,6 +146,11 @@ func readValidationOpts(path string) *ach.ValidateOpts {
 			fmt.Printf("ERROR: unmarshal of validate opts failed: %v\n", err)
 			os.Exit(1)
 		}
+		opts.CollectAllErrors = opts.CollectAllErrors || *flagValidateAll
+		return &opts
+	}
+	if *flagValidateAll {
+		opts.CollectAllErrors = true
 		return &opts
 	}
 	return nil
@@ -0,0 +1,72 @@
+// Copyright 2019 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/ach/cmd/achcli/internal/read"
+	"github.com/moov-io/ach/cmd/achcli/internal/write"
+)
+
+// returnFile reads the forward file at path and writes a return file next to it for the
+// entries described by spec, which is formatted as TRACE:CODE[:YYMMDD],... where the optional
+// date is the DateOfDeath for R14 and R15 returns.
+func returnFile(path string, spec string, validateOptsPath *string, skipAll *bool) (string, error) {
+	entries, err := parseReturnEntries(spec)
+	if err != nil {
+		return "", err
+	}
+
+	file, format, err := read.Filepath(path, validateOptsPath, skipAll)
+	if err != nil {
+		return "", fmt.Errorf("reading %s failed: %w", path, err)
+	}
+
+	returned, err := file.Return(time.Now().In(time.UTC), entries)
+	if err != nil {
+		return "", fmt.Errorf("creating return file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := write.File(&buf, returned, format); err != nil {
+		return "", fmt.Errorf("encoding return file as %s: %w", format, err)
+	}
+
+	newpath := path + ".return"
+	if err := os.WriteFile(newpath, buf.Bytes(), 0600); err != nil {
+		return "", fmt.Errorf("writing %s failed: %w", newpath, err)
+	}
+	return newpath, nil
+}
+
+func parseReturnEntries(spec string) ([]ach.ReturnEntry, error) {
+	var entries []ach.ReturnEntry
+	for _, part := range strings.Split(spec, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("invalid return %q, expected TRACE:CODE[:YYMMDD]", part)
+		}
+
+		entry := ach.ReturnEntry{
+			TraceNumber: fields[0],
+			ReturnCode:  strings.ToUpper(fields[1]),
+		}
+		if len(fields) == 3 {
+			dateOfDeath, err := time.Parse("060102", fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid date of death %q: %w", fields[2], err)
+			}
+			entry.DateOfDeath = dateOfDeath
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -78,8 +79,46 @@ func main() {
 	} else if v := os.Getenv("ACH_MAX_BODY_SIZE"); v != "" {
 		logger.Logf("Using %d bytes as max request body size", size)
 	}
-	r := server.NewRepositoryInMemory(achFileTTL, logger)
-	svc = server.NewService(r)
+	var serviceOptions []server.ServiceOption
+	if store, mode, err := server.ConfigureDuplicatesFromEnv(); err != nil {
+		logger.Fatal().LogErrorf("problem setting up duplicate detection: %v", err)
+		os.Exit(1)
+	} else if store != nil {
+		logger.Logf("Using %s mode for duplicate detection", mode)
+		serviceOptions = append(serviceOptions, server.WithDuplicates(store, mode))
+		if closer, ok := store.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
+	if seq, kind, err := server.ConfigureSequencerFromEnv(); err != nil {
+		logger.Fatal().LogErrorf("problem setting up sequencer: %v", err)
+		os.Exit(1)
+	} else if seq != nil {
+		logger.Logf("Using %s sequencer for FileIDModifiers and trace numbers", kind)
+		serviceOptions = append(serviceOptions, server.WithSequencer(seq))
+	}
+	if limits, err := server.ConfigureLimitsFromEnv(); err != nil {
+		logger.Fatal().LogErrorf("problem setting up limits: %v", err)
+		os.Exit(1)
+	} else if limits != nil {
+		logger.Logf("Rejecting files over the origination limits in %s", os.Getenv("ACH_LIMITS_PATH"))
+		serviceOptions = append(serviceOptions, server.WithLimits(limits))
+	}
+	events := server.NewEvents()
+	if webhooks, err := server.ConfigureWebhooksFromEnv(events, logger); err != nil {
+		logger.Fatal().LogErrorf("problem setting up webhooks: %v", err)
+		os.Exit(1)
+	} else if webhooks != nil {
+		logger.Logf("Delivering events to webhooks")
+		defer webhooks.Close()
+	}
+	r, closeRepository, err := setupRepository(achFileTTL, logger)
+	if err != nil {
+		logger.Fatal().LogErrorf("problem setting up storage: %v", err)
+		os.Exit(1)
+	}
+	defer closeRepository()
+	svc = server.NewServiceWithEvents(r, events, serviceOptions...)
 
 	// Create HTTP server
 	handler = server.MakeHTTPHandler(svc, r, kitlog.With(kitlogger, "component", "HTTP"))
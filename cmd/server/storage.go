@@ -0,0 +1,77 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach/server"
+	"github.com/moov-io/base/log"
+
+	_ "modernc.org/sqlite"
+)
+
+// setupRepository returns the file repository selected by ACH_STORAGE along with
+// a function to release its resources on shutdown.
+//
+// The sqlite option uses a pure Go driver, so it works in servers built with CGO_ENABLED=0.
+func setupRepository(ttl time.Duration, logger log.Logger) (server.Repository, func() error, error) {
+	noop := func() error { return nil }
+
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv("ACH_STORAGE")))
+	path := strings.TrimSpace(os.Getenv("ACH_STORAGE_PATH"))
+
+	switch kind {
+	case "", "memory":
+		return server.NewRepositoryInMemory(ttl, logger), noop, nil
+
+	case "filesystem":
+		if path == "" {
+			return nil, noop, errors.New("ACH_STORAGE_PATH is required for filesystem storage")
+		}
+		repo, err := server.NewRepositoryFilesystem(path, ttl, logger)
+		if err != nil {
+			return nil, noop, err
+		}
+		logger.Logf("Storing ACH files in %s", path)
+		return repo, noop, nil
+
+	case "sqlite":
+		if path == "" {
+			return nil, noop, errors.New("ACH_STORAGE_PATH is required for sqlite storage")
+		}
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			return nil, noop, fmt.Errorf("opening sqlite database: %w", err)
+		}
+		db.SetMaxOpenConns(1) // sqlite allows one writer at a time
+		repo, err := server.NewRepositorySQL(db, ttl, logger)
+		if err != nil {
+			db.Close()
+			return nil, noop, err
+		}
+		logger.Logf("Storing ACH files in sqlite database %s", path)
+		return repo, db.Close, nil
+	}
+	return nil, noop, fmt.Errorf("unknown ACH_STORAGE %q", kind)
+}
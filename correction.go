@@ -0,0 +1,163 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CorrectionEntry identifies an Entry from a forward File which needs to be corrected by the Originator
+// through a Notification of Change (NOC).
+type CorrectionEntry struct {
+	// TraceNumber is the TraceNumber of the forward Entry being corrected.
+	TraceNumber string `json:"traceNumber"`
+
+	// ChangeCode must be a valid code found by LookupChangeCode (e.g. C01, C02, C03).
+	ChangeCode string `json:"changeCode"`
+
+	// CorrectedData holds the corrected values for the ChangeCode. It is formatted with WriteCorrectionData.
+	CorrectedData *CorrectedData `json:"correctedData"`
+}
+
+// RefusedCorrectionEntry identifies an Entry from a received Notification of Change (COR) File which
+// is being refused and the code explaining why.
+type RefusedCorrectionEntry struct {
+	// TraceNumber is the TraceNumber of the Notification of Change Entry being refused.
+	TraceNumber string `json:"traceNumber"`
+
+	// RefusedChangeCode must be a refused change code (C61 through C69).
+	RefusedChangeCode string `json:"refusedChangeCode"`
+}
+
+var (
+	// ErrCorrectionNoEntries is given when File.Correction or File.RefusedCorrection are called without any entries
+	ErrCorrectionNoEntries = errors.New("no entries to correct")
+
+	// ErrCorrectionCorrectedData is given when a CorrectionEntry has no corrected data for its ChangeCode
+	ErrCorrectionCorrectedData = errors.New("missing corrected data for change code")
+)
+
+// Correction creates a Notification of Change (COR) File from a received forward File. Each CorrectionEntry
+// is matched to a forward Entry by its TraceNumber and converted into a zero dollar COR Entry with an Addenda98
+// record. The forward File is not modified.
+//
+// The returned File has its ImmediateOrigin and ImmediateDestination swapped from the forward File and each
+// COR batch is originated by the RDFI of the entries it contains.
+//
+// IAT and ADV batches are not supported.
+func (f *File) Correction(effectiveEntryDate time.Time, entries []CorrectionEntry) (*File, error) {
+	if f == nil {
+		return nil, errors.New("nil File")
+	}
+	if len(entries) == 0 {
+		return nil, ErrCorrectionNoEntries
+	}
+
+	wanted := make(map[string]CorrectionEntry, len(entries))
+	traceNumbers := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.TraceNumber == "" {
+			return nil, errors.New("missing TraceNumber on correction entry")
+		}
+		if _, exists := wanted[entry.TraceNumber]; exists {
+			return nil, fmt.Errorf("trace number %s is corrected more than once", entry.TraceNumber)
+		}
+		if LookupChangeCode(entry.ChangeCode) == nil || IsRefusedChangeCode(entry.ChangeCode) {
+			return nil, fmt.Errorf("trace number %s: %s %w", entry.TraceNumber, entry.ChangeCode, ErrAddenda98ChangeCode)
+		}
+		if entry.CorrectedData == nil || strings.TrimSpace(WriteCorrectionData(entry.ChangeCode, entry.CorrectedData)) == "" {
+			return nil, fmt.Errorf("trace number %s: %w %s", entry.TraceNumber, ErrCorrectionCorrectedData, entry.ChangeCode)
+		}
+		wanted[entry.TraceNumber] = entry
+		traceNumbers[entry.TraceNumber] = true
+	}
+
+	return respondToEntries(f, effectiveEntryDate, traceNumbers, COR, func(bh *BatchHeader, forward *EntryDetail, seq int) (*EntryDetail, error) {
+		req := wanted[forward.TraceNumber]
+
+		txCode, err := returnTransactionCode(forward.TransactionCode)
+		if err != nil {
+			return nil, err
+		}
+		ed := respondingEntryDetail(bh, forward, txCode, CategoryNOC)
+		ed.Amount = 0
+
+		addenda98 := NewAddenda98()
+		addenda98.ChangeCode = strings.ToUpper(req.ChangeCode)
+		addenda98.OriginalTrace = forward.TraceNumber
+		addenda98.OriginalDFI = forward.RDFIIdentification
+		addenda98.CorrectedData = strings.TrimSpace(WriteCorrectionData(req.ChangeCode, req.CorrectedData))
+		ed.Addenda98 = addenda98
+
+		ed.SetTraceNumber(forward.RDFIIdentification, seq)
+		return ed, nil
+	})
+}
+
+// RefusedCorrection creates a refused Notification of Change (COR) File from a received COR File. Each
+// RefusedCorrectionEntry is matched to a Notification of Change Entry by its TraceNumber and converted into
+// an Entry with an Addenda98Refused record which is sent back to the DFI which originated the NOC.
+// The received File is not modified.
+func (f *File) RefusedCorrection(effectiveEntryDate time.Time, entries []RefusedCorrectionEntry) (*File, error) {
+	if f == nil {
+		return nil, errors.New("nil File")
+	}
+	if len(entries) == 0 {
+		return nil, ErrCorrectionNoEntries
+	}
+
+	wanted := make(map[string]RefusedCorrectionEntry, len(entries))
+	traceNumbers := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.TraceNumber == "" {
+			return nil, errors.New("missing TraceNumber on refused correction entry")
+		}
+		if _, exists := wanted[entry.TraceNumber]; exists {
+			return nil, fmt.Errorf("trace number %s is refused more than once", entry.TraceNumber)
+		}
+		if !IsRefusedChangeCode(entry.RefusedChangeCode) {
+			return nil, fmt.Errorf("trace number %s: %s %w", entry.TraceNumber, entry.RefusedChangeCode, ErrAddenda98RefusedChangeCode)
+		}
+		wanted[entry.TraceNumber] = entry
+		traceNumbers[entry.TraceNumber] = true
+	}
+
+	return respondToEntries(f, effectiveEntryDate, traceNumbers, COR, func(bh *BatchHeader, noc *EntryDetail, seq int) (*EntryDetail, error) {
+		req := wanted[noc.TraceNumber]
+		if noc.Addenda98 == nil {
+			return nil, errors.New("entry is not a Notification of Change")
+		}
+
+		ed := respondingEntryDetail(bh, noc, noc.TransactionCode, CategoryNOC)
+
+		refused := NewAddenda98Refused()
+		refused.RefusedChangeCode = strings.ToUpper(req.RefusedChangeCode)
+		refused.OriginalTrace = noc.Addenda98.OriginalTrace
+		refused.OriginalDFI = noc.Addenda98.OriginalDFI
+		refused.CorrectedData = noc.Addenda98.CorrectedData
+		refused.ChangeCode = noc.Addenda98.ChangeCode
+		refused.TraceSequenceNumber = noc.TraceNumberField()[8:]
+		ed.Addenda98Refused = refused
+
+		ed.SetTraceNumber(noc.RDFIIdentification, seq)
+		return ed, nil
+	})
+}
@@ -0,0 +1,156 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Correction(t *testing.T) {
+	file, err := ReadFile(filepath.Join("test", "testdata", "ppd-mixedDebitCredit.ach"))
+	require.NoError(t, err)
+
+	effectiveEntryDate := time.Date(2019, time.July, 22, 10, 30, 0, 0, time.UTC)
+	cor, err := file.Correction(effectiveEntryDate, []CorrectionEntry{
+		{TraceNumber: "121042880000001", ChangeCode: "C01", CorrectedData: &CorrectedData{AccountNumber: "1918171614"}},
+		{TraceNumber: "121042880000002", ChangeCode: "C05", CorrectedData: &CorrectedData{TransactionCode: SavingsCredit}},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, file.Header.ImmediateOrigin, cor.Header.ImmediateDestination)
+	require.Equal(t, file.Header.ImmediateDestination, cor.Header.ImmediateOrigin)
+
+	require.Len(t, cor.Batches, 1)
+	require.Len(t, cor.NotificationOfChange, 1)
+
+	bh := cor.Batches[0].GetHeader()
+	require.Equal(t, COR, bh.StandardEntryClassCode)
+	require.Equal(t, "23138010", bh.ODFIIdentification)
+	require.Equal(t, MixedDebitsAndCredits, bh.ServiceClassCode)
+
+	entries := cor.Batches[0].GetEntries()
+	require.Len(t, entries, 2)
+
+	require.Equal(t, CheckingReturnNOCDebit, entries[0].TransactionCode)
+	require.Equal(t, 0, entries[0].Amount)
+	require.Equal(t, "12104288", entries[0].RDFIIdentification)
+	require.Equal(t, "231380100000001", entries[0].TraceNumber)
+	require.Equal(t, "C01", entries[0].Addenda98.ChangeCode)
+	require.Equal(t, "121042880000001", entries[0].Addenda98.OriginalTrace)
+	require.Equal(t, "23138010", entries[0].Addenda98.OriginalDFI)
+	require.Equal(t, "1918171614", entries[0].Addenda98.CorrectedData)
+	require.Equal(t, entries[0].TraceNumber, entries[0].Addenda98.TraceNumber)
+
+	require.Equal(t, CheckingReturnNOCCredit, entries[1].TransactionCode)
+	require.Equal(t, "C05", entries[1].Addenda98.ChangeCode)
+	require.Equal(t, SavingsCredit, entries[1].Addenda98.ParseCorrectedData().TransactionCode)
+
+	bc := cor.Batches[0].GetControl()
+	require.Equal(t, 0, bc.TotalDebitEntryDollarAmount)
+	require.Equal(t, 0, bc.TotalCreditEntryDollarAmount)
+
+	// The COR file can be written and read back
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf).Write(cor))
+	parsed, err := NewReader(&buf).Read()
+	require.NoError(t, err)
+	require.Len(t, parsed.NotificationOfChange, 1)
+}
+
+func TestFile_CorrectionErrors(t *testing.T) {
+	file, err := ReadFile(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	effectiveEntryDate := time.Now().In(time.UTC)
+
+	_, err = file.Correction(effectiveEntryDate, nil)
+	require.ErrorIs(t, err, ErrCorrectionNoEntries)
+
+	_, err = file.Correction(effectiveEntryDate, []CorrectionEntry{
+		{TraceNumber: "121042880000001", ChangeCode: "C99", CorrectedData: &CorrectedData{AccountNumber: "123"}},
+	})
+	require.ErrorIs(t, err, ErrAddenda98ChangeCode)
+
+	_, err = file.Correction(effectiveEntryDate, []CorrectionEntry{
+		{TraceNumber: "121042880000001", ChangeCode: "C02", CorrectedData: &CorrectedData{AccountNumber: "123"}},
+	})
+	require.ErrorIs(t, err, ErrCorrectionCorrectedData)
+
+	_, err = file.Correction(effectiveEntryDate, []CorrectionEntry{
+		{TraceNumber: "121042880000009", ChangeCode: "C01", CorrectedData: &CorrectedData{AccountNumber: "123"}},
+	})
+	require.ErrorContains(t, err, "trace numbers not found: 121042880000009")
+}
+
+func TestFile_RefusedCorrection(t *testing.T) {
+	forward, err := ReadFile(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	effectiveEntryDate := time.Date(2019, time.July, 22, 10, 30, 0, 0, time.UTC)
+	cor, err := forward.Correction(effectiveEntryDate, []CorrectionEntry{
+		{TraceNumber: "121042880000001", ChangeCode: "C02", CorrectedData: &CorrectedData{RoutingNumber: "231380104"}},
+	})
+	require.NoError(t, err)
+
+	nocTraceNumber := cor.Batches[0].GetEntries()[0].TraceNumber
+
+	_, err = cor.RefusedCorrection(effectiveEntryDate, []RefusedCorrectionEntry{
+		{TraceNumber: nocTraceNumber, RefusedChangeCode: "C01"},
+	})
+	require.ErrorIs(t, err, ErrAddenda98RefusedChangeCode)
+
+	refused, err := cor.RefusedCorrection(effectiveEntryDate, []RefusedCorrectionEntry{
+		{TraceNumber: nocTraceNumber, RefusedChangeCode: "C62"},
+	})
+	require.NoError(t, err)
+
+	// The refused NOC is sent back to the DFI which created the NOC
+	require.Equal(t, forward.Header.ImmediateOrigin, refused.Header.ImmediateOrigin)
+	require.Len(t, refused.NotificationOfChange, 1)
+
+	bh := refused.Batches[0].GetHeader()
+	require.Equal(t, COR, bh.StandardEntryClassCode)
+	require.Equal(t, "12104288", bh.ODFIIdentification)
+
+	entries := refused.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.Equal(t, CheckingReturnNOCDebit, entries[0].TransactionCode)
+	require.Equal(t, "23138010", entries[0].RDFIIdentification)
+	require.Nil(t, entries[0].Addenda98)
+
+	addenda := entries[0].Addenda98Refused
+	require.NotNil(t, addenda)
+	require.Equal(t, "C62", addenda.RefusedChangeCode)
+	require.Equal(t, "C02", addenda.ChangeCode)
+	require.Equal(t, "121042880000001", addenda.OriginalTrace)
+	require.Equal(t, "23138010", addenda.OriginalDFI)
+	require.Equal(t, "231380104", addenda.CorrectedData)
+	require.Equal(t, "0000001", addenda.TraceSequenceNumber)
+	require.Equal(t, entries[0].TraceNumber, addenda.TraceNumber)
+
+	// Forward entries cannot be refused
+	_, err = forward.RefusedCorrection(effectiveEntryDate, []RefusedCorrectionEntry{
+		{TraceNumber: "121042880000001", RefusedChangeCode: "C62"},
+	})
+	require.ErrorContains(t, err, "not a Notification of Change")
+}
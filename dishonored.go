@@ -0,0 +1,263 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+)
+
+// DishonoredReturnEntry identifies a return Entry which an ODFI is dishonoring and the reason why.
+type DishonoredReturnEntry struct {
+	// TraceNumber is the TraceNumber of the return Entry being dishonored.
+	TraceNumber string `json:"traceNumber"`
+
+	// ReturnCode must be a dishonored return code (R61, R62, R67, R68, R69 or R70).
+	ReturnCode string `json:"returnCode"`
+
+	// ReturnSettlementDate is the Settlement Date of the return Entry. When empty the SettlementDate
+	// of the return's batch header, which is inserted by the ACH Operator, is used.
+	ReturnSettlementDate time.Time `json:"returnSettlementDate,omitzero"`
+
+	// AddendaInformation is optional data included on the Addenda99Dishonored record.
+	AddendaInformation string `json:"addendaInformation,omitempty"`
+}
+
+// ContestedReturnEntry identifies a dishonored return Entry which an RDFI is contesting and the reason why.
+type ContestedReturnEntry struct {
+	// TraceNumber is the TraceNumber of the dishonored return Entry being contested.
+	TraceNumber string `json:"traceNumber"`
+
+	// ReturnCode must be a contested dishonored return code (R71 through R77).
+	ReturnCode string `json:"returnCode"`
+
+	// DateOriginalEntryReturned is the date the RDFI originally returned the forward Entry.
+	DateOriginalEntryReturned time.Time `json:"dateOriginalEntryReturned"`
+
+	// OriginalSettlementDate is the Settlement Date of the forward Entry.
+	OriginalSettlementDate time.Time `json:"originalSettlementDate"`
+
+	// DishonoredReturnSettlementDate is the Settlement Date of the dishonored return Entry. When empty the
+	// SettlementDate of the dishonored return's batch header, which is inserted by the ACH Operator, is used.
+	DishonoredReturnSettlementDate time.Time `json:"dishonoredReturnSettlementDate,omitzero"`
+}
+
+const (
+	// dishonoredReturnBankingDays is how many banking days after the Settlement Date of a return
+	// an ODFI has to transmit a dishonored return.
+	dishonoredReturnBankingDays = 5
+
+	// contestedReturnBankingDays is how many banking days after the Settlement Date of a dishonored
+	// return an RDFI has to transmit a contested dishonored return.
+	contestedReturnBankingDays = 2
+)
+
+var (
+	// ErrDishonoredReturnNoEntries is given when File.DishonoredReturn or File.ContestedDishonoredReturn are called without any entries
+	ErrDishonoredReturnNoEntries = errors.New("no entries to dishonor or contest")
+
+	// ErrDishonoredReturnTimeLimit is given when a dishonored return is created more than five banking days
+	// after the Settlement Date of the return Entry
+	ErrDishonoredReturnTimeLimit = errors.New("dishonored returns must be transmitted within five banking days of the return settlement date")
+
+	// ErrContestedReturnTimeLimit is given when a contested dishonored return is created more than two banking days
+	// after the Settlement Date of the dishonored return Entry
+	ErrContestedReturnTimeLimit = errors.New("contested dishonored returns must be transmitted within two banking days of the dishonored return settlement date")
+)
+
+// DishonoredReturn creates a File of dishonored returns from a received return File. Each DishonoredReturnEntry
+// is matched to a return Entry by its TraceNumber and converted into an Entry with an Addenda99Dishonored record
+// which is sent back to the RDFI that returned it. The received File is not modified.
+//
+// Nacha requires dishonored returns be transmitted within five banking days after the Settlement Date of the
+// return Entry, which is checked against effectiveEntryDate.
+func (f *File) DishonoredReturn(effectiveEntryDate time.Time, entries []DishonoredReturnEntry) (*File, error) {
+	if f == nil {
+		return nil, errors.New("nil File")
+	}
+	if len(entries) == 0 {
+		return nil, ErrDishonoredReturnNoEntries
+	}
+
+	wanted := make(map[string]DishonoredReturnEntry, len(entries))
+	traceNumbers := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.TraceNumber == "" {
+			return nil, errors.New("missing TraceNumber on dishonored return entry")
+		}
+		if _, exists := wanted[entry.TraceNumber]; exists {
+			return nil, fmt.Errorf("trace number %s is dishonored more than once", entry.TraceNumber)
+		}
+		if !IsDishonoredReturnCode(entry.ReturnCode) {
+			return nil, fmt.Errorf("trace number %s: %s %w", entry.TraceNumber, entry.ReturnCode, ErrAddenda99DishonoredReturnCode)
+		}
+		wanted[entry.TraceNumber] = entry
+		traceNumbers[entry.TraceNumber] = true
+	}
+
+	return respondToEntries(f, effectiveEntryDate, traceNumbers, "", func(bh *BatchHeader, ret *EntryDetail, seq int) (*EntryDetail, error) {
+		req := wanted[ret.TraceNumber]
+		if ret.Addenda99 == nil {
+			return nil, errors.New("entry is not a return")
+		}
+
+		settlementDate, err := settlementDateOrDefault(req.ReturnSettlementDate, bh.SettlementDate, effectiveEntryDate)
+		if err != nil {
+			return nil, fmt.Errorf("return settlement date: %w", err)
+		}
+		if err := withinBankingDays(settlementDate, effectiveEntryDate, dishonoredReturnBankingDays); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDishonoredReturnTimeLimit, err)
+		}
+
+		ed := respondingEntryDetail(bh, ret, ret.TransactionCode, CategoryDishonoredReturn)
+		if bh.StandardEntryClassCode == CTX || bh.StandardEntryClassCode == ATX {
+			ed.SetCATXAddendaRecords(1)
+		}
+
+		dishonored := NewAddenda99Dishonored()
+		dishonored.DishonoredReturnReasonCode = req.ReturnCode
+		dishonored.OriginalEntryTraceNumber = ret.Addenda99.OriginalTrace
+		dishonored.OriginalReceivingDFIIdentification = ret.Addenda99.OriginalDFI
+		dishonored.ReturnTraceNumber = ret.TraceNumber
+		dishonored.ReturnSettlementDate = julianDay(settlementDate)
+		dishonored.ReturnReasonCode = strings.TrimPrefix(ret.Addenda99.ReturnCode, "R")
+		dishonored.AddendaInformation = req.AddendaInformation
+		dishonored.SetValidation(f.validateOpts)
+		ed.Addenda99Dishonored = dishonored
+
+		ed.SetTraceNumber(ret.RDFIIdentification, seq)
+		return ed, nil
+	})
+}
+
+// ContestedDishonoredReturn creates a File of contested dishonored returns from a received dishonored return File.
+// Each ContestedReturnEntry is matched to a dishonored return Entry by its TraceNumber and converted into an Entry
+// with an Addenda99Contested record which is sent back to the ODFI that dishonored it. The received File is not modified.
+//
+// Nacha requires contested dishonored returns be transmitted within two banking days after the Settlement Date of
+// the dishonored return Entry, which is checked against effectiveEntryDate.
+func (f *File) ContestedDishonoredReturn(effectiveEntryDate time.Time, entries []ContestedReturnEntry) (*File, error) {
+	if f == nil {
+		return nil, errors.New("nil File")
+	}
+	if len(entries) == 0 {
+		return nil, ErrDishonoredReturnNoEntries
+	}
+
+	wanted := make(map[string]ContestedReturnEntry, len(entries))
+	traceNumbers := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.TraceNumber == "" {
+			return nil, errors.New("missing TraceNumber on contested return entry")
+		}
+		if _, exists := wanted[entry.TraceNumber]; exists {
+			return nil, fmt.Errorf("trace number %s is contested more than once", entry.TraceNumber)
+		}
+		if !IsContestedReturnCode(entry.ReturnCode) {
+			return nil, fmt.Errorf("trace number %s: %s %w", entry.TraceNumber, entry.ReturnCode, ErrAddenda99ContestedReturnCode)
+		}
+		if entry.DateOriginalEntryReturned.IsZero() {
+			return nil, fmt.Errorf("trace number %s: missing DateOriginalEntryReturned", entry.TraceNumber)
+		}
+		if entry.OriginalSettlementDate.IsZero() {
+			return nil, fmt.Errorf("trace number %s: missing OriginalSettlementDate", entry.TraceNumber)
+		}
+		wanted[entry.TraceNumber] = entry
+		traceNumbers[entry.TraceNumber] = true
+	}
+
+	return respondToEntries(f, effectiveEntryDate, traceNumbers, "", func(bh *BatchHeader, dishonored *EntryDetail, seq int) (*EntryDetail, error) {
+		req := wanted[dishonored.TraceNumber]
+		if dishonored.Addenda99Dishonored == nil {
+			return nil, errors.New("entry is not a dishonored return")
+		}
+
+		settlementDate, err := settlementDateOrDefault(req.DishonoredReturnSettlementDate, bh.SettlementDate, effectiveEntryDate)
+		if err != nil {
+			return nil, fmt.Errorf("dishonored return settlement date: %w", err)
+		}
+		if err := withinBankingDays(settlementDate, effectiveEntryDate, contestedReturnBankingDays); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrContestedReturnTimeLimit, err)
+		}
+
+		ed := respondingEntryDetail(bh, dishonored, dishonored.TransactionCode, CategoryDishonoredReturnContested)
+		if bh.StandardEntryClassCode == CTX || bh.StandardEntryClassCode == ATX {
+			ed.SetCATXAddendaRecords(1)
+		}
+
+		contested := NewAddenda99Contested()
+		contested.ContestedReturnCode = req.ReturnCode
+		contested.OriginalEntryTraceNumber = dishonored.Addenda99Dishonored.OriginalEntryTraceNumber
+		contested.DateOriginalEntryReturned = req.DateOriginalEntryReturned.Format("060102")
+		contested.OriginalReceivingDFIIdentification = dishonored.Addenda99Dishonored.OriginalReceivingDFIIdentification
+		contested.OriginalSettlementDate = julianDay(req.OriginalSettlementDate)
+		contested.ReturnTraceNumber = dishonored.Addenda99Dishonored.ReturnTraceNumber
+		contested.ReturnSettlementDate = dishonored.Addenda99Dishonored.ReturnSettlementDate
+		contested.ReturnReasonCode = dishonored.Addenda99Dishonored.ReturnReasonCode
+		contested.DishonoredReturnTraceNumber = dishonored.TraceNumber
+		contested.DishonoredReturnSettlementDate = julianDay(settlementDate)
+		contested.DishonoredReturnReasonCode = strings.TrimPrefix(dishonored.Addenda99Dishonored.DishonoredReturnReasonCode, "R")
+		contested.SetValidation(f.validateOpts)
+		ed.Addenda99Contested = contested
+
+		ed.SetTraceNumber(dishonored.RDFIIdentification, seq)
+		return ed, nil
+	})
+}
+
+// settlementDateOrDefault returns date when it's set, otherwise the Julian settlementDate from a batch header
+// is converted into the most recent matching date on or before effectiveEntryDate.
+func settlementDateOrDefault(date time.Time, settlementDate string, effectiveEntryDate time.Time) (time.Time, error) {
+	if !date.IsZero() {
+		return date, nil
+	}
+	settlementDate = strings.TrimSpace(settlementDate)
+	if settlementDate == "" {
+		return time.Time{}, errors.New("missing settlement date")
+	}
+	day, err := strconv.Atoi(settlementDate)
+	if err != nil || day < 1 || day > 366 {
+		return time.Time{}, fmt.Errorf("invalid julian settlement date %q", settlementDate)
+	}
+	out := time.Date(effectiveEntryDate.Year(), time.January, 1, 0, 0, 0, 0, effectiveEntryDate.Location()).AddDate(0, 0, day-1)
+	if out.After(effectiveEntryDate) {
+		out = time.Date(effectiveEntryDate.Year()-1, time.January, 1, 0, 0, 0, 0, effectiveEntryDate.Location()).AddDate(0, 0, day-1)
+	}
+	return out, nil
+}
+
+// withinBankingDays checks that when occurs no later than days banking days after settlementDate.
+func withinBankingDays(settlementDate, when time.Time, days int) error {
+	deadline := base.NewTime(settlementDate).AddBankingDay(days).Time
+	deadline = time.Date(deadline.Year(), deadline.Month(), deadline.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(when.Year(), when.Month(), when.Day(), 0, 0, 0, 0, time.UTC)
+	if day.After(deadline) {
+		return fmt.Errorf("%s is after %s", day.Format("2006-01-02"), deadline.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// julianDay formats t as the three digit day of the year used in Settlement Date fields.
+func julianDay(t time.Time) string {
+	return fmt.Sprintf("%03d", t.YearDay())
+}
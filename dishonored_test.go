@@ -0,0 +1,182 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_DishonoredReturn(t *testing.T) {
+	forward, err := ReadFile(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	returnDate := time.Date(2025, time.June, 13, 9, 0, 0, 0, time.UTC)
+	returned, err := forward.Return(returnDate, []ReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R01"},
+	})
+	require.NoError(t, err)
+
+	// The ACH Operator inserts the Settlement Date as a Julian day, 2025-06-16
+	returned.Batches[0].GetHeader().SettlementDate = "167"
+	returnTraceNumber := returned.Batches[0].GetEntries()[0].TraceNumber
+
+	// Juneteenth (2025-06-19) is skipped, so the fifth banking day is 2025-06-24
+	_, err = returned.DishonoredReturn(time.Date(2025, time.June, 25, 9, 0, 0, 0, time.UTC), []DishonoredReturnEntry{
+		{TraceNumber: returnTraceNumber, ReturnCode: "R69"},
+	})
+	require.ErrorIs(t, err, ErrDishonoredReturnTimeLimit)
+
+	dishonoredDate := time.Date(2025, time.June, 24, 9, 0, 0, 0, time.UTC)
+	dishonored, err := returned.DishonoredReturn(dishonoredDate, []DishonoredReturnEntry{
+		{TraceNumber: returnTraceNumber, ReturnCode: "R69", AddendaInformation: "01"},
+	})
+	require.NoError(t, err)
+
+	// Dishonored returns go back to the RDFI which returned the entry
+	require.Equal(t, returned.Header.ImmediateOrigin, dishonored.Header.ImmediateDestination)
+	require.Len(t, dishonored.Batches, 1)
+	require.Equal(t, "12104288", dishonored.Batches[0].GetHeader().ODFIIdentification)
+
+	entries := dishonored.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.Equal(t, CategoryDishonoredReturn, entries[0].Category)
+	require.Equal(t, CheckingReturnNOCDebit, entries[0].TransactionCode)
+	require.Equal(t, "23138010", entries[0].RDFIIdentification)
+	require.Equal(t, 100000000, entries[0].Amount)
+
+	addenda := entries[0].Addenda99Dishonored
+	require.NotNil(t, addenda)
+	require.Equal(t, "R69", addenda.DishonoredReturnReasonCode)
+	require.Equal(t, "121042880000001", addenda.OriginalEntryTraceNumber)
+	require.Equal(t, "23138010", addenda.OriginalReceivingDFIIdentification)
+	require.Equal(t, returnTraceNumber, addenda.ReturnTraceNumber)
+	require.Equal(t, "167", addenda.ReturnSettlementDate)
+	require.Equal(t, "01", addenda.ReturnReasonCode)
+	require.Equal(t, entries[0].TraceNumber, addenda.TraceNumber)
+
+	// The dishonored file can be written and read back
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf).Write(dishonored))
+	parsed, err := NewReader(&buf).Read()
+	require.NoError(t, err)
+	require.NotNil(t, parsed.Batches[0].GetEntries()[0].Addenda99Dishonored)
+
+	// Contest the dishonored return, settled on 2025-06-24
+	dishonoredTraceNumber := entries[0].TraceNumber
+	contest := []ContestedReturnEntry{
+		{
+			TraceNumber:                    dishonoredTraceNumber,
+			ReturnCode:                     "R73",
+			DateOriginalEntryReturned:      returnDate,
+			OriginalSettlementDate:         time.Date(2025, time.June, 12, 0, 0, 0, 0, time.UTC),
+			DishonoredReturnSettlementDate: dishonoredDate,
+		},
+	}
+	_, err = dishonored.ContestedDishonoredReturn(time.Date(2025, time.June, 27, 9, 0, 0, 0, time.UTC), contest)
+	require.ErrorIs(t, err, ErrContestedReturnTimeLimit)
+
+	contested, err := dishonored.ContestedDishonoredReturn(time.Date(2025, time.June, 26, 9, 0, 0, 0, time.UTC), contest)
+	require.NoError(t, err)
+
+	require.Equal(t, "23138010", contested.Batches[0].GetHeader().ODFIIdentification)
+
+	entries = contested.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.Equal(t, CategoryDishonoredReturnContested, entries[0].Category)
+	require.Equal(t, "12104288", entries[0].RDFIIdentification)
+
+	contestedAddenda := entries[0].Addenda99Contested
+	require.NotNil(t, contestedAddenda)
+	require.Equal(t, "R73", contestedAddenda.ContestedReturnCode)
+	require.Equal(t, "121042880000001", contestedAddenda.OriginalEntryTraceNumber)
+	require.Equal(t, "250613", contestedAddenda.DateOriginalEntryReturned)
+	require.Equal(t, "23138010", contestedAddenda.OriginalReceivingDFIIdentification)
+	require.Equal(t, "163", contestedAddenda.OriginalSettlementDate)
+	require.Equal(t, returnTraceNumber, contestedAddenda.ReturnTraceNumber)
+	require.Equal(t, "167", contestedAddenda.ReturnSettlementDate)
+	require.Equal(t, "01", contestedAddenda.ReturnReasonCode)
+	require.Equal(t, dishonoredTraceNumber, contestedAddenda.DishonoredReturnTraceNumber)
+	require.Equal(t, "175", contestedAddenda.DishonoredReturnSettlementDate)
+	require.Equal(t, "69", contestedAddenda.DishonoredReturnReasonCode)
+
+	buf.Reset()
+	require.NoError(t, NewWriter(&buf).Write(contested))
+	parsed, err = NewReader(&buf).Read()
+	require.NoError(t, err)
+	require.NotNil(t, parsed.Batches[0].GetEntries()[0].Addenda99Contested)
+}
+
+func TestFile_DishonoredReturnErrors(t *testing.T) {
+	forward, err := ReadFile(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	now := time.Now().In(time.UTC)
+
+	_, err = forward.DishonoredReturn(now, nil)
+	require.ErrorIs(t, err, ErrDishonoredReturnNoEntries)
+
+	_, err = forward.DishonoredReturn(now, []DishonoredReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R01"},
+	})
+	require.ErrorIs(t, err, ErrAddenda99DishonoredReturnCode)
+
+	_, err = forward.DishonoredReturn(now, []DishonoredReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R61", ReturnSettlementDate: now},
+	})
+	require.ErrorContains(t, err, "not a return")
+
+	_, err = forward.ContestedDishonoredReturn(now, []ContestedReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R69"},
+	})
+	require.ErrorIs(t, err, ErrAddenda99ContestedReturnCode)
+
+	_, err = forward.ContestedDishonoredReturn(now, []ContestedReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R71"},
+	})
+	require.ErrorContains(t, err, "missing DateOriginalEntryReturned")
+}
+
+func TestSettlementDateOrDefault(t *testing.T) {
+	effectiveEntryDate := time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC)
+
+	// explicit dates are used as-is
+	date := time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC)
+	got, err := settlementDateOrDefault(date, "001", effectiveEntryDate)
+	require.NoError(t, err)
+	require.Equal(t, date, got)
+
+	got, err = settlementDateOrDefault(time.Time{}, "002", effectiveEntryDate)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC), got)
+
+	// Julian days after the effective date are from the previous year
+	got, err = settlementDateOrDefault(time.Time{}, "365", effectiveEntryDate)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC), got)
+
+	_, err = settlementDateOrDefault(time.Time{}, "   ", effectiveEntryDate)
+	require.ErrorContains(t, err, "missing settlement date")
+
+	_, err = settlementDateOrDefault(time.Time{}, "400", effectiveEntryDate)
+	require.ErrorContains(t, err, "invalid julian settlement date")
+}
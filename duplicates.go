@@ -0,0 +1,540 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDuplicate is wrapped by DuplicatesError when a File or its entries have been seen before.
+var ErrDuplicate = errors.New("duplicate found")
+
+// DuplicateKind describes how a File or entry matched one seen before.
+type DuplicateKind string
+
+const (
+	// DuplicateFile is a File with the same ImmediateOrigin, ImmediateDestination, FileCreationDate,
+	// FileCreationTime and FileIDModifier as one seen before.
+	DuplicateFile DuplicateKind = "file"
+	// DuplicateEntry is an entry with the same TraceNumber, Amount, DFIAccountNumber, RDFI and
+	// EffectiveEntryDate as one seen before.
+	DuplicateEntry DuplicateKind = "entry"
+	// SuspectedDuplicateEntry is an entry which reuses a TraceNumber or has the same Amount, DFIAccountNumber,
+	// RDFI and EffectiveEntryDate as one seen before.
+	SuspectedDuplicateEntry DuplicateKind = "suspectedEntry"
+)
+
+// Duplicate is a File or entry which matches one seen before.
+type Duplicate struct {
+	Kind DuplicateKind `json:"kind"`
+
+	// TraceNumber is set for duplicate entries.
+	TraceNumber string `json:"traceNumber,omitempty"`
+
+	// Source is where the matching File or entry was recorded. It is blank when the match
+	// was found within the same File.
+	Source string `json:"source,omitempty"`
+}
+
+func (d Duplicate) String() string {
+	var buf strings.Builder
+	buf.WriteString(string(d.Kind))
+	if d.TraceNumber != "" {
+		buf.WriteString(" " + d.TraceNumber)
+	}
+	if d.Source != "" {
+		buf.WriteString(" seen in " + d.Source)
+	} else {
+		buf.WriteString(" repeated in file")
+	}
+	return buf.String()
+}
+
+// DuplicatesError is returned when duplicates are rejected. It contains every duplicate found.
+type DuplicatesError struct {
+	Duplicates []Duplicate `json:"duplicates"`
+}
+
+func (e *DuplicatesError) Error() string {
+	msgs := make([]string, len(e.Duplicates))
+	for i := range e.Duplicates {
+		msgs[i] = e.Duplicates[i].String()
+	}
+	return fmt.Sprintf("%v: %s", ErrDuplicate, strings.Join(msgs, ", "))
+}
+
+func (e *DuplicatesError) Unwrap() error {
+	return ErrDuplicate
+}
+
+// DefaultDuplicateRetention is how long fingerprints are kept when a store is created with a zero retention.
+// It covers the 60 day window in which entries can be returned.
+const DefaultDuplicateRetention = 60 * 24 * time.Hour
+
+// DuplicateStore holds the fingerprints of Files and entries which have been seen.
+// Implementations must be safe for concurrent use and should expire old fingerprints.
+type DuplicateStore interface {
+	// Lookup returns the source a fingerprint was recorded under.
+	Lookup(fingerprint string) (source string, found bool, err error)
+
+	// Record saves fingerprints under source. Fingerprints already recorded keep their first source.
+	Record(source string, fingerprints ...string) error
+}
+
+// DuplicateChecker finds Files and entries which have been seen before by fingerprinting them
+// against a DuplicateStore.
+//
+// ADV entries are not checked as they have no TraceNumber.
+type DuplicateChecker struct {
+	store DuplicateStore
+}
+
+// NewDuplicateChecker returns a DuplicateChecker using store. An in-memory store is used when store is nil.
+func NewDuplicateChecker(store DuplicateStore) *DuplicateChecker {
+	if store == nil {
+		store = NewMemoryDuplicateStore(0)
+	}
+	return &DuplicateChecker{store: store}
+}
+
+// Check returns the duplicates of file found in the store and within file itself.
+// Nothing is recorded, call Record once file has been accepted and saved.
+func (c *DuplicateChecker) Check(file *File) ([]Duplicate, error) {
+	var out []Duplicate
+	seen := make(map[string]bool)
+
+	lookup := func(fingerprint string) (string, bool, error) {
+		if seen[fingerprint] {
+			return "", true, nil
+		}
+		seen[fingerprint] = true
+		return c.store.Lookup(fingerprint)
+	}
+
+	source, found, err := c.store.Lookup(fileFingerprint(file))
+	if err != nil {
+		return nil, fmt.Errorf("looking up file: %w", err)
+	}
+	if found {
+		out = append(out, Duplicate{Kind: DuplicateFile, Source: source})
+	}
+
+	for _, fp := range entryFingerprints(file) {
+		source, found, err := lookup(fp.entry)
+		if err != nil {
+			return nil, fmt.Errorf("looking up entry %s: %w", fp.traceNumber, err)
+		}
+		if found {
+			out = append(out, Duplicate{Kind: DuplicateEntry, TraceNumber: fp.traceNumber, Source: source})
+			continue
+		}
+		for _, suspect := range []string{fp.trace, fp.payment} {
+			source, found, err = lookup(suspect)
+			if err != nil {
+				return nil, fmt.Errorf("looking up entry %s: %w", fp.traceNumber, err)
+			}
+			if found {
+				out = append(out, Duplicate{Kind: SuspectedDuplicateEntry, TraceNumber: fp.traceNumber, Source: source})
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// Record saves the fingerprints of file and its entries under source so later Files are checked against them.
+func (c *DuplicateChecker) Record(file *File, source string) error {
+	return c.record(source, fingerprints(file))
+}
+
+func (c *DuplicateChecker) record(source string, fingerprints []string) error {
+	if err := c.store.Record(source, fingerprints...); err != nil {
+		return fmt.Errorf("recording %s: %w", source, err)
+	}
+	return nil
+}
+
+// pending returns a pendingDuplicates which checks Files against c without recording in it.
+func (c *DuplicateChecker) pending() *pendingDuplicates {
+	accepted := NewMemoryDuplicateStore(0)
+	return &pendingDuplicates{
+		checker: &DuplicateChecker{store: layeredDuplicateStore{c.store, accepted}},
+		target:  c,
+	}
+}
+
+// pendingDuplicates checks Files against a DuplicateChecker and the Files accepted before them.
+// Accepted Files are only recorded in the DuplicateChecker once commit is called, so a failed
+// operation does not mark its Files as seen.
+type pendingDuplicates struct {
+	checker *DuplicateChecker
+	target  *DuplicateChecker
+
+	accepted []pendingFingerprints
+}
+
+type pendingFingerprints struct {
+	source       string
+	fingerprints []string
+}
+
+func (p *pendingDuplicates) Check(file *File) ([]Duplicate, error) {
+	return p.checker.Check(file)
+}
+
+// accept holds the fingerprints of file until commit
+func (p *pendingDuplicates) accept(file *File, source string) error {
+	fps := fingerprints(file)
+	p.accepted = append(p.accepted, pendingFingerprints{source: source, fingerprints: fps})
+	return p.checker.record(source, fps)
+}
+
+// commit records every accepted File in the DuplicateChecker
+func (p *pendingDuplicates) commit() error {
+	if p == nil {
+		return nil
+	}
+	for _, a := range p.accepted {
+		if err := p.target.record(a.source, a.fingerprints); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// layeredDuplicateStore looks up fingerprints in both stores and records them in pending only
+type layeredDuplicateStore struct {
+	base, pending DuplicateStore
+}
+
+func (s layeredDuplicateStore) Lookup(fingerprint string) (string, bool, error) {
+	source, found, err := s.base.Lookup(fingerprint)
+	if err != nil || found {
+		return source, found, err
+	}
+	return s.pending.Lookup(fingerprint)
+}
+
+func (s layeredDuplicateStore) Record(source string, fingerprints ...string) error {
+	return s.pending.Record(source, fingerprints...)
+}
+
+func fingerprints(file *File) []string {
+	out := []string{fileFingerprint(file)}
+	for _, fp := range entryFingerprints(file) {
+		out = append(out, fp.entry, fp.trace, fp.payment)
+	}
+	return out
+}
+
+func fileFingerprint(file *File) string {
+	fh := file.Header
+	return "file:" + strings.Join([]string{
+		strings.TrimSpace(fh.ImmediateOrigin),
+		strings.TrimSpace(fh.ImmediateDestination),
+		fh.FileCreationDate,
+		fh.FileCreationTime,
+		fh.FileIDModifier,
+	}, "|")
+}
+
+type entryFingerprint struct {
+	traceNumber string
+
+	entry   string // every field
+	trace   string // TraceNumber only
+	payment string // every field except TraceNumber
+}
+
+func newEntryFingerprint(traceNumber string, amount int, account, rdfi, effectiveEntryDate string) entryFingerprint {
+	payment := strings.Join([]string{
+		strconv.Itoa(amount),
+		strings.TrimSpace(account),
+		rdfi,
+		effectiveEntryDate,
+	}, "|")
+	return entryFingerprint{
+		traceNumber: traceNumber,
+		entry:       "entry:" + traceNumber + "|" + payment,
+		trace:       "trace:" + traceNumber,
+		payment:     "payment:" + payment,
+	}
+}
+
+func entryFingerprints(file *File) []entryFingerprint {
+	var out []entryFingerprint
+	for _, b := range file.Batches {
+		bh := b.GetHeader()
+		if bh == nil {
+			continue
+		}
+		for _, entry := range b.GetEntries() {
+			if entry == nil {
+				continue
+			}
+			out = append(out, newEntryFingerprint(entry.TraceNumber, entry.Amount, entry.DFIAccountNumber,
+				entry.RDFIIdentification+entry.CheckDigit, bh.EffectiveEntryDate))
+		}
+	}
+	for _, b := range file.IATBatches {
+		if b.Header == nil {
+			continue
+		}
+		for _, entry := range b.Entries {
+			if entry == nil {
+				continue
+			}
+			out = append(out, newEntryFingerprint(entry.TraceNumber, entry.Amount, entry.DFIAccountNumber,
+				entry.RDFIIdentification+entry.CheckDigit, b.Header.EffectiveEntryDate))
+		}
+	}
+	return out
+}
+
+// MemoryDuplicateStore is a DuplicateStore which keeps fingerprints in memory.
+// Fingerprints older than its retention are forgotten.
+type MemoryDuplicateStore struct {
+	mu        sync.RWMutex
+	sources   map[string]duplicateSource
+	retention time.Duration
+	pruned    time.Time
+
+	now func() time.Time
+}
+
+type duplicateSource struct {
+	source     string
+	recordedAt time.Time
+}
+
+// NewMemoryDuplicateStore returns an empty MemoryDuplicateStore which keeps fingerprints for retention.
+// DefaultDuplicateRetention is used when retention is zero.
+func NewMemoryDuplicateStore(retention time.Duration) *MemoryDuplicateStore {
+	if retention <= 0 {
+		retention = DefaultDuplicateRetention
+	}
+	return &MemoryDuplicateStore{
+		sources:   make(map[string]duplicateSource),
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryDuplicateStore) Lookup(fingerprint string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	src, found := s.sources[fingerprint]
+	if !found || s.expired(src, s.now()) {
+		return "", false, nil
+	}
+	return src.source, true, nil
+}
+
+func (s *MemoryDuplicateStore) Record(source string, fingerprints ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.prune(now)
+	s.record(source, now, fingerprints...)
+	return nil
+}
+
+func (s *MemoryDuplicateStore) expired(src duplicateSource, now time.Time) bool {
+	return now.Sub(src.recordedAt) >= s.retention
+}
+
+// record adds fingerprints which are not already known (or have expired) and returns them
+func (s *MemoryDuplicateStore) record(source string, recordedAt time.Time, fingerprints ...string) []string {
+	var added []string
+	for _, fp := range fingerprints {
+		if src, exists := s.sources[fp]; !exists || s.expired(src, recordedAt) {
+			s.sources[fp] = duplicateSource{source: source, recordedAt: recordedAt}
+			added = append(added, fp)
+		}
+	}
+	return added
+}
+
+// prune removes expired fingerprints, at most once a minute, and returns how many were removed
+func (s *MemoryDuplicateStore) prune(now time.Time) int {
+	if now.Sub(s.pruned) < time.Minute {
+		return 0
+	}
+	s.pruned = now
+
+	removed := 0
+	for fp, src := range s.sources {
+		if s.expired(src, now) {
+			delete(s.sources, fp)
+			removed++
+		}
+	}
+	return removed
+}
+
+// DiskDuplicateStore is a DuplicateStore which keeps fingerprints in memory and appends them
+// to a file so they are kept across restarts. The file is rewritten without expired fingerprints
+// when it is opened and when expired fingerprints are pruned.
+type DiskDuplicateStore struct {
+	mem  *MemoryDuplicateStore
+	path string
+	fd   *os.File
+}
+
+type diskDuplicateRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	Source      string    `json:"source"`
+	RecordedAt  time.Time `json:"recordedAt"`
+}
+
+// NewDiskDuplicateStore opens (or creates) the file at path and reads every fingerprint recorded in it
+// within retention. DefaultDuplicateRetention is used when retention is zero.
+func NewDiskDuplicateStore(path string, retention time.Duration) (*DiskDuplicateStore, error) {
+	fd, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening duplicate store: %w", err)
+	}
+
+	mem := NewMemoryDuplicateStore(retention)
+	now := mem.now()
+	expired := 0
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec diskDuplicateRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			fd.Close()
+			return nil, fmt.Errorf("reading duplicate store line %d: %w", line, err)
+		}
+		if rec.RecordedAt.IsZero() {
+			rec.RecordedAt = now // written before fingerprints expired
+		}
+		if mem.expired(duplicateSource{recordedAt: rec.RecordedAt}, now) {
+			expired++
+			continue
+		}
+		mem.record(rec.Source, rec.RecordedAt, rec.Fingerprint)
+	}
+	if err := scanner.Err(); err != nil {
+		fd.Close()
+		return nil, fmt.Errorf("reading duplicate store: %w", err)
+	}
+
+	store := &DiskDuplicateStore{
+		mem:  mem,
+		path: path,
+		fd:   fd,
+	}
+	if expired > 0 {
+		if err := store.compact(); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func (s *DiskDuplicateStore) Lookup(fingerprint string) (string, bool, error) {
+	return s.mem.Lookup(fingerprint)
+}
+
+func (s *DiskDuplicateStore) Record(source string, fingerprints ...string) error {
+	s.mem.mu.Lock()
+	defer s.mem.mu.Unlock()
+
+	now := s.mem.now()
+	pruned := s.mem.prune(now)
+	added := s.mem.record(source, now, fingerprints...)
+	if pruned > 0 {
+		return s.compact()
+	}
+
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	for _, fp := range added {
+		if err := enc.Encode(diskDuplicateRecord{Fingerprint: fp, Source: source, RecordedAt: now}); err != nil {
+			return err
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	if _, err := s.fd.WriteString(buf.String()); err != nil {
+		return fmt.Errorf("writing duplicate store: %w", err)
+	}
+	return nil
+}
+
+// compact replaces the file with the fingerprints held in memory. The caller must hold s.mem.mu
+// or be the only user of s.
+func (s *DiskDuplicateStore) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("compacting duplicate store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for fp, src := range s.mem.sources {
+		if err := enc.Encode(diskDuplicateRecord{Fingerprint: fp, Source: src.source, RecordedAt: src.recordedAt}); err != nil {
+			tmp.Close()
+			return fmt.Errorf("compacting duplicate store: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("compacting duplicate store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("compacting duplicate store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("compacting duplicate store: %w", err)
+	}
+
+	fd, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("opening duplicate store: %w", err)
+	}
+	s.fd.Close()
+	s.fd = fd
+	return nil
+}
+
+// Close closes the underlying file
+func (s *DiskDuplicateStore) Close() error {
+	if s == nil || s.fd == nil {
+		return nil
+	}
+	return s.fd.Close()
+}
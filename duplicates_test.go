@@ -0,0 +1,271 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateChecker(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	checker := NewDuplicateChecker(nil)
+	found, err := checker.Check(file)
+	require.NoError(t, err)
+	require.Empty(t, found)
+	require.NoError(t, checker.Record(file, "first.ach"))
+
+	t.Run("exact", func(t *testing.T) {
+		found, err := checker.Check(file)
+		require.NoError(t, err)
+		require.Equal(t, []Duplicate{
+			{Kind: DuplicateFile, Source: "first.ach"},
+			{Kind: DuplicateEntry, TraceNumber: "121042880000001", Source: "first.ach"},
+		}, found)
+	})
+
+	t.Run("suspected", func(t *testing.T) {
+		other, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+		other.Header.FileIDModifier = "B"
+
+		// same payment with a new trace number
+		other.Batches[0].GetEntries()[0].TraceNumber = "121042880000002"
+		found, err := checker.Check(other)
+		require.NoError(t, err)
+		require.Equal(t, []Duplicate{
+			{Kind: SuspectedDuplicateEntry, TraceNumber: "121042880000002", Source: "first.ach"},
+		}, found)
+
+		// trace number reused for a different payment
+		other.Batches[0].GetEntries()[0].TraceNumber = "121042880000001"
+		other.Batches[0].GetEntries()[0].Amount += 1
+		found, err = checker.Check(other)
+		require.NoError(t, err)
+		require.Equal(t, []Duplicate{
+			{Kind: SuspectedDuplicateEntry, TraceNumber: "121042880000001", Source: "first.ach"},
+		}, found)
+	})
+
+	t.Run("within file", func(t *testing.T) {
+		file := mockMergeFile(t, "ACME", "", 1, 1)
+		b := file.Batches[0].(*BatchPPD)
+		b.Entries = append(b.Entries, b.Entries[0])
+
+		found, err := NewDuplicateChecker(nil).Check(file)
+		require.NoError(t, err)
+		require.Equal(t, []Duplicate{
+			{Kind: DuplicateEntry, TraceNumber: b.Entries[0].TraceNumber},
+		}, found)
+
+		err = &DuplicatesError{Duplicates: found}
+		require.ErrorIs(t, err, ErrDuplicate)
+		require.Equal(t, "duplicate found: entry 121042880000001 repeated in file", err.Error())
+	})
+
+	t.Run("IAT", func(t *testing.T) {
+		iat, err := readACHFilepath(filepath.Join("test", "testdata", "20180713-IAT.ach"))
+		require.NoError(t, err)
+
+		checker := NewDuplicateChecker(nil)
+		require.NoError(t, checker.Record(iat, "iat.ach"))
+		found, err := checker.Check(iat)
+		require.NoError(t, err)
+		require.Len(t, found, 1+len(entryFingerprints(iat)))
+		require.Equal(t, DuplicateEntry, found[1].Kind)
+	})
+}
+
+func TestDiskDuplicateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "duplicates.json")
+
+	store, err := NewDiskDuplicateStore(path, 0)
+	require.NoError(t, err)
+	require.NoError(t, store.Record("a.ach", "file:1", "entry:1"))
+	require.NoError(t, store.Record("b.ach", "entry:1", "entry:2"))
+	require.NoError(t, store.Close())
+
+	store, err = NewDiskDuplicateStore(path, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	source, found, err := store.Lookup("entry:1")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "a.ach", source)
+
+	source, found, err = store.Lookup("entry:2")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "b.ach", source)
+
+	_, found, err = store.Lookup("entry:3")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	t.Run("retention", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "duplicates.json")
+		now := time.Now()
+
+		store, err := NewDiskDuplicateStore(path, time.Hour)
+		require.NoError(t, err)
+		store.mem.now = func() time.Time { return now.Add(-2 * time.Hour) }
+		require.NoError(t, store.Record("old.ach", "entry:1"))
+		store.mem.now = func() time.Time { return now }
+		require.NoError(t, store.Record("new.ach", "entry:2"))
+
+		_, found, err := store.Lookup("entry:1")
+		require.NoError(t, err)
+		require.False(t, found)
+		require.NoError(t, store.Close())
+
+		// Expired fingerprints are dropped from the file when it's opened
+		store, err = NewDiskDuplicateStore(path, time.Hour)
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+
+		bs, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NotContains(t, string(bs), "entry:1")
+		require.Contains(t, string(bs), "entry:2")
+		require.NoError(t, store.Record("c.ach", "entry:3"))
+
+		_, found, err = store.Lookup("entry:3")
+		require.NoError(t, err)
+		require.True(t, found)
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "duplicates.json")
+		require.NoError(t, os.WriteFile(path, []byte("{\n"), 0600))
+
+		_, err := NewDiskDuplicateStore(path, 0)
+		require.ErrorContains(t, err, "line 1")
+	})
+}
+
+func TestMemoryDuplicateStore_Retention(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryDuplicateStore(time.Hour)
+	store.now = func() time.Time { return now }
+	require.NoError(t, store.Record("a.ach", "entry:1"))
+
+	_, found, err := store.Lookup("entry:1")
+	require.NoError(t, err)
+	require.True(t, found)
+
+	now = now.Add(2 * time.Hour)
+	_, found, err = store.Lookup("entry:1")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.NoError(t, store.Record("b.ach", "entry:2"))
+	require.Len(t, store.sources, 1)
+
+	// Expired fingerprints are recorded again under their new source
+	require.NoError(t, store.Record("c.ach", "entry:1"))
+	source, found, err := store.Lookup("entry:1")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "c.ach", source)
+}
+
+type failingDuplicateStore struct{}
+
+func (failingDuplicateStore) Lookup(string) (string, bool, error) {
+	return "", false, errors.New("lookup failed")
+}
+
+func (failingDuplicateStore) Record(string, ...string) error {
+	return errors.New("record failed")
+}
+
+func TestMergeDir_Duplicates(t *testing.T) {
+	dir := t.TempDir()
+	bs, err := os.ReadFile(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.ach"), bs, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.ach"), bs, 0600))
+
+	t.Run("reject", func(t *testing.T) {
+		var reported []Duplicate
+		merged, err := MergeDir(dir, Conditions{}, &MergeDirOptions{
+			DuplicateChecker: NewDuplicateChecker(nil),
+			RejectDuplicates: true,
+			OnDuplicates: func(path string, duplicates []Duplicate) {
+				reported = append(reported, duplicates...)
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, merged, 1)
+		require.Len(t, merged[0].Batches, 1) // second file was not merged
+		require.Len(t, reported, 2)
+		require.Equal(t, DuplicateFile, reported[0].Kind)
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		var reported int
+		merged, err := MergeDir(dir, Conditions{}, &MergeDirOptions{
+			DuplicateChecker: NewDuplicateChecker(nil),
+			OnDuplicates: func(path string, duplicates []Duplicate) {
+				reported += len(duplicates)
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, merged, 1)
+		require.Len(t, merged[0].Batches, 2)
+		require.Equal(t, 2, reported)
+	})
+
+	t.Run("failed merge is not recorded", func(t *testing.T) {
+		checker := NewDuplicateChecker(nil)
+		_, err := MergeDir(dir, Conditions{Limits: &Limits{MaxEntryAmount: 1}}, &MergeDirOptions{
+			DuplicateChecker: checker,
+			RejectDuplicates: true,
+		})
+		require.ErrorIs(t, err, ErrLimitsExceeded)
+
+		// Retrying merges the first file instead of rejecting both
+		merged, err := MergeDir(dir, Conditions{}, &MergeDirOptions{
+			DuplicateChecker: checker,
+			RejectDuplicates: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, merged, 1)
+		require.Len(t, merged[0].Batches, 1)
+
+		// Merged files are recorded once merging succeeds
+		found, err := checker.Check(merged[0])
+		require.NoError(t, err)
+		require.NotEmpty(t, found)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		_, err := MergeDir(dir, Conditions{}, &MergeDirOptions{
+			DuplicateChecker: NewDuplicateChecker(failingDuplicateStore{}),
+		})
+		require.ErrorContains(t, err, "lookup failed")
+	})
+}
@@ -0,0 +1,142 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package edi820
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/moov-io/ach"
+)
+
+const (
+	// recordLength is the length of PaymentRelatedInformation in an Addenda05 record
+	recordLength = 80
+
+	// maxRecords is the most Addenda05 records a CTX entry can have
+	maxRecords = 9999
+)
+
+// ErrTooManyAddenda is returned when a remittance needs more Addenda05 records than a CTX entry can have
+var ErrTooManyAddenda = errors.New("remittance needs more than 9999 addenda records")
+
+// Join reassembles the PaymentRelatedInformation of Addenda05 records in SequenceNumber order.
+//
+// X12 data continues from one record into the next, so each record except the last is padded
+// back to 80 characters after the trailing spaces were trimmed while reading the record.
+func Join(records []*ach.Addenda05) string {
+	sorted := make([]*ach.Addenda05, 0, len(records))
+	for i := range records {
+		if records[i] != nil {
+			sorted = append(sorted, records[i])
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SequenceNumber < sorted[j].SequenceNumber
+	})
+
+	var buf strings.Builder
+	for i := range sorted {
+		buf.WriteString(sorted[i].PaymentRelatedInformation)
+		if i < len(sorted)-1 {
+			if n := utf8.RuneCountInString(sorted[i].PaymentRelatedInformation); n < recordLength {
+				buf.WriteString(strings.Repeat(" ", recordLength-n))
+			}
+		}
+	}
+	return buf.String()
+}
+
+// FromEntry parses the remittance carried in the Addenda05 records of ed.
+func FromEntry(ed *ach.EntryDetail) (*Remittance, error) {
+	if ed == nil || len(ed.Addenda05) == 0 {
+		return nil, fmt.Errorf("%w: no addenda records", ErrInvalidFormat)
+	}
+	return Parse(Join(ed.Addenda05))
+}
+
+// Addenda05 splits the remittance into Addenda05 records with SequenceNumber starting at 1.
+//
+// Segments are packed into records without splitting them when they fit, otherwise a segment
+// continues into the next record.
+func (r *Remittance) Addenda05() ([]*ach.Addenda05, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	d := r.delimiters()
+
+	var (
+		chunks  []string
+		current []rune
+	)
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, string(current))
+			current = nil
+		}
+	}
+	for _, seg := range r.segments() {
+		text := []rune(segmentString(seg, d))
+		if len(current)+len(text) > recordLength && len(text) <= recordLength {
+			flush()
+		}
+		for len(text) > 0 {
+			n := min(recordLength-len(current), len(text))
+			current = append(current, text[:n]...)
+			text = text[n:]
+			if len(current) == recordLength {
+				flush()
+			}
+		}
+	}
+	flush()
+
+	if len(chunks) > maxRecords {
+		return nil, fmt.Errorf("%w: needs %d records", ErrTooManyAddenda, len(chunks))
+	}
+	out := make([]*ach.Addenda05, len(chunks))
+	for i := range chunks {
+		out[i] = ach.NewAddenda05()
+		out[i].PaymentRelatedInformation = chunks[i]
+		out[i].SequenceNumber = i + 1
+	}
+	return out, nil
+}
+
+// SetCTXAddenda replaces the Addenda05 records of a CTX entry with the remittance and updates
+// its AddendaRecordIndicator and number of addenda records (SetCATXAddendaRecords).
+func SetCTXAddenda(ed *ach.EntryDetail, r *Remittance) error {
+	records, err := r.Addenda05()
+	if err != nil {
+		return err
+	}
+	if trace := ed.TraceNumberField(); len(trace) == 15 {
+		seq, _ := strconv.Atoi(trace[8:])
+		for i := range records {
+			records[i].EntryDetailSequenceNumber = seq
+		}
+	}
+	ed.Addenda05 = records
+	ed.SetCATXAddendaRecords(len(records))
+	ed.AddendaRecordIndicator = 1
+	return nil
+}
@@ -0,0 +1,170 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package edi820
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/moov-io/ach"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromEntry(t *testing.T) {
+	fd, err := os.Open(filepath.Join("..", "test", "testdata", "nonascii-utf8.ach"))
+	require.NoError(t, err)
+	t.Cleanup(func() { fd.Close() })
+
+	file, err := ach.NewReader(fd).Read()
+	require.NoError(t, err)
+
+	ed := file.Batches[0].GetEntries()[0]
+	require.Len(t, ed.Addenda05, 12)
+
+	r, err := FromEntry(ed)
+	require.NoError(t, err)
+
+	require.Equal(t, Delimiters{Element: '¦', Component: '^', Segment: '~'}, r.Delimiters)
+	require.Equal(t, "PAYEXPENSEPAY", r.Interchange.SenderID)
+	require.Equal(t, "17587397", r.Group.ControlNumber)
+	require.Equal(t, "0069", r.ControlNumber)
+
+	require.Equal(t, "1352.88", r.Payment.Amount)
+	require.Equal(t, "026009593", r.Payment.OriginatingDFI)
+	require.Equal(t, "8201665019", r.Payment.OriginatorID) // spans two records
+	require.Equal(t, "20230629", r.Payment.EffectiveDate)
+
+	require.Len(t, r.References, 10)
+	require.Equal(t, Reference{Qualifier: "VI", Description: "/PHON 888-888-8888"}, r.References[5])
+
+	require.Len(t, r.Details, 2)
+	require.Equal(t, "IK", r.Details[0].ReferenceQualifier)
+
+	_, err = FromEntry(ach.NewEntryDetail())
+	require.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+func TestJoin(t *testing.T) {
+	a1 := ach.NewAddenda05()
+	a1.PaymentRelatedInformation = "ST*820*1\\BPR*C*1.00*C" // trimmed before the record ended
+	a1.SequenceNumber = 1
+
+	a2 := ach.NewAddenda05()
+	a2.PaymentRelatedInformation = "*ACH*CTX\\SE*3*1\\"
+	a2.SequenceNumber = 2
+
+	joined := Join([]*ach.Addenda05{a2, nil, a1})
+	require.Equal(t, 80+len(a2.PaymentRelatedInformation), len(joined))
+	require.True(t, strings.HasPrefix(joined, a1.PaymentRelatedInformation))
+	require.True(t, strings.HasSuffix(joined, a2.PaymentRelatedInformation))
+}
+
+func TestRemittance__Addenda05(t *testing.T) {
+	r := sampleRemittance()
+
+	records, err := r.Addenda05()
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+
+	for i := range records {
+		require.Equal(t, i+1, records[i].SequenceNumber)
+		require.LessOrEqual(t, utf8.RuneCountInString(records[i].PaymentRelatedInformation), 80)
+	}
+	// The ISA segment is longer than a record so continues into the next
+	require.Equal(t, 80, utf8.RuneCountInString(records[0].PaymentRelatedInformation))
+	// Segments which fit in a record aren't split
+	require.True(t, strings.HasPrefix(records[4].PaymentRelatedInformation, "RMR*IV*INV-101"))
+
+	parsed, err := Parse(Join(records))
+	require.NoError(t, err)
+	require.Equal(t, r.String(), parsed.String())
+
+	t.Run("delimiter", func(t *testing.T) {
+		r := sampleRemittance()
+		r.Payment.OriginatorID = `ABC\123`
+		_, err := r.Addenda05()
+		require.ErrorIs(t, err, ErrDelimiter)
+	})
+
+	t.Run("too many", func(t *testing.T) {
+		r := &Remittance{ControlNumber: "1"}
+		for i := 0; i < maxRecords; i++ {
+			r.Details = append(r.Details, Detail{ReferenceQualifier: "IV", ReferenceID: strings.Repeat("A", 70)})
+		}
+		_, err := r.Addenda05()
+		require.ErrorIs(t, err, ErrTooManyAddenda)
+	})
+}
+
+func TestSetCTXAddenda(t *testing.T) {
+	bh := ach.NewBatchHeader()
+	bh.ServiceClassCode = ach.CreditsOnly
+	bh.CompanyName = "Originator"
+	bh.CompanyIdentification = "121042882"
+	bh.StandardEntryClassCode = ach.CTX
+	bh.CompanyEntryDescription = "PAYMENT"
+	bh.EffectiveEntryDate = "240108"
+	bh.ODFIIdentification = "12104288"
+
+	ed := ach.NewEntryDetail()
+	ed.TransactionCode = ach.CheckingCredit
+	ed.SetRDFI("231380104")
+	ed.DFIAccountNumber = "12345678"
+	ed.Amount = 150000
+	ed.IdentificationNumber = "45689033"
+	ed.SetCATXReceivingCompany("Receiver Company")
+	ed.SetTraceNumber(bh.ODFIIdentification, 3)
+
+	r := sampleRemittance()
+	require.NoError(t, SetCTXAddenda(ed, r))
+	require.Equal(t, "0006", ed.CATXAddendaRecordsField())
+	require.Equal(t, "Receiver Company", strings.TrimSpace(ed.CATXReceivingCompanyField()))
+	require.Equal(t, 1, ed.AddendaRecordIndicator)
+	require.Equal(t, 3, ed.Addenda05[5].EntryDetailSequenceNumber)
+
+	batch := ach.NewBatchCTX(bh)
+	batch.AddEntry(ed)
+	require.NoError(t, batch.Create())
+
+	fh := ach.NewFileHeader()
+	fh.ImmediateDestination = "231380104"
+	fh.ImmediateOrigin = "121042882"
+	fh.FileCreationDate = "240105"
+	fh.ImmediateDestinationName = "Federal Reserve Bank"
+	fh.ImmediateOriginName = "My Bank Name"
+
+	file := ach.NewFile()
+	file.SetHeader(fh)
+	file.AddBatch(batch)
+	require.NoError(t, file.Create())
+
+	var buf bytes.Buffer
+	require.NoError(t, ach.NewWriter(&buf).Write(file))
+
+	read, err := ach.NewReader(&buf).Read()
+	require.NoError(t, err)
+
+	parsed, err := FromEntry(read.Batches[0].GetEntries()[0])
+	require.NoError(t, err)
+	require.Equal(t, r.String(), parsed.String())
+}
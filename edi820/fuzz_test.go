@@ -0,0 +1,51 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package edi820
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func FuzzParse(f *testing.F) {
+	examples := []string{
+		sampleRemittance().String(),
+		"ST|820|1234~BPR|C|10.00|C|ACH|CTX~RMR|IV|A1||10.00~SE|4|1234~",
+		`RMR*IV*A1**10.00\RMR*IV*A2**5.00\`,
+		`ST*820*1\BPR*C\SE*3*1\`,
+		`ST*820*1\SE*2*1\ST*820*2\SE*2*2\`,
+		"ISA*00*",
+		"ISA¦00¦          ¦00¦          ¦ZZ¦PAYEXPENSEPAY  ¦ZZ¦PAYAECSUSO     ¦230628¦0219¦U¦00401¦017587397¦0¦P¦^~",
+		"*",
+		"~~~",
+	}
+	for i := range examples {
+		f.Add(examples[i])
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		require.NotPanics(t, func() {
+			r, _ := Parse(input)
+			if r != nil {
+				_ = r.String()
+				r.Addenda05()
+			}
+		})
+	})
+}
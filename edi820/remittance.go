@@ -0,0 +1,552 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package edi820 reads and writes ANSI ASC X12 820 (Payment Order/Remittance Advice) transaction
+// sets which are carried in the Addenda05 records of CTX and CCD entries.
+//
+// An 820 is made up of segments, each starting with an identifier and separated into elements.
+// The segments ISA, GS, ST, BPR, RMR, REF, DTM and SE are parsed into Go structs. Other segments
+// are kept as a Segment so they are written back out.
+package edi820
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrInvalidFormat is returned when remittance data can't be split into X12 segments
+	ErrInvalidFormat = errors.New("invalid X12 820 format")
+
+	// ErrTransactionSet is returned when the transaction set isn't an 820
+	ErrTransactionSet = errors.New("transaction set is not an 820")
+
+	// ErrSegmentCount is returned when the SE segment's count or control number doesn't match its transaction set
+	ErrSegmentCount = errors.New("SE segment does not match transaction set")
+
+	// ErrDelimiter is returned when an element contains one of the delimiters
+	ErrDelimiter = errors.New("element contains a delimiter")
+)
+
+// Delimiters separate the segments, elements and component elements of an 820.
+type Delimiters struct {
+	Element   rune `json:"element"`
+	Component rune `json:"component"`
+	Segment   rune `json:"segment"`
+}
+
+// DefaultDelimiters are the delimiters NACHA recommends for X12 data in Addenda05 records
+var DefaultDelimiters = Delimiters{
+	Element:   '*',
+	Component: '>',
+	Segment:   '\\',
+}
+
+// Segment is an X12 segment which isn't parsed into its own struct
+type Segment struct {
+	ID       string   `json:"id"`
+	Elements []string `json:"elements"`
+}
+
+// Interchange is the ISA (Interchange Control Header) segment
+type Interchange struct {
+	AuthorizationQualifier   string `json:"authorizationQualifier"`   // ISA01
+	AuthorizationInformation string `json:"authorizationInformation"` // ISA02
+	SecurityQualifier        string `json:"securityQualifier"`        // ISA03
+	SecurityInformation      string `json:"securityInformation"`      // ISA04
+	SenderQualifier          string `json:"senderQualifier"`          // ISA05
+	SenderID                 string `json:"senderID"`                 // ISA06
+	ReceiverQualifier        string `json:"receiverQualifier"`        // ISA07
+	ReceiverID               string `json:"receiverID"`               // ISA08
+	Date                     string `json:"date"`                     // ISA09, YYMMDD
+	Time                     string `json:"time"`                     // ISA10, HHMM
+	RepetitionSeparator      string `json:"repetitionSeparator"`      // ISA11, the standards identifier "U" before version 00402
+	Version                  string `json:"version"`                  // ISA12
+	ControlNumber            string `json:"controlNumber"`            // ISA13
+	AcknowledgmentRequested  string `json:"acknowledgmentRequested"`  // ISA14
+	UsageIndicator           string `json:"usageIndicator"`           // ISA15, P (production) or T (test)
+}
+
+// FunctionalGroup is the GS (Functional Group Header) segment
+type FunctionalGroup struct {
+	FunctionalIdentifierCode string `json:"functionalIdentifierCode"` // GS01, RA for 820s
+	SenderCode               string `json:"senderCode"`               // GS02
+	ReceiverCode             string `json:"receiverCode"`             // GS03
+	Date                     string `json:"date"`                     // GS04, CCYYMMDD
+	Time                     string `json:"time"`                     // GS05
+	ControlNumber            string `json:"controlNumber"`            // GS06
+	ResponsibleAgencyCode    string `json:"responsibleAgencyCode"`    // GS07, X for ASC X12
+	Version                  string `json:"version"`                  // GS08
+}
+
+// Payment is the BPR (Beginning Segment for Payment Order/Remittance Advice) segment
+type Payment struct {
+	TransactionHandlingCode     string `json:"transactionHandlingCode"`     // BPR01
+	Amount                      string `json:"amount"`                      // BPR02, see ParseAmount
+	CreditDebitFlag             string `json:"creditDebitFlag"`             // BPR03, C or D
+	PaymentMethod               string `json:"paymentMethod"`               // BPR04, ACH
+	PaymentFormat               string `json:"paymentFormat"`               // BPR05, CTX or CCP
+	OriginatingDFIQualifier     string `json:"originatingDFIQualifier"`     // BPR06
+	OriginatingDFI              string `json:"originatingDFI"`              // BPR07
+	OriginatingAccountQualifier string `json:"originatingAccountQualifier"` // BPR08
+	OriginatingAccount          string `json:"originatingAccount"`          // BPR09
+	OriginatorID                string `json:"originatorID"`                // BPR10
+	OriginatorSupplementalCode  string `json:"originatorSupplementalCode"`  // BPR11
+	ReceivingDFIQualifier       string `json:"receivingDFIQualifier"`       // BPR12
+	ReceivingDFI                string `json:"receivingDFI"`                // BPR13
+	ReceivingAccountQualifier   string `json:"receivingAccountQualifier"`   // BPR14
+	ReceivingAccount            string `json:"receivingAccount"`            // BPR15
+	EffectiveDate               string `json:"effectiveDate"`               // BPR16, CCYYMMDD
+}
+
+// Reference is a REF (Reference Information) segment
+type Reference struct {
+	Qualifier      string `json:"qualifier"`      // REF01
+	Identification string `json:"identification"` // REF02
+	Description    string `json:"description"`    // REF03
+}
+
+// DateTime is a DTM (Date/Time Reference) segment
+type DateTime struct {
+	Qualifier string `json:"qualifier"` // DTM01
+	Date      string `json:"date"`      // DTM02, CCYYMMDD
+	Time      string `json:"time"`      // DTM03
+}
+
+// Detail is an RMR (Remittance Advice Accounts Receivable Open Item Reference) segment along with
+// the segments which follow it.
+type Detail struct {
+	ReferenceQualifier string `json:"referenceQualifier"` // RMR01, IV for invoices
+	ReferenceID        string `json:"referenceID"`        // RMR02
+	PaymentActionCode  string `json:"paymentActionCode"`  // RMR03
+	Amount             string `json:"amount"`             // RMR04, amount paid
+	InvoiceAmount      string `json:"invoiceAmount"`      // RMR05
+	DiscountAmount     string `json:"discountAmount"`     // RMR06
+
+	References []Reference `json:"references,omitempty"`
+	Dates      []DateTime  `json:"dates,omitempty"`
+	Other      []Segment   `json:"other,omitempty"`
+}
+
+// Remittance is an 820 transaction set and the optional interchange and functional group around it.
+//
+// Segments are written in the order ISA, GS, ST, BPR, REF, DTM, other header segments, then each
+// RMR followed by its REF, DTM and other segments, and finally SE, GE and IEA. The ST and SE segments
+// are only written when ControlNumber is set.
+type Remittance struct {
+	// Delimiters are the delimiters the remittance was parsed with. DefaultDelimiters are used when unset.
+	Delimiters Delimiters `json:"delimiters"`
+
+	Interchange *Interchange     `json:"interchange,omitempty"`
+	Group       *FunctionalGroup `json:"group,omitempty"`
+
+	// ControlNumber is the transaction set control number of ST02 and SE02
+	ControlNumber string `json:"controlNumber"`
+
+	Payment    *Payment    `json:"payment,omitempty"`
+	References []Reference `json:"references,omitempty"`
+	Dates      []DateTime  `json:"dates,omitempty"`
+	Other      []Segment   `json:"other,omitempty"`
+
+	Details []Detail `json:"details,omitempty"`
+}
+
+// Parse reads an 820 from data, which is usually the joined PaymentRelatedInformation of Addenda05 records.
+// The delimiters are read from the ISA segment when there is one, otherwise the element delimiter
+// follows the first segment identifier and the segment terminator is '\' or '~'.
+//
+// The ISA, GS and ST segments are optional so remittance data holding only RMR segments can be parsed.
+// When an ST segment is present it must be closed by an SE segment with a matching count.
+func Parse(data string) (*Remittance, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return nil, ErrInvalidFormat
+	}
+	delims, err := detectDelimiters(data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Remittance{Delimiters: delims}
+	var (
+		detail   *Detail
+		started  bool // ST was read
+		finished bool // SE was read
+		count    int  // segments from ST through SE
+	)
+	for _, seg := range split(data, delims) {
+		if started && !finished {
+			count++
+		}
+		e := func(i int) string {
+			if i < len(seg.Elements) {
+				return seg.Elements[i]
+			}
+			return ""
+		}
+		switch seg.ID {
+		case "ISA":
+			if len(seg.Elements) < 16 {
+				return nil, fmt.Errorf("%w: ISA segment has %d elements", ErrInvalidFormat, len(seg.Elements))
+			}
+			r.Interchange = &Interchange{
+				AuthorizationQualifier:   e(0),
+				AuthorizationInformation: e(1),
+				SecurityQualifier:        e(2),
+				SecurityInformation:      e(3),
+				SenderQualifier:          e(4),
+				SenderID:                 e(5),
+				ReceiverQualifier:        e(6),
+				ReceiverID:               e(7),
+				Date:                     e(8),
+				Time:                     e(9),
+				RepetitionSeparator:      e(10),
+				Version:                  e(11),
+				ControlNumber:            e(12),
+				AcknowledgmentRequested:  e(13),
+				UsageIndicator:           e(14),
+			}
+		case "GS":
+			r.Group = &FunctionalGroup{
+				FunctionalIdentifierCode: e(0),
+				SenderCode:               e(1),
+				ReceiverCode:             e(2),
+				Date:                     e(3),
+				Time:                     e(4),
+				ControlNumber:            e(5),
+				ResponsibleAgencyCode:    e(6),
+				Version:                  e(7),
+			}
+		case "ST":
+			if started {
+				return nil, fmt.Errorf("%w: multiple transaction sets", ErrInvalidFormat)
+			}
+			if e(0) != "820" {
+				return nil, fmt.Errorf("%w: ST01 is %q", ErrTransactionSet, e(0))
+			}
+			started = true
+			count = 1
+			r.ControlNumber = e(1)
+		case "SE":
+			if !started || finished {
+				return nil, fmt.Errorf("%w: SE without ST", ErrInvalidFormat)
+			}
+			finished = true
+			if n, _ := strconv.Atoi(e(0)); n != count {
+				return nil, fmt.Errorf("%w: SE01 is %s but found %d segments", ErrSegmentCount, e(0), count)
+			}
+			if e(1) != r.ControlNumber {
+				return nil, fmt.Errorf("%w: SE02 is %s but ST02 is %s", ErrSegmentCount, e(1), r.ControlNumber)
+			}
+		case "GE", "IEA":
+			// trailers are written from the GS and ISA segments
+		case "BPR":
+			r.Payment = &Payment{
+				TransactionHandlingCode:     e(0),
+				Amount:                      e(1),
+				CreditDebitFlag:             e(2),
+				PaymentMethod:               e(3),
+				PaymentFormat:               e(4),
+				OriginatingDFIQualifier:     e(5),
+				OriginatingDFI:              e(6),
+				OriginatingAccountQualifier: e(7),
+				OriginatingAccount:          e(8),
+				OriginatorID:                e(9),
+				OriginatorSupplementalCode:  e(10),
+				ReceivingDFIQualifier:       e(11),
+				ReceivingDFI:                e(12),
+				ReceivingAccountQualifier:   e(13),
+				ReceivingAccount:            e(14),
+				EffectiveDate:               e(15),
+			}
+		case "RMR":
+			r.Details = append(r.Details, Detail{
+				ReferenceQualifier: e(0),
+				ReferenceID:        e(1),
+				PaymentActionCode:  e(2),
+				Amount:             e(3),
+				InvoiceAmount:      e(4),
+				DiscountAmount:     e(5),
+			})
+			detail = &r.Details[len(r.Details)-1]
+		case "REF":
+			ref := Reference{Qualifier: e(0), Identification: e(1), Description: e(2)}
+			if detail != nil {
+				detail.References = append(detail.References, ref)
+			} else {
+				r.References = append(r.References, ref)
+			}
+		case "DTM":
+			dtm := DateTime{Qualifier: e(0), Date: e(1), Time: e(2)}
+			if detail != nil {
+				detail.Dates = append(detail.Dates, dtm)
+			} else {
+				r.Dates = append(r.Dates, dtm)
+			}
+		default:
+			if detail != nil {
+				detail.Other = append(detail.Other, seg)
+			} else {
+				r.Other = append(r.Other, seg)
+			}
+		}
+	}
+	if started && !finished {
+		return nil, fmt.Errorf("%w: missing SE segment", ErrInvalidFormat)
+	}
+	return r, nil
+}
+
+// detectDelimiters reads the delimiters from an ISA segment or guesses them from the first segment
+func detectDelimiters(data string) (Delimiters, error) {
+	runes := []rune(data)
+	if strings.HasPrefix(data, "ISA") {
+		if len(runes) < 4 {
+			return Delimiters{}, ErrInvalidFormat
+		}
+		// ISA16 follows the 16th element delimiter and is followed by the segment terminator
+		d := Delimiters{Element: runes[3]}
+		seen := 0
+		for i := 3; i < len(runes); i++ {
+			if runes[i] != d.Element {
+				continue
+			}
+			if seen++; seen == 16 {
+				if i+2 >= len(runes) {
+					break
+				}
+				d.Component, d.Segment = runes[i+1], runes[i+2]
+				return d, nil
+			}
+		}
+		return Delimiters{}, fmt.Errorf("%w: incomplete ISA segment", ErrInvalidFormat)
+	}
+
+	d := DefaultDelimiters
+	idx := strings.IndexFunc(data, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if idx <= 0 {
+		return Delimiters{}, ErrInvalidFormat
+	}
+	d.Element, _ = utf8.DecodeRuneInString(data[idx:])
+	if !strings.ContainsRune(data, d.Segment) && strings.ContainsRune(data, '~') {
+		d.Segment = '~'
+	}
+	return d, nil
+}
+
+// split returns the segments of data. Whitespace around segments and elements is dropped, which
+// removes the padding between Addenda05 records.
+func split(data string, delims Delimiters) []Segment {
+	var out []Segment
+	for _, s := range strings.Split(data, string(delims.Segment)) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		elements := strings.Split(s, string(delims.Element))
+		for i := range elements {
+			elements[i] = strings.TrimSpace(elements[i])
+		}
+		out = append(out, Segment{ID: elements[0], Elements: elements[1:]})
+	}
+	return out
+}
+
+// Validate returns an error if an element contains one of the remittance's delimiters
+func (r *Remittance) Validate() error {
+	d := r.delimiters()
+	for _, seg := range r.segments() {
+		for i, elm := range seg.Elements {
+			if strings.ContainsRune(elm, d.Element) || strings.ContainsRune(elm, d.Segment) {
+				return fmt.Errorf("%w: %s%02d %q", ErrDelimiter, seg.ID, i+1, elm)
+			}
+		}
+	}
+	return nil
+}
+
+// String writes the remittance's segments, with no whitespace between them.
+func (r *Remittance) String() string {
+	d := r.delimiters()
+	var buf strings.Builder
+	for _, seg := range r.segments() {
+		buf.WriteString(segmentString(seg, d))
+	}
+	return buf.String()
+}
+
+// segmentString writes seg and its terminator. Every ISA element is written along with
+// ISA16, the component delimiter.
+func segmentString(seg Segment, d Delimiters) string {
+	if seg.ID == "ISA" {
+		return seg.ID + string(d.Element) + strings.Join(seg.Elements, string(d.Element)) +
+			string(d.Element) + string(d.Component) + string(d.Segment)
+	}
+	return seg.String(d)
+}
+
+// String writes the segment and its terminator. Trailing empty elements are left off.
+func (seg Segment) String(d Delimiters) string {
+	elements := seg.Elements
+	for len(elements) > 0 && elements[len(elements)-1] == "" {
+		elements = elements[:len(elements)-1]
+	}
+	var buf strings.Builder
+	buf.WriteString(seg.ID)
+	for _, elm := range elements {
+		buf.WriteRune(d.Element)
+		buf.WriteString(elm)
+	}
+	buf.WriteRune(d.Segment)
+	return buf.String()
+}
+
+func (r *Remittance) delimiters() Delimiters {
+	if r.Delimiters.Element == 0 || r.Delimiters.Segment == 0 {
+		return DefaultDelimiters
+	}
+	if r.Delimiters.Component == 0 {
+		d := r.Delimiters
+		d.Component = DefaultDelimiters.Component
+		return d
+	}
+	return r.Delimiters
+}
+
+// segments returns every segment of the remittance in the order they're written
+func (r *Remittance) segments() []Segment {
+	var out []Segment
+	if isa := r.Interchange; isa != nil {
+		out = append(out, Segment{ID: "ISA", Elements: []string{
+			pad(isa.AuthorizationQualifier, 2),
+			pad(isa.AuthorizationInformation, 10),
+			pad(isa.SecurityQualifier, 2),
+			pad(isa.SecurityInformation, 10),
+			pad(isa.SenderQualifier, 2),
+			pad(isa.SenderID, 15),
+			pad(isa.ReceiverQualifier, 2),
+			pad(isa.ReceiverID, 15),
+			pad(isa.Date, 6),
+			pad(isa.Time, 4),
+			pad(isa.RepetitionSeparator, 1),
+			pad(isa.Version, 5),
+			zeroPad(isa.ControlNumber, 9),
+			pad(isa.AcknowledgmentRequested, 1),
+			pad(isa.UsageIndicator, 1),
+		}})
+	}
+	if gs := r.Group; gs != nil {
+		out = append(out, Segment{ID: "GS", Elements: []string{
+			gs.FunctionalIdentifierCode, gs.SenderCode, gs.ReceiverCode, gs.Date,
+			gs.Time, gs.ControlNumber, gs.ResponsibleAgencyCode, gs.Version,
+		}})
+	}
+
+	start := len(out)
+	if r.ControlNumber != "" {
+		out = append(out, Segment{ID: "ST", Elements: []string{"820", r.ControlNumber}})
+	}
+	if p := r.Payment; p != nil {
+		out = append(out, Segment{ID: "BPR", Elements: []string{
+			p.TransactionHandlingCode, p.Amount, p.CreditDebitFlag, p.PaymentMethod,
+			p.PaymentFormat, p.OriginatingDFIQualifier, p.OriginatingDFI, p.OriginatingAccountQualifier,
+			p.OriginatingAccount, p.OriginatorID, p.OriginatorSupplementalCode, p.ReceivingDFIQualifier,
+			p.ReceivingDFI, p.ReceivingAccountQualifier, p.ReceivingAccount, p.EffectiveDate,
+		}})
+	}
+	out = appendReferences(out, r.References, r.Dates, r.Other)
+	for _, d := range r.Details {
+		out = append(out, Segment{ID: "RMR", Elements: []string{
+			d.ReferenceQualifier, d.ReferenceID, d.PaymentActionCode, d.Amount, d.InvoiceAmount, d.DiscountAmount,
+		}})
+		out = appendReferences(out, d.References, d.Dates, d.Other)
+	}
+	if r.ControlNumber != "" {
+		count := len(out) - start + 1
+		out = append(out, Segment{ID: "SE", Elements: []string{strconv.Itoa(count), r.ControlNumber}})
+	}
+
+	if r.Group != nil {
+		out = append(out, Segment{ID: "GE", Elements: []string{"1", r.Group.ControlNumber}})
+	}
+	if r.Interchange != nil {
+		out = append(out, Segment{ID: "IEA", Elements: []string{"1", zeroPad(r.Interchange.ControlNumber, 9)}})
+	}
+	return out
+}
+
+func appendReferences(out []Segment, refs []Reference, dates []DateTime, other []Segment) []Segment {
+	for _, ref := range refs {
+		out = append(out, Segment{ID: "REF", Elements: []string{ref.Qualifier, ref.Identification, ref.Description}})
+	}
+	for _, dtm := range dates {
+		out = append(out, Segment{ID: "DTM", Elements: []string{dtm.Qualifier, dtm.Date, dtm.Time}})
+	}
+	return append(out, other...)
+}
+
+// pad returns s left justified with spaces to n characters, as ISA elements are fixed width
+func pad(s string, n int) string {
+	if c := utf8.RuneCountInString(s); c < n {
+		return s + strings.Repeat(" ", n-c)
+	}
+	return s
+}
+
+func zeroPad(s string, n int) string {
+	if c := utf8.RuneCountInString(s); c < n {
+		return strings.Repeat("0", n-c) + s
+	}
+	return s
+}
+
+// ParseAmount returns the cents of an X12 decimal amount such as "1352.88"
+func ParseAmount(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if (whole == "" && frac == "") || len(frac) > 2 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+	cents, err := strconv.Atoi(whole + frac)
+	if err != nil || strings.ContainsAny(whole+frac, "+-") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// FormatAmount returns cents as an X12 decimal amount such as "1352.88"
+func FormatAmount(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,211 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package edi820
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sampleRemittance() *Remittance {
+	return &Remittance{
+		Interchange: &Interchange{
+			AuthorizationQualifier:  "00",
+			SecurityQualifier:       "00",
+			SenderQualifier:         "ZZ",
+			SenderID:                "ORIGINATOR",
+			ReceiverQualifier:       "ZZ",
+			ReceiverID:              "RECEIVER",
+			Date:                    "240105",
+			Time:                    "1200",
+			RepetitionSeparator:     "U",
+			Version:                 "00401",
+			ControlNumber:           "42",
+			AcknowledgmentRequested: "0",
+			UsageIndicator:          "P",
+		},
+		Group: &FunctionalGroup{
+			FunctionalIdentifierCode: "RA",
+			SenderCode:               "ORIGINATOR",
+			ReceiverCode:             "RECEIVER",
+			Date:                     "20240105",
+			Time:                     "1200",
+			ControlNumber:            "7",
+			ResponsibleAgencyCode:    "X",
+			Version:                  "004010",
+		},
+		ControlNumber: "0001",
+		Payment: &Payment{
+			TransactionHandlingCode: "C",
+			Amount:                  "1500.00",
+			CreditDebitFlag:         "C",
+			PaymentMethod:           "ACH",
+			PaymentFormat:           "CTX",
+			EffectiveDate:           "20240108",
+		},
+		References: []Reference{{Qualifier: "TN", Identification: "PAYMENT-1"}},
+		Dates:      []DateTime{{Qualifier: "097", Date: "20240105"}},
+		Details: []Detail{
+			{
+				ReferenceQualifier: "IV",
+				ReferenceID:        "INV-100",
+				Amount:             "1000.00",
+				InvoiceAmount:      "1000.00",
+				Dates:              []DateTime{{Qualifier: "003", Date: "20231201"}},
+			},
+			{
+				ReferenceQualifier: "IV",
+				ReferenceID:        "INV-101",
+				Amount:             "500.00",
+				InvoiceAmount:      "525.00",
+				DiscountAmount:     "25.00",
+				References:         []Reference{{Qualifier: "PO", Identification: "PO-9"}},
+			},
+		},
+	}
+}
+
+func TestRemittance__String(t *testing.T) {
+	r := sampleRemittance()
+
+	expected := "ISA*00*          *00*          *ZZ*ORIGINATOR     *ZZ*RECEIVER       *240105*1200*U*00401*000000042*0*P*>\\" +
+		"GS*RA*ORIGINATOR*RECEIVER*20240105*1200*7*X*004010\\" +
+		"ST*820*0001\\" +
+		"BPR*C*1500.00*C*ACH*CTX***********20240108\\" +
+		"REF*TN*PAYMENT-1\\" +
+		"DTM*097*20240105\\" +
+		"RMR*IV*INV-100**1000.00*1000.00\\" +
+		"DTM*003*20231201\\" +
+		"RMR*IV*INV-101**500.00*525.00*25.00\\" +
+		"REF*PO*PO-9\\" +
+		"SE*9*0001\\" +
+		"GE*1*7\\" +
+		"IEA*1*000000042\\"
+	require.Equal(t, expected, r.String())
+
+	// The ISA segment is fixed width
+	require.Len(t, expected[:len("ISA*00*          *00*          *ZZ*ORIGINATOR     *ZZ*RECEIVER       *240105*1200*U*00401*000000042*0*P*>\\")], 106)
+}
+
+func TestParse__roundTrip(t *testing.T) {
+	r := sampleRemittance()
+
+	parsed, err := Parse(r.String())
+	require.NoError(t, err)
+	require.Equal(t, DefaultDelimiters, parsed.Delimiters)
+
+	r.Interchange.ControlNumber = "000000042" // read back padded
+	r.Delimiters = DefaultDelimiters
+	require.Equal(t, r, parsed)
+	require.Equal(t, r.String(), parsed.String())
+}
+
+func TestParse__delimiters(t *testing.T) {
+	t.Run("ISA", func(t *testing.T) {
+		r := sampleRemittance()
+		r.Delimiters = Delimiters{Element: '|', Component: '^', Segment: '~'}
+
+		parsed, err := Parse(r.String())
+		require.NoError(t, err)
+		require.Equal(t, r.Delimiters, parsed.Delimiters)
+		require.Equal(t, "INV-101", parsed.Details[1].ReferenceID)
+	})
+
+	t.Run("no ISA", func(t *testing.T) {
+		parsed, err := Parse("ST|820|1234~BPR|C|10.00|C|ACH|CTX~RMR|IV|A1||10.00~SE|4|1234~")
+		require.NoError(t, err)
+		require.Equal(t, '|', parsed.Delimiters.Element)
+		require.Equal(t, '~', parsed.Delimiters.Segment)
+		require.Nil(t, parsed.Interchange)
+		require.Nil(t, parsed.Group)
+		require.Equal(t, "10.00", parsed.Payment.Amount)
+		require.Len(t, parsed.Details, 1)
+	})
+
+	t.Run("RMR only", func(t *testing.T) {
+		parsed, err := Parse(`RMR*IV*A1**10.00\RMR*IV*A2**5.00\`)
+		require.NoError(t, err)
+		require.Empty(t, parsed.ControlNumber)
+		require.Len(t, parsed.Details, 2)
+
+		// ST and SE aren't added when writing
+		require.Equal(t, `RMR*IV*A1**10.00\RMR*IV*A2**5.00\`, parsed.String())
+	})
+}
+
+func TestParse__segments(t *testing.T) {
+	parsed, err := Parse(`ST*820*1\BPR*C*1.00*C*ACH*CTX\N1*PE*JOHN DOE\REF*TN*1\RMR*IV*A1**1.00\NTE*INV*NOTE\SE*7*1\`)
+	require.NoError(t, err)
+
+	require.Equal(t, []Segment{{ID: "N1", Elements: []string{"PE", "JOHN DOE"}}}, parsed.Other)
+	require.Equal(t, []Reference{{Qualifier: "TN", Identification: "1"}}, parsed.References)
+	require.Equal(t, []Segment{{ID: "NTE", Elements: []string{"INV", "NOTE"}}}, parsed.Details[0].Other)
+}
+
+func TestParse__errors(t *testing.T) {
+	cases := map[string]error{
+		"":                                 ErrInvalidFormat,
+		"   ":                              ErrInvalidFormat,
+		"ISA*00*":                          ErrInvalidFormat,
+		`ST*810*1\SE*2*1\`:                 ErrTransactionSet,
+		`ST*820*1\BPR*C\SE*4*1\`:           ErrSegmentCount,
+		`ST*820*1\BPR*C\SE*3*2\`:           ErrSegmentCount,
+		`ST*820*1\BPR*C\`:                  ErrInvalidFormat,
+		`BPR*C\SE*2*1\`:                    ErrInvalidFormat,
+		`ST*820*1\SE*2*1\ST*820*2\SE*2*2\`: ErrInvalidFormat,
+	}
+	for input, expected := range cases {
+		_, err := Parse(input)
+		require.ErrorIs(t, err, expected, "input: %q", input)
+	}
+}
+
+func TestRemittance__Validate(t *testing.T) {
+	r := sampleRemittance()
+	require.NoError(t, r.Validate())
+
+	r.Details[0].ReferenceID = "INV*100"
+	err := r.Validate()
+	require.True(t, errors.Is(err, ErrDelimiter))
+	require.Contains(t, err.Error(), "RMR02")
+}
+
+func TestAmount(t *testing.T) {
+	for input, cents := range map[string]int{
+		"1352.88": 135288,
+		"1352.8":  135280,
+		"1352":    135200,
+		".5":      50,
+		"0.01":    1,
+		"-5.00":   -500,
+	} {
+		got, err := ParseAmount(input)
+		require.NoError(t, err, input)
+		require.Equal(t, cents, got, input)
+	}
+	for _, input := range []string{"", ".", "1.234", "abc", "1.-5", "--1"} {
+		_, err := ParseAmount(input)
+		require.Error(t, err, input)
+	}
+
+	require.Equal(t, "1352.88", FormatAmount(135288))
+	require.Equal(t, "0.05", FormatAmount(5))
+	require.Equal(t, "-5.00", FormatAmount(-500))
+}
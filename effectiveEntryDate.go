@@ -0,0 +1,329 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+)
+
+var (
+	// ErrEffectiveEntryDateNotBankingDay is given when a batch settles on a weekend or Federal Reserve holiday.
+	ErrEffectiveEntryDateNotBankingDay = errors.New("effective entry date is not a banking day")
+
+	// ErrEffectiveEntryDateTooFarOut is given when a batch settles more than ValidateOpts.MaxEffectiveEntryDateDays
+	// after the FileCreationDate.
+	ErrEffectiveEntryDateTooFarOut = errors.New("effective entry date is too far after the file creation date")
+)
+
+// BankingCalendar reports which days the ACH network settles entries on.
+type BankingCalendar interface {
+	IsBankingDay(day time.Time) bool
+}
+
+// FederalReserveCalendar is the BankingCalendar of the Federal Reserve, which is closed on weekends
+// and US holidays. Closed adds days the ODFI is closed on.
+type FederalReserveCalendar struct {
+	Closed []time.Time
+}
+
+func (c FederalReserveCalendar) IsBankingDay(day time.Time) bool {
+	for _, closed := range c.Closed {
+		if sameDate(closed, day) {
+			return false
+		}
+	}
+	return base.NewTime(day).IsBankingDay()
+}
+
+// Cutoff is a time of day files must be received by to be processed in a window.
+type Cutoff struct {
+	Hour   int `json:"hour"`
+	Minute int `json:"minute"`
+}
+
+// FedACHSameDayCutoffs are the same-day processing windows of the Federal Reserve, in Eastern Time.
+//
+// https://www.frbservices.org/resources/resource-centers/same-day-ach/fedach-processing-schedule.html
+var FedACHSameDayCutoffs = []Cutoff{
+	{Hour: 10, Minute: 30},
+	{Hour: 14, Minute: 45},
+	{Hour: 16, Minute: 45},
+}
+
+// FedACHNextDayCutoff is the final next-day deposit deadline of the Federal Reserve, 2:15 a.m. Eastern Time
+// on the morning after the processing day. Files submitted before it are processed overnight and settle on
+// the following banking day.
+var FedACHNextDayCutoff = Cutoff{Hour: 26, Minute: 15}
+
+// CutoffSchedule is the set of same-day and next-day cutoffs files are submitted against. Files submitted
+// after the last cutoff are processed on the next banking day.
+type CutoffSchedule struct {
+	// Location is the time zone of the cutoffs. Eastern Time (America/New_York) is used when nil.
+	Location *time.Location
+
+	// SameDayCutoffs are the cutoffs for same-day processing. FedACHSameDayCutoffs are used when empty.
+	// An ODFI will typically have earlier cutoffs than the Federal Reserve.
+	SameDayCutoffs []Cutoff
+
+	// NextDayCutoff is the deadline for files which settle on the following banking day. Hour may be
+	// 24 or more for deadlines after midnight of the processing day. FedACHNextDayCutoff is used when nil.
+	NextDayCutoff *Cutoff
+}
+
+func (s CutoffSchedule) location() (*time.Location, error) {
+	if s.Location != nil {
+		return s.Location, nil
+	}
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return nil, fmt.Errorf("loading cutoff location: %w", err)
+	}
+	return loc, nil
+}
+
+// lastCutoff returns the final same-day cutoff on day.
+func (s CutoffSchedule) lastCutoff(day time.Time) time.Time {
+	cutoffs := s.SameDayCutoffs
+	if len(cutoffs) == 0 {
+		cutoffs = FedACHSameDayCutoffs
+	}
+	var last time.Time
+	for _, c := range cutoffs {
+		t := time.Date(day.Year(), day.Month(), day.Day(), c.Hour, c.Minute, 0, 0, day.Location())
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// nextDayCutoff returns the next-day deposit deadline of files processed on day.
+func (s CutoffSchedule) nextDayCutoff(day time.Time) time.Time {
+	c := FedACHNextDayCutoff
+	if s.NextDayCutoff != nil {
+		c = *s.NextDayCutoff
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), c.Hour, c.Minute, 0, 0, day.Location())
+}
+
+// nextDayProcessingDate returns the banking day which processes a file submitted at processing for
+// next-day settlement. A deadline past midnight keeps the previous banking day open.
+func (s CutoffSchedule) nextDayProcessingDate(calendar BankingCalendar, processing time.Time) time.Time {
+	day := time.Date(processing.Year(), processing.Month(), processing.Day(), 0, 0, 0, 0, processing.Location())
+	if previous := day.AddDate(0, 0, -1); calendar.IsBankingDay(previous) && processing.Before(s.nextDayCutoff(previous)) {
+		return previous
+	}
+	if calendar.IsBankingDay(day) && processing.Before(s.nextDayCutoff(day)) {
+		return day
+	}
+	return addBankingDays(calendar, day, 1)
+}
+
+// EffectiveEntryDateOptions configure how EffectiveEntryDates are calculated.
+type EffectiveEntryDateOptions struct {
+	Cutoffs CutoffSchedule
+
+	// Calendar determines banking days. FederalReserveCalendar is used when nil.
+	Calendar BankingCalendar
+
+	// SameDay requests same-day settlement when the entries are eligible for it.
+	SameDay bool
+
+	// CreditSettlementDays is how many banking days after processing credits settle when they
+	// are not same-day. Nacha allows one or two, and one is used when zero.
+	CreditSettlementDays int
+}
+
+// EffectiveEntryDate is a calculated settlement date for a batch.
+type EffectiveEntryDate struct {
+	// Date is the day entries settle, which is zero for ENR batches.
+	Date time.Time
+
+	// ProcessingDate is the banking day the file is processed by the ACH Operator.
+	ProcessingDate time.Time
+
+	// SameDay is true when Date is the same banking day as ProcessingDate.
+	SameDay bool
+
+	// SameDayEligible is true when the SEC code can settle same-day. Entries over SameDayEntryLimit
+	// are never same-day eligible, see SplitSameDayEntries.
+	SameDayEligible bool
+}
+
+// String returns Date in the YYMMDD format of BatchHeader.EffectiveEntryDate.
+// An empty string is returned for ENR batches.
+func (e EffectiveEntryDate) String() string {
+	if e.Date.IsZero() {
+		return ""
+	}
+	return e.Date.Format("060102")
+}
+
+// CalculateEffectiveEntryDate returns the EffectiveEntryDate for a batch of secCode entries submitted at
+// processing. Same-day files submitted after the last same-day cutoff or on a non-banking day are processed
+// on the next banking day. Other files are processed on the day whose next-day cutoff they meet.
+//
+// Same-day entries settle on the processing date. Otherwise debits settle one banking day after
+// processing and credits settle EffectiveEntryDateOptions.CreditSettlementDays after processing.
+// IAT and ENR entries are never same-day eligible and ENR batches have an empty EffectiveEntryDate.
+func CalculateEffectiveEntryDate(processing time.Time, secCode string, transactionCode int, opts *EffectiveEntryDateOptions) (EffectiveEntryDate, error) {
+	if opts == nil {
+		opts = &EffectiveEntryDateOptions{}
+	}
+	var calendar BankingCalendar = FederalReserveCalendar{}
+	if opts.Calendar != nil {
+		calendar = opts.Calendar
+	}
+	settlementDays := 1
+	if creditOrDebit(transactionCode) == "C" && opts.CreditSettlementDays != 0 {
+		if opts.CreditSettlementDays < 1 || opts.CreditSettlementDays > 2 {
+			return EffectiveEntryDate{}, fmt.Errorf("credits settle one or two banking days after processing, not %d", opts.CreditSettlementDays)
+		}
+		settlementDays = opts.CreditSettlementDays
+	}
+
+	loc, err := opts.Cutoffs.location()
+	if err != nil {
+		return EffectiveEntryDate{}, err
+	}
+	processing = processing.In(loc)
+
+	out := EffectiveEntryDate{
+		SameDayEligible: secCode != IAT && secCode != ENR,
+	}
+	if opts.SameDay && out.SameDayEligible {
+		// Same-day files submitted after the last cutoff or on a non-banking day are processed on the next banking day
+		out.ProcessingDate = time.Date(processing.Year(), processing.Month(), processing.Day(), 0, 0, 0, 0, loc)
+		if !calendar.IsBankingDay(out.ProcessingDate) || !processing.Before(opts.Cutoffs.lastCutoff(processing)) {
+			out.ProcessingDate = addBankingDays(calendar, out.ProcessingDate, 1)
+		}
+		out.Date = out.ProcessingDate
+		out.SameDay = true
+		return out, nil
+	}
+
+	out.ProcessingDate = opts.Cutoffs.nextDayProcessingDate(calendar, processing)
+	if secCode == ENR {
+		return EffectiveEntryDate{ProcessingDate: out.ProcessingDate}, nil
+	}
+	out.Date = addBankingDays(calendar, out.ProcessingDate, settlementDays)
+	return out, nil
+}
+
+// SplitSameDayEntries moves entries over SameDayEntryLimit from a same-day batch into a new batch which
+// settles on the next banking day after b's EffectiveEntryDate. Both batches are returned after Create
+// is called on them. sameDay is nil when every entry was moved and nextDay is nil when none were.
+//
+// nextDay has the same BatchNumber as b and should be renumbered before being added to a File.
+func SplitSameDayEntries(b Batcher, calendar BankingCalendar) (sameDay Batcher, nextDay Batcher, err error) {
+	if b == nil || b.GetHeader() == nil {
+		return nil, nil, errors.New("nil Batch or BatchHeader")
+	}
+	if calendar == nil {
+		calendar = FederalReserveCalendar{}
+	}
+
+	var over []*EntryDetail
+	for _, ed := range b.GetEntries() {
+		if ed.Amount > SameDayEntryLimit {
+			over = append(over, ed)
+		}
+	}
+	if len(over) == 0 {
+		return b, nil, nil
+	}
+
+	effectiveEntryDate, err := b.GetHeader().LiftEffectiveEntryDate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing EffectiveEntryDate: %w", err)
+	}
+	bh := *b.GetHeader()
+	bh.EffectiveEntryDate = addBankingDays(calendar, effectiveEntryDate, 1).Format("060102")
+
+	nextDay, err = NewBatch(&bh)
+	if err != nil {
+		return nil, nil, err
+	}
+	nextDay.SetValidation(b.GetHeader().validateOpts)
+	for _, ed := range over {
+		nextDay.AddEntry(ed)
+	}
+	if err := nextDay.Create(); err != nil {
+		return nil, nil, fmt.Errorf("creating next-day batch: %w", err)
+	}
+
+	b.DeleteEntries(func(ed *EntryDetail) bool {
+		return ed.Amount > SameDayEntryLimit
+	})
+	if len(b.GetEntries()) == 0 {
+		return nil, nextDay, nil
+	}
+	if err := b.Create(); err != nil {
+		return nil, nil, fmt.Errorf("creating same-day batch: %w", err)
+	}
+	return b, nextDay, nil
+}
+
+// validateEffectiveEntryDate checks the EffectiveEntryDate of a batch against the options in v.
+func (v *ValidateOpts) validateEffectiveEntryDate(fh *FileHeader, secCode, date string) error {
+	if v == nil || (!v.RequireBankingDayEffectiveEntryDate && v.MaxEffectiveEntryDateDays <= 0) {
+		return nil
+	}
+	// ENR batches have no EffectiveEntryDate and the ACH Operator does not edit it on NOCs
+	if secCode == ENR || secCode == COR || strings.TrimSpace(date) == "" {
+		return nil
+	}
+	effectiveEntryDate, err := time.Parse("060102", date)
+	if err != nil {
+		return nil // malformed dates are reported by BatchHeader validation
+	}
+
+	var calendar BankingCalendar = FederalReserveCalendar{}
+	if v.EffectiveEntryDateCalendar != nil {
+		calendar = v.EffectiveEntryDateCalendar
+	}
+	if v.RequireBankingDayEffectiveEntryDate && !calendar.IsBankingDay(effectiveEntryDate) {
+		return fieldError("EffectiveEntryDate", ErrEffectiveEntryDateNotBankingDay, date)
+	}
+	if v.MaxEffectiveEntryDateDays > 0 && fh != nil {
+		created, err := time.Parse("060102", fh.FileCreationDate)
+		if err == nil && effectiveEntryDate.After(created.AddDate(0, 0, v.MaxEffectiveEntryDateDays)) {
+			return fieldError("EffectiveEntryDate", ErrEffectiveEntryDateTooFarOut, date)
+		}
+	}
+	return nil
+}
+
+func addBankingDays(calendar BankingCalendar, day time.Time, days int) time.Time {
+	for days > 0 {
+		day = day.AddDate(0, 0, 1)
+		if calendar.IsBankingDay(day) {
+			days--
+		}
+	}
+	return day
+}
+
+func sameDate(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
@@ -0,0 +1,220 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateEffectiveEntryDate(t *testing.T) {
+	eastern := time.FixedZone("EDT", -4*60*60)
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, time.July, day, hour, 0, 0, 0, eastern)
+	}
+
+	cases := []struct {
+		name            string
+		processing      time.Time
+		secCode         string
+		transactionCode int
+		opts            EffectiveEntryDateOptions
+
+		expected       string
+		processingDate string
+		sameDay        bool
+	}{
+		{
+			name:       "same-day credit",
+			processing: at(2, 9), secCode: PPD, transactionCode: CheckingCredit,
+			opts:     EffectiveEntryDateOptions{SameDay: true},
+			expected: "240702", processingDate: "240702", sameDay: true,
+		},
+		{
+			name:       "after last cutoff",
+			processing: at(2, 17), secCode: PPD, transactionCode: CheckingCredit,
+			opts:     EffectiveEntryDateOptions{SameDay: true},
+			expected: "240703", processingDate: "240703", sameDay: true,
+		},
+		{
+			name:       "debit over holiday",
+			processing: at(3, 17), secCode: CCD, transactionCode: CheckingDebit,
+			expected: "240705", processingDate: "240703",
+		},
+		{
+			name:       "next-day after same-day cutoffs",
+			processing: at(1, 18), secCode: PPD, transactionCode: CheckingDebit,
+			expected: "240702", processingDate: "240701",
+		},
+		{
+			name:       "before overnight cutoff",
+			processing: at(2, 1), secCode: PPD, transactionCode: CheckingDebit,
+			expected: "240702", processingDate: "240701",
+		},
+		{
+			name:       "after overnight cutoff",
+			processing: at(2, 3), secCode: PPD, transactionCode: CheckingDebit,
+			expected: "240703", processingDate: "240702",
+		},
+		{
+			name:       "ODFI next-day cutoff",
+			processing: at(1, 18), secCode: PPD, transactionCode: CheckingDebit,
+			opts: EffectiveEntryDateOptions{
+				Cutoffs: CutoffSchedule{NextDayCutoff: &Cutoff{Hour: 17}},
+			},
+			expected: "240703", processingDate: "240702",
+		},
+		{
+			name:       "credit two days",
+			processing: at(2, 9), secCode: PPD, transactionCode: CheckingCredit,
+			opts:     EffectiveEntryDateOptions{CreditSettlementDays: 2},
+			expected: "240705", processingDate: "240702",
+		},
+		{
+			name:       "weekend",
+			processing: at(6, 9), secCode: WEB, transactionCode: CheckingDebit,
+			opts:     EffectiveEntryDateOptions{SameDay: true},
+			expected: "240708", processingDate: "240708", sameDay: true,
+		},
+		{
+			name:       "IAT is not same-day",
+			processing: at(2, 9), secCode: IAT, transactionCode: CheckingCredit,
+			opts:     EffectiveEntryDateOptions{SameDay: true},
+			expected: "240703", processingDate: "240702",
+		},
+		{
+			name:       "ENR is space filled",
+			processing: at(2, 9), secCode: ENR, transactionCode: CheckingCredit,
+			expected: "", processingDate: "240702",
+		},
+		{
+			name:       "ODFI closed",
+			processing: at(2, 9), secCode: PPD, transactionCode: CheckingDebit,
+			opts: EffectiveEntryDateOptions{
+				Calendar: FederalReserveCalendar{Closed: []time.Time{at(3, 0)}},
+			},
+			expected: "240705", processingDate: "240702",
+		},
+		{
+			name:       "ODFI cutoff",
+			processing: at(2, 9), secCode: PPD, transactionCode: CheckingCredit,
+			opts: EffectiveEntryDateOptions{
+				SameDay: true,
+				Cutoffs: CutoffSchedule{SameDayCutoffs: []Cutoff{{Hour: 8, Minute: 30}}},
+			},
+			expected: "240703", processingDate: "240703", sameDay: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Cutoffs.Location = eastern
+
+			got, err := CalculateEffectiveEntryDate(tc.processing, tc.secCode, tc.transactionCode, &tc.opts)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, got.String())
+			require.Equal(t, tc.processingDate, got.ProcessingDate.Format("060102"))
+			require.Equal(t, tc.sameDay, got.SameDay)
+		})
+	}
+
+	t.Run("invalid credit days", func(t *testing.T) {
+		opts := &EffectiveEntryDateOptions{CreditSettlementDays: 3}
+		opts.Cutoffs.Location = eastern
+		_, err := CalculateEffectiveEntryDate(at(2, 9), PPD, CheckingCredit, opts)
+		require.ErrorContains(t, err, "not 3")
+	})
+}
+
+func TestSplitSameDayEntries(t *testing.T) {
+	bh := mockBatchPPDHeader()
+	bh.EffectiveEntryDate = "240703"
+	batch := NewBatchPPD(bh)
+
+	entry1 := mockPPDEntryDetail()
+	entry1.Amount = SameDayEntryLimit
+	batch.AddEntry(entry1)
+
+	entry2 := mockPPDEntryDetail()
+	entry2.Amount = SameDayEntryLimit + 1
+	entry2.SetTraceNumber(bh.ODFIIdentification, 2)
+	batch.AddEntry(entry2)
+	require.NoError(t, batch.Create())
+
+	sameDay, nextDay, err := SplitSameDayEntries(batch, nil)
+	require.NoError(t, err)
+	require.Equal(t, []*EntryDetail{entry1}, sameDay.GetEntries())
+	require.Equal(t, "240703", sameDay.GetHeader().EffectiveEntryDate)
+	require.Equal(t, SameDayEntryLimit, sameDay.GetControl().TotalCreditEntryDollarAmount)
+
+	require.Equal(t, []*EntryDetail{entry2}, nextDay.GetEntries())
+	require.Equal(t, "240705", nextDay.GetHeader().EffectiveEntryDate) // after July 4th
+	require.Equal(t, SameDayEntryLimit+1, nextDay.GetControl().TotalCreditEntryDollarAmount)
+
+	// Nothing left to split
+	sameDay, nextDay, err = SplitSameDayEntries(sameDay, nil)
+	require.NoError(t, err)
+	require.NotNil(t, sameDay)
+	require.Nil(t, nextDay)
+
+	// Every entry is moved
+	entry1.Amount = SameDayEntryLimit + 1
+	sameDay, nextDay, err = SplitSameDayEntries(sameDay, nil)
+	require.NoError(t, err)
+	require.Nil(t, sameDay)
+	require.Equal(t, []*EntryDetail{entry1}, nextDay.GetEntries())
+}
+
+func TestValidateOpts__EffectiveEntryDate(t *testing.T) {
+	file := mockFilePPD(t)
+	file.Header.FileCreationDate = "240701"
+	bh := file.Batches[0].GetHeader()
+
+	bh.EffectiveEntryDate = "240706" // Saturday
+	require.NoError(t, file.Validate())
+
+	err := file.ValidateWith(&ValidateOpts{RequireBankingDayEffectiveEntryDate: true})
+	require.ErrorIs(t, err, ErrEffectiveEntryDateNotBankingDay)
+	require.Equal(t, CodeEffectiveEntryDateNotBankingDay, errorCode(err))
+
+	// BypassBatchValidation doesn't skip the check, SkipAll does
+	err = file.ValidateWith(&ValidateOpts{RequireBankingDayEffectiveEntryDate: true, BypassBatchValidation: true})
+	require.ErrorIs(t, err, ErrEffectiveEntryDateNotBankingDay)
+	err = file.ValidateAll(&ValidateOpts{RequireBankingDayEffectiveEntryDate: true, BypassBatchValidation: true})
+	require.True(t, base.Has(err, ErrEffectiveEntryDateNotBankingDay))
+	require.NoError(t, file.ValidateWith(&ValidateOpts{RequireBankingDayEffectiveEntryDate: true, SkipAll: true}))
+
+	bh.EffectiveEntryDate = "240708"
+	require.NoError(t, file.ValidateWith(&ValidateOpts{RequireBankingDayEffectiveEntryDate: true}))
+
+	closed := FederalReserveCalendar{Closed: []time.Time{time.Date(2024, time.July, 8, 0, 0, 0, 0, time.UTC)}}
+	err = file.ValidateWith(&ValidateOpts{RequireBankingDayEffectiveEntryDate: true, EffectiveEntryDateCalendar: closed})
+	require.ErrorIs(t, err, ErrEffectiveEntryDateNotBankingDay)
+
+	opts := &ValidateOpts{MaxEffectiveEntryDateDays: 7}
+	require.NoError(t, file.ValidateWith(opts))
+
+	opts.MaxEffectiveEntryDateDays = 3
+	require.ErrorIs(t, file.ValidateWith(opts), ErrEffectiveEntryDateTooFarOut)
+
+	opts.CollectAllErrors = true
+	err = file.ValidateWith(opts)
+	require.True(t, base.Has(err, ErrEffectiveEntryDateTooFarOut))
+}
@@ -0,0 +1,441 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/moov-io/base"
+)
+
+// ErrorCode is a stable, machine readable identifier for a validation failure.
+// Codes will not change between releases, unlike error messages, so they can be
+// used to translate messages or highlight fields in a UI.
+type ErrorCode string
+
+// Rule returns a reference to the section of the Nacha Operating Rules which the code checks.
+func (c ErrorCode) Rule() string {
+	return errorCodeRules[c]
+}
+
+const (
+	// CodeInvalid is returned for errors which do not have a more specific code.
+	CodeInvalid ErrorCode = "ACH-INVALID"
+	// CodeFieldInvalid is returned for FieldErrors which do not have a more specific code.
+	CodeFieldInvalid ErrorCode = "ACH-FIELD-INVALID"
+	// CodeBatchInvalid is returned for BatchErrors which do not have a more specific code.
+	CodeBatchInvalid ErrorCode = "ACH-BATCH-INVALID"
+
+	// Field errors
+	CodeFieldNonAlphanumeric             ErrorCode = "ACH-FIELD-NON-ALPHANUMERIC"
+	CodeFieldNotUppercase                ErrorCode = "ACH-FIELD-NOT-UPPERCASE"
+	CodeFieldMandatory                   ErrorCode = "ACH-FIELD-MANDATORY"
+	CodeFieldRequired                    ErrorCode = "ACH-FIELD-REQUIRED"
+	CodeFieldOnlyZeros                   ErrorCode = "ACH-FIELD-ONLY-ZEROS"
+	CodeFieldLength                      ErrorCode = "ACH-FIELD-LENGTH"
+	CodeServiceClassCodeInvalid          ErrorCode = "ACH-SERVICE-CLASS-CODE-INVALID"
+	CodeSECCodeInvalid                   ErrorCode = "ACH-SEC-CODE-INVALID"
+	CodeOriginatorStatusCodeInvalid      ErrorCode = "ACH-ORIGINATOR-STATUS-CODE-INVALID"
+	CodeAddendaTypeCodeInvalid           ErrorCode = "ACH-ADDENDA-TYPE-CODE-INVALID"
+	CodeTransactionCodeInvalid           ErrorCode = "ACH-TRANSACTION-CODE-INVALID"
+	CodeIdentificationNumberInvalid      ErrorCode = "ACH-IDENTIFICATION-NUMBER-INVALID"
+	CodeCardTransactionTypeInvalid       ErrorCode = "ACH-CARD-TRANSACTION-TYPE-INVALID"
+	CodeMonthInvalid                     ErrorCode = "ACH-MONTH-INVALID"
+	CodeDayInvalid                       ErrorCode = "ACH-DAY-INVALID"
+	CodeYearInvalid                      ErrorCode = "ACH-YEAR-INVALID"
+	CodeStateInvalid                     ErrorCode = "ACH-STATE-INVALID"
+	CodeCountryCodeInvalid               ErrorCode = "ACH-COUNTRY-CODE-INVALID"
+	CodeCurrencyCodeInvalid              ErrorCode = "ACH-CURRENCY-CODE-INVALID"
+	CodeAmountNegative                   ErrorCode = "ACH-AMOUNT-NEGATIVE"
+	CodeCheckDigitInvalid                ErrorCode = "ACH-CHECK-DIGIT-INVALID"
+	CodeRecordTypeInvalid                ErrorCode = "ACH-RECORD-TYPE-INVALID"
+	CodeRecordSizeInvalid                ErrorCode = "ACH-RECORD-SIZE-INVALID"
+	CodeBlockingFactorInvalid            ErrorCode = "ACH-BLOCKING-FACTOR-INVALID"
+	CodeFormatCodeInvalid                ErrorCode = "ACH-FORMAT-CODE-INVALID"
+	CodeChangeCodeInvalid                ErrorCode = "ACH-CHANGE-CODE-INVALID"
+	CodeRefusedChangeCodeInvalid         ErrorCode = "ACH-REFUSED-CHANGE-CODE-INVALID"
+	CodeTraceSequenceNumberInvalid       ErrorCode = "ACH-TRACE-SEQUENCE-NUMBER-INVALID"
+	CodeCorrectedDataInvalid             ErrorCode = "ACH-CORRECTED-DATA-INVALID"
+	CodeReturnCodeInvalid                ErrorCode = "ACH-RETURN-CODE-INVALID"
+	CodeDishonoredReturnCodeInvalid      ErrorCode = "ACH-DISHONORED-RETURN-CODE-INVALID"
+	CodeContestedReturnCodeInvalid       ErrorCode = "ACH-CONTESTED-RETURN-CODE-INVALID"
+	CodeForeignExchangeIndicator         ErrorCode = "ACH-IAT-FOREIGN-EXCHANGE-INDICATOR-INVALID"
+	CodeForeignExchangeReference         ErrorCode = "ACH-IAT-FOREIGN-EXCHANGE-REFERENCE-INDICATOR-INVALID"
+	CodeTransactionTypeCodeInvalid       ErrorCode = "ACH-IAT-TRANSACTION-TYPE-CODE-INVALID"
+	CodeIDNumberQualifierInvalid         ErrorCode = "ACH-IAT-ID-NUMBER-QUALIFIER-INVALID"
+	CodeIATAddendaIndicatorInvalid       ErrorCode = "ACH-IAT-ADDENDA-INDICATOR-INVALID"
+	CodeBatchNoEntries                   ErrorCode = "ACH-BATCH-NO-ENTRIES"
+	CodeBatchADVCount                    ErrorCode = "ACH-BATCH-ADV-COUNT"
+	CodeBatchAddendaIndicator            ErrorCode = "ACH-BATCH-ADDENDA-INDICATOR"
+	CodeBatchOriginatorDNE               ErrorCode = "ACH-BATCH-ORIGINATOR-DNE"
+	CodeBatchCardTransactionType         ErrorCode = "ACH-BATCH-CARD-TRANSACTION-TYPE"
+	CodeBatchDebitOnly                   ErrorCode = "ACH-BATCH-DEBIT-ONLY"
+	CodeBatchCreditOnly                  ErrorCode = "ACH-BATCH-CREDIT-ONLY"
+	CodeBatchCheckSerialNumber           ErrorCode = "ACH-BATCH-CHECK-SERIAL-NUMBER"
+	CodeBatchSECCode                     ErrorCode = "ACH-BATCH-SEC-CODE"
+	CodeBatchServiceClassCode            ErrorCode = "ACH-BATCH-SERVICE-CLASS-CODE"
+	CodeBatchTransactionCode             ErrorCode = "ACH-BATCH-TRANSACTION-CODE"
+	CodeBatchTransactionCodeAddenda      ErrorCode = "ACH-BATCH-TRANSACTION-CODE-ADDENDA"
+	CodeBatchAmountNonZero               ErrorCode = "ACH-BATCH-AMOUNT-NONZERO"
+	CodeBatchAmountZero                  ErrorCode = "ACH-BATCH-AMOUNT-ZERO"
+	CodeBatchCompanyEntryDescription     ErrorCode = "ACH-BATCH-COMPANY-ENTRY-DESCRIPTION"
+	CodeBatchAddendaCategory             ErrorCode = "ACH-BATCH-ADDENDA-CATEGORY"
+	CodeBatchCORAddenda                  ErrorCode = "ACH-BATCH-COR-ADDENDA"
+	CodeBatchHeaderControlMismatch       ErrorCode = "ACH-BATCH-HEADER-CONTROL-MISMATCH"
+	CodeBatchControlTotals               ErrorCode = "ACH-BATCH-CONTROL-TOTALS"
+	CodeBatchTraceNumberOrder            ErrorCode = "ACH-BATCH-TRACE-NUMBER-ORDER"
+	CodeBatchCategory                    ErrorCode = "ACH-BATCH-CATEGORY"
+	CodeBatchTraceNumberODFI             ErrorCode = "ACH-BATCH-TRACE-NUMBER-ODFI"
+	CodeBatchAddendaTraceNumber          ErrorCode = "ACH-BATCH-ADDENDA-TRACE-NUMBER"
+	CodeBatchAddendaCount                ErrorCode = "ACH-BATCH-ADDENDA-COUNT"
+	CodeBatchAddendaRequired             ErrorCode = "ACH-BATCH-ADDENDA-REQUIRED"
+	CodeBatchAddendaExpected             ErrorCode = "ACH-BATCH-ADDENDA-EXPECTED"
+	CodeBatchServiceClassTransactionCode ErrorCode = "ACH-BATCH-SERVICE-CLASS-TRANSACTION-CODE"
+	CodeBatchAmountLimit                 ErrorCode = "ACH-BATCH-AMOUNT-LIMIT"
+	CodeBatchIATNOC                      ErrorCode = "ACH-BATCH-IAT-NOC"
+	CodeFileTooLong                      ErrorCode = "ACH-FILE-TOO-LONG"
+	CodeFileHeaderCount                  ErrorCode = "ACH-FILE-HEADER-COUNT"
+	CodeFileControlCount                 ErrorCode = "ACH-FILE-CONTROL-COUNT"
+	CodeFileHeaderMisplaced              ErrorCode = "ACH-FILE-HEADER-MISPLACED"
+	CodeFileExtraRecords                 ErrorCode = "ACH-FILE-EXTRA-RECORDS"
+	CodeFileEntryOutsideBatch            ErrorCode = "ACH-FILE-ENTRY-OUTSIDE-BATCH"
+	CodeFileAddendaOutsideBatch          ErrorCode = "ACH-FILE-ADDENDA-OUTSIDE-BATCH"
+	CodeFileAddendaOutsideEntry          ErrorCode = "ACH-FILE-ADDENDA-OUTSIDE-ENTRY"
+	CodeFileBatchControlOutsideBatch     ErrorCode = "ACH-FILE-BATCH-CONTROL-OUTSIDE-BATCH"
+	CodeFileConsecutiveBatchHeaders      ErrorCode = "ACH-FILE-CONSECUTIVE-BATCH-HEADERS"
+	CodeFileADVOnly                      ErrorCode = "ACH-FILE-ADV-ONLY"
+	CodeFileIATSEC                       ErrorCode = "ACH-FILE-IAT-SEC"
+	CodeFileNoBatches                    ErrorCode = "ACH-FILE-NO-BATCHES"
+	CodeFileRecordLength                 ErrorCode = "ACH-FILE-RECORD-LENGTH"
+	CodeFileUnknownRecordType            ErrorCode = "ACH-FILE-UNKNOWN-RECORD-TYPE"
+	CodeFileUnknownSEC                   ErrorCode = "ACH-FILE-UNKNOWN-SEC"
+	CodeFileControlTotals                ErrorCode = "ACH-FILE-CONTROL-TOTALS"
+	CodeFileBatchNumberOrder             ErrorCode = "ACH-FILE-BATCH-NUMBER-ORDER"
+
+	// Errors from ValidationRule checks, which do not have a Nacha rule reference
+	CodeRulePattern       ErrorCode = "ACH-RULE-PATTERN"
+	CodeRuleAllowedValues ErrorCode = "ACH-RULE-ALLOWED-VALUES"
+	CodeRuleBlockedValues ErrorCode = "ACH-RULE-BLOCKED-VALUES"
+	CodeRuleMaxAmount     ErrorCode = "ACH-RULE-MAX-AMOUNT"
+
+	// Errors from EffectiveEntryDate checks
+	CodeEffectiveEntryDateNotBankingDay ErrorCode = "ACH-EFFECTIVE-ENTRY-DATE-NOT-BANKING-DAY"
+	CodeEffectiveEntryDateTooFarOut     ErrorCode = "ACH-EFFECTIVE-ENTRY-DATE-TOO-FAR-OUT"
+
+	// Errors from Limits checks
+	CodeLimitsExceeded ErrorCode = "ACH-LIMITS-EXCEEDED"
+)
+
+var errorCodes = map[error]ErrorCode{
+	ErrNonAlphanumeric:                        CodeFieldNonAlphanumeric,
+	ErrUpperAlpha:                             CodeFieldNotUppercase,
+	ErrFieldInclusion:                         CodeFieldMandatory,
+	ErrConstructor:                            CodeFieldMandatory,
+	ErrFieldRequired:                          CodeFieldRequired,
+	ErrOnlyZeros:                              CodeFieldOnlyZeros,
+	ErrExceedsFieldLength:                     CodeFieldLength,
+	ErrServiceClass:                           CodeServiceClassCodeInvalid,
+	ErrSECCode:                                CodeSECCodeInvalid,
+	ErrOrigStatusCode:                         CodeOriginatorStatusCodeInvalid,
+	ErrAddendaTypeCode:                        CodeAddendaTypeCodeInvalid,
+	ErrTransactionCode:                        CodeTransactionCodeInvalid,
+	ErrIdentificationNumber:                   CodeIdentificationNumberInvalid,
+	ErrCardTransactionType:                    CodeCardTransactionTypeInvalid,
+	ErrValidMonth:                             CodeMonthInvalid,
+	ErrValidDay:                               CodeDayInvalid,
+	ErrValidYear:                              CodeYearInvalid,
+	ErrValidState:                             CodeStateInvalid,
+	ErrValidISO3166:                           CodeCountryCodeInvalid,
+	ErrValidISO4217:                           CodeCurrencyCodeInvalid,
+	ErrNegativeAmount:                         CodeAmountNegative,
+	ErrRecordSize:                             CodeRecordSizeInvalid,
+	ErrBlockingFactor:                         CodeBlockingFactorInvalid,
+	ErrFormatCode:                             CodeFormatCodeInvalid,
+	ErrAddenda98ChangeCode:                    CodeChangeCodeInvalid,
+	ErrAddenda98RefusedChangeCode:             CodeRefusedChangeCodeInvalid,
+	ErrAddenda98RefusedTraceSequenceNumber:    CodeTraceSequenceNumberInvalid,
+	ErrAddenda98CorrectedData:                 CodeCorrectedDataInvalid,
+	ErrAddenda99ReturnCode:                    CodeReturnCodeInvalid,
+	ErrAddenda99DishonoredReturnCode:          CodeDishonoredReturnCodeInvalid,
+	ErrAddenda99ContestedReturnCode:           CodeContestedReturnCodeInvalid,
+	ErrForeignExchangeIndicator:               CodeForeignExchangeIndicator,
+	ErrForeignExchangeReferenceIndicator:      CodeForeignExchangeReference,
+	ErrTransactionTypeCode:                    CodeTransactionTypeCodeInvalid,
+	ErrIDNumberQualifier:                      CodeIDNumberQualifierInvalid,
+	ErrIATBatchAddendaIndicator:               CodeIATAddendaIndicatorInvalid,
+	ErrBatchNoEntries:                         CodeBatchNoEntries,
+	ErrBatchADVCount:                          CodeBatchADVCount,
+	ErrBatchAddendaIndicator:                  CodeBatchAddendaIndicator,
+	ErrBatchOriginatorDNE:                     CodeBatchOriginatorDNE,
+	ErrBatchInvalidCardTransactionType:        CodeBatchCardTransactionType,
+	ErrBatchDebitOnly:                         CodeBatchDebitOnly,
+	ErrBatchCreditOnly:                        CodeBatchCreditOnly,
+	ErrBatchCheckSerialNumber:                 CodeBatchCheckSerialNumber,
+	ErrBatchSECType:                           CodeBatchSECCode,
+	ErrBatchServiceClassCode:                  CodeBatchServiceClassCode,
+	ErrBatchTransactionCode:                   CodeBatchTransactionCode,
+	ErrBatchTransactionCodeAddenda:            CodeBatchTransactionCodeAddenda,
+	ErrBatchAmountNonZero:                     CodeBatchAmountNonZero,
+	ErrBatchAmountZero:                        CodeBatchAmountZero,
+	ErrBatchCompanyEntryDescriptionAutoenroll: CodeBatchCompanyEntryDescription,
+	ErrBatchCompanyEntryDescriptionREDEPCHECK: CodeBatchCompanyEntryDescription,
+	ErrBatchAddendaCategory:                   CodeBatchAddendaCategory,
+	ErrBatchCORAddenda:                        CodeBatchCORAddenda,
+	ErrFileTooLong:                            CodeFileTooLong,
+	ErrFileHeader:                             CodeFileHeaderCount,
+	ErrFileControl:                            CodeFileControlCount,
+	ErrMisplacedFileHeader:                    CodeFileHeaderMisplaced,
+	ErrExtraRecordsAfterFileControl:           CodeFileExtraRecords,
+	ErrFileEntryOutsideBatch:                  CodeFileEntryOutsideBatch,
+	ErrFileAddendaOutsideBatch:                CodeFileAddendaOutsideBatch,
+	ErrFileAddendaOutsideEntry:                CodeFileAddendaOutsideEntry,
+	ErrFileBatchControlOutsideBatch:           CodeFileBatchControlOutsideBatch,
+	ErrFileConsecutiveBatchHeaders:            CodeFileConsecutiveBatchHeaders,
+	ErrFileADVOnly:                            CodeFileADVOnly,
+	ErrFileIATSEC:                             CodeFileIATSEC,
+	ErrFileNoBatches:                          CodeFileNoBatches,
+	ErrRulePattern:                            CodeRulePattern,
+	ErrRuleAllowedValues:                      CodeRuleAllowedValues,
+	ErrRuleBlockedValues:                      CodeRuleBlockedValues,
+	ErrRuleMaxAmount:                          CodeRuleMaxAmount,
+	ErrLimitsExceeded:                         CodeLimitsExceeded,
+	ErrEffectiveEntryDateNotBankingDay:        CodeEffectiveEntryDateNotBankingDay,
+	ErrEffectiveEntryDateTooFarOut:            CodeEffectiveEntryDateTooFarOut,
+}
+
+const (
+	ruleFileExchange   = "Nacha Operating Rules, Appendix One: ACH File Exchange Specifications"
+	ruleRecordFormat   = "Nacha Operating Rules, Appendix Three: ACH Record Format Specifications"
+	ruleReturnEntries  = "Nacha Operating Rules, Appendix Four: Return Entries"
+	ruleChangeEntries  = "Nacha Operating Rules, Appendix Five: Notification of Change"
+	ruleRecordSequence = "Nacha Operating Rules, Appendix Three, Subpart 3.1: Sequence of Records in ACH Files"
+	ruleODFIRisk       = "Nacha Operating Rules, Article Two, Subsection 2.2.3: ODFI Risk Management"
+)
+
+var errorCodeRules = map[ErrorCode]string{
+	CodeFieldNonAlphanumeric:             ruleRecordFormat,
+	CodeFieldNotUppercase:                ruleRecordFormat,
+	CodeFieldMandatory:                   ruleRecordFormat,
+	CodeFieldRequired:                    ruleRecordFormat,
+	CodeFieldOnlyZeros:                   ruleRecordFormat,
+	CodeFieldLength:                      ruleRecordFormat,
+	CodeServiceClassCodeInvalid:          ruleRecordFormat,
+	CodeSECCodeInvalid:                   ruleRecordFormat,
+	CodeOriginatorStatusCodeInvalid:      ruleRecordFormat,
+	CodeAddendaTypeCodeInvalid:           ruleRecordFormat,
+	CodeTransactionCodeInvalid:           ruleRecordFormat,
+	CodeIdentificationNumberInvalid:      ruleRecordFormat,
+	CodeCardTransactionTypeInvalid:       ruleRecordFormat,
+	CodeMonthInvalid:                     ruleRecordFormat,
+	CodeDayInvalid:                       ruleRecordFormat,
+	CodeYearInvalid:                      ruleRecordFormat,
+	CodeStateInvalid:                     ruleRecordFormat,
+	CodeCountryCodeInvalid:               ruleRecordFormat,
+	CodeCurrencyCodeInvalid:              ruleRecordFormat,
+	CodeAmountNegative:                   ruleRecordFormat,
+	CodeCheckDigitInvalid:                ruleRecordFormat,
+	CodeRecordTypeInvalid:                ruleRecordFormat,
+	CodeRecordSizeInvalid:                ruleRecordFormat,
+	CodeBlockingFactorInvalid:            ruleRecordFormat,
+	CodeFormatCodeInvalid:                ruleRecordFormat,
+	CodeChangeCodeInvalid:                ruleChangeEntries,
+	CodeRefusedChangeCodeInvalid:         ruleChangeEntries,
+	CodeTraceSequenceNumberInvalid:       ruleChangeEntries,
+	CodeCorrectedDataInvalid:             ruleChangeEntries,
+	CodeReturnCodeInvalid:                ruleReturnEntries,
+	CodeDishonoredReturnCodeInvalid:      ruleReturnEntries,
+	CodeContestedReturnCodeInvalid:       ruleReturnEntries,
+	CodeForeignExchangeIndicator:         ruleRecordFormat,
+	CodeForeignExchangeReference:         ruleRecordFormat,
+	CodeTransactionTypeCodeInvalid:       ruleRecordFormat,
+	CodeIDNumberQualifierInvalid:         ruleRecordFormat,
+	CodeIATAddendaIndicatorInvalid:       ruleRecordFormat,
+	CodeBatchNoEntries:                   ruleRecordSequence,
+	CodeBatchADVCount:                    ruleRecordFormat,
+	CodeBatchAddendaIndicator:            ruleRecordFormat,
+	CodeBatchOriginatorDNE:               ruleRecordFormat,
+	CodeBatchCardTransactionType:         ruleRecordFormat,
+	CodeBatchDebitOnly:                   ruleRecordFormat,
+	CodeBatchCreditOnly:                  ruleRecordFormat,
+	CodeBatchCheckSerialNumber:           ruleRecordFormat,
+	CodeBatchSECCode:                     ruleRecordFormat,
+	CodeBatchServiceClassCode:            ruleRecordFormat,
+	CodeBatchTransactionCode:             ruleRecordFormat,
+	CodeBatchTransactionCodeAddenda:      ruleRecordFormat,
+	CodeBatchAmountNonZero:               ruleRecordFormat,
+	CodeBatchAmountZero:                  ruleRecordFormat,
+	CodeBatchCompanyEntryDescription:     ruleRecordFormat,
+	CodeBatchAddendaCategory:             ruleRecordFormat,
+	CodeBatchCORAddenda:                  ruleChangeEntries,
+	CodeBatchHeaderControlMismatch:       ruleRecordFormat,
+	CodeBatchControlTotals:               ruleRecordFormat,
+	CodeBatchTraceNumberOrder:            ruleRecordFormat,
+	CodeBatchCategory:                    ruleRecordFormat,
+	CodeBatchTraceNumberODFI:             ruleRecordFormat,
+	CodeBatchAddendaTraceNumber:          ruleRecordFormat,
+	CodeBatchAddendaCount:                ruleRecordFormat,
+	CodeBatchAddendaRequired:             ruleRecordFormat,
+	CodeBatchAddendaExpected:             ruleRecordFormat,
+	CodeBatchServiceClassTransactionCode: ruleRecordFormat,
+	CodeBatchAmountLimit:                 ruleRecordFormat,
+	CodeBatchIATNOC:                      ruleChangeEntries,
+	CodeFileTooLong:                      ruleFileExchange,
+	CodeFileHeaderCount:                  ruleRecordSequence,
+	CodeFileControlCount:                 ruleRecordSequence,
+	CodeFileHeaderMisplaced:              ruleRecordSequence,
+	CodeFileExtraRecords:                 ruleRecordSequence,
+	CodeFileEntryOutsideBatch:            ruleRecordSequence,
+	CodeFileAddendaOutsideBatch:          ruleRecordSequence,
+	CodeFileAddendaOutsideEntry:          ruleRecordSequence,
+	CodeFileBatchControlOutsideBatch:     ruleRecordSequence,
+	CodeFileConsecutiveBatchHeaders:      ruleRecordSequence,
+	CodeFileADVOnly:                      ruleRecordFormat,
+	CodeFileIATSEC:                       ruleRecordFormat,
+	CodeFileNoBatches:                    ruleRecordSequence,
+	CodeFileRecordLength:                 ruleFileExchange,
+	CodeFileUnknownRecordType:            ruleRecordFormat,
+	CodeFileUnknownSEC:                   ruleRecordFormat,
+	CodeFileControlTotals:                ruleRecordFormat,
+	CodeLimitsExceeded:                   ruleODFIRisk,
+	CodeEffectiveEntryDateNotBankingDay:  ruleRecordFormat,
+	CodeEffectiveEntryDateTooFarOut:      ruleRecordFormat,
+	CodeFileBatchNumberOrder:             ruleRecordFormat,
+}
+
+// CodedError is implemented by errors which carry an ErrorCode. FieldError, BatchError and
+// the typed file and batch errors can all be found with errors.As:
+//
+//	var coded ach.CodedError
+//	if errors.As(err, &coded) {
+//	    fmt.Println(coded.Code())
+//	}
+type CodedError interface {
+	error
+	Code() ErrorCode
+}
+
+// Code returns the ErrorCode of the underlying error, or CodeFieldInvalid if it is unknown.
+func (e *FieldError) Code() ErrorCode {
+	return errorCode(e)
+}
+
+// Code returns the ErrorCode of the underlying error, or CodeBatchInvalid if it is unknown.
+func (e *BatchError) Code() ErrorCode {
+	return errorCode(e)
+}
+
+// errorCode finds the most specific ErrorCode in err's chain.
+func errorCode(err error) ErrorCode {
+	fallback := CodeInvalid
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case *FieldError:
+			fallback = CodeFieldInvalid
+			continue
+		case *BatchError:
+			if fallback == CodeInvalid {
+				fallback = CodeBatchInvalid
+			}
+			continue
+		case CodedError:
+			return e.Code()
+		}
+		// Only pointers are used as keys, other types might not be comparable
+		if reflect.TypeOf(err).Kind() == reflect.Ptr {
+			if code, exists := errorCodes[err]; exists {
+				return code
+			}
+		}
+	}
+	return fallback
+}
+
+// ErrorDetail is a machine readable description of a validation error.
+type ErrorDetail struct {
+	Code ErrorCode `json:"code"`
+
+	// Record is the type of record the error was found on (e.g. "EntryDetail")
+	// and Line is its line number in the file. Both are only known when the error
+	// came from a Reader or File.ValidateAll.
+	Record string `json:"record,omitempty"`
+	Line   int    `json:"line,omitempty"`
+
+	FieldName string      `json:"fieldName,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+
+	// Rule references the section of the Nacha Operating Rules which was checked.
+	Rule string `json:"rule,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// DescribeError returns an ErrorDetail for err, which may wrap a base.ParseError,
+// BatchError or FieldError.
+func DescribeError(err error) ErrorDetail {
+	if err == nil {
+		return ErrorDetail{}
+	}
+	code := errorCode(err)
+	out := ErrorDetail{
+		Code:    code,
+		Rule:    code.Rule(),
+		Message: err.Error(),
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch e := e.(type) {
+		case *base.ParseError:
+			out.Line = e.Line
+			out.Record = e.Record
+			if e.Err != nil {
+				out.Message = e.Err.Error()
+			}
+		case base.ParseError:
+			out.Line = e.Line
+			out.Record = e.Record
+			if e.Err != nil {
+				out.Message = e.Err.Error()
+			}
+		case *BatchError:
+			out.FieldName = e.FieldName
+			out.Value = e.FieldValue
+		case *FieldError:
+			// A FieldError is more specific than the BatchError wrapping it
+			out.FieldName = e.FieldName
+			out.Value = e.Value
+		}
+	}
+	return out
+}
+
+// DescribeErrors returns an ErrorDetail for each error in err, which is often a base.ErrorList
+// returned by a Reader or File.ValidateAll.
+func DescribeErrors(err error) []ErrorDetail {
+	if err == nil {
+		return nil
+	}
+	var errs base.ErrorList
+	if !errors.As(err, &errs) {
+		return []ErrorDetail{DescribeError(err)}
+	}
+	out := make([]ErrorDetail, 0, len(errs))
+	for i := range errs {
+		out = append(out, DescribeError(errs[i]))
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected ErrorCode
+	}{
+		{err: errors.New("other"), expected: CodeInvalid},
+		{err: fieldError("Amount", ErrNegativeAmount, -1), expected: CodeAmountNegative},
+		{err: fieldError("Name", fmt.Errorf("%w: %c", ErrNonAlphanumeric, 'Ñ')), expected: CodeFieldNonAlphanumeric},
+		{err: fieldError("Name", errors.New("other")), expected: CodeFieldInvalid},
+		{err: fieldError("CheckDigit", NewErrValidCheckDigit(7)), expected: CodeCheckDigitInvalid},
+		{err: (&Batch{Header: NewBatchHeader()}).Error("entries", ErrBatchNoEntries), expected: CodeBatchNoEntries},
+		{err: (&Batch{Header: NewBatchHeader()}).Error("other", errors.New("other")), expected: CodeBatchInvalid},
+		{err: (&Batch{Header: NewBatchHeader()}).Error("FieldError", fieldError("Name", errors.New("other"))), expected: CodeFieldInvalid},
+		{err: NewErrFileCalculatedControlEquality("EntryHash", 1, 2), expected: CodeFileControlTotals},
+		{err: &base.ParseError{Line: 3, Err: ErrFileEntryOutsideBatch}, expected: CodeFileEntryOutsideBatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			require.Equal(t, tc.expected, errorCode(tc.err))
+		})
+	}
+
+	// Every code with a Nacha rule reference
+	for _, code := range errorCodes {
+		if strings.HasPrefix(string(code), "ACH-RULE-") {
+			continue // custom ValidationRule checks
+		}
+		require.NotEmpty(t, code.Rule(), code)
+	}
+}
+
+func TestErrorCode__errorsAs(t *testing.T) {
+	bh := NewBatchHeader()
+	bh.BatchNumber = 1
+	bh.StandardEntryClassCode = PPD
+	err := (&Batch{Header: bh}).Error("FieldError", fieldError("Amount", ErrNegativeAmount, -1))
+
+	var coded CodedError
+	require.ErrorAs(t, err, &coded)
+	require.Equal(t, CodeAmountNegative, coded.Code())
+
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	require.Equal(t, CodeAmountNegative, fe.Code())
+
+	var be *BatchError
+	require.ErrorAs(t, err, &be)
+	require.Equal(t, CodeAmountNegative, be.Code())
+}
+
+func TestDescribeErrors(t *testing.T) {
+	require.Nil(t, DescribeErrors(nil))
+	require.Equal(t, ErrorDetail{}, DescribeError(nil))
+
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	file.Header.FileIDModifier = "?"
+	file.Control.EntryHash = 1
+
+	details := DescribeErrors(file.ValidateAll(nil))
+	require.Len(t, details, 2)
+
+	require.Equal(t, ErrorDetail{
+		Code:      CodeFieldNotUppercase,
+		Record:    "FileHeader",
+		Line:      1,
+		FieldName: "FileIDModifier",
+		Value:     "?",
+		Rule:      ruleRecordFormat,
+		Message:   "FileIDModifier ? is not uppercase A-Z or 0-9: ?",
+	}, details[0])
+
+	require.Equal(t, CodeFileControlTotals, details[1].Code)
+	require.Equal(t, "FileControl", details[1].Record)
+	require.Equal(t, 5, details[1].Line)
+
+	t.Run("batch", func(t *testing.T) {
+		bh := NewBatchHeader()
+		bh.BatchNumber = 2
+		bh.StandardEntryClassCode = PPD
+		err := (&Batch{Header: bh}).Error("ServiceClassCode", NewErrBatchHeaderControlEquality(200, 220), 220)
+
+		detail := DescribeError(err)
+		require.Equal(t, CodeBatchHeaderControlMismatch, detail.Code)
+		require.Equal(t, "ServiceClassCode", detail.FieldName)
+		require.Equal(t, 220, detail.Value)
+		require.Equal(t, err.Error(), detail.Message)
+	})
+}
@@ -0,0 +1,87 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package examples
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/moov-io/ach"
+)
+
+// Example_streamWrite writes a PPD credit file one entry at a time
+func Example_streamWrite() {
+	var buf bytes.Buffer
+	sw := ach.NewStreamWriter(&buf, nil)
+
+	fh := mockFileHeader()
+	if err := sw.WriteHeader(fh); err != nil {
+		log.Fatalf("Unexpected error writing file header: %s\n", err)
+	}
+
+	bh := ach.NewBatchHeader()
+	bh.ServiceClassCode = ach.CreditsOnly
+	bh.CompanyName = "Name on Account"
+	bh.CompanyIdentification = fh.ImmediateOrigin
+	bh.StandardEntryClassCode = ach.PPD
+	bh.CompanyEntryDescription = "REG.SALARY"
+	bh.EffectiveEntryDate = "190816" // need EffectiveEntryDate to be fixed so it can match output
+	bh.ODFIIdentification = "121042882"
+	if err := sw.OpenBatch(bh); err != nil {
+		log.Fatalf("Unexpected error opening batch: %s\n", err)
+	}
+
+	// Entries can be read from a database or another file without holding them all in memory
+	for i := 1; i <= 2; i++ {
+		entry := ach.NewEntryDetail()
+		entry.TransactionCode = ach.CheckingCredit
+		entry.SetRDFI("231380104")
+		entry.DFIAccountNumber = "987654321"
+		entry.Amount = 100000000
+		entry.IndividualName = fmt.Sprintf("Credit Account %d", i)
+
+		if err := sw.WriteEntry(entry); err != nil {
+			log.Fatalf("Unexpected error writing entry: %s\n", err)
+		}
+	}
+
+	if err := sw.CloseBatch(); err != nil {
+		log.Fatalf("Unexpected error closing batch: %s\n", err)
+	}
+	fc, err := sw.Close()
+	if err != nil {
+		log.Fatalf("Unexpected error closing file: %s\n", err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	for _, line := range lines[:6] { // skip block padding
+		fmt.Println(strings.TrimSpace(line))
+	}
+	fmt.Printf("blocks: %d", fc.BlockCount)
+
+	// Output:
+	// 101 031300012 2313801041908161055A094101Federal Reserve Bank   My Bank Name           12345678
+	// 5220Name on Account                     231380104 PPDREG.SALARY      190816   1121042880000001
+	// 622231380104987654321        0100000000               Credit Account 1        0121042880000001
+	// 622231380104987654321        0100000000               Credit Account 2        0121042880000002
+	// 82200000020046276020000000000000000200000000231380104                          121042880000001
+	// 9000001000001000000020046276020000000000000000200000000
+	// blocks: 1
+}
@@ -169,6 +169,10 @@ func (e ErrValidCheckDigit) Error() string {
 	return e.Message
 }
 
+func (e ErrValidCheckDigit) Code() ErrorCode {
+	return CodeCheckDigitInvalid
+}
+
 // ErrValidFieldLength is the error given when the field does not have the correct length
 type ErrValidFieldLength struct {
 	Message        string
@@ -187,6 +191,10 @@ func (e ErrValidFieldLength) Error() string {
 	return e.Message
 }
 
+func (e ErrValidFieldLength) Code() ErrorCode {
+	return CodeFieldLength
+}
+
 // ErrRecordType is the error given when the field does not have the right record type
 type ErrRecordType struct {
 	Message      string
@@ -204,3 +212,7 @@ func NewErrRecordType(expectedType int) ErrRecordType {
 func (e ErrRecordType) Error() string {
 	return e.Message
 }
+
+func (e ErrRecordType) Code() ErrorCode {
+	return CodeRecordTypeInvalid
+}
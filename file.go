@@ -19,10 +19,12 @@ package ach
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -73,6 +75,9 @@ type File struct {
 	ReturnEntries []Batcher `json:"ReturnEntries"`
 
 	validateOpts *ValidateOpts
+
+	// sequencer assigns the FileIDModifier and trace numbers once when Create is called
+	sequencer Sequencer
 }
 
 // NewFile constructs a file template.
@@ -746,12 +751,20 @@ func annotateIATEntryLineNumbers(ed *IATEntryDetail, startIndex int) int {
 // Since each Batch may modify computable fields in the File, any calls to
 // Batch.Create should be done before Create.
 //
+// The FileIDModifier and trace numbers are assigned first when a Sequencer has been set with SetSequencer.
+//
 // To check if the File is Nacha compliant, call Validate or ValidateWith.
 func (f *File) Create() error {
 	opts := f.validateOpts
 	if opts == nil {
 		opts = &ValidateOpts{}
 	}
+	if f.sequencer != nil {
+		if err := f.Sequence(f.sequencer); err != nil {
+			return err
+		}
+		f.sequencer = nil
+	}
 	if !opts.SkipAll {
 		// Requires a valid FileHeader to build FileControl
 		if !opts.AllowMissingFileHeader {
@@ -946,6 +959,16 @@ type ValidateOpts struct {
 	// Note: Functions cannot be serialized into/from JSON, so this check cannot be used from config files.
 	CheckTransactionCode func(code int) error `json:"-"`
 
+	// CustomRules are validation functions which run against each FileHeader, BatchHeader, EntryDetail,
+	// Addenda05 and IAT record after the Nacha rules.
+	//
+	// Note: Functions cannot be serialized into/from JSON, see Rules for checks that can be.
+	CustomRules *CustomRules `json:"-"`
+
+	// Rules are declarative checks (regex, allowed/blocked values, max amount per SEC code)
+	// of record fields which run alongside CustomRules.
+	Rules []ValidationRule `json:"rules,omitempty"`
+
 	// CustomTraceNumbers disables Nacha specified checks of TraceNumbers:
 	// - Ascending order of trace numbers within batches
 	// - Trace numbers beginning with their ODFI's routing number
@@ -999,7 +1022,8 @@ type ValidateOpts struct {
 	// for SEC codes that require the field to be non-blank (and non-zero)
 	AllowEmptyIndividualName bool `json:"allowEmptyIndividualName"`
 
-	// BypassBatchValidation will skip validation for batches in a file and only validate file header and control info
+	// BypassBatchValidation will skip validation for batches in a file and only validate file header and control info.
+	// Checks enabled by RequireBankingDayEffectiveEntryDate, MaxEffectiveEntryDateDays and ValidateTXP still run.
 	BypassBatchValidation bool `json:"bypassBatchValidation"`
 
 	// SkipFileCreationValidation will skip validation of the FileCreationTime and FileCreationDate fields in a file header
@@ -1007,6 +1031,26 @@ type ValidateOpts struct {
 
 	// SkipBatchHeaderCompanyValidation will bypass validation of Company fields in a BatchHeader
 	SkipBatchHeaderCompanyValidation bool `json:"skipBatchHeaderCompanyValidation"`
+
+	// CollectAllErrors will continue validating a File after the first error is found and return
+	// every error as a base.ErrorList. See File.ValidateAll for more details.
+	CollectAllErrors bool `json:"collectAllErrors"`
+
+	// RequireBankingDayEffectiveEntryDate rejects batches whose EffectiveEntryDate is a weekend or
+	// Federal Reserve holiday.
+	RequireBankingDayEffectiveEntryDate bool `json:"requireBankingDayEffectiveEntryDate"`
+
+	// EffectiveEntryDateCalendar determines banking days for RequireBankingDayEffectiveEntryDate.
+	// FederalReserveCalendar is used when nil.
+	EffectiveEntryDateCalendar BankingCalendar `json:"-"`
+
+	// MaxEffectiveEntryDateDays rejects batches whose EffectiveEntryDate is more than this many days
+	// after the FileCreationDate.
+	MaxEffectiveEntryDateDays int `json:"maxEffectiveEntryDateDays"`
+
+	// ValidateTXP checks Addenda05 records of CCD and CTX tax payments which start with TXP. Each must
+	// be a valid TXP addenda (see addenda.ParseTXP) and their tax amounts must add up to the entry's Amount.
+	ValidateTXP bool `json:"validateTXP"`
 }
 
 // merge will combine two ValidateOpts structs and keep any non-zero field values.
@@ -1042,6 +1086,11 @@ func (v *ValidateOpts) merge(other *ValidateOpts) *ValidateOpts {
 		BypassBatchValidation:            v.BypassBatchValidation || other.BypassBatchValidation,
 		SkipFileCreationValidation:       v.SkipFileCreationValidation || other.SkipFileCreationValidation,
 		SkipBatchHeaderCompanyValidation: v.SkipBatchHeaderCompanyValidation || other.SkipBatchHeaderCompanyValidation,
+		CollectAllErrors:                 v.CollectAllErrors || other.CollectAllErrors,
+
+		RequireBankingDayEffectiveEntryDate: v.RequireBankingDayEffectiveEntryDate || other.RequireBankingDayEffectiveEntryDate,
+		MaxEffectiveEntryDateDays:           cmp.Or(v.MaxEffectiveEntryDateDays, other.MaxEffectiveEntryDateDays),
+		ValidateTXP:                         v.ValidateTXP || other.ValidateTXP,
 	}
 
 	if v.CheckTransactionCode != nil {
@@ -1050,6 +1099,14 @@ func (v *ValidateOpts) merge(other *ValidateOpts) *ValidateOpts {
 	if other.CheckTransactionCode != nil {
 		out.CheckTransactionCode = other.CheckTransactionCode
 	}
+	if v.EffectiveEntryDateCalendar != nil {
+		out.EffectiveEntryDateCalendar = v.EffectiveEntryDateCalendar
+	}
+	if other.EffectiveEntryDateCalendar != nil {
+		out.EffectiveEntryDateCalendar = other.EffectiveEntryDateCalendar
+	}
+	out.CustomRules = v.CustomRules.merge(other.CustomRules)
+	out.Rules = slices.Concat(v.Rules, other.Rules)
 
 	return out
 }
@@ -1061,7 +1118,7 @@ func (v *ValidateOpts) merge(other *ValidateOpts) *ValidateOpts {
 // opts passed in will override ValidateOpts set by SetValidation.
 // The underlying Batches and Entries on this File will use their own ValidateOpts if they are set.
 //
-// The first error encountered is returned.
+// The first error encountered is returned, unless CollectAllErrors is set which returns every error.
 func (f *File) ValidateWith(opts *ValidateOpts) error {
 	if opts == nil {
 		opts = &ValidateOpts{}
@@ -1070,6 +1127,9 @@ func (f *File) ValidateWith(opts *ValidateOpts) error {
 	if opts.SkipAll {
 		return nil
 	}
+	if opts.CollectAllErrors {
+		return f.ValidateAll(opts)
+	}
 
 	if !opts.AllowMissingFileHeader {
 		if err := f.Header.ValidateWith(opts); err != nil {
@@ -1101,7 +1161,10 @@ func (f *File) ValidateWith(opts *ValidateOpts) error {
 				return err
 			}
 		}
-		return f.ValidateTotals()
+		if err := f.ValidateTotals(); err != nil {
+			return err
+		}
+		return f.validateRules(opts)
 	}
 
 	// File contains ADV batches BatchADV
@@ -1115,7 +1178,230 @@ func (f *File) ValidateWith(opts *ValidateOpts) error {
 			return err
 		}
 	}
-	return f.ValidateTotals()
+	if err := f.ValidateTotals(); err != nil {
+		return err
+	}
+	return f.validateRules(opts)
+}
+
+// ValidateAll performs the same checks as ValidateWith but continues after an error is found.
+// Every FileHeader, Batch, EntryDetail, Addenda and FileControl record is checked and each error
+// is returned in a base.ErrorList. Errors are wrapped in a base.ParseError containing the line
+// number and record name of the offending record, so FieldError, BatchError and FileError values
+// can be retrieved with errors.As.
+//
+// Line numbers are read from each record's LineNumber, which is set by Reader and File.Create.
+// ValidateAll will never modify the File. A nil error is returned when the File is valid.
+func (f *File) ValidateAll(opts *ValidateOpts) error {
+	if opts == nil {
+		opts = &ValidateOpts{}
+	}
+	if opts.SkipAll {
+		return nil
+	}
+
+	v := &validationReport{seen: make(map[string]bool)}
+
+	if !opts.AllowMissingFileHeader {
+		v.add(f.Header.LineNumber, "FileHeader", f.Header.ValidateWith(opts))
+	}
+
+	isADV := f.IsADV()
+	controlLine := f.Control.LineNumber
+	if isADV {
+		controlLine = f.ADVControl.LineNumber
+	}
+
+	if !opts.BypassBatchValidation {
+		for _, b := range f.Batches {
+			v.addBatch(b)
+		}
+		for i := range f.IATBatches {
+			v.addIATBatch(&f.IATBatches[i])
+		}
+	}
+
+	if !opts.AllowMissingFileControl {
+		if isADV {
+			v.add(controlLine, "FileControl", f.ADVControl.Validate())
+		} else {
+			v.add(controlLine, "FileControl", f.Control.Validate())
+		}
+	}
+	if !isADV && !opts.AllowUnorderedBatchNumbers {
+		v.add(controlLine, "FileControl", f.isSequenceAscending())
+	}
+	v.add(controlLine, "FileControl", f.isEntryAddendaCount(isADV))
+	v.add(controlLine, "FileControl", f.isFileAmount(isADV))
+	v.add(controlLine, "FileControl", f.isEntryHash(isADV))
+	v.add(controlLine, "FileControl", f.isBatchCount(isADV))
+
+	opts.checkOptionalRules(f, func(line int, record string, err error) {
+		v.add(line, record, err)
+	})
+	if err := opts.checkCustomRules(f, func(line int, record string, err error) {
+		v.add(line, record, err)
+	}); err != nil {
+		v.add(0, "", err)
+	}
+
+	if v.errors.Empty() {
+		return nil
+	}
+	return v.errors
+}
+
+// checkOptionalRules runs the checks ValidateOpts only enables on request against each batch in f and calls
+// onFailure for every failure. These are RequireBankingDayEffectiveEntryDate, MaxEffectiveEntryDateDays and
+// ValidateTXP. They run even with BypassBatchValidation, which only skips Nacha's batch rules, while SkipAll
+// disables them.
+func (v *ValidateOpts) checkOptionalRules(f *File, onFailure func(line int, record string, err error)) {
+	if v == nil || v.SkipAll || f == nil {
+		return
+	}
+	if !v.RequireBankingDayEffectiveEntryDate && v.MaxEffectiveEntryDateDays <= 0 && !v.ValidateTXP {
+		return
+	}
+	report := func(line int, record string, err error) {
+		if err != nil {
+			onFailure(line, record, err)
+		}
+	}
+
+	for _, b := range f.Batches {
+		bh := b.GetHeader()
+		if bh == nil {
+			continue
+		}
+		report(bh.LineNumber, "BatchHeader", v.validateEffectiveEntryDate(&f.Header, bh.StandardEntryClassCode, bh.EffectiveEntryDate))
+		for _, ed := range b.GetEntries() {
+			report(ed.LineNumber, "EntryDetail", v.validateTXP(bh, ed))
+		}
+	}
+	for i := range f.IATBatches {
+		bh := f.IATBatches[i].GetHeader()
+		if bh == nil {
+			continue
+		}
+		report(bh.LineNumber, "BatchHeader", v.validateEffectiveEntryDate(&f.Header, IAT, bh.EffectiveEntryDate))
+	}
+}
+
+// validationReport collects errors found by File.ValidateAll
+type validationReport struct {
+	errors base.ErrorList
+
+	// seen holds each reported error message so the same problem isn't listed twice
+	seen map[string]bool
+}
+
+func (v *validationReport) add(line int, record string, err error) bool {
+	if err == nil {
+		return false
+	}
+	key := fmt.Sprintf("%d:%s", line, err.Error())
+	if v.seen[key] {
+		return false
+	}
+	v.seen[key] = true
+	v.seen[err.Error()] = true
+
+	v.errors.Add(&base.ParseError{
+		Line:   line,
+		Record: record,
+		Err:    err,
+	})
+	return true
+}
+
+// reported returns true if err, or any error it wraps, has already been added.
+func (v *validationReport) reported(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if v.seen[err.Error()] {
+			return true
+		}
+	}
+	return false
+}
+
+// addBatch checks each record in the Batch before running the SEC code specific validation.
+func (v *validationReport) addBatch(b Batcher) {
+	bh := b.GetHeader()
+	if bh == nil {
+		return
+	}
+	v.add(bh.LineNumber, "BatchHeader", bh.Validate())
+
+	for _, entry := range b.GetEntries() {
+		v.add(entry.LineNumber, "EntryDetail", entry.Validate())
+
+		if entry.Addenda02 != nil {
+			v.add(entry.Addenda02.LineNumber, "Addenda", entry.Addenda02.Validate())
+		}
+		for _, addenda05 := range entry.Addenda05 {
+			v.add(addenda05.LineNumber, "Addenda", addenda05.Validate())
+		}
+		if entry.Addenda98 != nil {
+			v.add(entry.Addenda98.LineNumber, "Addenda", entry.Addenda98.Validate())
+		}
+		if entry.Addenda98Refused != nil {
+			v.add(entry.Addenda98Refused.LineNumber, "Addenda", entry.Addenda98Refused.Validate())
+		}
+		if entry.Addenda99 != nil {
+			v.add(entry.Addenda99.LineNumber, "Addenda", entry.Addenda99.Validate())
+		}
+		if entry.Addenda99Dishonored != nil {
+			v.add(entry.Addenda99Dishonored.LineNumber, "Addenda", entry.Addenda99Dishonored.Validate())
+		}
+		if entry.Addenda99Contested != nil {
+			v.add(entry.Addenda99Contested.LineNumber, "Addenda", entry.Addenda99Contested.Validate())
+		}
+	}
+	for _, entry := range b.GetADVEntries() {
+		v.add(entry.LineNumber, "EntryDetail", entry.Validate())
+	}
+
+	controlLine := bh.LineNumber
+	if bc := b.GetControl(); bc != nil {
+		controlLine = bc.LineNumber
+		v.add(bc.LineNumber, "BatchControl", bc.Validate())
+	}
+	if bc := b.GetADVControl(); bc != nil {
+		controlLine = bc.LineNumber
+		v.add(bc.LineNumber, "BatchControl", bc.Validate())
+	}
+
+	// Entry specific rules for the batch's SEC code
+	if ie, ok := b.(interface{ InvalidEntries() []InvalidEntry }); ok {
+		for _, invalid := range ie.InvalidEntries() {
+			switch {
+			case invalid.Entry != nil:
+				v.add(invalid.Entry.LineNumber, "EntryDetail", invalid.Error)
+			case invalid.ADVEntry != nil:
+				v.add(invalid.ADVEntry.LineNumber, "EntryDetail", invalid.Error)
+			default:
+				v.add(bh.LineNumber, "BatchHeader", invalid.Error)
+			}
+		}
+	}
+
+	// Validate stops at the first error, which is often one reported above.
+	if err := b.Validate(); err != nil && !v.reported(err) {
+		v.add(controlLine, "BatchControl", err)
+	}
+}
+
+func (v *validationReport) addIATBatch(b *IATBatch) {
+	line := 0
+	if b.Header != nil {
+		line = b.Header.LineNumber
+	}
+	if b.Control != nil {
+		line = b.Control.LineNumber
+	}
+	if err := b.Validate(); err != nil && !v.reported(err) {
+		v.add(line, "BatchControl", err)
+	}
 }
 
 // ValidateTotals performs checks on: 1.File entry addenda counts 2. File credit/debit totals 3. File entry hash 4. File batch count
@@ -1364,7 +1650,16 @@ func (f *File) createFileADV() error {
 //
 // The File returned may not be valid and callers should confirm with Validate. Invalid files may be rejected
 // by other Financial Institutions or ACH tools.
-func (f *File) SegmentFile(_ *SegmentFileConfiguration) (*File, *File, error) {
+//
+// config may only segment by SegmentByCreditDebit, use SegmentFiles to split a File by other rules.
+func (f *File) SegmentFile(config *SegmentFileConfiguration) (*File, *File, error) {
+	rules, err := config.rules()
+	if err != nil {
+		return nil, nil, err
+	}
+	if (config != nil && config.KeyFunc != nil) || len(rules) != 1 || rules[0] != SegmentByCreditDebit {
+		return nil, nil, ErrSegmentFileRules
+	}
 	if err := f.Validate(); err != nil {
 		return nil, nil, err
 	}
@@ -1410,6 +1705,222 @@ func (f *File) SegmentFile(_ *SegmentFileConfiguration) (*File, *File, error) {
 	return creditFile, debitFile, nil
 }
 
+// SegmentFiles takes a valid ACH File and splits its entries into Files according to the SegmentBy rules
+// and KeyFunc of config. Each entry is placed in a batch copied from its original batch header, so a File
+// contains one batch for every original batch with entries in that segment. Files are returned in the order
+// their first entry appears in f.
+//
+// A nil config splits the File into credits and debits.
+func (f *File) SegmentFiles(config *SegmentFileConfiguration) ([]SegmentedFile, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	rules, err := config.rules()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []SegmentedFile
+	files := make(map[string]*File)
+	fileFor := func(values map[string]string, key string) *File {
+		if file, exists := files[key]; exists {
+			return file
+		}
+		file := NewFile()
+		if f.validateOpts != nil {
+			file.SetValidation(f.validateOpts)
+		}
+		f.addFileHeaderData(file)
+		files[key] = file
+		out = append(out, SegmentedFile{Segment: values, File: file})
+		return file
+	}
+
+	for _, batch := range f.Batches {
+		bh := batch.GetHeader()
+		batches := make(map[string]Batcher)
+		var keys []string
+		batchFor := func(values map[string]string, key string) (Batcher, error) {
+			if b, exists := batches[key]; exists {
+				return b, nil
+			}
+			nbh := *bh
+			nbh.ID = base.ID()
+			b, err := NewBatch(&nbh)
+			if err != nil {
+				return nil, err
+			}
+			b.SetValidation(f.validateOpts)
+			batches[key] = b
+			keys = append(keys, key)
+			fileFor(values, key).AddBatch(b)
+			return b, nil
+		}
+		// Entries are copied so creating the segmented batches leaves f unchanged
+		if bh.StandardEntryClassCode == ADV {
+			for _, entry := range batch.GetADVEntries() {
+				values, key := config.segment(rules, f.Header, &IteratorRecord{BatchHeader: bh, ADVEntry: entry})
+				b, err := batchFor(values, key)
+				if err != nil {
+					return nil, err
+				}
+				b.AddADVEntry(cloneADVEntryDetail(entry))
+			}
+		} else {
+			for _, entry := range batch.GetEntries() {
+				values, key := config.segment(rules, f.Header, &IteratorRecord{BatchHeader: bh, Entry: entry})
+				b, err := batchFor(values, key)
+				if err != nil {
+					return nil, err
+				}
+				b.AddEntry(cloneEntryDetail(entry))
+			}
+		}
+		for _, key := range keys {
+			b := batches[key]
+			if bh.StandardEntryClassCode != ADV {
+				b.GetHeader().ServiceClassCode = segmentServiceClassCode(b.GetEntries())
+			}
+			if err := b.Create(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, iatb := range f.IATBatches {
+		bh := iatb.GetHeader()
+		batches := make(map[string]*IATBatch)
+		var keys []string
+		for _, entry := range iatb.GetEntries() {
+			values, key := config.segment(rules, f.Header, &IteratorRecord{IATBatchHeader: bh, IATEntry: entry})
+			b, exists := batches[key]
+			if !exists {
+				nbh := *bh
+				nbh.ID = base.ID()
+				nb := NewIATBatch(&nbh)
+				b = &nb
+				batches[key] = b
+				keys = append(keys, key)
+			}
+			b.AddEntry(cloneIATEntryDetail(entry))
+			if !exists {
+				fileFor(values, key)
+			}
+		}
+		for _, key := range keys {
+			b := batches[key]
+			b.GetHeader().ServiceClassCode = segmentIATServiceClassCode(b.GetEntries())
+			if err := b.Create(); err != nil {
+				return nil, err
+			}
+			files[key].AddIATBatch(*b)
+		}
+	}
+
+	for i := range out {
+		if err := out[i].File.Create(); err != nil {
+			return nil, err
+		}
+		if err := out[i].File.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// cloneEntryDetail returns a copy of ed and its addenda records
+func cloneEntryDetail(ed *EntryDetail) *EntryDetail {
+	out := *ed
+	out.Addenda02 = clonePtr(ed.Addenda02)
+	out.Addenda05 = clonePtrs(ed.Addenda05)
+	out.Addenda98 = clonePtr(ed.Addenda98)
+	out.Addenda98Refused = clonePtr(ed.Addenda98Refused)
+	out.Addenda99 = clonePtr(ed.Addenda99)
+	out.Addenda99Contested = clonePtr(ed.Addenda99Contested)
+	out.Addenda99Dishonored = clonePtr(ed.Addenda99Dishonored)
+	return &out
+}
+
+// cloneADVEntryDetail returns a copy of ed and its addenda record
+func cloneADVEntryDetail(ed *ADVEntryDetail) *ADVEntryDetail {
+	out := *ed
+	out.Addenda99 = clonePtr(ed.Addenda99)
+	return &out
+}
+
+// cloneIATEntryDetail returns a copy of ed and its addenda records
+func cloneIATEntryDetail(ed *IATEntryDetail) *IATEntryDetail {
+	out := *ed
+	out.Addenda10 = clonePtr(ed.Addenda10)
+	out.Addenda11 = clonePtr(ed.Addenda11)
+	out.Addenda12 = clonePtr(ed.Addenda12)
+	out.Addenda13 = clonePtr(ed.Addenda13)
+	out.Addenda14 = clonePtr(ed.Addenda14)
+	out.Addenda15 = clonePtr(ed.Addenda15)
+	out.Addenda16 = clonePtr(ed.Addenda16)
+	out.Addenda17 = clonePtrs(ed.Addenda17)
+	out.Addenda18 = clonePtrs(ed.Addenda18)
+	out.Addenda98 = clonePtr(ed.Addenda98)
+	out.Addenda99 = clonePtr(ed.Addenda99)
+	return &out
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	out := *p
+	return &out
+}
+
+func clonePtrs[T any](ps []*T) []*T {
+	if ps == nil {
+		return nil
+	}
+	out := make([]*T, len(ps))
+	for i := range ps {
+		out[i] = clonePtr(ps[i])
+	}
+	return out
+}
+
+// segmentServiceClassCode returns the ServiceClassCode for a batch containing entries
+func segmentServiceClassCode(entries []*EntryDetail) int {
+	var credits, debits bool
+	for _, entry := range entries {
+		switch entry.CreditOrDebit() {
+		case "C":
+			credits = true
+		case "D":
+			debits = true
+		}
+	}
+	return serviceClassCodeFor(credits, debits)
+}
+
+func segmentIATServiceClassCode(entries []*IATEntryDetail) int {
+	var credits, debits bool
+	for _, entry := range entries {
+		switch creditOrDebit(entry.TransactionCode) {
+		case "C":
+			credits = true
+		case "D":
+			debits = true
+		}
+	}
+	return serviceClassCodeFor(credits, debits)
+}
+
+func serviceClassCodeFor(credits, debits bool) int {
+	switch {
+	case credits && !debits:
+		return CreditsOnly
+	case debits && !credits:
+		return DebitsOnly
+	}
+	return MixedDebitsAndCredits
+}
+
 func (f *File) segmentFileBatches(creditFile, debitFile *File) error {
 	for _, batch := range f.Batches {
 		bh := batch.GetHeader()
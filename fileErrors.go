@@ -71,6 +71,10 @@ func (e RecordWrongLengthErr) Error() string {
 	return e.Message
 }
 
+func (e RecordWrongLengthErr) Code() ErrorCode {
+	return CodeFileRecordLength
+}
+
 // ErrUnknownRecordType is the error given when a record does not have a known type
 type ErrUnknownRecordType struct {
 	Message string
@@ -89,6 +93,10 @@ func (e ErrUnknownRecordType) Error() string {
 	return e.Message
 }
 
+func (e ErrUnknownRecordType) Code() ErrorCode {
+	return CodeFileUnknownRecordType
+}
+
 // ErrFileUnknownSEC is the error given when a record does not have a known type
 type ErrFileUnknownSEC struct {
 	Message string
@@ -107,6 +115,10 @@ func (e ErrFileUnknownSEC) Error() string {
 	return e.Message
 }
 
+func (e ErrFileUnknownSEC) Code() ErrorCode {
+	return CodeFileUnknownSEC
+}
+
 // ErrFileCalculatedControlEquality is the error given when the control record does not match the calculated value
 type ErrFileCalculatedControlEquality struct {
 	Message         string
@@ -129,6 +141,10 @@ func (e ErrFileCalculatedControlEquality) Error() string {
 	return e.Message
 }
 
+func (e ErrFileCalculatedControlEquality) Code() ErrorCode {
+	return CodeFileControlTotals
+}
+
 // ErrFileBatchNumberAscending is the error given when the batch numbers in a file are not in ascending order
 type ErrFileBatchNumberAscending struct {
 	Message       string
@@ -148,3 +164,7 @@ func NewErrFileBatchNumberAscending(previous, current int) ErrFileBatchNumberAsc
 func (e ErrFileBatchNumberAscending) Error() string {
 	return e.Message
 }
+
+func (e ErrFileBatchNumberAscending) Code() ErrorCode {
+	return CodeFileBatchNumberOrder
+}
@@ -2403,6 +2403,50 @@ func TestFile_BypassBatchValidation(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestFile_ValidateAll(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	require.NoError(t, file.ValidateAll(nil))
+
+	// Break records throughout the file
+	file.Header.FileIDModifier = "?"
+	entry := file.Batches[0].GetEntries()[0]
+	entry.IndividualName = "testÑåṁe"
+	file.Control.EntryHash = 1
+
+	// Validate only reports the first error
+	err = file.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "FileIDModifier")
+
+	err = file.ValidateAll(nil)
+	require.Error(t, err)
+
+	var el base.ErrorList
+	require.ErrorAs(t, err, &el)
+	require.Len(t, el, 3)
+
+	var pe *base.ParseError
+	require.ErrorAs(t, el[0], &pe)
+	require.Equal(t, 1, pe.Line)
+	require.Equal(t, "FileHeader", pe.Record)
+
+	var fe *FieldError
+	require.ErrorAs(t, el[1], &pe)
+	require.Equal(t, 3, pe.Line)
+	require.Equal(t, "EntryDetail", pe.Record)
+	require.ErrorAs(t, el[1], &fe)
+
+	require.ErrorAs(t, el[2], &pe)
+	require.Equal(t, 5, pe.Line)
+	require.Equal(t, "FileControl", pe.Record)
+	require.ErrorIs(t, el[2], NewErrFileCalculatedControlEquality("EntryHash", 23138010, 1))
+
+	// CollectAllErrors returns the same list from ValidateWith
+	err = file.ValidateWith(&ValidateOpts{CollectAllErrors: true})
+	require.Equal(t, el, err)
+}
+
 // TestFile_ValidateTotals tests the ValidateTotals method
 func TestFile_ValidateTotals(t *testing.T) {
 	t.Run("valid file with PPD batch", func(t *testing.T) {
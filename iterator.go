@@ -31,9 +31,17 @@ import (
 // It is useful for processing large ACH files efficiently.
 // The iterator maintains internal state to track the current position in the file.
 type Iterator struct {
+	converters
+
 	reader     *Reader
 	scanner    *bufio.Scanner
 	cachedLine string
+
+	// running totals verified by NextRecord
+	adv         bool
+	batchCount  int
+	batchTotals streamTotals
+	fileTotals  streamTotals
 }
 
 // NewIterator creates a new Iterator for reading ACH files from the provided io.Reader.
@@ -87,7 +95,7 @@ func (i *Iterator) GetControl() *FileControl {
 // NextEntry advances the iterator and returns the next EntryDetail record along with its associated BatchHeader.
 // Returns (nil, nil, nil) when there are no more entries.
 // Returns an error if the file is malformed or if the max lines limit is exceeded.
-// IAT entries are not currently supported.
+// IAT and ADV entries are not supported, use NextRecord for those files.
 func (i *Iterator) NextEntry() (*BatchHeader, *EntryDetail, error) {
 start:
 	// Read the file one line at a time
@@ -209,3 +217,322 @@ func allSpaces(input string) bool {
 	}
 	return len(input) > 0
 }
+
+// IteratorRecord is a single entry or batch boundary returned by Iterator.NextRecord.
+//
+// Every record has the header of its batch set, BatchHeader for domestic and ADV batches or
+// IATBatchHeader for IAT batches. A record with no entry or control set marks the start of a batch.
+// Entries are returned with all of their addenda records and the end of each batch is returned with
+// its parsed control record.
+type IteratorRecord struct {
+	BatchHeader    *BatchHeader    `json:"batchHeader,omitempty"`
+	IATBatchHeader *IATBatchHeader `json:"iatBatchHeader,omitempty"`
+
+	Entry    *EntryDetail    `json:"entryDetail,omitempty"`
+	ADVEntry *ADVEntryDetail `json:"advEntryDetail,omitempty"`
+	IATEntry *IATEntryDetail `json:"iatEntryDetail,omitempty"`
+
+	BatchControl    *BatchControl    `json:"batchControl,omitempty"`
+	ADVBatchControl *ADVBatchControl `json:"advBatchControl,omitempty"`
+}
+
+// GetADVControl returns the ADVFileControl record from an ADV file read with NextRecord.
+// Returns nil if the end of the file has not been reached yet.
+func (i *Iterator) GetADVControl() *ADVFileControl {
+	if i.reader != nil {
+		return &i.reader.File.ADVControl
+	}
+	return nil
+}
+
+// ForEachRecord calls fn with every record returned from NextRecord until the end of the file is reached.
+// The first error from reading the file or returned by fn stops iteration and is returned.
+func (i *Iterator) ForEachRecord(fn func(record *IteratorRecord) error) error {
+	for {
+		record, err := i.NextRecord()
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return nil
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// NextRecord advances the iterator and returns the next batch header, entry or batch control from the file.
+// Domestic, IAT and ADV batches are supported. Returns (nil, nil) when there are no more records.
+//
+// Only the current entry is kept in memory. Batch and file control totals are verified as each control
+// record is read, so files of any size can be validated in constant memory.
+// NextRecord and NextEntry should not be mixed on the same Iterator.
+func (i *Iterator) NextRecord() (*IteratorRecord, error) {
+	for {
+		line, err := i.nextLine()
+		if err != nil || line == "" {
+			return nil, err
+		}
+
+		switch line[:1] {
+		case batchHeaderPos:
+			if err := i.readRecord(line); err != nil {
+				return nil, err
+			}
+			i.batchTotals = streamTotals{}
+			if i.reader.currentBatch != nil {
+				if i.reader.currentBatch.GetHeader().StandardEntryClassCode == ADV {
+					i.adv = true
+				}
+				return &IteratorRecord{BatchHeader: i.reader.currentBatch.GetHeader()}, nil
+			}
+			return &IteratorRecord{IATBatchHeader: i.reader.IATCurrentBatch.Header}, nil
+
+		case entryDetailPos:
+			return i.nextEntryRecord(line)
+
+		case batchControlPos:
+			return i.batchControlRecord(line)
+
+		case fileControlPos:
+			if strings.HasPrefix(line, "99") {
+				continue // padding
+			}
+			if err := i.fileControl(line); err != nil {
+				return nil, err
+			}
+
+		default:
+			if err := i.readRecord(line); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// nextLine returns the next non-blank line of the file, or an empty string once the input is exhausted.
+func (i *Iterator) nextLine() (string, error) {
+	if line := i.cachedLine; line != "" {
+		i.cachedLine = ""
+		return line, nil
+	}
+	for i.scanner.Scan() {
+		line := i.scanner.Text()
+		i.reader.lineNum++
+		if i.reader.maxLines > 0 && i.reader.lineNum > i.reader.maxLines {
+			return "", fmt.Errorf("line %d: %w", i.reader.lineNum, ErrFileTooLong)
+		}
+		if line != "" && !allSpaces(line) {
+			return line, nil
+		}
+	}
+	if err := i.scanner.Err(); err != nil {
+		return "", fmt.Errorf("scanning line %d failed: %w", i.reader.lineNum+1, err)
+	}
+	return "", nil
+}
+
+func (i *Iterator) readRecord(line string) error {
+	if err := i.reader.readLine(line); err != nil {
+		return fmt.Errorf("reading line %d failed: %w", i.reader.lineNum, err)
+	}
+	return nil
+}
+
+func (i *Iterator) skipTotals() bool {
+	opts := i.reader.File.validateOpts
+	return opts != nil && opts.SkipAll
+}
+
+// nextEntryRecord parses an entry and every addenda record following it.
+func (i *Iterator) nextEntryRecord(line string) (*IteratorRecord, error) {
+	// Drop the previously returned entry so only one is held in memory
+	if b := i.reader.currentBatch; b != nil {
+		b.DeleteEntries(func(*EntryDetail) bool { return true })
+		b.DeleteADVEntries(func(*ADVEntryDetail) bool { return true })
+	}
+	if i.reader.IATCurrentBatch.Header != nil {
+		i.reader.IATCurrentBatch.Entries = nil
+	}
+
+	if err := i.readRecord(line); err != nil {
+		return nil, err
+	}
+	for {
+		next, err := i.nextLine()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(next, entryAddendaPos) {
+			i.cachedLine = next
+			break
+		}
+		if err := i.readRecord(next); err != nil {
+			return nil, err
+		}
+	}
+
+	if b := i.reader.currentBatch; b != nil {
+		bh := b.GetHeader()
+		if bh.StandardEntryClassCode == ADV {
+			entries := b.GetADVEntries()
+			ed := entries[len(entries)-1]
+
+			single := &Batch{Header: bh, ADVEntries: []*ADVEntryDetail{ed}}
+			credit, debit := single.calculateADVBatchAmounts()
+			i.batchTotals.entryAddendaCount++
+			if ed.Addenda99 != nil {
+				i.batchTotals.entryAddendaCount++
+			}
+			i.batchTotals.entryHash += single.calculateEntryHash()
+			i.batchTotals.credit += credit
+			i.batchTotals.debit += debit
+			return &IteratorRecord{BatchHeader: bh, ADVEntry: ed}, nil
+		}
+
+		entries := b.GetEntries()
+		ed := entries[len(entries)-1]
+
+		single := &Batch{Header: bh, Entries: []*EntryDetail{ed}}
+		credit, debit := single.calculateBatchAmounts()
+		i.batchTotals.entryAddendaCount += 1 + ed.addendaCount()
+		i.batchTotals.entryHash += single.calculateEntryHash()
+		i.batchTotals.credit += credit
+		i.batchTotals.debit += debit
+		return &IteratorRecord{BatchHeader: bh, Entry: ed}, nil
+	}
+
+	entries := i.reader.IATCurrentBatch.GetEntries()
+	ed := entries[len(entries)-1]
+
+	single := &IATBatch{Entries: []*IATEntryDetail{ed}}
+	credit, debit := single.calculateBatchAmounts()
+	i.batchTotals.entryAddendaCount += 1 + ed.addendaCount()
+	i.batchTotals.entryHash += single.calculateEntryHash()
+	i.batchTotals.credit += credit
+	i.batchTotals.debit += debit
+	return &IteratorRecord{IATBatchHeader: i.reader.IATCurrentBatch.Header, IATEntry: ed}, nil
+}
+
+// batchControlRecord parses a BatchControl or ADVBatchControl and compares it against
+// the totals accumulated from the batch's entries.
+func (i *Iterator) batchControlRecord(line string) (*IteratorRecord, error) {
+	padded, err := rightPadShortLine(line)
+	if err != nil {
+		return nil, fmt.Errorf("reading line %d failed: %w", i.reader.lineNum, i.reader.parseError(err))
+	}
+	i.reader.line = padded
+	if err := i.reader.parseBatchControl(); err != nil {
+		return nil, fmt.Errorf("reading line %d failed: %w", i.reader.lineNum, err)
+	}
+
+	var record *IteratorRecord
+	if b := i.reader.currentBatch; b != nil {
+		i.reader.currentBatch = nil
+
+		record = &IteratorRecord{BatchHeader: b.GetHeader()}
+		if b.GetHeader().StandardEntryClassCode == ADV {
+			bc := b.GetADVControl()
+			record.ADVBatchControl = bc
+			err = i.verifyBatch(b.Error, bc.EntryAddendaCount, bc.EntryHash, bc.TotalCreditEntryDollarAmount, bc.TotalDebitEntryDollarAmount)
+		} else {
+			bc := b.GetControl()
+			record.BatchControl = bc
+			err = i.verifyBatch(b.Error, bc.EntryAddendaCount, bc.EntryHash, bc.TotalCreditEntryDollarAmount, bc.TotalDebitEntryDollarAmount)
+		}
+	} else {
+		b := i.reader.IATCurrentBatch
+		i.reader.IATCurrentBatch = IATBatch{}
+
+		bc := b.GetControl()
+		record = &IteratorRecord{IATBatchHeader: b.GetHeader(), BatchControl: bc}
+		err = i.verifyBatch(b.Error, bc.EntryAddendaCount, bc.EntryHash, bc.TotalCreditEntryDollarAmount, bc.TotalDebitEntryDollarAmount)
+	}
+	if err != nil {
+		i.reader.recordName = "Batches"
+		return nil, fmt.Errorf("reading line %d failed: %w", i.reader.lineNum, i.reader.parseError(err))
+	}
+	return record, nil
+}
+
+func (i *Iterator) verifyBatch(batchError func(string, error, ...interface{}) error, count, hash, credit, debit int) error {
+	i.batchCount++
+	i.fileTotals.entryAddendaCount += count
+	i.fileTotals.entryHash += hash
+	i.fileTotals.credit += credit
+	i.fileTotals.debit += debit
+
+	if i.skipTotals() {
+		return nil
+	}
+	opts := i.reader.File.validateOpts
+	if calculated := i.batchTotals.entryAddendaCount; calculated != count {
+		if opts == nil || !opts.UnequalAddendaCounts {
+			return batchError("EntryAddendaCount", NewErrBatchCalculatedControlEquality(calculated, count))
+		}
+	}
+	if calculated := i.leastSignificantDigits(i.batchTotals.entryHash, 10); calculated != hash {
+		return batchError("EntryHash", NewErrBatchCalculatedControlEquality(calculated, hash))
+	}
+	if i.batchTotals.debit != debit {
+		return batchError("TotalDebitEntryDollarAmount", NewErrBatchCalculatedControlEquality(i.batchTotals.debit, debit))
+	}
+	if i.batchTotals.credit != credit {
+		return batchError("TotalCreditEntryDollarAmount", NewErrBatchCalculatedControlEquality(i.batchTotals.credit, credit))
+	}
+	return nil
+}
+
+// fileControl parses the FileControl (or ADVFileControl) and compares it against the batch controls read.
+func (i *Iterator) fileControl(line string) error {
+	if !i.adv {
+		if err := i.readRecord(line); err != nil {
+			return err
+		}
+		fc := i.reader.File.Control
+		return i.verifyFile(fc.BatchCount, fc.EntryAddendaCount, fc.EntryHash, fc.TotalCreditEntryDollarAmountInFile, fc.TotalDebitEntryDollarAmountInFile)
+	}
+
+	padded, err := rightPadShortLine(line)
+	if err != nil {
+		return fmt.Errorf("reading line %d failed: %w", i.reader.lineNum, i.reader.parseError(err))
+	}
+	i.reader.line = padded
+	i.reader.recordName = "FileControl"
+	if (ADVFileControl{}) != i.reader.File.ADVControl {
+		return fmt.Errorf("reading line %d failed: %w", i.reader.lineNum, i.reader.parseError(ErrFileControl))
+	}
+	fc := &i.reader.File.ADVControl
+	fc.Parse(i.reader.line)
+	fc.LineNumber = i.reader.lineNum
+	if err := maybeValidate(fc, i.reader.File.validateOpts); err != nil {
+		return fmt.Errorf("reading line %d failed: %w", i.reader.lineNum, i.reader.parseError(err))
+	}
+	return i.verifyFile(fc.BatchCount, fc.EntryAddendaCount, fc.EntryHash, fc.TotalCreditEntryDollarAmountInFile, fc.TotalDebitEntryDollarAmountInFile)
+}
+
+func (i *Iterator) verifyFile(batchCount, count, hash, credit, debit int) error {
+	if i.skipTotals() {
+		return nil
+	}
+	opts := i.reader.File.validateOpts
+
+	var err error
+	switch {
+	case i.batchCount != batchCount:
+		err = NewErrFileCalculatedControlEquality("BatchCount", i.batchCount, batchCount)
+	case i.fileTotals.entryAddendaCount != count && (opts == nil || !opts.UnequalAddendaCounts):
+		err = NewErrFileCalculatedControlEquality("EntryAddendaCount", i.fileTotals.entryAddendaCount, count)
+	case i.leastSignificantDigits(i.fileTotals.entryHash, 10) != hash:
+		err = NewErrFileCalculatedControlEquality("EntryHash", i.leastSignificantDigits(i.fileTotals.entryHash, 10), hash)
+	case i.fileTotals.debit != debit:
+		err = NewErrFileCalculatedControlEquality("TotalDebitEntryDollarAmountInFile", i.fileTotals.debit, debit)
+	case i.fileTotals.credit != credit:
+		err = NewErrFileCalculatedControlEquality("TotalCreditEntryDollarAmountInFile", i.fileTotals.credit, credit)
+	}
+	if err != nil {
+		return fmt.Errorf("reading line %d failed: %w", i.reader.lineNum, i.reader.parseError(err))
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterator_NextRecord(t *testing.T) {
+	t.Run("domestic", func(t *testing.T) {
+		paths := []string{
+			filepath.Join("test", "testdata", "ppd-mixedDebitCredit.ach"),
+			filepath.Join("test", "testdata", "web-debit.ach"),
+			filepath.Join("test", "testdata", "two-micro-deposits.ach"),
+		}
+		for _, where := range paths {
+			file := openFile(t, where, nil)
+			iter := iteratorFromFile(t, where, nil)
+
+			var batches []Batcher
+			var entries []*EntryDetail
+			err := iter.ForEachRecord(func(record *IteratorRecord) error {
+				require.NotNil(t, record.BatchHeader, where)
+				require.Nil(t, record.IATBatchHeader)
+
+				switch {
+				case record.Entry != nil:
+					entries = append(entries, record.Entry)
+				case record.BatchControl != nil:
+					b, err := NewBatch(record.BatchHeader)
+					require.NoError(t, err)
+					for _, ed := range entries {
+						b.AddEntry(ed)
+					}
+					b.SetControl(record.BatchControl)
+					batches = append(batches, b)
+					entries = nil
+				}
+				return nil
+			})
+			require.NoError(t, err, where)
+			require.Len(t, batches, len(file.Batches), where)
+
+			for i := range batches {
+				require.Equal(t, file.Batches[i].GetHeader(), batches[i].GetHeader())
+				require.Equal(t, file.Batches[i].GetEntries(), batches[i].GetEntries())
+				require.Equal(t, file.Batches[i].GetControl(), batches[i].GetControl())
+			}
+			require.Equal(t, file.Header, *iter.GetHeader())
+			require.Equal(t, file.Control.EntryHash, iter.GetControl().EntryHash)
+			require.Equal(t, file.Control.EntryAddendaCount, iter.GetControl().EntryAddendaCount)
+		}
+	})
+
+	t.Run("IAT", func(t *testing.T) {
+		paths := []string{
+			filepath.Join("test", "testdata", "iat-debit.ach"),
+			filepath.Join("test", "testdata", "iat-mixedDebitCredit.ach"),
+			filepath.Join("test", "testdata", "20180716-IAT-A17-A18.ach"),
+		}
+		for _, where := range paths {
+			file := openFile(t, where, nil)
+			iter := iteratorFromFile(t, where, nil)
+
+			var headers, controls int
+			var entries []*IATEntryDetail
+			for {
+				record, err := iter.NextRecord()
+				require.NoError(t, err, where)
+				if record == nil {
+					break
+				}
+				require.NotNil(t, record.IATBatchHeader)
+				require.Nil(t, record.BatchHeader)
+
+				switch {
+				case record.IATEntry != nil:
+					entries = append(entries, record.IATEntry)
+				case record.BatchControl != nil:
+					require.Equal(t, file.IATBatches[controls].GetControl(), record.BatchControl)
+					controls++
+				default:
+					require.Equal(t, file.IATBatches[headers].GetHeader(), record.IATBatchHeader)
+					headers++
+				}
+			}
+			require.Equal(t, len(file.IATBatches), headers)
+			require.Equal(t, len(file.IATBatches), controls)
+
+			var expected []*IATEntryDetail
+			for _, b := range file.IATBatches {
+				expected = append(expected, b.Entries...)
+			}
+			require.Equal(t, expected, entries)
+		}
+	})
+
+	t.Run("domestic and IAT batches", func(t *testing.T) {
+		where := filepath.Join("test", "testdata", "20110805A.ach")
+		file := openFile(t, where, nil)
+		iter := iteratorFromFile(t, where, nil)
+
+		var domestic, iat int
+		err := iter.ForEachRecord(func(record *IteratorRecord) error {
+			if record.BatchControl != nil {
+				if record.IATBatchHeader != nil {
+					iat++
+				} else {
+					domestic++
+				}
+			}
+			return nil
+		})
+		// The FileControl of this file claims 5 batches
+		require.ErrorContains(t, err, "BatchCount calculated 4 is out-of-balance with file control 5")
+		require.Equal(t, len(file.Batches), domestic)
+		require.Equal(t, len(file.IATBatches), iat)
+	})
+
+	t.Run("ADV", func(t *testing.T) {
+		where := filepath.Join("test", "testdata", "adv.ach")
+		file := openFile(t, where, nil)
+		iter := iteratorFromFile(t, where, nil)
+
+		var entries []*ADVEntryDetail
+		var control *ADVBatchControl
+		err := iter.ForEachRecord(func(record *IteratorRecord) error {
+			if record.ADVEntry != nil {
+				entries = append(entries, record.ADVEntry)
+			}
+			if record.ADVBatchControl != nil {
+				control = record.ADVBatchControl
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, file.Batches[0].GetADVEntries(), entries)
+		require.Equal(t, file.Batches[0].GetADVControl(), control)
+		require.Equal(t, file.ADVControl.EntryHash, iter.GetADVControl().EntryHash)
+		require.Equal(t, file.ADVControl.TotalDebitEntryDollarAmountInFile, iter.GetADVControl().TotalDebitEntryDollarAmountInFile)
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		iter := iteratorFromFile(t, filepath.Join("test", "testdata", "ppd-debit.ach"), nil)
+
+		stop := errors.New("stop")
+		var calls int
+		err := iter.ForEachRecord(func(record *IteratorRecord) error {
+			calls++
+			return stop
+		})
+		require.ErrorIs(t, err, stop)
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestIterator_NextRecordTotals(t *testing.T) {
+	bs, err := os.ReadFile(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	lines := strings.Split(string(bs), "\n")
+
+	iterate := func(t *testing.T, lines []string, opts *ValidateOpts) error {
+		t.Helper()
+
+		iter := NewIterator(strings.NewReader(strings.Join(lines, "\n")))
+		iter.SetValidation(opts)
+		return iter.ForEachRecord(func(*IteratorRecord) error { return nil })
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		require.NoError(t, iterate(t, lines, nil))
+	})
+
+	t.Run("batch control", func(t *testing.T) {
+		modified := append([]string(nil), lines...)
+		bc := []rune(modified[3])
+		require.Equal(t, '8', bc[0])
+		bc[31] = '9' // TotalDebitEntryDollarAmount
+		modified[3] = string(bc)
+
+		err := iterate(t, modified, nil)
+		require.ErrorContains(t, err, "TotalDebitEntryDollarAmount")
+
+		var batchErr *BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, "TotalDebitEntryDollarAmount", batchErr.FieldName)
+
+		// SkipAll doesn't check totals
+		require.NoError(t, iterate(t, modified, &ValidateOpts{SkipAll: true}))
+	})
+
+	t.Run("file control", func(t *testing.T) {
+		modified := append([]string(nil), lines...)
+		fc := []rune(modified[4])
+		require.Equal(t, '9', fc[0])
+		fc[6] = '2' // BatchCount
+		modified[4] = string(fc)
+
+		err := iterate(t, modified, nil)
+		require.ErrorContains(t, err, "BatchCount")
+	})
+}
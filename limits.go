@@ -0,0 +1,262 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SameDayEntryLimit is the largest Amount, in cents, of an entry which is eligible for same-day settlement.
+const SameDayEntryLimit = 1_000_000_00
+
+// ErrLimitsExceeded is wrapped by LimitsError when Files exceed their origination limits.
+var ErrLimitsExceeded = errors.New("origination limits exceeded")
+
+// Limits are origination exposure limits an ODFI enforces on Files before they are transmitted.
+// Amounts are in cents and zero values are not checked.
+type Limits struct {
+	// MaxEntryAmount is the largest Amount of any single entry.
+	MaxEntryAmount int `json:"maxEntryAmount,omitempty"`
+
+	// MaxFileCreditAmount and MaxFileDebitAmount are the largest credit and debit totals of each File.
+	MaxFileCreditAmount int `json:"maxFileCreditAmount,omitempty"`
+	MaxFileDebitAmount  int `json:"maxFileDebitAmount,omitempty"`
+
+	// Companies holds limits for each CompanyIdentification (or OriginatorIdentification for IAT batches).
+	// Limits under the "*" key apply to any company not listed.
+	Companies map[string]CompanyLimits `json:"companies,omitempty"`
+
+	// MaxEntriesPerSEC is the largest number of entries for each StandardEntryClassCode.
+	MaxEntriesPerSEC map[string]int `json:"maxEntriesPerSEC,omitempty"`
+
+	// SameDayEntryLimit rejects entries over SameDayEntryLimit in same-day batches, which are
+	// batches with an EffectiveEntryDate equal to the FileCreationDate.
+	SameDayEntryLimit bool `json:"sameDayEntryLimit,omitempty"`
+
+	// Offsets are the offset accounts of the checked files. Offset records, and entries sent to these
+	// accounts, move funds within the ODFI and are not checked or counted toward any limit.
+	Offsets []*Offset `json:"offsets,omitempty"`
+}
+
+// CompanyLimits are the daily totals a company can originate. Days are determined by the
+// EffectiveEntryDate of each batch.
+type CompanyLimits struct {
+	MaxDailyCreditAmount int `json:"maxDailyCreditAmount,omitempty"`
+	MaxDailyDebitAmount  int `json:"maxDailyDebitAmount,omitempty"`
+}
+
+// LimitViolation describes a limit which was exceeded.
+type LimitViolation struct {
+	// Limit is the name of the field in Limits or CompanyLimits which was exceeded
+	Limit string `json:"limit"`
+
+	FileID                string `json:"fileID,omitempty"`
+	CompanyIdentification string `json:"companyIdentification,omitempty"`
+	StandardEntryClass    string `json:"standardEntryClass,omitempty"`
+	EffectiveEntryDate    string `json:"effectiveEntryDate,omitempty"`
+	TraceNumber           string `json:"traceNumber,omitempty"`
+
+	// Actual is the amount or count found, which is larger than Max
+	Actual int `json:"actual"`
+	Max    int `json:"max"`
+}
+
+func (v LimitViolation) Error() string {
+	var buf strings.Builder
+	buf.WriteString(v.Limit)
+	if v.CompanyIdentification != "" {
+		fmt.Fprintf(&buf, " for company %s", v.CompanyIdentification)
+	}
+	if v.StandardEntryClass != "" {
+		fmt.Fprintf(&buf, " for %s", v.StandardEntryClass)
+	}
+	if v.EffectiveEntryDate != "" {
+		fmt.Fprintf(&buf, " on %s", v.EffectiveEntryDate)
+	}
+	if v.TraceNumber != "" {
+		fmt.Fprintf(&buf, " by entry %s", v.TraceNumber)
+	}
+	fmt.Fprintf(&buf, ": %d exceeds %d", v.Actual, v.Max)
+	return buf.String()
+}
+
+// LimitsError is returned when Files exceed their Limits. It contains every violation found.
+type LimitsError struct {
+	Violations []LimitViolation `json:"violations"`
+}
+
+func (e *LimitsError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i := range e.Violations {
+		msgs[i] = e.Violations[i].Error()
+	}
+	return fmt.Sprintf("%v: %s", ErrLimitsExceeded, strings.Join(msgs, ", "))
+}
+
+func (e *LimitsError) Unwrap() error {
+	return ErrLimitsExceeded
+}
+
+// Check evaluates files together against the limits and returns a *LimitsError if any are exceeded.
+// Company and SEC code limits are checked against the combined totals of every file.
+func (l *Limits) Check(files ...*File) error {
+	violations := l.Violations(files...)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &LimitsError{Violations: violations}
+}
+
+type companyDay struct {
+	company            string
+	effectiveEntryDate string
+}
+
+// Violations evaluates files together against the limits and returns each limit exceeded.
+func (l *Limits) Violations(files ...*File) []LimitViolation {
+	if l == nil {
+		return nil
+	}
+
+	var out []LimitViolation
+	credits, debits := make(map[companyDay]int), make(map[companyDay]int)
+	secCounts := make(map[string]int)
+
+	for _, f := range files {
+		if f == nil {
+			continue
+		}
+		var fileCredits, fileDebits int
+
+		addEntry := func(company, sec, effectiveEntryDate, traceNumber string, amount, transactionCode int) {
+			day := companyDay{company: company, effectiveEntryDate: effectiveEntryDate}
+			switch creditOrDebit(transactionCode) {
+			case "C":
+				credits[day] += amount
+				fileCredits += amount
+			case "D":
+				debits[day] += amount
+				fileDebits += amount
+			}
+			secCounts[sec]++
+
+			if l.MaxEntryAmount > 0 && amount > l.MaxEntryAmount {
+				out = append(out, LimitViolation{
+					Limit:                 "maxEntryAmount",
+					FileID:                f.ID,
+					CompanyIdentification: company,
+					TraceNumber:           traceNumber,
+					Actual:                amount,
+					Max:                   l.MaxEntryAmount,
+				})
+			}
+			if l.SameDayEntryLimit && amount > SameDayEntryLimit && effectiveEntryDate == f.Header.FileCreationDate {
+				out = append(out, LimitViolation{
+					Limit:                 "sameDayEntryLimit",
+					FileID:                f.ID,
+					CompanyIdentification: company,
+					EffectiveEntryDate:    effectiveEntryDate,
+					TraceNumber:           traceNumber,
+					Actual:                amount,
+					Max:                   SameDayEntryLimit,
+				})
+			}
+		}
+
+		for _, b := range f.Batches {
+			bh := b.GetHeader()
+			if bh == nil {
+				continue
+			}
+			isOffset := offsetRecordFunc(b, l.Offsets)
+			for _, ed := range b.GetEntries() {
+				if isOffset(ed) {
+					continue
+				}
+				addEntry(bh.CompanyIdentification, bh.StandardEntryClassCode, bh.EffectiveEntryDate, ed.TraceNumber, ed.Amount, ed.TransactionCode)
+			}
+		}
+		for i := range f.IATBatches {
+			bh := f.IATBatches[i].GetHeader()
+			if bh == nil {
+				continue
+			}
+			for _, ed := range f.IATBatches[i].GetEntries() {
+				addEntry(bh.OriginatorIdentification, IAT, bh.EffectiveEntryDate, ed.TraceNumber, ed.Amount, ed.TransactionCode)
+			}
+		}
+
+		if l.MaxFileCreditAmount > 0 && fileCredits > l.MaxFileCreditAmount {
+			out = append(out, LimitViolation{Limit: "maxFileCreditAmount", FileID: f.ID, Actual: fileCredits, Max: l.MaxFileCreditAmount})
+		}
+		if l.MaxFileDebitAmount > 0 && fileDebits > l.MaxFileDebitAmount {
+			out = append(out, LimitViolation{Limit: "maxFileDebitAmount", FileID: f.ID, Actual: fileDebits, Max: l.MaxFileDebitAmount})
+		}
+	}
+
+	out = append(out, l.companyViolations("maxDailyCreditAmount", credits, func(c CompanyLimits) int { return c.MaxDailyCreditAmount })...)
+	out = append(out, l.companyViolations("maxDailyDebitAmount", debits, func(c CompanyLimits) int { return c.MaxDailyDebitAmount })...)
+
+	for _, sec := range sortedKeys(l.MaxEntriesPerSEC) {
+		n := l.MaxEntriesPerSEC[sec]
+		if n > 0 && secCounts[sec] > n {
+			out = append(out, LimitViolation{Limit: "maxEntriesPerSEC", StandardEntryClass: sec, Actual: secCounts[sec], Max: n})
+		}
+	}
+
+	return out
+}
+
+func (l *Limits) companyViolations(limit string, totals map[companyDay]int, maxFor func(CompanyLimits) int) []LimitViolation {
+	var out []LimitViolation
+	for _, day := range sortedKeysFunc(totals, func(a, b companyDay) int {
+		return cmp.Or(cmp.Compare(a.company, b.company), cmp.Compare(a.effectiveEntryDate, b.effectiveEntryDate))
+	}) {
+		companyLimits, exists := l.Companies[day.company]
+		if !exists {
+			companyLimits = l.Companies["*"]
+		}
+		if m := maxFor(companyLimits); m > 0 && totals[day] > m {
+			out = append(out, LimitViolation{
+				Limit:                 limit,
+				CompanyIdentification: day.company,
+				EffectiveEntryDate:    day.effectiveEntryDate,
+				Actual:                totals[day],
+				Max:                   m,
+			})
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return sortedKeysFunc(m, strings.Compare)
+}
+
+func sortedKeysFunc[K comparable, V any](m map[K]V, compare func(a, b K) int) []K {
+	out := make([]K, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	slices.SortFunc(out, compare)
+	return out
+}
@@ -0,0 +1,214 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits__nil(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	var limits *Limits
+	require.Empty(t, limits.Violations(file))
+	require.NoError(t, limits.Check(file))
+
+	limits = &Limits{}
+	require.NoError(t, limits.Check(file))
+}
+
+func TestLimits__Entry(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	limits := &Limits{MaxEntryAmount: 50000000}
+	violations := limits.Violations(file)
+	require.Len(t, violations, 1)
+	require.Equal(t, LimitViolation{
+		Limit:                 "maxEntryAmount",
+		CompanyIdentification: "121042882",
+		TraceNumber:           "121042880000001",
+		Actual:                100000000,
+		Max:                   50000000,
+	}, violations[0])
+	require.Equal(t, "maxEntryAmount for company 121042882 by entry 121042880000001: 100000000 exceeds 50000000", violations[0].Error())
+
+	err = limits.Check(file)
+	require.ErrorIs(t, err, ErrLimitsExceeded)
+	require.Equal(t, CodeLimitsExceeded, errorCode(err))
+
+	var limitsErr *LimitsError
+	require.True(t, errors.As(err, &limitsErr))
+	require.Equal(t, violations, limitsErr.Violations)
+}
+
+func TestLimits__IAT(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "iat-debit.ach"))
+	require.NoError(t, err)
+
+	limits := &Limits{
+		MaxEntryAmount:   1,
+		MaxEntriesPerSEC: map[string]int{IAT: 1},
+	}
+	violations := limits.Violations(file)
+	require.Len(t, violations, 1)
+	require.Equal(t, "maxEntryAmount", violations[0].Limit)
+	require.Equal(t, file.IATBatches[0].Header.OriginatorIdentification, violations[0].CompanyIdentification)
+}
+
+func TestLimits__Totals(t *testing.T) {
+	file1, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file2, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	t.Run("file", func(t *testing.T) {
+		limits := &Limits{
+			MaxFileCreditAmount: 1,
+			MaxFileDebitAmount:  150000000,
+		}
+		require.Empty(t, limits.Violations(file1, file2))
+
+		limits.MaxFileDebitAmount = 50000000
+		violations := limits.Violations(file1, file2)
+		require.Len(t, violations, 2)
+		require.Equal(t, "maxFileDebitAmount", violations[0].Limit)
+		require.Equal(t, 100000000, violations[0].Actual)
+	})
+
+	t.Run("company", func(t *testing.T) {
+		limits := &Limits{
+			Companies: map[string]CompanyLimits{
+				"121042882": {MaxDailyCreditAmount: 1, MaxDailyDebitAmount: 150000000},
+			},
+		}
+		violations := limits.Violations(file1, file2)
+		require.Len(t, violations, 1)
+		require.Equal(t, LimitViolation{
+			Limit:                 "maxDailyDebitAmount",
+			CompanyIdentification: "121042882",
+			EffectiveEntryDate:    "190625",
+			Actual:                200000000,
+			Max:                   150000000,
+		}, violations[0])
+
+		// Only one day is over the limit
+		file2.Batches[0].GetHeader().EffectiveEntryDate = "190626"
+		require.Empty(t, limits.Violations(file1, file2))
+		file2.Batches[0].GetHeader().EffectiveEntryDate = "190625"
+	})
+
+	t.Run("any company", func(t *testing.T) {
+		limits := &Limits{
+			Companies: map[string]CompanyLimits{
+				"987654320": {MaxDailyDebitAmount: 500000000},
+				"*":         {MaxDailyDebitAmount: 150000000},
+			},
+		}
+		violations := limits.Violations(file1, file2)
+		require.Len(t, violations, 1)
+		require.Equal(t, "121042882", violations[0].CompanyIdentification)
+	})
+
+	t.Run("SEC", func(t *testing.T) {
+		limits := &Limits{
+			MaxEntriesPerSEC: map[string]int{PPD: 1, CCD: 1},
+		}
+		violations := limits.Violations(file1, file2)
+		require.Len(t, violations, 1)
+		require.Equal(t, "maxEntriesPerSEC for PPD: 2 exceeds 1", violations[0].Error())
+	})
+}
+
+func TestLimits__Offsets(t *testing.T) {
+	off := &Offset{RoutingNumber: "231380104", AccountNumber: "99887766", AccountType: OffsetChecking}
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	require.NoError(t, file.Balance(off))
+	require.Len(t, file.Batches[0].GetEntries(), 2)
+
+	// the credit offset doesn't count toward the company's credits
+	limits := &Limits{
+		MaxEntryAmount:      100000000,
+		MaxFileCreditAmount: 1,
+		Companies: map[string]CompanyLimits{
+			"121042882": {MaxDailyCreditAmount: 1, MaxDailyDebitAmount: 100000000},
+		},
+		MaxEntriesPerSEC: map[string]int{PPD: 1},
+	}
+	require.Empty(t, limits.Violations(file))
+
+	// after reading the file its offset records are only known from the offset account
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf).Write(file))
+	read, err := NewReader(&buf).Read()
+	require.NoError(t, err)
+	require.Len(t, limits.Violations(&read), 3)
+
+	limits.Offsets = []*Offset{off}
+	require.Empty(t, limits.Violations(&read))
+}
+
+func TestLimits__SameDay(t *testing.T) {
+	file := mockFilePPD(t)
+	require.Equal(t, file.Header.FileCreationDate, file.Batches[0].GetHeader().EffectiveEntryDate)
+
+	limits := &Limits{SameDayEntryLimit: true}
+	require.Empty(t, limits.Violations(file))
+
+	file.Batches[0].GetEntries()[0].Amount = SameDayEntryLimit + 1
+	violations := limits.Violations(file)
+	require.Len(t, violations, 1)
+	require.Equal(t, "sameDayEntryLimit", violations[0].Limit)
+	require.Equal(t, file.ID, violations[0].FileID)
+
+	// Entries settling on a later day are not limited
+	file.Header.FileCreationDate = "190624"
+	require.Empty(t, limits.Violations(file))
+}
+
+func TestMergeFiles__Limits(t *testing.T) {
+	file1, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file2, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file2.Batches[0].GetEntries()[0].TraceNumber = "121042880000002"
+	require.NoError(t, file2.Create())
+
+	conditions := Conditions{
+		Limits: &Limits{
+			Companies: map[string]CompanyLimits{
+				"121042882": {MaxDailyDebitAmount: 200000000},
+			},
+		},
+	}
+	merged, err := MergeFilesWith([]*File{file1, file2}, conditions)
+	require.NoError(t, err)
+	require.Len(t, merged, 1)
+
+	conditions.Limits.Companies["121042882"] = CompanyLimits{MaxDailyDebitAmount: 150000000}
+	merged, err = MergeFilesWith([]*File{file1, file2}, conditions)
+	require.ErrorIs(t, err, ErrLimitsExceeded)
+	require.Empty(t, merged)
+}
@@ -18,15 +18,16 @@
 package ach
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 
@@ -47,7 +48,7 @@ const (
 // Entries with duplicate TraceNumbers are allowed in the same file, but must be in separate batches
 // and are automatically separated.
 //
-// ADV Batches and Entries are currently not merged together.
+// ADV Batches are merged into separate files from other batches.
 //
 // Old rules limit files to 10,000 lines (when rendered in their ASCII encoding), which
 // is the default for this function. Use MergeFilesWith for a higher limit.
@@ -88,6 +89,32 @@ type Conditions struct {
 
 	// MaxDollarAmount will limit each merged file's total dollar amount per side
 	MaxDollarAmount int64 `json:"maxDollarAmount"`
+
+	// MaxEntriesPerBatch will split merged batches which would contain more entries.
+	// ADV batches are always limited to 9999 entries.
+	MaxEntriesPerBatch int `json:"maxEntriesPerBatch,omitempty"`
+
+	// MaxBatchesPerFile will limit the number of batches in each merged file.
+	MaxBatchesPerFile int `json:"maxBatchesPerFile,omitempty"`
+
+	// GroupByEffectiveEntryDate will only merge batches with the same EffectiveEntryDate into a file.
+	GroupByEffectiveEntryDate bool `json:"groupByEffectiveEntryDate,omitempty"`
+
+	// PreserveBatches keeps each incoming batch separate rather than consolidating
+	// batches with equal BatchHeaders into fewer batches.
+	PreserveBatches bool `json:"preserveBatches,omitempty"`
+
+	// SortOutput orders merged files by their routing numbers and batches by their header
+	// values instead of the order files were merged. Use this when merging concurrently
+	// (e.g. MergeDir) and the output needs to be reproducible.
+	SortOutput bool `json:"sortOutput,omitempty"`
+
+	// Limits will reject merged files which exceed origination limits.
+	// A *LimitsError is returned with every violation found.
+	Limits *Limits `json:"limits,omitempty"`
+
+	// Sequencer assigns the FileIDModifier of each merged file. Trace numbers are not changed.
+	Sequencer Sequencer `json:"-"`
 }
 
 // MergeFilesWith is a function for consolidating an array of ACH Files into a few files as possible.
@@ -99,12 +126,18 @@ type Conditions struct {
 // Entries with duplicate TraceNumbers are allowed in the same file, but must be in separate batches
 // and are automatically separated.
 //
-// ADV Batches and Entries are currently not merged together.
+// ADV Batches are merged into separate files from other batches.
 //
-// Conditions allows for capping the maximum line length or dollar amount of merged files.
+// Conditions allows for capping the maximum line length, dollar amount, batch size or batch count of merged files
+// along with grouping by EffectiveEntryDate, keeping original batches and sorting the output.
+// Conditions.Limits will reject merged files which exceed origination limits.
 //
 // File Batches can only be merged if they are unique and routed to and from the same ABA routing numbers.
 func MergeFilesWith(incoming []*File, conditions Conditions) ([]*File, error) {
+	return mergeFilesWith(incoming, conditions, nil)
+}
+
+func mergeFilesWith(incoming []*File, conditions Conditions, prov *mergeProvenance) ([]*File, error) {
 	if len(incoming) == 0 {
 		return nil, nil
 	}
@@ -115,7 +148,8 @@ func MergeFilesWith(incoming []*File, conditions Conditions) ([]*File, error) {
 	}
 
 	for i := range incoming {
-		err := sorted.add(incoming[i])
+		prov.record("", incoming[i])
+		err := sorted.add(incoming[i], conditions)
 		if err != nil {
 			return nil, err
 		}
@@ -153,6 +187,19 @@ type MergeDirOptions struct {
 
 	// SubDirectories is a setting to traverse subdirectories for mergable ACH files.
 	SubDirectories bool
+
+	// DuplicateChecker is used to find Files and entries which have been seen before.
+	// Each File merged is recorded under its path once merging succeeds.
+	DuplicateChecker *DuplicateChecker
+
+	// RejectDuplicates will skip Files which contain any duplicates instead of merging them.
+	RejectDuplicates bool
+
+	// OnDuplicates is called with the duplicates found in each File.
+	OnDuplicates func(path string, duplicates []Duplicate)
+
+	// paths records the filepath each File was read from for a MergeManifest or DuplicateChecker
+	paths *sync.Map
 }
 
 // DefaultFileAcceptor is the default logic for which file extensions to merge and how to read them.
@@ -181,16 +228,25 @@ func DefaultFileAcceptor(path string) FileAcceptance {
 // Entries with duplicate TraceNumbers are allowed in the same file, but must be in separate batches
 // and are automatically separated.
 //
-// ADV Batches and Entries are currently not merged together.
+// ADV Batches are merged into separate files from other batches.
 //
 // MergeDir is typically more performant than MergeFiles as it reads files concurrently while merging occurs.
 // This has a more stable cpu and memory usage trend over reading all files into memory and then calling MergeFiles.
 //
 // File Batches can only be merged if they are unique and routed to and from the same ABA routing numbers.
 func MergeDir(dir string, conditions Conditions, opts *MergeDirOptions) ([]*File, error) {
+	return mergeDir(dir, conditions, opts, nil)
+}
+
+func mergeDir(dir string, conditions Conditions, opts *MergeDirOptions, prov *mergeProvenance) ([]*File, error) {
 	if opts == nil {
 		opts = &MergeDirOptions{}
 	}
+	if prov != nil || opts.DuplicateChecker != nil {
+		o := *opts
+		o.paths = &sync.Map{}
+		opts = &o
+	}
 	if opts.AcceptFile == nil {
 		opts.AcceptFile = DefaultFileAcceptor
 	}
@@ -225,6 +281,11 @@ func MergeDir(dir string, conditions Conditions, opts *MergeDirOptions) ([]*File
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	var duplicates *pendingDuplicates
+	if opts.DuplicateChecker != nil {
+		duplicates = opts.DuplicateChecker.pending()
+	}
+
 	var g errgroup.Group
 
 	parseWorkers := defaultParseWorkers()
@@ -278,12 +339,31 @@ func MergeDir(dir string, conditions Conditions, opts *MergeDirOptions) ([]*File
 			if file == nil {
 				continue
 			}
+			var path string
+			if opts.paths != nil {
+				if v, ok := opts.paths.Load(file); ok {
+					path, _ = v.(string)
+				}
+			}
+			if duplicates != nil {
+				merge, err := checkDuplicates(path, file, opts, duplicates)
+				if err != nil {
+					cancel()
+					for range mergableFiles {
+					}
+					return err
+				}
+				if !merge {
+					continue
+				}
+			}
 			if first {
 				sorted.header = file.Header
 				sorted.validateOpts = file.GetValidation()
 				first = false
 			}
-			if err := sorted.add(file); err != nil {
+			prov.record(path, file)
+			if err := sorted.add(file, conditions); err != nil {
 				cancel()
 				// Drain remaining files so parser workers are not stuck on send.
 				for range mergableFiles {
@@ -302,7 +382,35 @@ func MergeDir(dir string, conditions Conditions, opts *MergeDirOptions) ([]*File
 		return nil, fmt.Errorf("merging %s failed: %w", dir, parseErr)
 	}
 
-	return convertToFiles(sorted, conditions)
+	files, err := convertToFiles(sorted, conditions)
+	if err != nil {
+		return nil, err
+	}
+	if err := duplicates.commit(); err != nil {
+		return nil, fmt.Errorf("merging %s failed: %w", dir, err)
+	}
+	return files, nil
+}
+
+// checkDuplicates reports the duplicates found in file and returns if file should be merged.
+// Files which are merged are held in pending until the merge succeeds.
+func checkDuplicates(path string, file *File, opts *MergeDirOptions, pending *pendingDuplicates) (bool, error) {
+	duplicates, err := pending.Check(file)
+	if err != nil {
+		return false, fmt.Errorf("checking %s for duplicates: %w", path, err)
+	}
+	if len(duplicates) > 0 {
+		if opts.OnDuplicates != nil {
+			opts.OnDuplicates(path, duplicates)
+		}
+		if opts.RejectDuplicates {
+			return false, nil
+		}
+	}
+	if err := pending.accept(file, path); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 func defaultParseWorkers() int {
@@ -398,6 +506,9 @@ func readFileForMerging(ctx context.Context, discoveredPaths <-chan string, merg
 			if file == nil {
 				continue
 			}
+			if opts.paths != nil {
+				opts.paths.Store(file, path)
+			}
 
 			select {
 			case mergableFiles <- file:
@@ -485,31 +596,67 @@ type outFile struct {
 	batches []*batch
 
 	iatBatches []*iatBatch
+	advBatches []*advBatch
+
+	// effectiveEntryDate is set when Conditions.GroupByEffectiveEntryDate is used
+	effectiveEntryDate string
+	adv                bool
 
 	validateOpts *ValidateOpts
 
 	next *outFile
 }
 
-func (outf *outFile) add(incoming *File) error {
-	outFile := pickOutFile(incoming.Header, outf)
-	if outFile == nil {
-		return fmt.Errorf("found no outfile: %w", ErrPleaseReportBug)
-	}
-	outFile.validateOpts = outFile.validateOpts.merge(incoming.GetValidation())
-
+func (outf *outFile) add(incoming *File, conditions Conditions) error {
 	incomingValidateOpts := incoming.GetValidation()
 
-	for j := range incoming.Batches {
-		if len(incoming.Batches[j].GetADVEntries()) > 0 {
-			return errors.New("merging ADV batches is not supported")
+	pick := func(effectiveEntryDate string, adv bool) (*outFile, error) {
+		if !conditions.GroupByEffectiveEntryDate {
+			effectiveEntryDate = ""
+		}
+		outFile := pickOutFile(incoming.Header, effectiveEntryDate, adv, outf)
+		if outFile == nil {
+			return nil, fmt.Errorf("found no outfile: %w", ErrPleaseReportBug)
 		}
+		outFile.validateOpts = outFile.validateOpts.merge(incomingValidateOpts)
+		return outFile, nil
+	}
 
+	for j := range incoming.Batches {
 		bh := incoming.Batches[j].GetHeader()
 		if bh == nil {
 			return fmt.Errorf("batch[%d] has nil BatchHeader", j)
 		}
 
+		if bh.StandardEntryClassCode == ADV {
+			outFile, err := pick(bh.EffectiveEntryDate, true)
+			if err != nil {
+				return err
+			}
+			var current *advBatch
+			if !conditions.PreserveBatches {
+				current = findOutADVBatch(bh, outFile.advBatches)
+			}
+			if current == nil {
+				current = &advBatch{
+					header:       *bh,
+					validateOpts: incomingValidateOpts,
+				}
+				outFile.advBatches = append(outFile.advBatches, current)
+			}
+			for _, entry := range incoming.Batches[j].GetADVEntries() {
+				if entry != nil {
+					current.entries = append(current.entries, entry)
+				}
+			}
+			continue
+		}
+
+		outFile, err := pick(bh.EffectiveEntryDate, false)
+		if err != nil {
+			return err
+		}
+
 		entries := incoming.Batches[j].GetEntries()
 		// Cache the current destination batch across entries that share a header.
 		// Most incoming batches have unique trace numbers, so this avoids an
@@ -521,7 +668,10 @@ func (outf *outFile) add(incoming *File) error {
 				continue
 			}
 			if current == nil || current.entries.Contains(entry.TraceNumber) {
-				current = findOutBatch(bh, outFile.batches, entry)
+				current = nil
+				if !conditions.PreserveBatches {
+					current = findOutBatch(bh, outFile.batches, entry)
+				}
 				if current == nil {
 					current = &batch{
 						header:       *bh,
@@ -540,6 +690,10 @@ func (outf *outFile) add(incoming *File) error {
 		if ibh == nil {
 			return fmt.Errorf("IATBatch[%d] has nil IATBatchHeader", j)
 		}
+		outFile, err := pick(ibh.EffectiveEntryDate, false)
+		if err != nil {
+			return err
+		}
 
 		entries := incoming.IATBatches[j].GetEntries()
 		var current *iatBatch
@@ -549,7 +703,10 @@ func (outf *outFile) add(incoming *File) error {
 				continue
 			}
 			if current == nil || current.entries.Contains(entry.TraceNumber) {
-				current = findOutIATBatch(ibh, outFile.iatBatches, entry)
+				current = nil
+				if !conditions.PreserveBatches {
+					current = findOutIATBatch(ibh, outFile.iatBatches, entry)
+				}
 				if current == nil {
 					current = &iatBatch{
 						header:       *ibh,
@@ -571,261 +728,354 @@ func convertToFiles(sorted *outFile, conditions Conditions) ([]*File, error) {
 	if conditions.MaxDollarAmount == 0 || conditions.MaxDollarAmount > NachaFileDebitCreditLimit {
 		conditions.MaxDollarAmount = NachaFileDebitCreditLimit
 	}
+	if conditions.SortOutput {
+		sorted = sortOutFiles(sorted)
+	}
 
-	var batchNumber int
-
-	var out []*File
+	m := &mergedFiles{conditions: conditions}
 	for {
 		// Run through the linked list (sorted.next) until we terminate
 		if sorted == nil {
 			break
 		}
 
-		file := NewFile()
-		file.Header = sorted.header
+		m.header = sorted.header
+		m.validateOpts = sorted.validateOpts
+		m.startFile()
 
-		if sorted.validateOpts != nil {
-			file.SetValidation(sorted.validateOpts)
+		for i := range sorted.batches {
+			if err := m.addBatch(sorted.batches[i]); err != nil {
+				return nil, fmt.Errorf("creating batch from sorted.batches[%d] failed: %w", i, err)
+			}
+		}
+		for i := range sorted.iatBatches {
+			if err := m.addIATBatch(sorted.iatBatches[i]); err != nil {
+				return nil, err
+			}
+		}
+		for i := range sorted.advBatches {
+			if err := m.addADVBatch(sorted.advBatches[i]); err != nil {
+				return nil, fmt.Errorf("creating ADV batch from sorted.advBatches[%d] failed: %w", i, err)
+			}
 		}
 
-		currentFileLineCount := 2 // FileHeader, FileControl
-		// NACHA enforces separate debit and credit file totals (each up to MaxDollarAmount),
-		// so track them independently rather than summing absolute amounts together.
-		var currentFileDebitAmount, currentFileCreditAmount int64
+		if err := m.finishFile("outfile"); err != nil {
+			return nil, err
+		}
 
-		for i := range sorted.batches {
-			nextBatch := sorted.batches[i]
-
-			batchNumber += 1
-			batch, err := NewBatch(&BatchHeader{ // don't let BatchHeader escape and mutate
-				ServiceClassCode:         nextBatch.header.ServiceClassCode,
-				CompanyName:              nextBatch.header.CompanyName,
-				CompanyDiscretionaryData: nextBatch.header.CompanyDiscretionaryData,
-				CompanyIdentification:    nextBatch.header.CompanyIdentification,
-				StandardEntryClassCode:   nextBatch.header.StandardEntryClassCode,
-				CompanyEntryDescription:  nextBatch.header.CompanyEntryDescription,
-				CompanyDescriptiveDate:   nextBatch.header.CompanyDescriptiveDate,
-				EffectiveEntryDate:       nextBatch.header.EffectiveEntryDate,
-				SettlementDate:           nextBatch.header.SettlementDate,
-				OriginatorStatusCode:     nextBatch.header.OriginatorStatusCode,
-				ODFIIdentification:       nextBatch.header.ODFIIdentification,
-				BatchNumber:              batchNumber,
-			})
-			if err != nil {
-				return nil, fmt.Errorf("creating batch from sorted.batches[%d] failed: %w", i, err)
+		sorted = sorted.next
+	}
+
+	if err := conditions.Limits.Check(m.out...); err != nil {
+		return nil, err
+	}
+	if conditions.Sequencer != nil {
+		for _, file := range m.out {
+			if err := sequenceFileIDModifier(conditions.Sequencer, &file.Header); err != nil {
+				return nil, err
 			}
-			batch.SetValidation(nextBatch.validateOpts)
+		}
+	}
+	return m.out, nil
+}
 
-			currentFileLineCount += 2 // BatchHeader, BatchControl
+// mergedFiles builds the Files returned from merging while enforcing Conditions
+type mergedFiles struct {
+	conditions  Conditions
+	out         []*File
+	batchNumber int
 
-			// add each entry detail
-			for it := nextBatch.entries.Iterator(); it.Valid(); it.Next() {
-				nextEntry := it.Value()
+	header       FileHeader
+	validateOpts *ValidateOpts
 
-				// Check if we're going to exceed the merge conditions before adding the entry
-				entryLineCount := 1 + nextEntry.addendaCount()
-				if conditions.MaxLines > 0 {
-					// File will be too large, so make a new file and batch
-					if currentFileLineCount+entryLineCount > conditions.MaxLines {
-						goto overflow
-					}
-				}
+	// the File currently being filled
+	file          *File
+	lines         int
+	entries       int
+	debit, credit int64
+}
 
-				// File would exceed the per-side dollar amount we're limited to
-				if conditions.MaxDollarAmount > 0 && wouldExceedDollarAmount(conditions.MaxDollarAmount, currentFileDebitAmount, currentFileCreditAmount, nextEntry.Amount, creditOrDebit(nextEntry.TransactionCode)) {
-					goto overflow
-				}
+func (m *mergedFiles) startFile() {
+	m.file = NewFile()
+	m.file.Header = m.header
+	if m.validateOpts != nil {
+		m.file.SetValidation(m.validateOpts)
+	}
+	m.lines = 2 // FileHeader, FileControl
+	m.entries = 0
+	m.debit, m.credit = 0, 0
+}
 
-				// Without a condition being exceeded jump into adding the entry in the current batch
-				goto merge
+// finishFile adds the current File to the output if it contains any batches
+func (m *mergedFiles) finishFile(name string) error {
+	if len(m.file.Batches) > 0 || len(m.file.IATBatches) > 0 {
+		if err := m.file.Create(); err != nil {
+			return fmt.Errorf("problem creating %s: %w", name, err)
+		}
+		m.out = append(m.out, m.file)
+	}
+	return nil
+}
 
-			overflow:
-				// Close out the current batch and file since we exceeded some limit
-				if len(batch.GetEntries()) > 0 {
-					err = batch.Create()
-					if err != nil {
-						return nil, fmt.Errorf("problem creating batch for new file/batch: %w", err)
-					}
-					file.AddBatch(batch)
-				}
-				if len(file.Batches) > 0 || len(file.IATBatches) > 0 {
-					err = file.Create()
-					if err != nil {
-						return nil, fmt.Errorf("problem creating file for new file/batch: %w", err)
-					}
-					out = append(out, file)
-				}
+// fits reports if an entry can be added to the current File without exceeding the Conditions.
+// newBatch includes the BatchHeader and BatchControl of another batch.
+func (m *mergedFiles) fits(newBatch bool, entryLines, amount int, side string) bool {
+	if m.entries == 0 {
+		return true // always accept the first entry of a file
+	}
+	lines := m.lines + entryLines
+	if newBatch {
+		if max := m.conditions.MaxBatchesPerFile; max > 0 && len(m.file.Batches)+len(m.file.IATBatches) >= max {
+			return false
+		}
+		lines += 2
+	}
+	if m.conditions.MaxLines > 0 && lines > m.conditions.MaxLines {
+		return false
+	}
+	return !wouldExceedDollarAmount(m.conditions.MaxDollarAmount, m.debit, m.credit, amount, side)
+}
 
-				// Reset counters
-				currentFileLineCount = 4 // FileHeader, FileControl, BatchHeader, BatchControl
-				currentFileDebitAmount = 0
-				currentFileCreditAmount = 0
-
-				// Create the new file and batch
-				file = NewFile()
-				file.Header = sorted.header
-
-				batchNumber += 1
-				batch, err = NewBatch(&BatchHeader{ // don't let BatchHeader escape and mutate
-					ServiceClassCode:         nextBatch.header.ServiceClassCode,
-					CompanyName:              nextBatch.header.CompanyName,
-					CompanyDiscretionaryData: nextBatch.header.CompanyDiscretionaryData,
-					CompanyIdentification:    nextBatch.header.CompanyIdentification,
-					StandardEntryClassCode:   nextBatch.header.StandardEntryClassCode,
-					CompanyEntryDescription:  nextBatch.header.CompanyEntryDescription,
-					CompanyDescriptiveDate:   nextBatch.header.CompanyDescriptiveDate,
-					EffectiveEntryDate:       nextBatch.header.EffectiveEntryDate,
-					SettlementDate:           nextBatch.header.SettlementDate,
-					OriginatorStatusCode:     nextBatch.header.OriginatorStatusCode,
-					ODFIIdentification:       nextBatch.header.ODFIIdentification,
-					BatchNumber:              batchNumber,
-				})
-				if err != nil {
-					return nil, fmt.Errorf("problem creating overflow batch: %w", err)
-				}
-				batch.SetValidation(nextBatch.validateOpts)
+func (m *mergedFiles) added(entryLines, amount int, side string) {
+	m.lines += entryLines
+	m.entries++
+	m.debit, m.credit = addEntryAmount(m.debit, m.credit, amount, side)
+}
 
-			merge:
-				// Add the entry to the current batch
-				batch.AddEntry(nextEntry)
+func (m *mergedFiles) batchFull(entries, max int) bool {
+	if m.conditions.MaxEntriesPerBatch > 0 && (max == 0 || m.conditions.MaxEntriesPerBatch < max) {
+		max = m.conditions.MaxEntriesPerBatch
+	}
+	return max > 0 && entries >= max
+}
 
-				currentFileLineCount += 1 + nextEntry.addendaCount()
-				currentFileDebitAmount, currentFileCreditAmount = addEntryAmount(currentFileDebitAmount, currentFileCreditAmount, nextEntry.Amount, creditOrDebit(nextEntry.TransactionCode))
-			}
+func (m *mergedFiles) newBatch(header BatchHeader, validateOpts *ValidateOpts) (Batcher, error) {
+	m.batchNumber += 1
+	m.lines += 2 // BatchHeader, BatchControl
+
+	batch, err := NewBatch(&BatchHeader{ // don't let BatchHeader escape and mutate
+		ServiceClassCode:         header.ServiceClassCode,
+		CompanyName:              header.CompanyName,
+		CompanyDiscretionaryData: header.CompanyDiscretionaryData,
+		CompanyIdentification:    header.CompanyIdentification,
+		StandardEntryClassCode:   header.StandardEntryClassCode,
+		CompanyEntryDescription:  header.CompanyEntryDescription,
+		CompanyDescriptiveDate:   header.CompanyDescriptiveDate,
+		EffectiveEntryDate:       header.EffectiveEntryDate,
+		SettlementDate:           header.SettlementDate,
+		OriginatorStatusCode:     header.OriginatorStatusCode,
+		ODFIIdentification:       header.ODFIIdentification,
+		BatchNumber:              m.batchNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	batch.SetValidation(validateOpts)
+	return batch, nil
+}
 
-			if len(batch.GetEntries()) > 0 {
-				err = batch.Create()
-				if err != nil {
-					return nil, fmt.Errorf("problem creating batch for outfile: %w", err)
+func (m *mergedFiles) closeBatch(batch Batcher, name string) error {
+	if len(batch.GetEntries()) == 0 && len(batch.GetADVEntries()) == 0 {
+		return nil
+	}
+	if err := batch.Create(); err != nil {
+		return fmt.Errorf("problem creating %s: %w", name, err)
+	}
+	m.file.AddBatch(batch)
+	return nil
+}
+
+func (m *mergedFiles) addBatch(next *batch) error {
+	var current Batcher
+	var err error
+
+	for it := next.entries.Iterator(); it.Valid(); it.Next() {
+		entry := it.Value()
+		entryLines := 1 + entry.addendaCount()
+		side := creditOrDebit(entry.TransactionCode)
+
+		switch {
+		case current == nil || m.batchFull(len(current.GetEntries()), 0):
+			if current != nil {
+				if err := m.closeBatch(current, "batch for outfile"); err != nil {
+					return err
+				}
+			}
+			if !m.fits(true, entryLines, entry.Amount, side) {
+				if err := m.finishFile("file for new file/batch"); err != nil {
+					return err
 				}
-				file.AddBatch(batch)
+				m.startFile()
+			}
+			current, err = m.newBatch(next.header, next.validateOpts)
+			if err != nil {
+				return err
+			}
+
+		case !m.fits(false, entryLines, entry.Amount, side):
+			// Close out the current batch and file since we exceeded some limit
+			if err := m.closeBatch(current, "batch for new file/batch"); err != nil {
+				return err
+			}
+			if err := m.finishFile("file for new file/batch"); err != nil {
+				return err
+			}
+			m.startFile()
+
+			current, err = m.newBatch(next.header, next.validateOpts)
+			if err != nil {
+				return fmt.Errorf("problem creating overflow batch: %w", err)
 			}
 		}
 
-		for i := range sorted.iatBatches {
-			nextBatch := sorted.iatBatches[i]
-
-			batchNumber += 1
-			iatBh := &IATBatchHeader{
-				ServiceClassCode:                  nextBatch.header.ServiceClassCode,
-				IATIndicator:                      nextBatch.header.IATIndicator,
-				ForeignExchangeIndicator:          nextBatch.header.ForeignExchangeIndicator,
-				ForeignExchangeReferenceIndicator: nextBatch.header.ForeignExchangeReferenceIndicator,
-				ForeignExchangeReference:          nextBatch.header.ForeignExchangeReference,
-				ISODestinationCountryCode:         nextBatch.header.ISODestinationCountryCode,
-				OriginatorIdentification:          nextBatch.header.OriginatorIdentification,
-				StandardEntryClassCode:            nextBatch.header.StandardEntryClassCode,
-				CompanyEntryDescription:           nextBatch.header.CompanyEntryDescription,
-				ISOOriginatingCurrencyCode:        nextBatch.header.ISOOriginatingCurrencyCode,
-				ISODestinationCurrencyCode:        nextBatch.header.ISODestinationCurrencyCode,
-				EffectiveEntryDate:                nextBatch.header.EffectiveEntryDate,
-				SettlementDate:                    nextBatch.header.SettlementDate,
-				OriginatorStatusCode:              nextBatch.header.OriginatorStatusCode,
-				ODFIIdentification:                nextBatch.header.ODFIIdentification,
-				BatchNumber:                       batchNumber,
-			}
-			iatBatch := NewIATBatch(iatBh)
-			iatBatch.SetValidation(nextBatch.validateOpts)
-
-			currentFileLineCount += 2 // IATBatchHeader, BatchControl
-
-			// add each IAT entry detail
-			for it := nextBatch.entries.Iterator(); it.Valid(); it.Next() {
-				nextEntry := it.Value()
-
-				// Check if we're going to exceed the merge conditions before adding the entry
-				entryLineCount := 1 + nextEntry.addendaCount()
-				if conditions.MaxLines > 0 {
-					// File will be too large, so make a new file and batch
-					if currentFileLineCount+entryLineCount > conditions.MaxLines {
-						goto iatOverflow
-					}
-				}
+		current.AddEntry(entry)
+		m.added(entryLines, entry.Amount, side)
+	}
+	if current != nil {
+		return m.closeBatch(current, "batch for outfile")
+	}
+	return nil
+}
 
-				// File would exceed the per-side dollar amount we're limited to
-				if conditions.MaxDollarAmount > 0 && wouldExceedDollarAmount(conditions.MaxDollarAmount, currentFileDebitAmount, currentFileCreditAmount, nextEntry.Amount, creditOrDebit(nextEntry.TransactionCode)) {
-					goto iatOverflow
-				}
+func (m *mergedFiles) newIATBatch(header IATBatchHeader, validateOpts *ValidateOpts) IATBatch {
+	m.batchNumber += 1
+	m.lines += 2 // IATBatchHeader, BatchControl
+
+	iatBatch := NewIATBatch(&IATBatchHeader{
+		ServiceClassCode:                  header.ServiceClassCode,
+		IATIndicator:                      header.IATIndicator,
+		ForeignExchangeIndicator:          header.ForeignExchangeIndicator,
+		ForeignExchangeReferenceIndicator: header.ForeignExchangeReferenceIndicator,
+		ForeignExchangeReference:          header.ForeignExchangeReference,
+		ISODestinationCountryCode:         header.ISODestinationCountryCode,
+		OriginatorIdentification:          header.OriginatorIdentification,
+		StandardEntryClassCode:            header.StandardEntryClassCode,
+		CompanyEntryDescription:           header.CompanyEntryDescription,
+		ISOOriginatingCurrencyCode:        header.ISOOriginatingCurrencyCode,
+		ISODestinationCurrencyCode:        header.ISODestinationCurrencyCode,
+		EffectiveEntryDate:                header.EffectiveEntryDate,
+		SettlementDate:                    header.SettlementDate,
+		OriginatorStatusCode:              header.OriginatorStatusCode,
+		ODFIIdentification:                header.ODFIIdentification,
+		BatchNumber:                       m.batchNumber,
+	})
+	iatBatch.SetValidation(validateOpts)
+	return iatBatch
+}
 
-				// Without a condition being exceeded jump into adding the entry in the current batch
-				goto iatMerge
+func (m *mergedFiles) closeIATBatch(iatBatch *IATBatch, name string) error {
+	if len(iatBatch.Entries) == 0 {
+		return nil
+	}
+	if err := iatBatch.Create(); err != nil {
+		return fmt.Errorf("problem creating %s: %w", name, err)
+	}
+	m.file.AddIATBatch(*iatBatch)
+	return nil
+}
 
-			iatOverflow:
-				// Close out the current batch and file since we exceeded some limit
-				if len(iatBatch.Entries) > 0 {
-					err := iatBatch.Create()
-					if err != nil {
-						return nil, fmt.Errorf("problem creating IAT batch for new file/batch: %w", err)
-					}
-					file.AddIATBatch(iatBatch)
+func (m *mergedFiles) addIATBatch(next *iatBatch) error {
+	var current *IATBatch
+
+	for it := next.entries.Iterator(); it.Valid(); it.Next() {
+		entry := it.Value()
+		entryLines := 1 + entry.addendaCount()
+		side := creditOrDebit(entry.TransactionCode)
+
+		switch {
+		case current == nil || m.batchFull(len(current.Entries), 0):
+			if current != nil {
+				if err := m.closeIATBatch(current, "IAT batch for outfile"); err != nil {
+					return err
 				}
-				if len(file.Batches) > 0 || len(file.IATBatches) > 0 {
-					err := file.Create()
-					if err != nil {
-						return nil, fmt.Errorf("problem creating file for new file/batch: %w", err)
-					}
-					out = append(out, file)
+			}
+			if !m.fits(true, entryLines, entry.Amount, side) {
+				if err := m.finishFile("file for new file/batch"); err != nil {
+					return err
 				}
+				m.startFile()
+			}
+			iatBatch := m.newIATBatch(next.header, next.validateOpts)
+			current = &iatBatch
 
-				// Reset counters
-				currentFileLineCount = 4 // FileHeader, FileControl, IATBatchHeader, BatchControl
-				currentFileDebitAmount = 0
-				currentFileCreditAmount = 0
-
-				// Create the new file and batch
-				file = NewFile()
-				file.Header = sorted.header
-
-				batchNumber += 1
-				iatBh = &IATBatchHeader{
-					ServiceClassCode:                  nextBatch.header.ServiceClassCode,
-					IATIndicator:                      nextBatch.header.IATIndicator,
-					ForeignExchangeIndicator:          nextBatch.header.ForeignExchangeIndicator,
-					ForeignExchangeReferenceIndicator: nextBatch.header.ForeignExchangeReferenceIndicator,
-					ForeignExchangeReference:          nextBatch.header.ForeignExchangeReference,
-					ISODestinationCountryCode:         nextBatch.header.ISODestinationCountryCode,
-					OriginatorIdentification:          nextBatch.header.OriginatorIdentification,
-					StandardEntryClassCode:            nextBatch.header.StandardEntryClassCode,
-					CompanyEntryDescription:           nextBatch.header.CompanyEntryDescription,
-					ISOOriginatingCurrencyCode:        nextBatch.header.ISOOriginatingCurrencyCode,
-					ISODestinationCurrencyCode:        nextBatch.header.ISODestinationCurrencyCode,
-					EffectiveEntryDate:                nextBatch.header.EffectiveEntryDate,
-					SettlementDate:                    nextBatch.header.SettlementDate,
-					OriginatorStatusCode:              nextBatch.header.OriginatorStatusCode,
-					ODFIIdentification:                nextBatch.header.ODFIIdentification,
-					BatchNumber:                       batchNumber,
-				}
-				iatBatch = NewIATBatch(iatBh)
-				iatBatch.SetValidation(nextBatch.validateOpts)
+		case !m.fits(false, entryLines, entry.Amount, side):
+			// Close out the current batch and file since we exceeded some limit
+			if err := m.closeIATBatch(current, "IAT batch for new file/batch"); err != nil {
+				return err
+			}
+			if err := m.finishFile("file for new file/batch"); err != nil {
+				return err
+			}
+			m.startFile()
 
-			iatMerge:
-				// Add the entry to the current batch
-				iatBatch.AddEntry(nextEntry)
+			iatBatch := m.newIATBatch(next.header, next.validateOpts)
+			current = &iatBatch
+		}
 
-				currentFileLineCount += 1 + nextEntry.addendaCount()
-				currentFileDebitAmount, currentFileCreditAmount = addEntryAmount(currentFileDebitAmount, currentFileCreditAmount, nextEntry.Amount, creditOrDebit(nextEntry.TransactionCode))
-			}
+		current.AddEntry(entry)
+		m.added(entryLines, entry.Amount, side)
+	}
+	if current != nil {
+		return m.closeIATBatch(current, "IAT batch for outfile")
+	}
+	return nil
+}
 
-			if len(iatBatch.Entries) > 0 {
-				err := iatBatch.Create()
-				if err != nil {
-					return nil, fmt.Errorf("problem creating IAT batch for outfile: %w", err)
+// maxADVBatchEntries is the most ADV entries a batch can have, see ErrBatchADVCount
+const maxADVBatchEntries = 9999
+
+func (m *mergedFiles) addADVBatch(next *advBatch) error {
+	var current Batcher
+	var err error
+
+	for _, entry := range next.entries {
+		entryLines := 1
+		if entry.Addenda99 != nil {
+			entryLines++
+		}
+		side := advCreditOrDebit(entry.TransactionCode)
+
+		switch {
+		case current == nil || m.batchFull(len(current.GetADVEntries()), maxADVBatchEntries):
+			if current != nil {
+				if err := m.closeBatch(current, "ADV batch for outfile"); err != nil {
+					return err
 				}
-				file.AddIATBatch(iatBatch)
 			}
-		}
+			if !m.fits(true, entryLines, entry.Amount, side) {
+				if err := m.finishFile("file for new file/batch"); err != nil {
+					return err
+				}
+				m.startFile()
+			}
+			current, err = m.newBatch(next.header, next.validateOpts)
+			if err != nil {
+				return err
+			}
 
-		if len(file.Batches) > 0 || len(file.IATBatches) > 0 {
-			err := file.Create()
+		case !m.fits(false, entryLines, entry.Amount, side):
+			// Close out the current batch and file since we exceeded some limit
+			if err := m.closeBatch(current, "ADV batch for new file/batch"); err != nil {
+				return err
+			}
+			if err := m.finishFile("file for new file/batch"); err != nil {
+				return err
+			}
+			m.startFile()
+
+			current, err = m.newBatch(next.header, next.validateOpts)
 			if err != nil {
-				return nil, fmt.Errorf("problem creating outfile: %w", err)
+				return fmt.Errorf("problem creating overflow batch: %w", err)
 			}
-			out = append(out, file)
 		}
 
-		sorted = sorted.next
+		current.AddADVEntry(entry)
+		m.added(entryLines, entry.Amount, side)
 	}
-	return out, nil
+	if current != nil {
+		return m.closeBatch(current, "ADV batch for outfile")
+	}
+	return nil
 }
 
 // batch contains a BatchHeader and tree of entries sorted by TraceNumber, which allows for
@@ -844,25 +1094,38 @@ type iatBatch struct {
 	validateOpts *ValidateOpts
 }
 
-// pickOutFile will search for an existing outFile matching the FileHeader Origin and Destination.
+// advBatch contains a BatchHeader and ADV entries in the order they were merged
+type advBatch struct {
+	header       BatchHeader
+	entries      []*ADVEntryDetail
+	validateOpts *ValidateOpts
+}
+
+// pickOutFile will search for an existing outFile matching the FileHeader Origin and Destination,
+// EffectiveEntryDate and if the file contains ADV batches.
 // If no such file can be found it will create one. A nil file will never be returned.
-func pickOutFile(fh FileHeader, file *outFile) *outFile {
+func pickOutFile(fh FileHeader, effectiveEntryDate string, adv bool, file *outFile) *outFile {
 	if file == nil {
 		return &outFile{
-			header: fh,
+			header:             fh,
+			effectiveEntryDate: effectiveEntryDate,
+			adv:                adv,
 		}
 	}
 	if fh.ImmediateOrigin == file.header.ImmediateOrigin &&
-		fh.ImmediateDestination == file.header.ImmediateDestination {
+		fh.ImmediateDestination == file.header.ImmediateDestination &&
+		effectiveEntryDate == file.effectiveEntryDate && adv == file.adv {
 		return file
 	}
 	if file.next == nil {
 		file.next = &outFile{
-			header: fh,
+			header:             fh,
+			effectiveEntryDate: effectiveEntryDate,
+			adv:                adv,
 		}
 		return file.next
 	}
-	return pickOutFile(fh, file.next)
+	return pickOutFile(fh, effectiveEntryDate, adv, file.next)
 }
 
 // findOutBatch searches an array of batches for one whose BatchHeader matches bh
@@ -901,6 +1164,108 @@ func findOutIATBatch(bh *IATBatchHeader, batches []*iatBatch, entry *IATEntryDet
 	return nil
 }
 
+// findOutADVBatch searches an array of ADV batches for one whose BatchHeader matches bh
+func findOutADVBatch(bh *BatchHeader, batches []*advBatch) *advBatch {
+	for i := range batches {
+		if batches[i].header.Equal(bh) {
+			return batches[i]
+		}
+	}
+	return nil
+}
+
+// sortOutFiles orders outFiles and their batches by header values so merged output
+// does not depend on the order files were merged in.
+func sortOutFiles(sorted *outFile) *outFile {
+	var files []*outFile
+	for f := sorted; f != nil; f = f.next {
+		files = append(files, f)
+	}
+	slices.SortStableFunc(files, func(a, b *outFile) int {
+		return cmp.Or(
+			cmp.Compare(a.header.ImmediateOrigin, b.header.ImmediateOrigin),
+			cmp.Compare(a.header.ImmediateDestination, b.header.ImmediateDestination),
+			cmp.Compare(a.effectiveEntryDate, b.effectiveEntryDate),
+			compareBool(a.adv, b.adv),
+		)
+	})
+	for i, f := range files {
+		slices.SortStableFunc(f.batches, func(a, b *batch) int {
+			return cmp.Or(compareBatchHeaders(&a.header, &b.header), cmp.Compare(a.firstTraceNumber(), b.firstTraceNumber()))
+		})
+		slices.SortStableFunc(f.iatBatches, func(a, b *iatBatch) int {
+			return cmp.Or(
+				cmp.Compare(a.header.StandardEntryClassCode, b.header.StandardEntryClassCode),
+				cmp.Compare(a.header.OriginatorIdentification, b.header.OriginatorIdentification),
+				cmp.Compare(a.header.EffectiveEntryDate, b.header.EffectiveEntryDate),
+				cmp.Compare(a.header.ServiceClassCode, b.header.ServiceClassCode),
+				cmp.Compare(a.header.ISODestinationCountryCode, b.header.ISODestinationCountryCode),
+				cmp.Compare(a.header.CompanyEntryDescription, b.header.CompanyEntryDescription),
+				cmp.Compare(a.header.ODFIIdentification, b.header.ODFIIdentification),
+				cmp.Compare(a.firstTraceNumber(), b.firstTraceNumber()),
+			)
+		})
+		slices.SortStableFunc(f.advBatches, func(a, b *advBatch) int {
+			return compareBatchHeaders(&a.header, &b.header)
+		})
+		for _, b := range f.advBatches {
+			slices.SortStableFunc(b.entries, func(x, y *ADVEntryDetail) int {
+				return cmp.Compare(x.String(), y.String())
+			})
+		}
+		f.next = nil
+		if i > 0 {
+			files[i-1].next = f
+		}
+	}
+	return files[0]
+}
+
+func compareBatchHeaders(a, b *BatchHeader) int {
+	return cmp.Or(
+		cmp.Compare(a.StandardEntryClassCode, b.StandardEntryClassCode),
+		cmp.Compare(a.CompanyIdentification, b.CompanyIdentification),
+		cmp.Compare(a.EffectiveEntryDate, b.EffectiveEntryDate),
+		cmp.Compare(a.ServiceClassCode, b.ServiceClassCode),
+		cmp.Compare(a.CompanyName, b.CompanyName),
+		cmp.Compare(a.CompanyEntryDescription, b.CompanyEntryDescription),
+		cmp.Compare(a.ODFIIdentification, b.ODFIIdentification),
+	)
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+func (b *batch) firstTraceNumber() string {
+	if it := b.entries.Iterator(); it.Valid() {
+		return it.Key()
+	}
+	return ""
+}
+
+func (b *iatBatch) firstTraceNumber() string {
+	if it := b.entries.Iterator(); it.Valid() {
+		return it.Key()
+	}
+	return ""
+}
+
+// advCreditOrDebit returns "C" or "D" for an ADV TransactionCode
+func advCreditOrDebit(code int) string {
+	switch code {
+	case CreditForDebitsOriginated, CreditForCreditsReceived, CreditForCreditsRejected, CreditSummary:
+		return "C"
+	}
+	return "D"
+}
+
 // wouldExceedDollarAmount reports whether adding amount on the debit or credit side
 // would push that side over max. NACHA file control totals are tracked per side.
 // side is "C", "D", or "" from creditOrDebit.
@@ -0,0 +1,232 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+// MergeManifest describes where every entry in a set of merged files came from.
+//
+// Files[i] describes the i-th File returned from merging.
+type MergeManifest struct {
+	Files []MergeManifestFile `json:"files"`
+
+	// Rejected lists the incoming entries which were not included in any merged file.
+	Rejected []MergeRejection `json:"rejected,omitempty"`
+}
+
+// MergeManifestFile describes the batches of one merged File.
+type MergeManifestFile struct {
+	// FileID is the ID of the merged File, which is often blank until the caller assigns one.
+	FileID  string               `json:"fileID,omitempty"`
+	Batches []MergeManifestBatch `json:"batches"`
+}
+
+// MergeManifestBatch describes the entries of one batch in a merged File.
+type MergeManifestBatch struct {
+	BatchNumber int                  `json:"batchNumber"`
+	Entries     []MergeManifestEntry `json:"entries"`
+}
+
+// MergeManifestEntry maps an entry in a merged File back to its source.
+type MergeManifestEntry struct {
+	TraceNumber string `json:"traceNumber,omitempty"`
+
+	// SequenceNumber is set for ADV entries which have no TraceNumber.
+	SequenceNumber int `json:"sequenceNumber,omitempty"`
+
+	Source MergeSource `json:"source"`
+}
+
+// MergeSource identifies an entry within one of the files given to merging.
+type MergeSource struct {
+	// Path is the filepath the entry was read from. It is only set by MergeDirWithManifest.
+	Path string `json:"path,omitempty"`
+
+	// FileID is the ID of the incoming File.
+	FileID string `json:"fileID,omitempty"`
+
+	BatchNumber    int    `json:"batchNumber"`
+	TraceNumber    string `json:"traceNumber,omitempty"`
+	SequenceNumber int    `json:"sequenceNumber,omitempty"`
+}
+
+// MergeRejection is an incoming entry which was not included in the merged files.
+type MergeRejection struct {
+	Source MergeSource `json:"source"`
+	Reason string      `json:"reason"`
+}
+
+// MergeFilesWithManifest merges files like MergeFilesWith and also returns a MergeManifest
+// which maps every merged entry back to the File, batch and trace number it came from.
+func MergeFilesWithManifest(incoming []*File, conditions Conditions) ([]*File, *MergeManifest, error) {
+	prov := newMergeProvenance()
+	files, err := mergeFilesWith(incoming, conditions, prov)
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, prov.manifest(files), nil
+}
+
+// MergeDirWithManifest merges a directory like MergeDir and also returns a MergeManifest
+// which maps every merged entry back to the path, batch and trace number it came from.
+func MergeDirWithManifest(dir string, conditions Conditions, opts *MergeDirOptions) ([]*File, *MergeManifest, error) {
+	prov := newMergeProvenance()
+	files, err := mergeDir(dir, conditions, opts, prov)
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, prov.manifest(files), nil
+}
+
+// mergeProvenance records the source of each incoming entry. Merging keeps the incoming
+// entry pointers, so the sources are found again by looking up each merged entry.
+type mergeProvenance struct {
+	sources map[any]MergeSource
+	order   []any // incoming entries in the order they were recorded
+
+	rejected []MergeRejection
+}
+
+func newMergeProvenance() *mergeProvenance {
+	return &mergeProvenance{
+		sources: make(map[any]MergeSource),
+	}
+}
+
+func (p *mergeProvenance) record(path string, file *File) {
+	if p == nil || file == nil {
+		return
+	}
+	source := MergeSource{
+		Path:   path,
+		FileID: file.ID,
+	}
+	for _, b := range file.Batches {
+		bh := b.GetHeader()
+		if bh == nil {
+			continue // merging fails on nil headers
+		}
+		source.BatchNumber = bh.BatchNumber
+
+		for _, entry := range b.GetEntries() {
+			if entry == nil {
+				p.reject(source, "nil entry")
+				continue
+			}
+			src := source
+			src.TraceNumber = entry.TraceNumber
+			p.add(entry, src)
+		}
+		for _, entry := range b.GetADVEntries() {
+			if entry == nil {
+				p.reject(source, "nil ADV entry")
+				continue
+			}
+			src := source
+			src.SequenceNumber = entry.SequenceNumber
+			p.add(entry, src)
+		}
+	}
+	for _, b := range file.IATBatches {
+		if b.Header == nil {
+			continue
+		}
+		source.BatchNumber = b.Header.BatchNumber
+
+		for _, entry := range b.Entries {
+			if entry == nil {
+				p.reject(source, "nil IAT entry")
+				continue
+			}
+			src := source
+			src.TraceNumber = entry.TraceNumber
+			p.add(entry, src)
+		}
+	}
+}
+
+func (p *mergeProvenance) add(entry any, source MergeSource) {
+	if _, exists := p.sources[entry]; exists {
+		return // keep the first source of an entry merged twice
+	}
+	p.sources[entry] = source
+	p.order = append(p.order, entry)
+}
+
+func (p *mergeProvenance) reject(source MergeSource, reason string) {
+	p.rejected = append(p.rejected, MergeRejection{
+		Source: source,
+		Reason: reason,
+	})
+}
+
+func (p *mergeProvenance) manifest(files []*File) *MergeManifest {
+	out := &MergeManifest{
+		Files: make([]MergeManifestFile, 0, len(files)),
+	}
+	found := make(map[any]bool, len(p.sources))
+
+	for _, file := range files {
+		mf := MergeManifestFile{
+			FileID: file.ID,
+		}
+		for _, b := range file.Batches {
+			mb := MergeManifestBatch{
+				BatchNumber: b.GetHeader().BatchNumber,
+			}
+			for _, entry := range b.GetEntries() {
+				found[entry] = true
+				mb.Entries = append(mb.Entries, MergeManifestEntry{
+					TraceNumber: entry.TraceNumber,
+					Source:      p.sources[entry],
+				})
+			}
+			for _, entry := range b.GetADVEntries() {
+				found[entry] = true
+				mb.Entries = append(mb.Entries, MergeManifestEntry{
+					SequenceNumber: entry.SequenceNumber,
+					Source:         p.sources[entry],
+				})
+			}
+			mf.Batches = append(mf.Batches, mb)
+		}
+		for _, b := range file.IATBatches {
+			mb := MergeManifestBatch{
+				BatchNumber: b.Header.BatchNumber,
+			}
+			for _, entry := range b.Entries {
+				found[entry] = true
+				mb.Entries = append(mb.Entries, MergeManifestEntry{
+					TraceNumber: entry.TraceNumber,
+					Source:      p.sources[entry],
+				})
+			}
+			mf.Batches = append(mf.Batches, mb)
+		}
+		out.Files = append(out.Files, mf)
+	}
+
+	out.Rejected = append(out.Rejected, p.rejected...)
+	for _, entry := range p.order {
+		if !found[entry] {
+			out.Rejected = append(out.Rejected, MergeRejection{
+				Source: p.sources[entry],
+				Reason: "entry not found in merged files",
+			})
+		}
+	}
+	return out
+}
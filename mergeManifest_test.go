@@ -0,0 +1,133 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeFilesWithManifest(t *testing.T) {
+	f1, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	f1.ID = "first"
+
+	f2, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	f2.ID = "second"
+	b := f2.Batches[0].(*BatchPPD)
+	b.Entries = append(b.Entries, nil)
+
+	adv, err := readACHFilepath(filepath.Join("test", "testdata", "adv.ach"))
+	require.NoError(t, err)
+	adv.ID = "adv"
+
+	merged, manifest, err := MergeFilesWithManifest([]*File{f1, f2, adv}, Conditions{})
+	require.NoError(t, err)
+	require.Len(t, merged, 2)
+	require.Len(t, manifest.Files, 2)
+
+	// duplicate trace numbers are split into two batches
+	ppd := manifest.Files[0]
+	require.Len(t, ppd.Batches, 2)
+	require.Equal(t, 1, ppd.Batches[0].BatchNumber)
+	require.Equal(t, 2, ppd.Batches[1].BatchNumber)
+
+	traceNumber := f1.Batches[0].GetEntries()[0].TraceNumber
+	require.Equal(t, []MergeManifestEntry{{
+		TraceNumber: traceNumber,
+		Source: MergeSource{
+			FileID:      "first",
+			BatchNumber: 1,
+			TraceNumber: traceNumber,
+		},
+	}}, ppd.Batches[0].Entries)
+	require.Equal(t, "second", ppd.Batches[1].Entries[0].Source.FileID)
+
+	// ADV entries are mapped by their sequence number
+	advEntries := adv.Batches[0].GetADVEntries()
+	var count int
+	for _, b := range manifest.Files[1].Batches {
+		for _, entry := range b.Entries {
+			require.Equal(t, "adv", entry.Source.FileID)
+			require.NotZero(t, entry.SequenceNumber)
+			count++
+		}
+	}
+	require.Equal(t, len(advEntries), count)
+
+	require.Len(t, manifest.Rejected, 1)
+	require.Equal(t, "second", manifest.Rejected[0].Source.FileID)
+	require.Equal(t, "nil entry", manifest.Rejected[0].Reason)
+
+	bs, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.Contains(t, string(bs), `"rejected":[{"source":{"fileID":"second","batchNumber":1},"reason":"nil entry"}]`)
+
+	t.Run("empty", func(t *testing.T) {
+		merged, manifest, err := MergeFilesWithManifest(nil, Conditions{})
+		require.NoError(t, err)
+		require.Empty(t, merged)
+		require.Empty(t, manifest.Files)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		_, manifest, err := MergeFilesWithManifest([]*File{f1}, Conditions{
+			Limits: &Limits{MaxEntryAmount: 1},
+		})
+		require.ErrorIs(t, err, ErrLimitsExceeded)
+		require.Nil(t, manifest)
+	})
+}
+
+func TestMergeDirWithManifest(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.ach", "b.ach"} {
+		src, err := os.Open(filepath.Join("test", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+		dst, err := os.Create(filepath.Join(dir, name))
+		require.NoError(t, err)
+		_, err = io.Copy(dst, src)
+		require.NoError(t, err)
+		require.NoError(t, dst.Close())
+		require.NoError(t, src.Close())
+	}
+
+	merged, manifest, err := MergeDirWithManifest(dir, Conditions{SortOutput: true}, &MergeDirOptions{ParseWorkers: 2})
+	require.NoError(t, err)
+	require.Len(t, merged, 1)
+	require.Len(t, manifest.Files, 1)
+	require.Empty(t, manifest.Rejected)
+
+	paths := make(map[string]bool)
+	for _, b := range manifest.Files[0].Batches {
+		for _, entry := range b.Entries {
+			require.Equal(t, 1, entry.Source.BatchNumber)
+			paths[entry.Source.Path] = true
+		}
+	}
+	require.Equal(t, map[string]bool{
+		filepath.Join(dir, "a.ach"): true,
+		filepath.Join(dir, "b.ach"): true,
+	}, paths)
+}
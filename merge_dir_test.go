@@ -182,16 +182,9 @@ func TestMergeDir_DeadlockPrevention(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, dst1.Close())
 
-	// Copy an ADV file which will cause sorted.add to fail
-	src2, err := os.Open(filepath.Join("test", "testdata", "adv.ach"))
+	// Write a JSON file with an empty FileHeader which will cause a parse worker to fail
+	err = os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"fileHeader": {}, "batches": [{"entryDetails": []}]}`), 0600)
 	require.NoError(t, err)
-	defer src2.Close()
-
-	dst2, err := os.Create(filepath.Join(dir, "adv.ach"))
-	require.NoError(t, err)
-	_, err = io.Copy(dst2, src2)
-	require.NoError(t, err)
-	require.NoError(t, dst2.Close())
 
 	// Copy another valid file to ensure concurrency
 	src3, err := os.Open(filepath.Join("test", "testdata", "web-debit.ach"))
@@ -204,10 +197,10 @@ func TestMergeDir_DeadlockPrevention(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, dst3.Close())
 
-	// MergeDir should fail due to ADV file, but not deadlock
+	// MergeDir should fail due to the invalid file, but not deadlock
 	_, err = MergeDir(dir, Conditions{}, nil)
 	require.Error(t, err)
-	require.Contains(t, err.Error(), "merging ADV batches is not supported")
+	require.Contains(t, err.Error(), "invalid.json failed: ImmediateDestination")
 }
 
 func TestMergeDir_HeaderFromFirstFileNoRace(t *testing.T) {
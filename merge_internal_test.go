@@ -32,7 +32,7 @@ func TestOutFileAdd_NilHeadersAndEntries(t *testing.T) {
 		file.Batches = append(file.Batches, &Batch{Header: nil})
 
 		outf := &outFile{header: mockFileHeader()}
-		err := outf.add(file)
+		err := outf.add(file, Conditions{})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "nil BatchHeader")
 	})
@@ -48,7 +48,7 @@ func TestOutFileAdd_NilHeadersAndEntries(t *testing.T) {
 		file.Batches = append(file.Batches, batch)
 
 		outf := &outFile{header: mockFileHeader()}
-		require.NoError(t, outf.add(file))
+		require.NoError(t, outf.add(file, Conditions{}))
 		require.Len(t, outf.batches, 1)
 		require.Equal(t, 1, outf.batches[0].entries.Len())
 	})
@@ -59,7 +59,7 @@ func TestOutFileAdd_NilHeadersAndEntries(t *testing.T) {
 		file.IATBatches = append(file.IATBatches, IATBatch{Header: nil})
 
 		outf := &outFile{header: mockFileHeader()}
-		err := outf.add(file)
+		err := outf.add(file, Conditions{})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "nil IATBatchHeader")
 	})
@@ -76,7 +76,7 @@ func TestOutFileAdd_NilHeadersAndEntries(t *testing.T) {
 		file.IATBatches = append(file.IATBatches, iat)
 
 		outf := &outFile{header: mockFileHeader()}
-		require.NoError(t, outf.add(file))
+		require.NoError(t, outf.add(file, Conditions{}))
 		require.Len(t, outf.iatBatches, 1)
 		require.Equal(t, 1, outf.iatBatches[0].entries.Len())
 	})
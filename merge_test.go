@@ -578,7 +578,7 @@ func TestMergeFilesHelpers(t *testing.T) {
 		fh := mockFileHeader()
 		var input *outFile
 
-		output := pickOutFile(fh, input)
+		output := pickOutFile(fh, "", false, input)
 		require.Equal(t, fh, output.header)
 		require.Empty(t, output.batches)
 		require.Nil(t, output.next)
@@ -586,11 +586,11 @@ func TestMergeFilesHelpers(t *testing.T) {
 		input = &outFile{
 			header: mockFileHeader(),
 		}
-		require.Equal(t, input, pickOutFile(fh, input))
+		require.Equal(t, input, pickOutFile(fh, "", false, input))
 
 		fh2 := mockFileHeader()
 		fh2.ImmediateOrigin = "123456780"
-		output = pickOutFile(fh2, input)
+		output = pickOutFile(fh2, "", false, input)
 		require.Equal(t, output, input.next) // verify the chain continues
 		require.Equal(t, fh2, output.header)
 		require.Empty(t, output.batches)
@@ -598,7 +598,7 @@ func TestMergeFilesHelpers(t *testing.T) {
 
 		fh3 := mockFileHeader()
 		fh3.ImmediateDestination = "123456780"
-		output = pickOutFile(fh3, input)
+		output = pickOutFile(fh3, "", false, input)
 		require.Equal(t, fh3, output.header)
 	})
 
@@ -789,16 +789,37 @@ func TestMergeFilesWith_Empty(t *testing.T) {
 	require.Nil(t, out)
 }
 
-func TestMergeFilesWith_ADVRejected(t *testing.T) {
+func TestMergeFilesWith_ADV(t *testing.T) {
 	adv, err := readACHFilepath(filepath.Join("test", "testdata", "adv.ach"))
 	require.NoError(t, err)
 	require.NotEmpty(t, adv.Batches)
 	require.NotEmpty(t, adv.Batches[0].GetADVEntries())
 
-	out, err := MergeFilesWith([]*File{adv}, Conditions{})
-	require.Error(t, err)
-	require.Nil(t, out)
-	require.Contains(t, err.Error(), "merging ADV batches is not supported")
+	adv2, err := readACHFilepath(filepath.Join("test", "testdata", "adv.ach"))
+	require.NoError(t, err)
+
+	ppd, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	ppd.Header = adv.Header
+
+	out, err := MergeFilesWith([]*File{adv, ppd, adv2}, Conditions{})
+	require.NoError(t, err)
+	require.Len(t, out, 2)
+
+	// ADV batches are kept in their own file
+	require.False(t, out[0].IsADV())
+	require.True(t, out[1].IsADV())
+	require.Len(t, out[1].Batches, 1)
+	require.Len(t, out[1].Batches[0].GetADVEntries(), 2*len(adv.Batches[0].GetADVEntries()))
+	require.Equal(t, 2*adv.ADVControl.TotalDebitEntryDollarAmountInFile, out[1].ADVControl.TotalDebitEntryDollarAmountInFile)
+	require.NoError(t, out[1].Validate())
+
+	// ADV batches are split after 9999 entries or MaxEntriesPerBatch
+	out, err = MergeFilesWith([]*File{adv, adv2}, Conditions{MaxEntriesPerBatch: 1})
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Len(t, out[0].Batches, 4)
+	require.NoError(t, out[0].Validate())
 }
 
 func TestWouldExceedDollarAmount_Edges(t *testing.T) {
@@ -810,17 +831,17 @@ func TestWouldExceedDollarAmount_Edges(t *testing.T) {
 }
 
 func TestMergeFilesWith_AddError(t *testing.T) {
-	// MergeFilesWith must surface errors from outFile.add (e.g. ADV)
+	// MergeFilesWith must surface errors from outFile.add (e.g. nil BatchHeader)
 	ppd, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
 	require.NoError(t, err)
-	adv, err := readACHFilepath(filepath.Join("test", "testdata", "adv.ach"))
-	require.NoError(t, err)
-	adv.Header = ppd.Header
+	invalid := NewFile()
+	invalid.Header = ppd.Header
+	invalid.Batches = append(invalid.Batches, &Batch{Header: nil})
 
-	out, err := MergeFilesWith([]*File{ppd, adv}, Conditions{})
+	out, err := MergeFilesWith([]*File{ppd, invalid}, Conditions{})
 	require.Error(t, err)
 	require.Nil(t, out)
-	require.Contains(t, err.Error(), "ADV")
+	require.Contains(t, err.Error(), "nil BatchHeader")
 }
 
 func TestMergeFiles_NoEntriesLostOnSplit(t *testing.T) {
@@ -870,3 +891,131 @@ func TestMergeFiles_NoEntriesLostOnSplit(t *testing.T) {
 	}
 	require.Equal(t, wantTraces, gotTraces, "merge split dropped or duplicated entries")
 }
+
+func mockMergeFile(t *testing.T, companyName, effectiveEntryDate string, firstTrace, entries int) *File {
+	t.Helper()
+
+	file := NewFile()
+	file.SetHeader(mockFileHeader())
+	bh := mockBatchPPDHeader()
+	bh.CompanyName = companyName
+	bh.EffectiveEntryDate = effectiveEntryDate
+	batch := NewBatchPPD(bh)
+	for i := 0; i < entries; i++ {
+		ed := mockPPDEntryDetail()
+		ed.SetTraceNumber(bh.ODFIIdentification, firstTrace+i)
+		batch.AddEntry(ed)
+	}
+	require.NoError(t, batch.Create())
+	file.AddBatch(batch)
+	require.NoError(t, file.Create())
+	return file
+}
+
+func TestMergeFilesWith_Conditions(t *testing.T) {
+	t.Run("MaxEntriesPerBatch", func(t *testing.T) {
+		out, err := MergeFilesWith([]*File{
+			mockMergeFile(t, "Company", "190816", 1, 5),
+			mockMergeFile(t, "Company", "190816", 10, 2),
+		}, Conditions{MaxEntriesPerBatch: 3})
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Len(t, out[0].Batches, 3)
+		require.Len(t, out[0].Batches[0].GetEntries(), 3)
+		require.Len(t, out[0].Batches[1].GetEntries(), 3)
+		require.Len(t, out[0].Batches[2].GetEntries(), 1)
+		require.Equal(t, 7, countTraceNumbers(out...))
+		require.NoError(t, out[0].Validate())
+	})
+
+	t.Run("MaxBatchesPerFile", func(t *testing.T) {
+		out, err := MergeFilesWith([]*File{
+			mockMergeFile(t, "Company A", "190816", 1, 2),
+			mockMergeFile(t, "Company B", "190816", 10, 2),
+			mockMergeFile(t, "Company C", "190816", 20, 2),
+		}, Conditions{MaxBatchesPerFile: 2})
+		require.NoError(t, err)
+		require.Len(t, out, 2)
+		require.Len(t, out[0].Batches, 2)
+		require.Len(t, out[1].Batches, 1)
+		require.Equal(t, 6, countTraceNumbers(out...))
+
+		// Combined with MaxEntriesPerBatch
+		out, err = MergeFilesWith([]*File{
+			mockMergeFile(t, "Company A", "190816", 1, 5),
+		}, Conditions{MaxEntriesPerBatch: 2, MaxBatchesPerFile: 2})
+		require.NoError(t, err)
+		require.Len(t, out, 2)
+		require.Len(t, out[0].Batches, 2)
+		require.Len(t, out[1].Batches, 1)
+		for _, f := range out {
+			require.NoError(t, f.Validate())
+		}
+	})
+
+	t.Run("GroupByEffectiveEntryDate", func(t *testing.T) {
+		files := func() []*File {
+			return []*File{
+				mockMergeFile(t, "Company A", "190816", 1, 1),
+				mockMergeFile(t, "Company B", "190817", 10, 1),
+				mockMergeFile(t, "Company C", "190816", 20, 1),
+			}
+		}
+		out, err := MergeFilesWith(files(), Conditions{})
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+
+		out, err = MergeFilesWith(files(), Conditions{GroupByEffectiveEntryDate: true})
+		require.NoError(t, err)
+		require.Len(t, out, 2)
+		require.Len(t, out[0].Batches, 2)
+		require.Equal(t, "190816", out[0].Batches[1].GetHeader().EffectiveEntryDate)
+		require.Len(t, out[1].Batches, 1)
+		require.Equal(t, "190817", out[1].Batches[0].GetHeader().EffectiveEntryDate)
+	})
+
+	t.Run("PreserveBatches", func(t *testing.T) {
+		files := func() []*File {
+			return []*File{
+				mockMergeFile(t, "Company", "190816", 1, 2),
+				mockMergeFile(t, "Company", "190816", 10, 2),
+			}
+		}
+		out, err := MergeFilesWith(files(), Conditions{})
+		require.NoError(t, err)
+		require.Len(t, out[0].Batches, 1)
+
+		out, err = MergeFilesWith(files(), Conditions{PreserveBatches: true})
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Len(t, out[0].Batches, 2)
+		require.Len(t, out[0].Batches[0].GetEntries(), 2)
+		require.Len(t, out[0].Batches[1].GetEntries(), 2)
+	})
+
+	t.Run("SortOutput", func(t *testing.T) {
+		a := mockMergeFile(t, "Company A", "190816", 1, 1)
+		b := mockMergeFile(t, "Company B", "190816", 10, 1)
+		c := mockMergeFile(t, "Company C", "190816", 20, 1)
+		c.Header.ImmediateOrigin = "987654320"
+
+		names := func(files []*File) []string {
+			var out []string
+			for _, f := range files {
+				for _, b := range f.Batches {
+					out = append(out, b.GetHeader().CompanyName)
+				}
+			}
+			return out
+		}
+
+		out, err := MergeFilesWith([]*File{c, b, a}, Conditions{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Company C", "Company B", "Company A"}, names(out))
+
+		out, err = MergeFilesWith([]*File{c, b, a}, Conditions{SortOutput: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Company A", "Company B", "Company C"}, names(out))
+		require.Equal(t, "987654320", out[1].Header.ImmediateOrigin)
+	})
+}
@@ -0,0 +1,238 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MicroEntryDescription is the CompanyEntryDescription Nacha requires on micro-entries used to verify accounts.
+const MicroEntryDescription = "ACCTVERIFY"
+
+var (
+	// ErrMicroDepositSECCode is given when a micro-deposit batch is not PPD, CCD or WEB
+	ErrMicroDepositSECCode = errors.New("micro-deposits must be PPD, CCD or WEB")
+	// ErrMicroDepositDescription is given when a micro-deposit batch is not described as ACCTVERIFY
+	ErrMicroDepositDescription = fmt.Errorf("micro-deposits require the Company Entry Description %s", MicroEntryDescription)
+	// ErrMicroDepositAmount is given when a micro-deposit credit is not between $0.01 and $0.99
+	ErrMicroDepositAmount = errors.New("micro-deposit credits must be between $0.01 and $0.99")
+	// ErrMicroDepositCredits is given when a micro-deposit batch has no credits to the account being verified
+	ErrMicroDepositCredits = errors.New("micro-deposits require credits to the account being verified")
+	// ErrMicroDepositAccount is given when micro-deposits are sent to more than one account
+	ErrMicroDepositAccount = errors.New("micro-deposits must be sent to a single account")
+	// ErrMicroDepositDebits is given when the debits of a micro-deposit batch exceed its credits
+	ErrMicroDepositDebits = errors.New("micro-deposit debits must not exceed the credits")
+)
+
+// MicroDepositOptions describes the originator, the account being verified and the offset account
+// which funds the micro-deposits.
+type MicroDepositOptions struct {
+	// ODFIIdentification is the routing number of the originating bank
+	ODFIIdentification    string
+	CompanyName           string
+	CompanyIdentification string
+
+	// StandardEntryClassCode is PPD, CCD or WEB. Defaults to WEB.
+	StandardEntryClassCode string
+	EffectiveEntryDate     time.Time
+
+	// RDFIIdentification is the routing number of the account being verified
+	RDFIIdentification string
+	DFIAccountNumber   string
+	// AccountType of the account being verified, checking or savings
+	AccountType OffsetAccountType
+	// IndividualName is the receiver's name, or the receiving company's name for CCD
+	IndividualName string
+
+	// Offset is debited the total of the micro-deposits
+	Offset *Offset
+
+	// Count is how many credits are sent. Defaults to 2.
+	Count int
+}
+
+// MicroDeposits is a batch of micro-deposits and the amounts the receiver confirms to verify their account.
+type MicroDeposits struct {
+	Batch   Batcher
+	Amounts []int
+}
+
+// NewMicroDeposits builds a batch of credits for random and distinct amounts under $1 to the account being
+// verified, along with an offset debit for their total. Keep the returned Amounts to compare against those
+// reported by the receiver with VerifyMicroDeposits.
+func NewMicroDeposits(opts MicroDepositOptions) (*MicroDeposits, error) {
+	if opts.StandardEntryClassCode == "" {
+		opts.StandardEntryClassCode = WEB
+	}
+	switch opts.StandardEntryClassCode {
+	case PPD, CCD, WEB:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrMicroDepositSECCode, opts.StandardEntryClassCode)
+	}
+	if opts.Count == 0 {
+		opts.Count = 2
+	}
+	if opts.Count < 1 || opts.Count > 99 {
+		return nil, fmt.Errorf("invalid micro-deposit count %d", opts.Count)
+	}
+	if opts.Offset == nil {
+		return nil, errors.New("micro-deposits require an offset account")
+	}
+	if err := CheckRoutingNumber(opts.RDFIIdentification); err != nil {
+		return nil, fmt.Errorf("micro-deposits: invalid RDFI routing number %s: %w", opts.RDFIIdentification, err)
+	}
+
+	var transactionCode int
+	switch opts.AccountType {
+	case OffsetChecking:
+		transactionCode = CheckingCredit
+	case OffsetSavings:
+		transactionCode = SavingsCredit
+	default:
+		return nil, fmt.Errorf("micro-deposits: unknown account type: %s", opts.AccountType)
+	}
+
+	bh := NewBatchHeader()
+	bh.ServiceClassCode = MixedDebitsAndCredits
+	bh.CompanyName = opts.CompanyName
+	bh.CompanyIdentification = opts.CompanyIdentification
+	bh.StandardEntryClassCode = opts.StandardEntryClassCode
+	bh.CompanyEntryDescription = MicroEntryDescription
+	bh.EffectiveEntryDate = opts.EffectiveEntryDate.Format("060102") // YYMMDD
+	bh.ODFIIdentification = aba8(opts.ODFIIdentification)
+
+	batch, err := NewBatch(bh)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := randomMicroDepositAmounts(opts.Count)
+	for i, amount := range amounts {
+		ed := NewEntryDetail()
+		ed.TransactionCode = transactionCode
+		ed.SetRDFI(opts.RDFIIdentification)
+		ed.DFIAccountNumber = opts.DFIAccountNumber
+		ed.Amount = amount
+		ed.SetTraceNumber(bh.ODFIIdentification, i+1)
+		if opts.StandardEntryClassCode == CCD {
+			ed.SetReceivingCompany(opts.IndividualName)
+		} else {
+			ed.IndividualName = opts.IndividualName
+		}
+		if opts.StandardEntryClassCode == WEB {
+			ed.SetPaymentType("S")
+		}
+		batch.AddEntry(ed)
+	}
+	batch.WithOffset(opts.Offset)
+
+	if err := batch.Create(); err != nil {
+		return nil, err
+	}
+	if err := ValidateMicroDeposits(batch, opts.Offset); err != nil {
+		return nil, err
+	}
+	return &MicroDeposits{
+		Batch:   batch,
+		Amounts: amounts,
+	}, nil
+}
+
+// randomMicroDepositAmounts returns count distinct amounts between 1 and 99 cents
+func randomMicroDepositAmounts(count int) []int {
+	amounts := make([]int, 0, count)
+	for len(amounts) < count {
+		amount := rand.IntN(99) + 1
+		if !slices.Contains(amounts, amount) {
+			amounts = append(amounts, amount)
+		}
+	}
+	return amounts
+}
+
+// VerifyMicroDeposits returns if the amounts reported by a receiver match those which were sent, in any order.
+func VerifyMicroDeposits(sent, reported []int) bool {
+	if len(sent) != len(reported) {
+		return false
+	}
+	sent, reported = slices.Clone(sent), slices.Clone(reported)
+	slices.Sort(sent)
+	slices.Sort(reported)
+	return slices.Equal(sent, reported)
+}
+
+// ValidateMicroDeposits checks the Nacha rules for micro-entries used to verify an account.
+//
+// The batch must be PPD, CCD or WEB with a CompanyEntryDescription of ACCTVERIFY. Every credit to the
+// account being verified must be under $1 and all entries, except offset records and entries sent to an offset
+// account of offsets, must be for the same account. Debits, whether offset records or debits of the verified
+// account, must not exceed the credits.
+func ValidateMicroDeposits(batch Batcher, offsets ...*Offset) error {
+	if batch == nil {
+		return errors.New("nil batch")
+	}
+	bh := batch.GetHeader()
+	switch bh.StandardEntryClassCode {
+	case PPD, CCD, WEB:
+	default:
+		return batch.Error("StandardEntryClassCode", ErrMicroDepositSECCode, bh.StandardEntryClassCode)
+	}
+	if !strings.EqualFold(strings.TrimSpace(bh.CompanyEntryDescription), MicroEntryDescription) {
+		return batch.Error("CompanyEntryDescription", ErrMicroDepositDescription, bh.CompanyEntryDescription)
+	}
+
+	var account string
+	var credits, debits, creditCount int
+	isOffset := offsetRecordFunc(batch, offsets)
+	for _, ed := range batch.GetEntries() {
+		switch ed.CreditOrDebit() {
+		case "C":
+			credits += ed.Amount
+		case "D":
+			debits += ed.Amount
+		}
+		if isOffset(ed) {
+			continue
+		}
+
+		key := ed.RDFIIdentification + ed.CheckDigit + "/" + strings.TrimSpace(ed.DFIAccountNumber)
+		if account == "" {
+			account = key
+		} else if key != account {
+			return batch.Error("DFIAccountNumber", ErrMicroDepositAccount, ed.DFIAccountNumber)
+		}
+		if ed.CreditOrDebit() == "C" {
+			if ed.Amount < 1 || ed.Amount > 99 {
+				return batch.Error("Amount", ErrMicroDepositAmount, ed.Amount)
+			}
+			creditCount++
+		}
+	}
+	if creditCount == 0 {
+		return batch.Error("Entries", ErrMicroDepositCredits)
+	}
+	if debits > credits {
+		return batch.Error("TotalDebitEntryDollarAmount", ErrMicroDepositDebits, debits)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func microDepositOptions() MicroDepositOptions {
+	return MicroDepositOptions{
+		ODFIIdentification:    "121042882",
+		CompanyName:           "Moov, Inc",
+		CompanyIdentification: "121042882",
+		EffectiveEntryDate:    time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+		RDFIIdentification:    "231380104",
+		DFIAccountNumber:      "12345678",
+		AccountType:           OffsetChecking,
+		IndividualName:        "Jane Doe",
+		Offset: &Offset{
+			RoutingNumber: "121042882",
+			AccountNumber: "99887766",
+			AccountType:   OffsetChecking,
+			Description:   "FUNDING",
+		},
+	}
+}
+
+func TestNewMicroDeposits(t *testing.T) {
+	for _, sec := range []string{PPD, CCD, WEB} {
+		t.Run(sec, func(t *testing.T) {
+			opts := microDepositOptions()
+			opts.StandardEntryClassCode = sec
+
+			md, err := NewMicroDeposits(opts)
+			require.NoError(t, err)
+			require.Len(t, md.Amounts, 2)
+			require.NotEqual(t, md.Amounts[0], md.Amounts[1])
+
+			bh := md.Batch.GetHeader()
+			require.Equal(t, sec, bh.StandardEntryClassCode)
+			require.Equal(t, MicroEntryDescription, bh.CompanyEntryDescription)
+			require.Equal(t, "240501", bh.EffectiveEntryDate)
+
+			entries := md.Batch.GetEntries()
+			require.Len(t, entries, 3)
+			for i, amount := range md.Amounts {
+				require.Equal(t, CheckingCredit, entries[i].TransactionCode)
+				require.Equal(t, amount, entries[i].Amount)
+				require.Equal(t, "12345678", entries[i].DFIAccountNumber)
+				require.Less(t, amount, 100)
+				require.Greater(t, amount, 0)
+			}
+
+			offset := entries[2]
+			require.Equal(t, CheckingDebit, offset.TransactionCode)
+			require.Equal(t, md.Amounts[0]+md.Amounts[1], offset.Amount)
+			require.Equal(t, "99887766", offset.DFIAccountNumber)
+
+			file := NewFile()
+			file.SetHeader(staticFileHeader())
+			file.AddBatch(md.Batch)
+			require.NoError(t, file.Create())
+			require.NoError(t, file.Validate())
+
+			// after reading the batch its offset record is only known from the offset account
+			var buf bytes.Buffer
+			require.NoError(t, NewWriter(&buf).Write(file))
+			read, err := NewReader(&buf).Read()
+			require.NoError(t, err)
+			require.ErrorIs(t, ValidateMicroDeposits(read.Batches[0]), ErrMicroDepositAccount)
+			require.NoError(t, ValidateMicroDeposits(read.Batches[0], opts.Offset))
+		})
+	}
+
+	t.Run("savings", func(t *testing.T) {
+		opts := microDepositOptions()
+		opts.AccountType = OffsetSavings
+		opts.Count = 3
+
+		md, err := NewMicroDeposits(opts)
+		require.NoError(t, err)
+		require.Len(t, md.Amounts, 3)
+		for _, ed := range md.Batch.GetEntries()[:3] {
+			require.Equal(t, SavingsCredit, ed.TransactionCode)
+		}
+	})
+}
+
+func TestNewMicroDeposits__errors(t *testing.T) {
+	cases := map[string]func(opts *MicroDepositOptions){
+		"sec code":     func(opts *MicroDepositOptions) { opts.StandardEntryClassCode = TEL },
+		"count":        func(opts *MicroDepositOptions) { opts.Count = 100 },
+		"no offset":    func(opts *MicroDepositOptions) { opts.Offset = nil },
+		"rdfi":         func(opts *MicroDepositOptions) { opts.RDFIIdentification = "123456789" },
+		"account type": func(opts *MicroDepositOptions) { opts.AccountType = "loan" },
+		"offset":       func(opts *MicroDepositOptions) { opts.Offset.RoutingNumber = "1" },
+	}
+	for name, modify := range cases {
+		t.Run(name, func(t *testing.T) {
+			opts := microDepositOptions()
+			modify(&opts)
+
+			_, err := NewMicroDeposits(opts)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestVerifyMicroDeposits(t *testing.T) {
+	require.True(t, VerifyMicroDeposits([]int{12, 34}, []int{34, 12}))
+	require.False(t, VerifyMicroDeposits([]int{12, 34}, []int{12, 43}))
+	require.False(t, VerifyMicroDeposits([]int{12, 34}, []int{12}))
+	require.False(t, VerifyMicroDeposits([]int{12, 12}, []int{12, 34}))
+}
+
+func TestValidateMicroDeposits(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "two-micro-deposits.ach"))
+	require.NoError(t, err)
+	require.Len(t, file.Batches, 2)
+
+	batch := file.Batches[0]
+	require.ErrorIs(t, ValidateMicroDeposits(batch), ErrMicroDepositDescription)
+
+	batch.GetHeader().CompanyEntryDescription = MicroEntryDescription
+	require.NoError(t, ValidateMicroDeposits(batch))
+
+	entries := batch.GetEntries()
+
+	t.Run("amount", func(t *testing.T) {
+		amount := entries[0].Amount
+		t.Cleanup(func() { entries[0].Amount = amount })
+
+		entries[0].Amount = 100
+		require.ErrorIs(t, ValidateMicroDeposits(batch), ErrMicroDepositAmount)
+	})
+
+	t.Run("account", func(t *testing.T) {
+		account := entries[1].DFIAccountNumber
+		t.Cleanup(func() { entries[1].DFIAccountNumber = account })
+
+		entries[1].DFIAccountNumber = "987654321"
+		require.ErrorIs(t, ValidateMicroDeposits(batch), ErrMicroDepositAccount)
+	})
+
+	t.Run("debits", func(t *testing.T) {
+		amount := entries[2].Amount
+		t.Cleanup(func() { entries[2].Amount = amount })
+
+		entries[2].Amount += 1
+		require.ErrorIs(t, ValidateMicroDeposits(batch), ErrMicroDepositDebits)
+	})
+
+	t.Run("credits", func(t *testing.T) {
+		bh := NewBatchHeader()
+		bh.StandardEntryClassCode = PPD
+		bh.CompanyEntryDescription = MicroEntryDescription
+		require.ErrorIs(t, ValidateMicroDeposits(NewBatchPPD(bh)), ErrMicroDepositCredits)
+	})
+
+	t.Run("sec code", func(t *testing.T) {
+		bh := NewBatchHeader()
+		bh.StandardEntryClassCode = TEL
+		require.ErrorIs(t, ValidateMicroDeposits(NewBatchTEL(bh)), ErrMicroDepositSECCode)
+	})
+}
+
+func TestValidateMicroDeposits__malformed(t *testing.T) {
+	file, _ := readACHFilepath(filepath.Join("test", "testdata", "invalid-two-micro-deposits.ach"))
+	require.NotNil(t, file)
+	require.Len(t, file.Batches, 2)
+
+	batch := file.Batches[1]
+	batch.GetHeader().CompanyEntryDescription = MicroEntryDescription
+	require.ErrorIs(t, ValidateMicroDeposits(batch), ErrMicroDepositAccount) // malformed DFIAccountNumber
+}
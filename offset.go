@@ -0,0 +1,364 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OffsetStrategy determines which records File.Balance offsets together.
+type OffsetStrategy string
+
+const (
+	// OffsetPerBatch adds offset records to the end of each batch. This is the default strategy.
+	OffsetPerBatch OffsetStrategy = "batch"
+	// OffsetPerFile adds one batch to the end of a File which offsets all of its batches.
+	OffsetPerFile OffsetStrategy = "file"
+)
+
+// debitAccount returns the account which receives the debit offset record
+func (off *Offset) debitAccount() OffsetAccount {
+	if off.DebitAccount != nil {
+		return off.DebitAccount.withDescription(off.Description)
+	}
+	return off.account()
+}
+
+// creditAccount returns the account which receives the credit offset record
+func (off *Offset) creditAccount() OffsetAccount {
+	if off.CreditAccount != nil {
+		return off.CreditAccount.withDescription(off.Description)
+	}
+	return off.account()
+}
+
+func (off *Offset) account() OffsetAccount {
+	return OffsetAccount{
+		RoutingNumber: off.RoutingNumber,
+		AccountNumber: off.AccountNumber,
+		AccountType:   off.AccountType,
+		Description:   off.Description,
+	}
+}
+
+// withDescription returns acct with description set when acct has none
+func (acct OffsetAccount) withDescription(description string) OffsetAccount {
+	if acct.Description == "" {
+		acct.Description = description
+	}
+	return acct
+}
+
+func (acct OffsetAccount) validate() error {
+	if err := CheckRoutingNumber(acct.RoutingNumber); err != nil {
+		return fmt.Errorf("offset: invalid routing number %s: %v", acct.RoutingNumber, err)
+	}
+	return acct.AccountType.validate()
+}
+
+// validateDebit returns an error when acct can't receive a debit offset record. LoanDebit (55) is
+// only allowed for reversals, so loan accounts only receive credit offset records.
+func (acct OffsetAccount) validateDebit() error {
+	if acct.AccountType == OffsetLoan {
+		return fmt.Errorf("offset: loan account %s can't receive a debit offset record", acct.AccountNumber)
+	}
+	return nil
+}
+
+// transactionCode returns the TransactionCode of a debit or credit offset record to the account
+func (acct OffsetAccount) transactionCode(debit bool) int {
+	switch acct.AccountType {
+	case OffsetChecking:
+		if debit {
+			return CheckingDebit
+		}
+		return CheckingCredit
+	case OffsetSavings:
+		if debit {
+			return SavingsDebit
+		}
+		return SavingsCredit
+	case OffsetGL:
+		if debit {
+			return GLDebit
+		}
+		return GLCredit
+	case OffsetLoan:
+		if debit {
+			return LoanDebit
+		}
+		return LoanCredit
+	}
+	return 0
+}
+
+// entryDetail returns a debit or credit offset record to the account for amount. The record's
+// Category is copied from the first entry.
+func (acct OffsetAccount) entryDetail(debit bool, amount int, entries []*EntryDetail) *EntryDetail {
+	ed := NewEntryDetail()
+	ed.TransactionCode = acct.transactionCode(debit)
+	ed.RDFIIdentification = acct.RoutingNumber[:8]
+	ed.CheckDigit = acct.RoutingNumber[8:9]
+	ed.DFIAccountNumber = acct.AccountNumber
+	ed.Amount = amount
+	ed.IdentificationNumber = "" // left empty
+	ed.IndividualName = offsetIndividualName
+	ed.DiscretionaryData = acct.Description
+	if len(entries) > 0 {
+		ed.Category = entries[0].Category
+	}
+	return ed
+}
+
+// receives returns if entry is sent to acct with the TransactionCode of its debit or credit offset record
+func (acct OffsetAccount) receives(entry *EntryDetail, debit bool) bool {
+	return entry.TransactionCode == acct.transactionCode(debit) &&
+		entry.RDFIIdentification+entry.CheckDigit == acct.RoutingNumber &&
+		strings.TrimSpace(entry.DFIAccountNumber) == strings.TrimSpace(acct.AccountNumber)
+}
+
+// matches returns if entry is sent to the debit or credit account of off with the TransactionCode
+// of an offset record, or is named OFFSET when MatchIndividualName is set.
+func (off *Offset) matches(entry *EntryDetail) bool {
+	if off == nil || entry == nil {
+		return false
+	}
+	if off.debitAccount().receives(entry, true) || off.creditAccount().receives(entry, false) {
+		return true
+	}
+	return off.MatchIndividualName && strings.EqualFold(entry.IndividualName, offsetIndividualName)
+}
+
+// records returns the offset records of off in entries, which follow records totaling credits and debits.
+//
+// An entry is the debit (or credit) offset record when it's sent to the debit (or credit) account of off with
+// the account's TransactionCode and its amount balances the credits (or debits) before it. Other entries to an
+// offset account are not offset records, and there's at most one debit and one credit offset record. Entries
+// named OFFSET are also offset records when MatchIndividualName is set.
+func (off *Offset) records(entries []*EntryDetail, credits, debits int) []*EntryDetail {
+	if off == nil {
+		return nil
+	}
+	debitAccount, creditAccount := off.debitAccount(), off.creditAccount()
+
+	var out []*EntryDetail
+	var debitFound, creditFound bool
+	for _, ed := range entries {
+		if ed == nil {
+			continue
+		}
+		switch {
+		case !debitFound && ed.Amount > 0 && ed.Amount == credits && debitAccount.receives(ed, true):
+			debitFound = true
+			out = append(out, ed)
+			continue
+		case !creditFound && ed.Amount > 0 && ed.Amount == debits && creditAccount.receives(ed, false):
+			creditFound = true
+			out = append(out, ed)
+			continue
+		case off.MatchIndividualName && strings.EqualFold(ed.IndividualName, offsetIndividualName):
+			out = append(out, ed)
+			continue
+		}
+		switch ed.CreditOrDebit() {
+		case "C":
+			credits += ed.Amount
+		case "D":
+			debits += ed.Amount
+		}
+	}
+	return out
+}
+
+// offsetter is implemented by every batch type which embeds Batch
+type offsetter interface {
+	offsetRecords() []*EntryDetail
+}
+
+// offsetRecords returns the offset records of batch
+func offsetRecords(batch Batcher) []*EntryDetail {
+	if o, ok := batch.(offsetter); ok {
+		return o.offsetRecords()
+	}
+	return nil
+}
+
+// offsetRecordFunc returns a func which reports if an entry of batch is one of its offset records
+// or is sent to an offset account of offsets
+func offsetRecordFunc(batch Batcher, offsets []*Offset) func(*EntryDetail) bool {
+	records := offsetRecords(batch)
+	return func(entry *EntryDetail) bool {
+		if slices.Contains(records, entry) {
+			return true
+		}
+		for _, off := range offsets {
+			if off.matches(entry) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Balance adds offset records to f which balance its debits and credits, replacing offset records
+// added before. OffsetPerBatch, the default Strategy, offsets each batch with Batch.WithOffset.
+// OffsetPerFile removes the offset records of each batch and adds a CCD batch to the end of f which
+// offsets the entire File.
+//
+// ADV and IAT batches are not offset.
+func (f *File) Balance(off *Offset) error {
+	if f == nil {
+		return errors.New("nil File")
+	}
+	if off == nil {
+		return errors.New("nil Offset")
+	}
+
+	switch off.Strategy {
+	case "", OffsetPerBatch:
+		for i := range f.Batches {
+			if len(f.Batches[i].GetADVEntries()) > 0 {
+				continue
+			}
+			f.Batches[i].WithOffset(off)
+			if err := f.Batches[i].Create(); err != nil {
+				return err
+			}
+		}
+	case OffsetPerFile:
+		if err := f.balanceFile(off); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown offset strategy: %s", off.Strategy)
+	}
+	return f.Create()
+}
+
+// balanceFile replaces the offset records of every batch in f with a batch which offsets them all
+func (f *File) balanceFile(off *Offset) error {
+	debitAccount, creditAccount := off.debitAccount(), off.creditAccount()
+	if err := debitAccount.validate(); err != nil {
+		return err
+	}
+	if err := creditAccount.validate(); err != nil {
+		return err
+	}
+
+	var batches []Batcher
+	var template *BatchHeader
+	var credits, debits int
+	for _, batch := range f.Batches {
+		if len(batch.GetADVEntries()) > 0 {
+			batches = append(batches, batch)
+			continue
+		}
+
+		// Remove offset records on the batch, or the batch itself when it only has offset records. These are
+		// the batch's own offset records and those of off, which balance the batch or every batch before it.
+		records := offsetRecords(batch)
+		records = append(records, off.records(batch.GetEntries(), 0, 0)...)
+		records = append(records, off.records(batch.GetEntries(), credits, debits)...)
+		batch.DeleteEntries(func(ed *EntryDetail) bool {
+			return slices.Contains(records, ed)
+		})
+		batch.WithOffset(nil)
+		if len(batch.GetEntries()) == 0 {
+			continue
+		}
+		if err := batch.Create(); err != nil {
+			return err
+		}
+		batches = append(batches, batch)
+
+		if template == nil {
+			template = batch.GetHeader()
+		}
+		for _, ed := range batch.GetEntries() {
+			switch ed.CreditOrDebit() {
+			case "C":
+				credits += ed.Amount
+			case "D":
+				debits += ed.Amount
+			}
+		}
+	}
+	f.Batches = batches
+	if template == nil || (credits == 0 && debits == 0) {
+		return nil
+	}
+	if credits > 0 {
+		if err := debitAccount.validateDebit(); err != nil {
+			return err
+		}
+	}
+
+	header := NewBatchHeader()
+	header.StandardEntryClassCode = CCD
+	header.CompanyName = template.CompanyName
+	header.CompanyDiscretionaryData = template.CompanyDiscretionaryData
+	header.CompanyIdentification = template.CompanyIdentification
+	header.CompanyEntryDescription = offsetIndividualName
+	header.EffectiveEntryDate = template.EffectiveEntryDate
+	header.OriginatorStatusCode = template.OriginatorStatusCode
+	header.ODFIIdentification = template.ODFIIdentification
+	header.ServiceClassCode = MixedDebitsAndCredits
+
+	batch, err := NewBatch(header)
+	if err != nil {
+		return err
+	}
+	seq := f.lastTraceSequence(header.ODFIIdentification)
+	if credits > 0 {
+		seq++
+		ed := debitAccount.entryDetail(true, credits, nil)
+		ed.SetTraceNumber(header.ODFIIdentification, seq)
+		batch.AddEntry(ed)
+	}
+	if debits > 0 {
+		seq++
+		ed := creditAccount.entryDetail(false, debits, nil)
+		ed.SetTraceNumber(header.ODFIIdentification, seq)
+		batch.AddEntry(ed)
+	}
+	if err := batch.Create(); err != nil {
+		return fmt.Errorf("offset batch: %w", err)
+	}
+	f.AddBatch(batch)
+	return nil
+}
+
+// lastTraceSequence returns the largest sequence number of trace numbers in f from odfi
+func (f *File) lastTraceSequence(odfi string) int {
+	last := 0
+	for _, batch := range f.Batches {
+		for _, ed := range batch.GetEntries() {
+			if len(ed.TraceNumber) != 15 || !strings.HasPrefix(ed.TraceNumber, odfi) {
+				continue
+			}
+			if n, err := strconv.Atoi(ed.TraceNumber[8:]); err == nil && n > last {
+				last = n
+			}
+		}
+	}
+	return last
+}
@@ -0,0 +1,346 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetAccount__transactionCode(t *testing.T) {
+	cases := map[OffsetAccountType][2]int{
+		OffsetChecking: {CheckingDebit, CheckingCredit},
+		OffsetSavings:  {SavingsDebit, SavingsCredit},
+		OffsetGL:       {GLDebit, GLCredit},
+		OffsetLoan:     {LoanDebit, LoanCredit},
+	}
+	for accountType, codes := range cases {
+		acct := OffsetAccount{AccountType: accountType}
+		require.Equal(t, codes[0], acct.transactionCode(true), accountType)
+		require.Equal(t, codes[1], acct.transactionCode(false), accountType)
+	}
+}
+
+// mixedBatch returns a PPD batch with a debit and a credit
+func mixedBatch(t *testing.T) *BatchPPD {
+	t.Helper()
+
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	batch, ok := file.Batches[0].(*BatchPPD)
+	require.True(t, ok)
+	batch.Header.ServiceClassCode = MixedDebitsAndCredits
+
+	credit := *batch.Entries[0]
+	credit.TransactionCode = CheckingCredit
+	credit.Amount = 2500
+	credit.TraceNumber = "121042880000002"
+	batch.AddEntry(&credit)
+	require.NoError(t, batch.Create())
+	return batch
+}
+
+func TestBatch__OffsetAccounts(t *testing.T) {
+	batch := mixedBatch(t)
+	batch.WithOffset(&Offset{
+		DebitAccount: &OffsetAccount{
+			RoutingNumber: "121042882",
+			AccountNumber: "1111",
+			AccountType:   OffsetGL,
+		},
+		CreditAccount: &OffsetAccount{
+			RoutingNumber: "231380104",
+			AccountNumber: "2222",
+			AccountType:   OffsetLoan,
+		},
+	})
+	require.NoError(t, batch.Create())
+
+	entries := batch.GetEntries()
+	require.Len(t, entries, 4)
+
+	debit, credit := entries[2], entries[3]
+	require.Equal(t, GLDebit, debit.TransactionCode)
+	require.Equal(t, "1111", debit.DFIAccountNumber)
+	require.Equal(t, 2500, debit.Amount)
+	require.Equal(t, "121042880000003", debit.TraceNumber)
+
+	require.Equal(t, LoanCredit, credit.TransactionCode)
+	require.Equal(t, "2222", credit.DFIAccountNumber)
+	require.Equal(t, 100000000, credit.Amount)
+	require.Equal(t, "23138010", credit.RDFIIdentification)
+	require.Equal(t, "121042880000004", credit.TraceNumber)
+
+	require.Equal(t, batch.Control.TotalDebitEntryDollarAmount, batch.Control.TotalCreditEntryDollarAmount)
+
+	// an invalid account is rejected
+	batch.offset.CreditAccount.AccountType = "invalid"
+	require.Error(t, batch.Create())
+}
+
+func TestBatch__OffsetIdentification(t *testing.T) {
+	batch := mixedBatch(t)
+	batch.WithOffset(&Offset{
+		RoutingNumber: "121042882",
+		AccountNumber: "1111",
+		AccountType:   OffsetChecking,
+	})
+	require.NoError(t, batch.Create())
+	require.Len(t, batch.Entries, 4)
+
+	// offset records are found without their IndividualName
+	for _, ed := range batch.Entries[2:] {
+		ed.IndividualName = "Settlement"
+	}
+	require.NoError(t, batch.Create())
+	require.Len(t, batch.Entries, 4)
+
+	// and by their account after reading the batch from JSON
+	bs, err := json.Marshal(batch)
+	require.NoError(t, err)
+
+	var read Batch
+	require.NoError(t, json.Unmarshal(bs, &read))
+	require.Len(t, read.Entries, 4)
+	require.NoError(t, read.build())
+	require.Len(t, read.Entries, 4)
+	require.Equal(t, read.Control.TotalDebitEntryDollarAmount, read.Control.TotalCreditEntryDollarAmount)
+
+	// entries named OFFSET to another account are only offset records with MatchIndividualName
+	other := *batch.Entries[3]
+	other.DFIAccountNumber = "2222"
+	other.IndividualName = offsetIndividualName
+	other.TraceNumber = "121042880000005"
+	read.AddEntry(&other)
+	read.offsetEntries = nil
+	require.NoError(t, read.build())
+	require.Len(t, read.Entries, 5)
+	require.Contains(t, read.Entries, &other)
+
+	read.offset.MatchIndividualName = true
+	read.offsetEntries = nil
+	require.NoError(t, read.build())
+	require.Len(t, read.Entries, 4)
+	require.NotContains(t, read.Entries, &other)
+}
+
+func TestFile__BalanceAfterRead(t *testing.T) {
+	off := &Offset{
+		RoutingNumber: "121042882",
+		AccountNumber: "1111",
+		AccountType:   OffsetChecking,
+		Description:   "Settlement",
+	}
+	for _, strategy := range []OffsetStrategy{OffsetPerBatch, OffsetPerFile} {
+		t.Run(string(strategy), func(t *testing.T) {
+			file := NewFile()
+			file.SetHeader(staticFileHeader())
+			file.AddBatch(mixedBatch(t))
+			require.NoError(t, file.Create())
+			off.Strategy = strategy
+			require.NoError(t, file.Balance(off))
+			written := file.Control
+
+			// offset records are found by their account after the file is written and read
+			var buf bytes.Buffer
+			require.NoError(t, NewWriter(&buf).Write(file))
+			read, err := NewReader(&buf).Read()
+			require.NoError(t, err)
+			for _, batch := range read.Batches {
+				for _, ed := range batch.GetEntries() {
+					ed.IndividualName = "Renamed"
+				}
+			}
+
+			require.NoError(t, read.Balance(off))
+			require.Equal(t, written.EntryAddendaCount, read.Control.EntryAddendaCount)
+			require.Equal(t, written.TotalDebitEntryDollarAmountInFile, read.Control.TotalDebitEntryDollarAmountInFile)
+			require.Equal(t, written.TotalCreditEntryDollarAmountInFile, read.Control.TotalCreditEntryDollarAmountInFile)
+		})
+	}
+}
+
+func TestBatch__OffsetAccountEntry(t *testing.T) {
+	batch := mixedBatch(t)
+
+	// a real entry to the offset account isn't an offset record
+	entry := *batch.Entries[1]
+	entry.RDFIIdentification = "12104288"
+	entry.CheckDigit = "2"
+	entry.DFIAccountNumber = "999"
+	entry.IndividualName = "Real Person"
+	entry.Amount = 1000
+	entry.TraceNumber = "121042880000003"
+	batch.AddEntry(&entry)
+
+	batch.WithOffset(&Offset{
+		RoutingNumber: "121042882",
+		AccountNumber: "999",
+		AccountType:   OffsetChecking,
+	})
+	for range 2 {
+		require.NoError(t, batch.Create())
+		require.Len(t, batch.Entries, 5)
+		require.Contains(t, batch.Entries, &entry)
+		require.Equal(t, 2500+1000, batch.Entries[3].Amount)
+		require.Equal(t, batch.Control.TotalDebitEntryDollarAmount, batch.Control.TotalCreditEntryDollarAmount)
+	}
+
+	file := NewFile()
+	file.SetHeader(staticFileHeader())
+	file.AddBatch(batch)
+	require.NoError(t, file.Create())
+	require.NoError(t, file.Balance(&Offset{
+		RoutingNumber: "121042882",
+		AccountNumber: "999",
+		AccountType:   OffsetChecking,
+		Strategy:      OffsetPerFile,
+	}))
+	require.Len(t, file.Batches, 2)
+	require.Contains(t, file.Batches[0].GetEntries(), &entry)
+}
+
+func TestBatch__OffsetLoanDebit(t *testing.T) {
+	loan := OffsetAccount{
+		RoutingNumber: "231380104",
+		AccountNumber: "2222",
+		AccountType:   OffsetLoan,
+	}
+
+	// credits would need a LoanDebit offset
+	batch := mixedBatch(t)
+	batch.WithOffset(&Offset{DebitAccount: &loan, CreditAccount: &loan})
+	require.ErrorContains(t, batch.Create(), "can't receive a debit offset record")
+
+	file := NewFile()
+	file.SetHeader(staticFileHeader())
+	file.AddBatch(mixedBatch(t))
+	require.NoError(t, file.Create())
+	err := file.Balance(&Offset{DebitAccount: &loan, CreditAccount: &loan, Strategy: OffsetPerFile})
+	require.ErrorContains(t, err, "can't receive a debit offset record")
+
+	// debits are offset with a LoanCredit
+	file, err = readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	require.NoError(t, file.Balance(&Offset{RoutingNumber: loan.RoutingNumber, AccountNumber: loan.AccountNumber, AccountType: OffsetLoan}))
+	entries := file.Batches[0].GetEntries()
+	require.Len(t, entries, 2)
+	require.Equal(t, LoanCredit, entries[1].TransactionCode)
+}
+
+func TestBatch__OffsetServiceClassCode(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	batch := file.Batches[0]
+	batch.GetEntries()[0].Amount = 0
+	batch.SetValidation(&ValidateOpts{AllowZeroEntryAmount: true})
+	batch.WithOffset(&Offset{
+		RoutingNumber: "121042882",
+		AccountNumber: "1111",
+		AccountType:   OffsetChecking,
+	})
+	require.NoError(t, batch.Create())
+
+	// nothing to offset so the batch is unchanged
+	require.Len(t, batch.GetEntries(), 1)
+	require.Equal(t, DebitsOnly, batch.GetHeader().ServiceClassCode)
+}
+
+func TestFile__Balance(t *testing.T) {
+	newFile := func(t *testing.T) *File {
+		t.Helper()
+
+		file := NewFile()
+		file.SetHeader(staticFileHeader())
+		file.AddBatch(mixedBatch(t))
+
+		second := mixedBatch(t)
+		for i, ed := range second.Entries {
+			ed.Amount = 1000 * (i + 1)
+			ed.SetTraceNumber(second.Header.ODFIIdentification, 10+i)
+		}
+		require.NoError(t, second.Create())
+		file.AddBatch(second)
+
+		require.NoError(t, file.Create())
+		return file
+	}
+	off := &Offset{
+		RoutingNumber: "121042882",
+		AccountNumber: "1111",
+		AccountType:   OffsetSavings,
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		file := newFile(t)
+		require.NoError(t, file.Balance(off))
+		require.NoError(t, file.Validate())
+
+		require.Len(t, file.Batches, 2)
+		for _, batch := range file.Batches {
+			require.Len(t, batch.GetEntries(), 4)
+			require.Equal(t, batch.GetControl().TotalDebitEntryDollarAmount, batch.GetControl().TotalCreditEntryDollarAmount)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		file := newFile(t)
+		off := *off
+		off.Strategy = OffsetPerFile
+
+		// offsets added per batch are replaced
+		require.NoError(t, file.Balance(&Offset{RoutingNumber: "121042882", AccountNumber: "1111", AccountType: OffsetSavings}))
+
+		for range 2 {
+			require.NoError(t, file.Balance(&off))
+			require.NoError(t, file.Validate())
+
+			require.Len(t, file.Batches, 3)
+			require.Len(t, file.Batches[0].GetEntries(), 2)
+			require.Len(t, file.Batches[1].GetEntries(), 2)
+
+			offsets := file.Batches[2]
+			require.Equal(t, CCD, offsets.GetHeader().StandardEntryClassCode)
+			require.Equal(t, 3, offsets.GetHeader().BatchNumber)
+
+			entries := offsets.GetEntries()
+			require.Len(t, entries, 2)
+			require.Equal(t, SavingsDebit, entries[0].TransactionCode)
+			require.Equal(t, 2500+2000, entries[0].Amount)
+			require.Equal(t, "121042880000012", entries[0].TraceNumber)
+			require.Equal(t, SavingsCredit, entries[1].TransactionCode)
+			require.Equal(t, 100000000+1000, entries[1].Amount)
+			require.Equal(t, "121042880000013", entries[1].TraceNumber)
+
+			require.Equal(t, file.Control.TotalDebitEntryDollarAmountInFile, file.Control.TotalCreditEntryDollarAmountInFile)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		file := newFile(t)
+		require.Error(t, file.Balance(nil))
+		require.Error(t, file.Balance(&Offset{Strategy: "bogus"}))
+		require.Error(t, file.Balance(&Offset{Strategy: OffsetPerFile, RoutingNumber: "1"}))
+	})
+}
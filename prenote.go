@@ -0,0 +1,362 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPrenoteSECCode is given when prenotes are requested for a batch whose SEC code does not allow them
+	ErrPrenoteSECCode = errors.New("SEC code does not allow prenotes")
+	// ErrPrenoteNoEntries is given when there are no entries to create prenotes for
+	ErrPrenoteNoEntries = errors.New("no entries to prenote")
+	// ErrPrenoteMissing is given when a live entry is sent to an account without a prenote
+	ErrPrenoteMissing = errors.New("no prenote was sent to the account")
+	// ErrPrenoteTooRecent is given when a live entry settles too soon after the account's prenote
+	ErrPrenoteTooRecent = errors.New("prenote was sent too recently")
+)
+
+// prenoteSECCodes are the SEC codes which prenotes can be originated for
+var prenoteSECCodes = []string{CCD, CIE, CTX, PPD, TEL, WEB}
+
+// PrenoteTransactionCode returns the prenote TransactionCode for a live credit or debit, e.g. 23 for a
+// CheckingCredit (22) or 28 for a CheckingDebit (27). Prenote codes are returned unchanged.
+//
+// Returns and loan debits have no prenote code.
+func PrenoteTransactionCode(code int) (int, error) {
+	if code < CheckingReturnNOCCredit || code > LoanDebit {
+		return 0, fmt.Errorf("no prenote TransactionCode for %d", code)
+	}
+	if code == LoanDebit {
+		return 0, fmt.Errorf("no prenote TransactionCode for loan debit %d", code)
+	}
+	switch code % 10 {
+	case 2, 3, 4:
+		return code - (code % 10) + 3, nil
+	case 7, 8, 9:
+		return code - (code % 10) + 8, nil
+	}
+	return 0, fmt.Errorf("no prenote TransactionCode for return %d", code)
+}
+
+// Prenote creates a File of zero dollar prenotes for the entries in f, which is not modified. Each batch
+// becomes a prenote batch settling on effectiveEntryDate with one prenote for every account it sends entries to.
+//
+// Offset records, entries sent to an offset account of offsets, returns and NOCs are skipped. Addenda05 records are kept for SEC codes which allow them.
+// Trace numbers are assigned sequentially across the file for each ODFI, starting from 1.
+//
+// IAT and ADV batches are not supported.
+func (f *File) Prenote(effectiveEntryDate time.Time, offsets ...*Offset) (*File, error) {
+	if f == nil {
+		return nil, errors.New("nil File")
+	}
+
+	out := NewFile()
+	out.SetValidation(f.validateOpts)
+	out.Header = f.Header
+	out.Header.ID = ""
+	now := time.Now()
+	out.Header.FileCreationDate = now.Format("060102")
+	out.Header.FileCreationTime = now.Format("1504")
+	out.Header.SetValidation(f.validateOpts)
+
+	for i := range f.Batches {
+		bh := f.Batches[i].GetHeader()
+		if bh == nil || len(f.Batches[i].GetADVEntries()) > 0 {
+			continue
+		}
+		entries := slices.DeleteFunc(slices.Clone(f.Batches[i].GetEntries()), offsetRecordFunc(f.Batches[i], offsets))
+		batch, err := prenoteBatch(bh, effectiveEntryDate, entries, out.lastTraceSequence(bh.ODFIIdentification))
+		if errors.Is(err, ErrPrenoteNoEntries) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("batch %s: %w", bh.ID, err)
+		}
+		batch.SetValidation(f.validateOpts)
+		out.AddBatch(batch)
+	}
+	if len(out.Batches) == 0 {
+		return nil, ErrPrenoteNoEntries
+	}
+	if err := out.Create(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NewPrenoteFile creates a File with a batch of zero dollar prenotes for entries, such as the first
+// entries planned for new receivers. The batch uses bh, settling on effectiveEntryDate.
+func NewPrenoteFile(fh FileHeader, bh *BatchHeader, effectiveEntryDate time.Time, entries []*EntryDetail) (*File, error) {
+	if bh == nil {
+		return nil, errors.New("nil BatchHeader provided")
+	}
+
+	batch, err := prenoteBatch(bh, effectiveEntryDate, entries, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := NewFile()
+	out.SetHeader(fh)
+	out.AddBatch(batch)
+	if err := out.Create(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// prenoteBatch returns a batch with one prenote for each account entries are sent to. Trace numbers
+// continue from lastSequence, the last trace number sequence already used by the ODFI.
+func prenoteBatch(bh *BatchHeader, effectiveEntryDate time.Time, entries []*EntryDetail, lastSequence int) (Batcher, error) {
+	if !slices.Contains(prenoteSECCodes, bh.StandardEntryClassCode) {
+		return nil, fmt.Errorf("%w: %s", ErrPrenoteSECCode, bh.StandardEntryClassCode)
+	}
+
+	header := *bh
+	header.ID = ""
+	header.EffectiveEntryDate = effectiveEntryDate.Format("060102")
+
+	var prenotes []*EntryDetail
+	accounts := make(map[string]bool)
+	for _, entry := range entries {
+		if entry == nil || !isLiveEntry(entry) {
+			continue
+		}
+		if entry.isPrenote(entry.TransactionCode) {
+			continue // prenotes are created from live entries
+		}
+		account := prenoteAccount(entry)
+		if accounts[account] {
+			continue
+		}
+		accounts[account] = true
+
+		prenote, err := prenoteEntry(header.StandardEntryClassCode, entry)
+		if err != nil {
+			return nil, fmt.Errorf("trace number %s: %w", entry.TraceNumber, err)
+		}
+		prenote.SetTraceNumber(header.ODFIIdentification, lastSequence+len(prenotes)+1)
+		prenotes = append(prenotes, prenote)
+	}
+	if len(prenotes) == 0 {
+		return nil, ErrPrenoteNoEntries
+	}
+	header.ServiceClassCode = responseServiceClassCode(prenotes)
+
+	batch, err := NewBatch(&header)
+	if err != nil {
+		return nil, err
+	}
+	for _, prenote := range prenotes {
+		batch.AddEntry(prenote)
+	}
+	if err := batch.Create(); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// prenoteEntry converts a live entry into a zero dollar prenote
+func prenoteEntry(secCode string, entry *EntryDetail) (*EntryDetail, error) {
+	code, err := PrenoteTransactionCode(entry.TransactionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	ed := NewEntryDetail()
+	ed.TransactionCode = code
+	ed.RDFIIdentification = entry.RDFIIdentification
+	ed.CheckDigit = entry.CheckDigit
+	ed.DFIAccountNumber = entry.DFIAccountNumber
+	ed.Amount = 0
+	ed.IdentificationNumber = entry.IdentificationNumber
+	ed.IndividualName = entry.IndividualName
+	ed.DiscretionaryData = entry.DiscretionaryData
+	ed.Category = CategoryForward
+
+	// TEL entries do not have addenda records. CTX entries keep every addenda record so
+	// the count in IndividualName is still correct.
+	if secCode != TEL {
+		for _, addenda05 := range entry.Addenda05 {
+			if addenda05 == nil {
+				continue
+			}
+			copied := *addenda05
+			ed.AddAddenda05(&copied)
+		}
+	}
+	if len(ed.Addenda05) > 0 {
+		ed.AddendaRecordIndicator = 1
+	}
+	return ed, nil
+}
+
+// isLiveEntry returns if entry is a forward entry
+func isLiveEntry(entry *EntryDetail) bool {
+	if entry.Addenda98 != nil || entry.Addenda98Refused != nil ||
+		entry.Addenda99 != nil || entry.Addenda99Contested != nil || entry.Addenda99Dishonored != nil {
+		return false
+	}
+	if entry.Category != "" && entry.Category != CategoryForward {
+		return false
+	}
+	return true
+}
+
+// prenoteAccount identifies the account of entry by its RDFI, account number and account type
+func prenoteAccount(entry *EntryDetail) string {
+	accountType := entry.TransactionCode / 10 // checking, savings, GL or loan
+	return fmt.Sprintf("%s%s/%s/%d", entry.RDFIIdentification, entry.CheckDigit, strings.TrimSpace(entry.DFIAccountNumber), accountType)
+}
+
+// PrenoteViolation is a live entry which was sent without waiting for a prenote.
+type PrenoteViolation struct {
+	TraceNumber string `json:"traceNumber"`
+
+	// Err is ErrPrenoteMissing or ErrPrenoteTooRecent
+	Err error `json:"-"`
+
+	// PrenoteDate is when the account's prenote settled, which is zero when no prenote was recorded.
+	PrenoteDate time.Time `json:"prenoteDate,omitzero"`
+}
+
+func (v PrenoteViolation) Error() string {
+	if v.PrenoteDate.IsZero() {
+		return fmt.Sprintf("trace number %s: %v", v.TraceNumber, v.Err)
+	}
+	return fmt.Sprintf("trace number %s: %v on %s", v.TraceNumber, v.Err, v.PrenoteDate.Format("2006-01-02"))
+}
+
+func (v PrenoteViolation) Unwrap() error {
+	return v.Err
+}
+
+// PrenoteChecker records when prenotes settle for each account and confirms live entries to those
+// accounts settle at least Days banking days later. Prenotes which are returned or corrected should
+// not be recorded. The zero value is ready to use and is safe for concurrent use.
+type PrenoteChecker struct {
+	// Days is how many banking days after a prenote's settlement date live entries can settle. Nacha
+	// rules allow live entries to settle on the same day as the prenote (zero days) while earlier rules
+	// and some ODFIs require three.
+	Days int
+
+	// Calendar determines banking days. FederalReserveCalendar is used when nil.
+	Calendar BankingCalendar
+
+	// RequirePrenote reports live entries to accounts without a recorded prenote. Otherwise only
+	// entries to accounts with a recent prenote are reported.
+	RequirePrenote bool
+
+	// Offsets are the offset accounts of the checked files. Entries sent to them are not checked.
+	Offsets []*Offset
+
+	mu       sync.Mutex
+	prenotes map[string]time.Time // earliest settlement date of each account's prenote
+}
+
+// Record saves the settlement dates of every prenote in file.
+func (c *PrenoteChecker) Record(file *File) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.prenotes == nil {
+		c.prenotes = make(map[string]time.Time)
+	}
+	return eachPrenoteEntry(file, c.Offsets, func(entry *EntryDetail, settlement time.Time, prenote bool) {
+		if !prenote {
+			return
+		}
+		account := prenoteAccount(entry)
+		if existing, exists := c.prenotes[account]; !exists || settlement.Before(existing) {
+			c.prenotes[account] = settlement
+		}
+	})
+}
+
+// Check returns the live entries in file which settle too soon after their account's prenote, or
+// which do not have a prenote when RequirePrenote is set. Live entries are matched to prenotes by their
+// RDFI, DFIAccountNumber and account type (checking, savings, GL or loan).
+func (c *PrenoteChecker) Check(file *File) ([]PrenoteViolation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var calendar BankingCalendar = FederalReserveCalendar{}
+	if c.Calendar != nil {
+		calendar = c.Calendar
+	}
+
+	var violations []PrenoteViolation
+	err := eachPrenoteEntry(file, c.Offsets, func(entry *EntryDetail, settlement time.Time, prenote bool) {
+		if prenote {
+			return
+		}
+		prenoteDate, exists := c.prenotes[prenoteAccount(entry)]
+		switch {
+		case !exists && c.RequirePrenote:
+			violations = append(violations, PrenoteViolation{
+				TraceNumber: entry.TraceNumber,
+				Err:         ErrPrenoteMissing,
+			})
+		case exists && settlement.Before(addBankingDays(calendar, prenoteDate, c.Days)):
+			violations = append(violations, PrenoteViolation{
+				TraceNumber: entry.TraceNumber,
+				Err:         ErrPrenoteTooRecent,
+				PrenoteDate: prenoteDate,
+			})
+		}
+	})
+	return violations, err
+}
+
+// eachPrenoteEntry calls fn with every live entry and prenote in file along with its batch's settlement date.
+// Offset records and entries sent to an offset account of offsets are skipped.
+func eachPrenoteEntry(file *File, offsets []*Offset, fn func(entry *EntryDetail, settlement time.Time, prenote bool)) error {
+	if file == nil {
+		return errors.New("nil File")
+	}
+	for _, batch := range file.Batches {
+		bh := batch.GetHeader()
+		if bh == nil || !slices.Contains(prenoteSECCodes, bh.StandardEntryClassCode) {
+			continue
+		}
+
+		var settlement time.Time
+		isOffset := offsetRecordFunc(batch, offsets)
+		for _, entry := range batch.GetEntries() {
+			if entry == nil || !isLiveEntry(entry) || isOffset(entry) {
+				continue
+			}
+			if settlement.IsZero() {
+				t, err := time.Parse("060102", bh.EffectiveEntryDate)
+				if err != nil {
+					return fmt.Errorf("batch %s: invalid EffectiveEntryDate %q: %w", bh.ID, bh.EffectiveEntryDate, err)
+				}
+				settlement = t
+			}
+			fn(entry, settlement, entry.isPrenote(entry.TransactionCode))
+		}
+	}
+	return nil
+}
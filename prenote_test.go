@@ -0,0 +1,303 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrenoteTransactionCode(t *testing.T) {
+	cases := map[int]int{
+		CheckingCredit:                    CheckingPrenoteCredit,
+		CheckingDebit:                     CheckingPrenoteDebit,
+		CheckingPrenoteCredit:             CheckingPrenoteCredit,
+		CheckingZeroDollarRemittanceDebit: CheckingPrenoteDebit,
+		SavingsCredit:                     SavingsPrenoteCredit,
+		SavingsDebit:                      SavingsPrenoteDebit,
+		GLCredit:                          GLPrenoteCredit,
+		GLDebit:                           GLPrenoteDebit,
+		LoanCredit:                        LoanPrenoteCredit,
+	}
+	for code, expected := range cases {
+		got, err := PrenoteTransactionCode(code)
+		require.NoError(t, err)
+		require.Equal(t, expected, got, "TransactionCode %d", code)
+	}
+
+	for _, code := range []int{0, CheckingReturnNOCCredit, SavingsReturnNOCDebit, LoanDebit, 99} {
+		_, err := PrenoteTransactionCode(code)
+		require.Error(t, err, "TransactionCode %d", code)
+	}
+}
+
+func TestFile__Prenote(t *testing.T) {
+	effectiveEntryDate := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("PPD", func(t *testing.T) {
+		file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+
+		// a second entry to the same account only needs one prenote
+		second := *file.Batches[0].GetEntries()[0]
+		second.TraceNumber = "121042880000002"
+		file.Batches[0].AddEntry(&second)
+		require.NoError(t, file.Create())
+
+		prenotes, err := file.Prenote(effectiveEntryDate)
+		require.NoError(t, err)
+		require.NoError(t, prenotes.Validate())
+		require.Equal(t, file.Header.ImmediateOrigin, prenotes.Header.ImmediateOrigin)
+		require.Len(t, prenotes.Batches, 1)
+
+		bh := prenotes.Batches[0].GetHeader()
+		require.Equal(t, "240501", bh.EffectiveEntryDate)
+		require.Equal(t, DebitsOnly, bh.ServiceClassCode)
+
+		entries := prenotes.Batches[0].GetEntries()
+		require.Len(t, entries, 1)
+		require.Equal(t, CheckingPrenoteDebit, entries[0].TransactionCode)
+		require.Zero(t, entries[0].Amount)
+		require.Equal(t, second.DFIAccountNumber, entries[0].DFIAccountNumber)
+		require.Equal(t, "121042880000001", entries[0].TraceNumber)
+
+		// the live file is unchanged
+		require.Equal(t, CheckingDebit, file.Batches[0].GetEntries()[0].TransactionCode)
+		require.NotZero(t, file.Batches[0].GetEntries()[0].Amount)
+	})
+
+	t.Run("multiple batches", func(t *testing.T) {
+		file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+
+		// a second batch from the same ODFI to another account
+		bh := *file.Batches[0].GetHeader()
+		bh.BatchNumber = 2
+		batch, err := NewBatch(&bh)
+		require.NoError(t, err)
+		entry := *file.Batches[0].GetEntries()[0]
+		entry.DFIAccountNumber = "1122334455"
+		entry.TraceNumber = "121042880000002"
+		batch.AddEntry(&entry)
+		require.NoError(t, batch.Create())
+		file.AddBatch(batch)
+		require.NoError(t, file.Create())
+
+		prenotes, err := file.Prenote(effectiveEntryDate)
+		require.NoError(t, err)
+		require.NoError(t, prenotes.Validate())
+		require.Len(t, prenotes.Batches, 2)
+		require.Equal(t, "121042880000001", prenotes.Batches[0].GetEntries()[0].TraceNumber)
+		require.Equal(t, "121042880000002", prenotes.Batches[1].GetEntries()[0].TraceNumber)
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+		file.Batches[0].WithOffset(&Offset{
+			RoutingNumber: "231380104",
+			AccountNumber: "99887766",
+			AccountType:   OffsetChecking,
+		})
+		require.NoError(t, file.Batches[0].Create())
+		require.Len(t, file.Batches[0].GetEntries(), 2)
+
+		prenotes, err := file.Prenote(effectiveEntryDate)
+		require.NoError(t, err)
+		entries := prenotes.Batches[0].GetEntries()
+		require.Len(t, entries, 1)
+		require.NotEqual(t, offsetIndividualName, entries[0].IndividualName)
+		require.Equal(t, DebitsOnly, prenotes.Batches[0].GetHeader().ServiceClassCode)
+
+		// after reading a file the offset account identifies its offset records
+		require.NoError(t, file.Create())
+		var buf bytes.Buffer
+		require.NoError(t, NewWriter(&buf).Write(file))
+		read, err := NewReader(&buf).Read()
+		require.NoError(t, err)
+
+		prenotes, err = read.Prenote(effectiveEntryDate)
+		require.NoError(t, err)
+		require.Len(t, prenotes.Batches[0].GetEntries(), 2)
+
+		prenotes, err = read.Prenote(effectiveEntryDate, &Offset{
+			RoutingNumber: "231380104",
+			AccountNumber: "99887766",
+			AccountType:   OffsetChecking,
+		})
+		require.NoError(t, err)
+		require.Len(t, prenotes.Batches[0].GetEntries(), 1)
+	})
+
+	t.Run("CTX", func(t *testing.T) {
+		file, err := readACHFilepath(filepath.Join("test", "ach-ctx-read", "ctx-debit.ach"))
+		require.NoError(t, err)
+
+		prenotes, err := file.Prenote(effectiveEntryDate)
+		require.NoError(t, err)
+		require.NoError(t, prenotes.Validate())
+
+		live := file.Batches[0].GetEntries()[0]
+		prenote := prenotes.Batches[0].GetEntries()[0]
+		require.Equal(t, CheckingPrenoteDebit, prenote.TransactionCode)
+		require.Len(t, prenote.Addenda05, len(live.Addenda05))
+		require.Equal(t, live.CATXAddendaRecordsField(), prenote.CATXAddendaRecordsField())
+	})
+
+	t.Run("WEB", func(t *testing.T) {
+		file, err := readACHFilepath(filepath.Join("test", "testdata", "web-debit.ach"))
+		require.NoError(t, err)
+
+		prenotes, err := file.Prenote(effectiveEntryDate)
+		require.NoError(t, err)
+		require.NoError(t, prenotes.Validate())
+		require.Equal(t, WEB, prenotes.Batches[0].GetHeader().StandardEntryClassCode)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var file *File
+		_, err := file.Prenote(effectiveEntryDate)
+		require.Error(t, err)
+
+		file, err = readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+		file.Batches[0].GetHeader().StandardEntryClassCode = ARC
+		_, err = file.Prenote(effectiveEntryDate)
+		require.ErrorIs(t, err, ErrPrenoteSECCode)
+
+		_, err = NewFile().Prenote(effectiveEntryDate)
+		require.ErrorIs(t, err, ErrPrenoteNoEntries)
+	})
+}
+
+func TestNewPrenoteFile(t *testing.T) {
+	bh := mockBatchPPDHeader()
+
+	credit := mockPPDEntryDetail()
+	credit.TransactionCode = SavingsCredit
+	credit.DFIAccountNumber = "1122334455"
+
+	file, err := NewPrenoteFile(staticFileHeader(), bh, time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC), []*EntryDetail{mockPPDEntryDetail(), credit})
+	require.NoError(t, err)
+	require.NoError(t, file.Validate())
+
+	require.Equal(t, CreditsOnly, file.Batches[0].GetHeader().ServiceClassCode)
+	entries := file.Batches[0].GetEntries()
+	require.Len(t, entries, 2)
+	require.Equal(t, CheckingPrenoteCredit, entries[0].TransactionCode)
+	require.Equal(t, SavingsPrenoteCredit, entries[1].TransactionCode)
+
+	_, err = NewPrenoteFile(staticFileHeader(), nil, time.Now(), nil)
+	require.Error(t, err)
+
+	_, err = NewPrenoteFile(staticFileHeader(), bh, time.Now(), nil)
+	require.ErrorIs(t, err, ErrPrenoteNoEntries)
+}
+
+func TestPrenoteChecker(t *testing.T) {
+	live, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	live.Batches[0].GetHeader().EffectiveEntryDate = "240506" // Monday
+
+	prenotes, err := live.Prenote(time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)) // Wednesday
+	require.NoError(t, err)
+
+	t.Run("missing", func(t *testing.T) {
+		checker := &PrenoteChecker{}
+		violations, err := checker.Check(live)
+		require.NoError(t, err)
+		require.Empty(t, violations)
+
+		checker.RequirePrenote = true
+		violations, err = checker.Check(live)
+		require.NoError(t, err)
+		require.Len(t, violations, 1)
+		require.ErrorIs(t, violations[0], ErrPrenoteMissing)
+		require.Equal(t, "121042880000001", violations[0].TraceNumber)
+	})
+
+	t.Run("banking days", func(t *testing.T) {
+		checker := &PrenoteChecker{Days: 3, RequirePrenote: true}
+		require.NoError(t, checker.Record(prenotes))
+
+		// Thursday, Friday and Monday are three banking days after Wednesday
+		violations, err := checker.Check(live)
+		require.NoError(t, err)
+		require.Empty(t, violations)
+
+		live.Batches[0].GetHeader().EffectiveEntryDate = "240503" // Friday
+		t.Cleanup(func() { live.Batches[0].GetHeader().EffectiveEntryDate = "240506" })
+
+		violations, err = checker.Check(live)
+		require.NoError(t, err)
+		require.Len(t, violations, 1)
+		require.ErrorIs(t, violations[0], ErrPrenoteTooRecent)
+		require.Equal(t, "2024-05-01", violations[0].PrenoteDate.Format("2006-01-02"))
+		require.Contains(t, violations[0].Error(), "too recently on 2024-05-01")
+	})
+
+	t.Run("account type", func(t *testing.T) {
+		checker := &PrenoteChecker{RequirePrenote: true}
+		require.NoError(t, checker.Record(prenotes))
+
+		entry := live.Batches[0].GetEntries()[0]
+		entry.TransactionCode = SavingsDebit
+		t.Cleanup(func() { entry.TransactionCode = CheckingDebit })
+
+		violations, err := checker.Check(live)
+		require.NoError(t, err)
+		require.Len(t, violations, 1)
+		require.ErrorIs(t, violations[0], ErrPrenoteMissing)
+	})
+
+	t.Run("offsets", func(t *testing.T) {
+		off := &Offset{RoutingNumber: "231380104", AccountNumber: "99887766", AccountType: OffsetChecking}
+		balanced, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+		balanced.Batches[0].GetHeader().EffectiveEntryDate = "240506"
+		require.NoError(t, balanced.Balance(off))
+
+		var buf bytes.Buffer
+		require.NoError(t, NewWriter(&buf).Write(balanced))
+		read, err := NewReader(&buf).Read()
+		require.NoError(t, err)
+
+		checker := &PrenoteChecker{RequirePrenote: true}
+		require.NoError(t, checker.Record(prenotes))
+		violations, err := checker.Check(&read)
+		require.NoError(t, err)
+		require.Len(t, violations, 1) // the offset record
+
+		checker.Offsets = []*Offset{off}
+		violations, err = checker.Check(&read)
+		require.NoError(t, err)
+		require.Empty(t, violations)
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		live.Batches[0].GetHeader().EffectiveEntryDate = "bogus"
+		t.Cleanup(func() { live.Batches[0].GetHeader().EffectiveEntryDate = "240506" })
+
+		_, err := (&PrenoteChecker{}).Check(live)
+		require.Error(t, err)
+	})
+}
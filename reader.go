@@ -300,6 +300,17 @@ func (r *Reader) Read() (File, error) {
 			}
 		}
 	}
+
+	// Run the optional and custom validation rules against the records read
+	onFailure := func(line int, record string, err error) {
+		r.errors.Add(&base.ParseError{Line: line, Record: record, Err: err})
+	}
+	r.File.validateOpts.checkOptionalRules(&r.File, onFailure)
+	err := r.File.validateOpts.checkCustomRules(&r.File, onFailure)
+	if err != nil {
+		r.errors.Add(err)
+	}
+
 	if r.errors.Empty() {
 		return r.File, nil
 	}
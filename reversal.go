@@ -18,7 +18,11 @@
 package ach
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -114,3 +118,259 @@ func (f *File) Reversal(effectiveEntryDate time.Time) error {
 	}
 	return f.Create()
 }
+
+// ReturnEntry identifies an Entry from a forward File which is being returned and the
+// Nacha return code which describes why.
+type ReturnEntry struct {
+	// TraceNumber is the TraceNumber of the forward Entry being returned.
+	TraceNumber string `json:"traceNumber"`
+
+	// ReturnCode must be a valid code found by LookupReturnCode (e.g. R01, R03, R10).
+	ReturnCode string `json:"returnCode"`
+
+	// DateOfDeath is required when returning entries with R14 or R15.
+	DateOfDeath time.Time `json:"dateOfDeath,omitzero"`
+
+	// AddendaInformation is optional data included on the Addenda99 record.
+	AddendaInformation string `json:"addendaInformation,omitempty"`
+}
+
+var (
+	// ErrReturnNoEntries is given when File.Return is called without any entries to return
+	ErrReturnNoEntries = errors.New("no entries to return")
+
+	// ErrReturnDateOfDeath is given when an R14 or R15 return is missing the DateOfDeath
+	ErrReturnDateOfDeath = errors.New("date of death is required for R14 and R15 returns")
+)
+
+// Return creates a Nacha compliant return File from a received forward File. Each ReturnEntry is
+// matched to a forward Entry by its TraceNumber and converted into a return Entry with an Addenda99
+// record. The forward File is not modified.
+//
+// The returned File has its ImmediateOrigin and ImmediateDestination swapped from the forward File,
+// and each return batch is originated by the RDFI of the entries it contains. TransactionCodes are
+// converted to their return variants (e.g. 22 to 21, 27 to 26).
+//
+// IAT and ADV batches are not supported.
+func (f *File) Return(effectiveEntryDate time.Time, entries []ReturnEntry) (*File, error) {
+	if f == nil {
+		return nil, errors.New("nil File")
+	}
+	if len(entries) == 0 {
+		return nil, ErrReturnNoEntries
+	}
+
+	// Validate the requested returns
+	wanted := make(map[string]ReturnEntry, len(entries))
+	for _, entry := range entries {
+		if entry.TraceNumber == "" {
+			return nil, errors.New("missing TraceNumber on return entry")
+		}
+		if _, exists := wanted[entry.TraceNumber]; exists {
+			return nil, fmt.Errorf("trace number %s is returned more than once", entry.TraceNumber)
+		}
+		if LookupReturnCode(entry.ReturnCode) == nil {
+			return nil, fmt.Errorf("trace number %s: %s %w", entry.TraceNumber, entry.ReturnCode, ErrAddenda99ReturnCode)
+		}
+		if (entry.ReturnCode == "R14" || entry.ReturnCode == "R15") && entry.DateOfDeath.IsZero() {
+			return nil, fmt.Errorf("trace number %s: %w", entry.TraceNumber, ErrReturnDateOfDeath)
+		}
+		wanted[entry.TraceNumber] = entry
+	}
+
+	traceNumbers := make(map[string]bool, len(wanted))
+	for traceNumber := range wanted {
+		traceNumbers[traceNumber] = true
+	}
+	return respondToEntries(f, effectiveEntryDate, traceNumbers, "", func(bh *BatchHeader, forward *EntryDetail, seq int) (*EntryDetail, error) {
+		return returnEntryDetail(bh, forward, wanted[forward.TraceNumber], seq)
+	})
+}
+
+// respondToEntries builds a File sent back to the originator of f for each Entry whose TraceNumber is in
+// traceNumbers. Entries are converted by convert and grouped into batches by their forward batch and the
+// DFI responding to them. secCode overrides the StandardEntryClassCode of each batch when non-empty.
+//
+// This is used for returns and notifications of change which are originated by the RDFI of the forward Entry.
+func respondToEntries(f *File, effectiveEntryDate time.Time, traceNumbers map[string]bool, secCode string, convert func(bh *BatchHeader, forward *EntryDetail, seq int) (*EntryDetail, error)) (*File, error) {
+	out := NewFile()
+	out.SetValidation(f.validateOpts)
+	out.Header = f.Header
+	out.Header.ID = ""
+	out.Header.ImmediateOrigin = f.Header.ImmediateDestination
+	out.Header.ImmediateOriginName = f.Header.ImmediateDestinationName
+	out.Header.ImmediateDestination = f.Header.ImmediateOrigin
+	out.Header.ImmediateDestinationName = f.Header.ImmediateOriginName
+	out.Header.FileCreationDate = effectiveEntryDate.Format("060102")
+	out.Header.FileCreationTime = effectiveEntryDate.Format("1504")
+	out.Header.SetValidation(f.validateOpts)
+
+	// Entries are grouped by their forward batch and the DFI responding to them.
+	// Trace numbers are sequenced per responding DFI across the entire File.
+	traceSequences := make(map[string]int)
+	batchNumber := 1
+	found := make(map[string]bool, len(traceNumbers))
+
+	for i := range f.Batches {
+		bh := f.Batches[i].GetHeader()
+		if bh == nil || len(f.Batches[i].GetADVEntries()) > 0 {
+			continue
+		}
+
+		var respondingDFIs []string
+		responses := make(map[string][]*EntryDetail)
+
+		for _, forward := range f.Batches[i].GetEntries() {
+			if !traceNumbers[forward.TraceNumber] {
+				continue
+			}
+			if found[forward.TraceNumber] {
+				return nil, fmt.Errorf("trace number %s found in multiple entries", forward.TraceNumber)
+			}
+			found[forward.TraceNumber] = true
+
+			respondingDFI := forward.RDFIIdentification
+			if _, exists := responses[respondingDFI]; !exists {
+				respondingDFIs = append(respondingDFIs, respondingDFI)
+			}
+
+			traceSequences[respondingDFI]++
+			entry, err := convert(bh, forward, traceSequences[respondingDFI])
+			if err != nil {
+				return nil, fmt.Errorf("trace number %s: %w", forward.TraceNumber, err)
+			}
+			responses[respondingDFI] = append(responses[respondingDFI], entry)
+		}
+
+		for _, respondingDFI := range respondingDFIs {
+			header := NewBatchHeader()
+			header.CompanyName = bh.CompanyName
+			header.CompanyDiscretionaryData = bh.CompanyDiscretionaryData
+			header.CompanyIdentification = bh.CompanyIdentification
+			header.StandardEntryClassCode = cmp.Or(secCode, bh.StandardEntryClassCode)
+			header.CompanyEntryDescription = bh.CompanyEntryDescription
+			header.CompanyDescriptiveDate = bh.CompanyDescriptiveDate
+			header.EffectiveEntryDate = effectiveEntryDate.Format("060102")
+			header.OriginatorStatusCode = bh.OriginatorStatusCode
+			header.ODFIIdentification = respondingDFI
+			header.BatchNumber = batchNumber
+			header.ServiceClassCode = responseServiceClassCode(responses[respondingDFI])
+
+			batch, err := NewBatch(header)
+			if err != nil {
+				return nil, fmt.Errorf("creating batch for batch index %d: %w", i, err)
+			}
+			batch.SetValidation(f.validateOpts)
+			for _, entry := range responses[respondingDFI] {
+				batch.AddEntry(entry)
+			}
+			if err := batch.Create(); err != nil {
+				return nil, fmt.Errorf("creating batch for batch index %d: %w", i, err)
+			}
+			out.AddBatch(batch)
+			batchNumber++
+		}
+	}
+
+	if len(found) != len(traceNumbers) {
+		var missing []string
+		for traceNumber := range traceNumbers {
+			if !found[traceNumber] {
+				missing = append(missing, traceNumber)
+			}
+		}
+		slices.Sort(missing)
+		return nil, fmt.Errorf("trace numbers not found: %s", strings.Join(missing, ", "))
+	}
+
+	if err := out.Create(); err != nil {
+		return nil, err
+	}
+	if err := out.Validate(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// returnEntryDetail copies a forward EntryDetail into a return Entry with its Addenda99 record populated.
+func returnEntryDetail(bh *BatchHeader, forward *EntryDetail, req ReturnEntry, seq int) (*EntryDetail, error) {
+	txCode, err := returnTransactionCode(forward.TransactionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	ed := respondingEntryDetail(bh, forward, txCode, CategoryReturn)
+
+	addenda99 := NewAddenda99()
+	addenda99.ReturnCode = req.ReturnCode
+	addenda99.OriginalTrace = forward.TraceNumber
+	addenda99.OriginalDFI = forward.RDFIIdentification
+	addenda99.AddendaInformation = req.AddendaInformation
+	if !req.DateOfDeath.IsZero() {
+		addenda99.DateOfDeath = req.DateOfDeath.Format("060102")
+	}
+	ed.Addenda99 = addenda99
+
+	switch bh.StandardEntryClassCode {
+	case ATX, CTX:
+		// Return entries carry only their Addenda99, never the forward Addenda05 records
+		ed.SetCATXAddendaRecords(1)
+	}
+
+	ed.SetTraceNumber(forward.RDFIIdentification, seq)
+
+	return ed, nil
+}
+
+// respondingEntryDetail copies entry into a new EntryDetail which is sent back to the ODFI of bh.
+// The new Entry has an AddendaRecordIndicator of 1 as it's expected to carry a return or NOC addenda.
+func respondingEntryDetail(bh *BatchHeader, entry *EntryDetail, txCode int, category string) *EntryDetail {
+	ed := NewEntryDetail()
+	ed.TransactionCode = txCode
+	ed.RDFIIdentification = bh.ODFIIdentificationField()
+	ed.CheckDigit = fmt.Sprintf("%d", CalculateCheckDigit(ed.RDFIIdentification))
+	ed.DFIAccountNumber = entry.DFIAccountNumber
+	ed.Amount = entry.Amount
+	ed.IdentificationNumber = entry.IdentificationNumber
+	ed.IndividualName = entry.IndividualName
+	ed.DiscretionaryData = entry.DiscretionaryData
+	ed.AddendaRecordIndicator = 1
+	ed.Category = category
+	return ed
+}
+
+// returnTransactionCode converts a forward TransactionCode into its return variant.
+func returnTransactionCode(code int) (int, error) {
+	if code < CheckingCredit || code > LoanDebit {
+		return 0, fmt.Errorf("unable to return TransactionCode %d", code)
+	}
+	switch code % 10 {
+	case 1, 6:
+		return 0, fmt.Errorf("unable to return an Entry with return TransactionCode %d", code)
+	case 2, 3, 4:
+		return code - (code % 10) + 1, nil
+	default:
+		return code - (code % 10) + 6, nil
+	}
+}
+
+// responseServiceClassCode computes the ServiceClassCode for a batch of return or NOC entries.
+func responseServiceClassCode(entries []*EntryDetail) int {
+	hasCredits, hasDebits := false, false
+	for _, entry := range entries {
+		switch entry.CreditOrDebit() {
+		case "C":
+			hasCredits = true
+		case "D":
+			hasDebits = true
+		}
+	}
+	switch {
+	case hasCredits && hasDebits:
+		return MixedDebitsAndCredits
+	case hasDebits:
+		return DebitsOnly
+	default:
+		return CreditsOnly
+	}
+}
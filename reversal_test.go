@@ -18,6 +18,7 @@
 package ach
 
 import (
+	"bytes"
 	"path/filepath"
 	"testing"
 	"time"
@@ -144,3 +145,153 @@ func TestReversal_TEL(t *testing.T) {
 	err = file.Validate()
 	require.NoError(t, err)
 }
+
+func TestFile_Return(t *testing.T) {
+	file, err := ReadFile(filepath.Join("test", "testdata", "ppd-mixedDebitCredit.ach"))
+	require.NoError(t, err)
+
+	effectiveEntryDate := time.Date(2019, time.July, 22, 10, 30, 0, 0, time.UTC)
+	returned, err := file.Return(effectiveEntryDate, []ReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R01"},
+		{TraceNumber: "121042880000003", ReturnCode: "R03"},
+	})
+	require.NoError(t, err)
+
+	// Origin and destination are swapped
+	require.Equal(t, file.Header.ImmediateOrigin, returned.Header.ImmediateDestination)
+	require.Equal(t, file.Header.ImmediateDestination, returned.Header.ImmediateOrigin)
+	require.Equal(t, "190722", returned.Header.FileCreationDate)
+	require.Equal(t, "1030", returned.Header.FileCreationTime)
+
+	require.Len(t, returned.Batches, 1)
+	require.Len(t, returned.ReturnEntries, 1)
+
+	bh := returned.Batches[0].GetHeader()
+	require.Equal(t, "23138010", bh.ODFIIdentification)
+	require.Equal(t, MixedDebitsAndCredits, bh.ServiceClassCode)
+	require.Equal(t, "190722", bh.EffectiveEntryDate)
+
+	entries := returned.Batches[0].GetEntries()
+	require.Len(t, entries, 2)
+
+	require.Equal(t, CheckingReturnNOCDebit, entries[0].TransactionCode)
+	require.Equal(t, "12104288", entries[0].RDFIIdentification)
+	require.Equal(t, "2", entries[0].CheckDigit)
+	require.Equal(t, "231380100000001", entries[0].TraceNumber)
+	require.Equal(t, 200000000, entries[0].Amount)
+	require.Equal(t, "R01", entries[0].Addenda99.ReturnCode)
+	require.Equal(t, "121042880000001", entries[0].Addenda99.OriginalTrace)
+	require.Equal(t, "23138010", entries[0].Addenda99.OriginalDFI)
+	require.Equal(t, entries[0].TraceNumber, entries[0].Addenda99.TraceNumber)
+
+	require.Equal(t, CheckingReturnNOCCredit, entries[1].TransactionCode)
+	require.Equal(t, "231380100000002", entries[1].TraceNumber)
+	require.Equal(t, "R03", entries[1].Addenda99.ReturnCode)
+	require.Equal(t, "121042880000003", entries[1].Addenda99.OriginalTrace)
+
+	bc := returned.Batches[0].GetControl()
+	require.Equal(t, 200000000, bc.TotalDebitEntryDollarAmount)
+	require.Equal(t, 100000000, bc.TotalCreditEntryDollarAmount)
+	require.Equal(t, 4, bc.EntryAddendaCount)
+
+	// The return file can be written and read back
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf).Write(returned))
+	parsed, err := NewReader(&buf).Read()
+	require.NoError(t, err)
+	require.Len(t, parsed.ReturnEntries, 1)
+
+	// The forward file is left as-is
+	require.Equal(t, CheckingDebit, file.Batches[0].GetEntries()[0].TransactionCode)
+	require.NoError(t, file.Validate())
+}
+
+func TestFile_ReturnDateOfDeath(t *testing.T) {
+	file, err := ReadFile(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	effectiveEntryDate := time.Now().In(time.UTC)
+	_, err = file.Return(effectiveEntryDate, []ReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R15"},
+	})
+	require.ErrorIs(t, err, ErrReturnDateOfDeath)
+
+	dateOfDeath := time.Date(2019, time.June, 1, 0, 0, 0, 0, time.UTC)
+	returned, err := file.Return(effectiveEntryDate, []ReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R15", DateOfDeath: dateOfDeath},
+	})
+	require.NoError(t, err)
+
+	entries := returned.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.Equal(t, CheckingReturnNOCDebit, entries[0].TransactionCode)
+	require.Equal(t, "190601", entries[0].Addenda99.DateOfDeath)
+}
+
+func TestFile_ReturnCTX(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "ach-ctx-read", "ctx-debit.ach"))
+	require.NoError(t, err)
+
+	effectiveEntryDate := time.Date(2018, time.November, 28, 0, 0, 0, 0, time.UTC)
+	returned, err := file.Return(effectiveEntryDate, []ReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R01"},
+	})
+	require.NoError(t, err)
+
+	entries := returned.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.Empty(t, entries[0].Addenda05)
+	require.Equal(t, "0001", entries[0].CATXAddendaRecordsField())
+	require.Equal(t, "R01", entries[0].Addenda99.ReturnCode)
+	require.Equal(t, 2, returned.Batches[0].GetControl().EntryAddendaCount)
+}
+
+func TestFile_ReturnErrors(t *testing.T) {
+	file, err := ReadFile(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	effectiveEntryDate := time.Now().In(time.UTC)
+
+	_, err = file.Return(effectiveEntryDate, nil)
+	require.ErrorIs(t, err, ErrReturnNoEntries)
+
+	_, err = file.Return(effectiveEntryDate, []ReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R99"},
+	})
+	require.ErrorIs(t, err, ErrAddenda99ReturnCode)
+
+	_, err = file.Return(effectiveEntryDate, []ReturnEntry{
+		{TraceNumber: "121042880000001", ReturnCode: "R01"},
+		{TraceNumber: "121042880000001", ReturnCode: "R03"},
+	})
+	require.ErrorContains(t, err, "returned more than once")
+
+	_, err = file.Return(effectiveEntryDate, []ReturnEntry{
+		{TraceNumber: "121042880000009", ReturnCode: "R01"},
+	})
+	require.ErrorContains(t, err, "trace numbers not found: 121042880000009")
+}
+
+func TestReturnTransactionCode(t *testing.T) {
+	cases := map[int]int{
+		CheckingCredit:        CheckingReturnNOCCredit,
+		CheckingPrenoteCredit: CheckingReturnNOCCredit,
+		CheckingDebit:         CheckingReturnNOCDebit,
+		SavingsCredit:         SavingsReturnNOCCredit,
+		SavingsDebit:          SavingsReturnNOCDebit,
+		GLDebit:               GLReturnNOCDebit,
+		LoanCredit:            LoanReturnNOCCredit,
+		LoanDebit:             LoanReturnNOCDebit,
+	}
+	for input, expected := range cases {
+		got, err := returnTransactionCode(input)
+		require.NoError(t, err)
+		require.Equal(t, expected, got, "input %d", input)
+	}
+
+	_, err := returnTransactionCode(CheckingReturnNOCDebit)
+	require.Error(t, err)
+
+	_, err = returnTransactionCode(81)
+	require.Error(t, err)
+}
@@ -17,14 +17,151 @@
 
 package ach
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // SegmentFileConfiguration contains configuration setting for sorting during Segment File Creation.
 //
-// It is currently not defined, but can/will be expanded later and File.SegmentFile enhanced to use the
-// configuration settings
-type SegmentFileConfiguration struct{}
+// File.SegmentFile always returns one credit File and one debit File and rejects any other rules. File.SegmentFiles
+// uses SegmentBy and KeyFunc to split the entries of a File into as many Files as there are distinct segments.
+type SegmentFileConfiguration struct {
+	// SegmentBy are the rules used to group entries into Files. Entries are placed in the same File
+	// when every rule has the same value for them. SegmentByCreditDebit is used when empty.
+	SegmentBy []SegmentRule `json:"segmentBy,omitempty"`
+
+	// KeyFunc is an optional caller-supplied rule. Entries which return different keys are placed
+	// in different Files. Only one of the Entry, ADVEntry or IATEntry fields is set on each record.
+	KeyFunc func(record *IteratorRecord) string `json:"-"`
+}
 
 // SegmentFileConfiguration returns a new SegmentFileConfiguration with default values for non exported fields
 func NewSegmentFileConfiguration() *SegmentFileConfiguration {
 	sfc := &SegmentFileConfiguration{}
 	return sfc
 }
+
+var (
+	// ErrSegmentRule is returned when a SegmentFileConfiguration contains an unknown SegmentRule
+	ErrSegmentRule = errors.New("unknown segment rule")
+
+	// ErrSegmentFileRules is returned when File.SegmentFile is given rules other than SegmentByCreditDebit
+	ErrSegmentFileRules = errors.New("SegmentFile only segments by credit and debit, use SegmentFiles")
+)
+
+// SegmentRule is a way of grouping entries into separate Files
+type SegmentRule string
+
+const (
+	// SegmentByCreditDebit separates credit and debit entries. Segment values are "credit" and "debit".
+	SegmentByCreditDebit SegmentRule = "creditDebit"
+	// SegmentByStandardEntryClass separates entries by the StandardEntryClassCode of their batch.
+	SegmentByStandardEntryClass SegmentRule = "standardEntryClass"
+	// SegmentByCompanyIdentification separates entries by the CompanyIdentification (or OriginatorIdentification
+	// for IAT) of their batch.
+	SegmentByCompanyIdentification SegmentRule = "companyIdentification"
+	// SegmentByRDFI separates entries by the routing number of the receiving institution.
+	SegmentByRDFI SegmentRule = "rdfi"
+	// SegmentBySameDay separates same-day entries from next-day entries. Segment values are "sameDay" and "nextDay".
+	//
+	// Batches are same-day when their EffectiveEntryDate is on or before the FileCreationDate or their
+	// CompanyDescriptiveDate starts with "SD" (e.g. SD1300).
+	SegmentBySameDay SegmentRule = "sameDay"
+	// SegmentByPrenote separates prenotifications from live entries. Segment values are "prenote" and "live".
+	SegmentByPrenote SegmentRule = "prenote"
+
+	// segmentKeyFunc is the Segment key for values returned by SegmentFileConfiguration.KeyFunc
+	segmentKeyFunc = "key"
+)
+
+// SegmentedFile is one of the Files returned by File.SegmentFiles
+type SegmentedFile struct {
+	// Segment holds the value of each SegmentRule shared by every entry in File.
+	// Values from KeyFunc are stored under "key".
+	Segment map[string]string `json:"segment"`
+
+	File *File `json:"file"`
+}
+
+func (sfc *SegmentFileConfiguration) rules() ([]SegmentRule, error) {
+	if sfc == nil || (len(sfc.SegmentBy) == 0 && sfc.KeyFunc == nil) {
+		return []SegmentRule{SegmentByCreditDebit}, nil
+	}
+	for _, rule := range sfc.SegmentBy {
+		switch rule {
+		case SegmentByCreditDebit, SegmentByStandardEntryClass, SegmentByCompanyIdentification,
+			SegmentByRDFI, SegmentBySameDay, SegmentByPrenote:
+		default:
+			return nil, fmt.Errorf("%w %q", ErrSegmentRule, rule)
+		}
+	}
+	return sfc.SegmentBy, nil
+}
+
+// segment returns the value of each rule for an entry along with a key combining them.
+func (sfc *SegmentFileConfiguration) segment(rules []SegmentRule, fh FileHeader, record *IteratorRecord) (map[string]string, string) {
+	var secCode, companyID, effectiveDate, descriptiveDate, rdfi string
+	var transactionCode int
+	switch {
+	case record.IATEntry != nil:
+		bh := record.IATBatchHeader
+		secCode, companyID, effectiveDate = bh.StandardEntryClassCode, bh.OriginatorIdentification, bh.EffectiveEntryDate
+		rdfi, transactionCode = record.IATEntry.RDFIIdentification, record.IATEntry.TransactionCode
+	case record.ADVEntry != nil:
+		bh := record.BatchHeader
+		secCode, companyID, effectiveDate, descriptiveDate = bh.StandardEntryClassCode, bh.CompanyIdentification, bh.EffectiveEntryDate, bh.CompanyDescriptiveDate
+		rdfi, transactionCode = record.ADVEntry.RDFIIdentification, record.ADVEntry.TransactionCode
+	default:
+		bh := record.BatchHeader
+		secCode, companyID, effectiveDate, descriptiveDate = bh.StandardEntryClassCode, bh.CompanyIdentification, bh.EffectiveEntryDate, bh.CompanyDescriptiveDate
+		rdfi, transactionCode = record.Entry.RDFIIdentification, record.Entry.TransactionCode
+	}
+
+	values := make(map[string]string)
+	var key strings.Builder
+	add := func(name, value string) {
+		values[name] = value
+		key.WriteString(name + "=" + value + ";")
+	}
+	for _, rule := range rules {
+		switch rule {
+		case SegmentByCreditDebit:
+			if isSegmentCredit(record.ADVEntry != nil, transactionCode) {
+				add(string(rule), "credit")
+			} else {
+				add(string(rule), "debit")
+			}
+		case SegmentByStandardEntryClass:
+			add(string(rule), secCode)
+		case SegmentByCompanyIdentification:
+			add(string(rule), companyID)
+		case SegmentByRDFI:
+			add(string(rule), rdfi)
+		case SegmentBySameDay:
+			if strings.HasPrefix(descriptiveDate, "SD") || (effectiveDate != "" && effectiveDate <= fh.FileCreationDate) {
+				add(string(rule), "sameDay")
+			} else {
+				add(string(rule), "nextDay")
+			}
+		case SegmentByPrenote:
+			if (&validator{}).isPrenote(transactionCode) {
+				add(string(rule), "prenote")
+			} else {
+				add(string(rule), "live")
+			}
+		}
+	}
+	if sfc != nil && sfc.KeyFunc != nil {
+		add(segmentKeyFunc, sfc.KeyFunc(record))
+	}
+	return values, key.String()
+}
+
+func isSegmentCredit(adv bool, transactionCode int) bool {
+	if adv {
+		return advCreditOrDebit(transactionCode) == "C"
+	}
+	return creditOrDebit(transactionCode) == "C"
+}
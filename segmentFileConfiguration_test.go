@@ -17,7 +17,13 @@
 
 package ach
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
 
 // mockSegmentFileConfiguration creates a Segment File Configuration
 func mockSegmentFileConfiguration() *SegmentFileConfiguration {
@@ -32,3 +38,159 @@ func TestSegmentFileConfiguration(t *testing.T) {
 		t.Error("mockSegmentFileConfiguration does not validate and will break other tests")
 	}
 }
+
+func readSegmentTestFile(t *testing.T, name string) *File {
+	t.Helper()
+
+	fd, err := os.Open(filepath.Join("test", "testdata", name))
+	require.NoError(t, err)
+	defer fd.Close()
+
+	file, err := NewReader(fd).Read()
+	require.NoError(t, err)
+	return &file
+}
+
+func TestFile__SegmentFiles(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		file := readSegmentTestFile(t, "ppd-mixedDebitCredit.ach")
+
+		files, err := file.SegmentFiles(nil)
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+
+		require.Equal(t, map[string]string{"creditDebit": "debit"}, files[0].Segment)
+		require.Equal(t, 200000000, files[0].File.Control.TotalDebitEntryDollarAmountInFile)
+		require.Equal(t, 0, files[0].File.Control.TotalCreditEntryDollarAmountInFile)
+		require.Equal(t, DebitsOnly, files[0].File.Batches[0].GetHeader().ServiceClassCode)
+
+		require.Equal(t, map[string]string{"creditDebit": "credit"}, files[1].Segment)
+		require.Equal(t, 200000000, files[1].File.Control.TotalCreditEntryDollarAmountInFile)
+		require.Len(t, files[1].File.Batches[0].GetEntries(), 2)
+		require.Equal(t, CreditsOnly, files[1].File.Batches[0].GetHeader().ServiceClassCode)
+	})
+
+	t.Run("rdfi and prenote", func(t *testing.T) {
+		file := readSegmentTestFile(t, "ppd-mixedDebitCredit.ach")
+		entries := file.Batches[0].GetEntries()
+		entries[1].RDFIIdentification = "12104288"
+		entries[1].CheckDigit = "2"
+		entries[2].TransactionCode = CheckingPrenoteCredit
+		entries[2].Amount = 0
+		require.NoError(t, file.Batches[0].Create())
+		require.NoError(t, file.Create())
+
+		files, err := file.SegmentFiles(&SegmentFileConfiguration{
+			SegmentBy: []SegmentRule{SegmentByRDFI, SegmentByPrenote},
+		})
+		require.NoError(t, err)
+		require.Len(t, files, 3)
+
+		require.Equal(t, map[string]string{"rdfi": "23138010", "prenote": "live"}, files[0].Segment)
+		require.Equal(t, map[string]string{"rdfi": "12104288", "prenote": "live"}, files[1].Segment)
+		require.Equal(t, map[string]string{"rdfi": "23138010", "prenote": "prenote"}, files[2].Segment)
+
+		// ServiceClassCode matches the entries in each batch
+		require.Equal(t, DebitsOnly, files[0].File.Batches[0].GetHeader().ServiceClassCode)
+		require.Equal(t, CreditsOnly, files[2].File.Batches[0].GetHeader().ServiceClassCode)
+		for _, f := range files {
+			require.NoError(t, f.File.Validate())
+		}
+	})
+
+	t.Run("same day", func(t *testing.T) {
+		file := readSegmentTestFile(t, "ppd-mixedDebitCredit.ach")
+		file.Header.FileCreationDate = file.Batches[0].GetHeader().EffectiveEntryDate
+
+		files, err := file.SegmentFiles(&SegmentFileConfiguration{
+			SegmentBy: []SegmentRule{SegmentBySameDay, SegmentByStandardEntryClass, SegmentByCompanyIdentification},
+		})
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		require.Equal(t, map[string]string{
+			"sameDay":               "sameDay",
+			"standardEntryClass":    PPD,
+			"companyIdentification": "121042882",
+		}, files[0].Segment)
+	})
+
+	t.Run("entries are copied", func(t *testing.T) {
+		file := readSegmentTestFile(t, "ppd-mixedDebitCredit.ach")
+		original := file.Batches[0].GetEntries()[0].TraceNumber
+
+		files, err := file.SegmentFiles(nil)
+		require.NoError(t, err)
+
+		segmented := files[0].File.Batches[0].GetEntries()[0]
+		segmented.SetTraceNumber("99999999", 5)
+		segmented.IndividualName = "Changed"
+		require.Equal(t, original, file.Batches[0].GetEntries()[0].TraceNumber)
+		require.NotEqual(t, "Changed", file.Batches[0].GetEntries()[0].IndividualName)
+	})
+
+	t.Run("KeyFunc", func(t *testing.T) {
+		file := readSegmentTestFile(t, "ppd-mixedDebitCredit.ach")
+
+		files, err := file.SegmentFiles(&SegmentFileConfiguration{
+			KeyFunc: func(record *IteratorRecord) string {
+				return record.Entry.IndividualName[:6]
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		require.Equal(t, map[string]string{"key": "Debit "}, files[0].Segment)
+		require.Equal(t, map[string]string{"key": "Credit"}, files[1].Segment)
+	})
+
+	t.Run("IAT", func(t *testing.T) {
+		file := readSegmentTestFile(t, "iat-mixedDebitCredit.ach")
+
+		files, err := file.SegmentFiles(nil)
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		require.Equal(t, DebitsOnly, files[0].File.IATBatches[0].GetHeader().ServiceClassCode)
+		require.Equal(t, CreditsOnly, files[1].File.IATBatches[0].GetHeader().ServiceClassCode)
+	})
+
+	t.Run("ADV", func(t *testing.T) {
+		file := readSegmentTestFile(t, "adv.ach")
+
+		files, err := file.SegmentFiles(&SegmentFileConfiguration{
+			SegmentBy: []SegmentRule{SegmentByCreditDebit},
+		})
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		require.Len(t, files[0].File.Batches[0].GetADVEntries(), 1)
+		require.Len(t, files[1].File.Batches[0].GetADVEntries(), 1)
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		file := readSegmentTestFile(t, "ppd-mixedDebitCredit.ach")
+
+		_, err := file.SegmentFiles(&SegmentFileConfiguration{
+			SegmentBy: []SegmentRule{"color"},
+		})
+		require.ErrorContains(t, err, `unknown segment rule "color"`)
+	})
+}
+
+func TestFile__SegmentFileRules(t *testing.T) {
+	file := readSegmentTestFile(t, "ppd-mixedDebitCredit.ach")
+
+	creditFile, debitFile, err := file.SegmentFile(&SegmentFileConfiguration{
+		SegmentBy: []SegmentRule{SegmentByCreditDebit},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, creditFile)
+	require.NotNil(t, debitFile)
+
+	_, _, err = file.SegmentFile(&SegmentFileConfiguration{
+		SegmentBy: []SegmentRule{SegmentByRDFI},
+	})
+	require.ErrorIs(t, err, ErrSegmentFileRules)
+
+	_, _, err = file.SegmentFile(&SegmentFileConfiguration{
+		SegmentBy: []SegmentRule{"other"},
+	})
+	require.ErrorIs(t, err, ErrSegmentRule)
+}
@@ -0,0 +1,269 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrSequenceExhausted is returned when a Sequencer has assigned every FileIDModifier for the day.
+var ErrSequenceExhausted = errors.New("sequence exhausted")
+
+// maxTraceSequence is the largest sequence number which fits in the last 7 digits of a TraceNumber
+const maxTraceSequence = 9999999
+
+// Sequencer assigns FileIDModifiers and trace numbers so they do not collide between Files.
+// Implementations must be safe for concurrent use.
+type Sequencer interface {
+	// NextFileIDModifier returns the next FileIDModifier (A-Z then 0-9) for Files from origin
+	// to destination created on date (YYMMDD).
+	NextFileIDModifier(origin, destination, date string) (string, error)
+
+	// NextTraceNumbers reserves count contiguous trace number sequences for the ODFI and returns the first.
+	// Sequences start at 1 and restart from 1 when count sequences don't fit before 9999999.
+	NextTraceNumbers(odfi string, count int) (int, error)
+}
+
+// SetSequencer assigns the FileIDModifier and trace numbers from seq the next time Create is called.
+func (f *File) SetSequencer(seq Sequencer) {
+	if f == nil {
+		return
+	}
+	f.sequencer = seq
+}
+
+// Sequence assigns the FileIDModifier and a new TraceNumber to every entry from seq.
+// Batches are created again so their controls and addenda match the new trace numbers.
+//
+// Return and NOC entries keep their TraceNumber, as do ADV entries which have none.
+func (f *File) Sequence(seq Sequencer) error {
+	if err := sequenceFileIDModifier(seq, &f.Header); err != nil {
+		return err
+	}
+
+	for _, batch := range f.Batches {
+		var entries []*EntryDetail
+		for _, entry := range batch.GetEntries() {
+			if entry.Category != CategoryReturn && entry.Category != CategoryNOC {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		odfi := batch.GetHeader().ODFIIdentification
+		first, err := seq.NextTraceNumbers(odfi, len(entries))
+		if err != nil {
+			return fmt.Errorf("sequencing batch %d: %w", batch.GetHeader().BatchNumber, err)
+		}
+		for i, entry := range entries {
+			entry.SetTraceNumber(odfi, first+i)
+		}
+		if err := batch.Create(); err != nil {
+			return err
+		}
+	}
+	for i := range f.IATBatches {
+		batch := &f.IATBatches[i]
+		if len(batch.Entries) == 0 {
+			continue
+		}
+		odfi := batch.Header.ODFIIdentification
+		first, err := seq.NextTraceNumbers(odfi, len(batch.Entries))
+		if err != nil {
+			return fmt.Errorf("sequencing IAT batch %d: %w", batch.Header.BatchNumber, err)
+		}
+		for n, entry := range batch.Entries {
+			entry.SetTraceNumber(odfi, first+n)
+		}
+		if err := batch.Create(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sequenceFileIDModifier(seq Sequencer, fh *FileHeader) error {
+	modifier, err := seq.NextFileIDModifier(strings.TrimSpace(fh.ImmediateOrigin), strings.TrimSpace(fh.ImmediateDestination), fh.FileCreationDate)
+	if err != nil {
+		return fmt.Errorf("sequencing FileIDModifier: %w", err)
+	}
+	fh.FileIDModifier = modifier
+	return nil
+}
+
+// sequencerState holds the last FileIDModifier index and trace number sequence assigned
+type sequencerState struct {
+	// FileIDModifiers is keyed by origin, destination and date
+	FileIDModifiers map[string]int `json:"fileIDModifiers"`
+
+	// TraceNumbers is keyed by ODFI
+	TraceNumbers map[string]int `json:"traceNumbers"`
+}
+
+func (s *sequencerState) nextFileIDModifier(origin, destination, date string) (string, error) {
+	if s.FileIDModifiers == nil {
+		s.FileIDModifiers = make(map[string]int)
+	}
+	key := origin + "|" + destination + "|" + date
+	idx := s.FileIDModifiers[key]
+	if idx >= len(fileIDModifiers) {
+		return "", fmt.Errorf("%w: %d files from %s to %s on %s", ErrSequenceExhausted, idx, origin, destination, date)
+	}
+	s.FileIDModifiers[key] = idx + 1
+	return string(fileIDModifiers[idx]), nil
+}
+
+func (s *sequencerState) nextTraceNumbers(odfi string, count int) (int, error) {
+	if count <= 0 || count > maxTraceSequence {
+		return 0, fmt.Errorf("invalid trace number count %d", count)
+	}
+	if s.TraceNumbers == nil {
+		s.TraceNumbers = make(map[string]int)
+	}
+	last := s.TraceNumbers[odfi]
+	if last+count > maxTraceSequence {
+		last = 0 // restart so the sequences stay contiguous
+	}
+	s.TraceNumbers[odfi] = last + count
+	return last + 1, nil
+}
+
+// MemorySequencer is a Sequencer which keeps its sequences in memory.
+type MemorySequencer struct {
+	mu    sync.Mutex
+	state sequencerState
+}
+
+// NewMemorySequencer returns a MemorySequencer starting every sequence from the beginning
+func NewMemorySequencer() *MemorySequencer {
+	return &MemorySequencer{}
+}
+
+func (s *MemorySequencer) NextFileIDModifier(origin, destination, date string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state.nextFileIDModifier(origin, destination, date)
+}
+
+func (s *MemorySequencer) NextTraceNumbers(odfi string, count int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state.nextTraceNumbers(odfi, count)
+}
+
+// FileSequencer is a Sequencer which saves its sequences to a file after every change
+// so they are kept across restarts.
+type FileSequencer struct {
+	path string
+
+	mu    sync.Mutex
+	state sequencerState
+}
+
+// NewFileSequencer reads the sequences saved at path. The file is created on the first change if it does not exist.
+func NewFileSequencer(path string) (*FileSequencer, error) {
+	seq := &FileSequencer{path: path}
+
+	bs, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading sequencer: %w", err)
+	}
+	if len(bs) > 0 {
+		if err := json.Unmarshal(bs, &seq.state); err != nil {
+			return nil, fmt.Errorf("reading sequencer %s: %w", path, err)
+		}
+	}
+	return seq, nil
+}
+
+func (s *FileSequencer) NextFileIDModifier(origin, destination, date string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := origin + "|" + destination + "|" + date
+	prev, existed := s.state.FileIDModifiers[key]
+
+	modifier, err := s.state.nextFileIDModifier(origin, destination, date)
+	if err != nil {
+		return "", err
+	}
+	if err := s.save(); err != nil {
+		if existed {
+			s.state.FileIDModifiers[key] = prev
+		} else {
+			delete(s.state.FileIDModifiers, key)
+		}
+		return "", err
+	}
+	return modifier, nil
+}
+
+func (s *FileSequencer) NextTraceNumbers(odfi string, count int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, existed := s.state.TraceNumbers[odfi]
+
+	first, err := s.state.nextTraceNumbers(odfi, count)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.save(); err != nil {
+		if existed {
+			s.state.TraceNumbers[odfi] = prev
+		} else {
+			delete(s.state.TraceNumbers, odfi)
+		}
+		return 0, err
+	}
+	return first, nil
+}
+
+// save writes the state to a temporary file and renames it over path so a crash never leaves a partial file
+func (s *FileSequencer) save() error {
+	bs, err := json.Marshal(s.state)
+	if err != nil {
+		return fmt.Errorf("encoding sequencer: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("saving sequencer: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving sequencer: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving sequencer: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("saving sequencer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySequencer(t *testing.T) {
+	seq := NewMemorySequencer()
+
+	for _, expected := range []string{"A", "B", "C"} {
+		modifier, err := seq.NextFileIDModifier("121042882", "231380104", "260101")
+		require.NoError(t, err)
+		require.Equal(t, expected, modifier)
+	}
+
+	// other days and routing numbers have their own sequence
+	modifier, err := seq.NextFileIDModifier("121042882", "231380104", "260102")
+	require.NoError(t, err)
+	require.Equal(t, "A", modifier)
+
+	for i := 0; i < 33; i++ {
+		_, err := seq.NextFileIDModifier("121042882", "231380104", "260101")
+		require.NoError(t, err)
+	}
+	_, err = seq.NextFileIDModifier("121042882", "231380104", "260101")
+	require.ErrorIs(t, err, ErrSequenceExhausted)
+
+	first, err := seq.NextTraceNumbers("12104288", 5)
+	require.NoError(t, err)
+	require.Equal(t, 1, first)
+
+	first, err = seq.NextTraceNumbers("12104288", 1)
+	require.NoError(t, err)
+	require.Equal(t, 6, first)
+
+	_, err = seq.NextTraceNumbers("12104288", 0)
+	require.Error(t, err)
+
+	t.Run("wrap", func(t *testing.T) {
+		seq := NewMemorySequencer()
+		seq.state.TraceNumbers = map[string]int{"12104288": maxTraceSequence - 1}
+
+		// the last sequence still fits
+		first, err := seq.NextTraceNumbers("12104288", 1)
+		require.NoError(t, err)
+		require.Equal(t, maxTraceSequence, first)
+
+		// three sequences don't fit, so they restart from 1
+		seq.state.TraceNumbers["12104288"] = maxTraceSequence - 1
+		first, err = seq.NextTraceNumbers("12104288", 3)
+		require.NoError(t, err)
+		require.Equal(t, 1, first)
+		require.Equal(t, 3, seq.state.TraceNumbers["12104288"])
+
+		_, err = seq.NextTraceNumbers("12104288", maxTraceSequence+1)
+		require.Error(t, err)
+	})
+}
+
+func TestFileSequencer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sequences.json")
+
+	seq, err := NewFileSequencer(path)
+	require.NoError(t, err)
+
+	modifier, err := seq.NextFileIDModifier("121042882", "231380104", "260101")
+	require.NoError(t, err)
+	require.Equal(t, "A", modifier)
+
+	first, err := seq.NextTraceNumbers("12104288", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, first)
+
+	// sequences continue after reopening
+	seq, err = NewFileSequencer(path)
+	require.NoError(t, err)
+
+	modifier, err = seq.NextFileIDModifier("121042882", "231380104", "260101")
+	require.NoError(t, err)
+	require.Equal(t, "B", modifier)
+
+	first, err = seq.NextTraceNumbers("12104288", 1)
+	require.NoError(t, err)
+	require.Equal(t, 11, first)
+
+	t.Run("corrupt", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "sequences.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+
+		_, err := NewFileSequencer(path)
+		require.ErrorContains(t, err, "reading sequencer")
+	})
+
+	t.Run("save error", func(t *testing.T) {
+		seq, err := NewFileSequencer(filepath.Join(t.TempDir(), "missing", "sequences.json"))
+		require.NoError(t, err)
+
+		_, err = seq.NextFileIDModifier("121042882", "231380104", "260101")
+		require.ErrorContains(t, err, "saving sequencer")
+		require.Empty(t, seq.state.FileIDModifiers)
+
+		_, err = seq.NextTraceNumbers("12104288", 1)
+		require.ErrorContains(t, err, "saving sequencer")
+		require.Empty(t, seq.state.TraceNumbers)
+	})
+}
+
+func TestFile_Sequence(t *testing.T) {
+	seq := NewMemorySequencer()
+
+	first := mockMergeFile(t, "ACME", "", 1, 3)
+	first.SetSequencer(seq)
+	require.NoError(t, first.Create())
+	require.Equal(t, "A", first.Header.FileIDModifier)
+
+	second := mockMergeFile(t, "ACME", "", 1, 2)
+	second.SetSequencer(seq)
+	require.NoError(t, second.Create())
+	require.Equal(t, "B", second.Header.FileIDModifier)
+
+	entries := second.Batches[0].GetEntries()
+	require.Equal(t, "121042880000004", entries[0].TraceNumber)
+	require.Equal(t, "121042880000005", entries[1].TraceNumber)
+	require.NoError(t, second.Validate())
+
+	// sequences are only assigned once
+	require.NoError(t, second.Create())
+	require.Equal(t, "B", second.Header.FileIDModifier)
+	require.Equal(t, "121042880000004", entries[0].TraceNumber)
+
+	t.Run("boundary", func(t *testing.T) {
+		seq := NewMemorySequencer()
+		seq.state.TraceNumbers = map[string]int{"12104288": maxTraceSequence - 1}
+
+		file := mockMergeFile(t, "ACME", "", 1, 3)
+		require.NoError(t, file.Sequence(seq))
+		require.NoError(t, file.Validate())
+
+		entries := file.Batches[0].GetEntries()
+		require.Equal(t, "121042880000001", entries[0].TraceNumber)
+		require.Equal(t, "121042880000003", entries[2].TraceNumber)
+	})
+
+	t.Run("IAT", func(t *testing.T) {
+		iat, err := readACHFilepath(filepath.Join("test", "testdata", "20180713-IAT.ach"))
+		require.NoError(t, err)
+
+		require.NoError(t, iat.Sequence(seq))
+		require.Equal(t, "A", iat.Header.FileIDModifier)
+		odfi := iat.IATBatches[0].Header.ODFIIdentification
+		require.Equal(t, odfi+"0000001", iat.IATBatches[0].Entries[0].TraceNumber)
+		require.NoError(t, iat.Create())
+		require.NoError(t, iat.Validate())
+	})
+
+	t.Run("returns keep trace numbers", func(t *testing.T) {
+		file, err := readACHFilepath(filepath.Join("test", "testdata", "return-WEB.ach"))
+		require.NoError(t, err)
+		traceNumber := file.Batches[0].GetEntries()[0].TraceNumber
+
+		require.NoError(t, file.Sequence(NewMemorySequencer()))
+		require.Equal(t, traceNumber, file.Batches[0].GetEntries()[0].TraceNumber)
+	})
+}
+
+func TestMergeFilesWith_Sequencer(t *testing.T) {
+	seq := NewMemorySequencer()
+
+	merged, err := MergeFilesWith([]*File{
+		mockMergeFile(t, "ACME", "", 1, 5),
+		mockMergeFile(t, "Other", "", 1, 5),
+	}, Conditions{
+		MaxLines:  10,
+		Sequencer: seq,
+	})
+	require.NoError(t, err)
+	require.Len(t, merged, 2)
+	require.Equal(t, "A", merged[0].Header.FileIDModifier)
+	require.Equal(t, "B", merged[1].Header.FileIDModifier)
+}
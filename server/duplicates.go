@@ -0,0 +1,209 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/ach"
+)
+
+// DuplicatesMode is how created files containing duplicates are handled.
+type DuplicatesMode string
+
+const (
+	// DuplicatesFlag stores files and returns the duplicates found in the response.
+	DuplicatesFlag DuplicatesMode = "flag"
+	// DuplicatesReject refuses to store files containing any duplicates.
+	DuplicatesReject DuplicatesMode = "reject"
+)
+
+// WithDuplicates checks files created by the Service for duplicates against the fingerprints in store and
+// records them once stored. mode decides if files containing duplicates are stored.
+func WithDuplicates(store ach.DuplicateStore, mode DuplicatesMode) ServiceOption {
+	return func(s *service) {
+		s.duplicates = newDuplicateDetection(store, mode)
+	}
+}
+
+// duplicateDetection checks created files against the fingerprints of stored files and of files
+// which are being stored.
+type duplicateDetection struct {
+	mode DuplicatesMode
+
+	mu       sync.Mutex            // held while checking and recording so concurrent creates see each other
+	checker  *ach.DuplicateChecker // looks up recorded and reserved fingerprints, records into reserved
+	recorder *ach.DuplicateChecker // records into the DuplicateStore
+	reserved *reservedDuplicateStore
+}
+
+func newDuplicateDetection(store ach.DuplicateStore, mode DuplicatesMode) *duplicateDetection {
+	if store == nil {
+		store = ach.NewMemoryDuplicateStore(0)
+	}
+	reserved := &reservedDuplicateStore{sources: make(map[string]string)}
+	return &duplicateDetection{
+		mode:     mode,
+		checker:  ach.NewDuplicateChecker(reservedLayer{base: store, reserved: reserved}),
+		recorder: ach.NewDuplicateChecker(store),
+		reserved: reserved,
+	}
+}
+
+// reserve checks file for duplicates and holds its fingerprints under file.ID until record or release is
+// called. The file as checked is returned for record. A *ach.DuplicatesError is returned when rejecting file.
+func (d *duplicateDetection) reserve(file *ach.File) (*ach.File, []ach.Duplicate, error) {
+	if d == nil || file == nil {
+		return nil, nil, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	found, err := d.checker.Check(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(found) > 0 && d.mode == DuplicatesReject {
+		return nil, found, &ach.DuplicatesError{Duplicates: found}
+	}
+	checked, err := cloneFile(file)
+	if err != nil {
+		return nil, found, err
+	}
+	if err := d.checker.Record(checked, file.ID); err != nil {
+		d.reserved.release(file.ID)
+		return nil, found, err
+	}
+	return checked, found, nil
+}
+
+// record saves the fingerprints of files under source and drops its reservation
+func (d *duplicateDetection) record(source string, files ...*ach.File) error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer d.reserved.release(source)
+
+	for _, f := range files {
+		if err := d.recorder.Record(f, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release drops the reservation of source when it was not stored
+func (d *duplicateDetection) release(source string) {
+	if d != nil {
+		d.reserved.release(source)
+	}
+}
+
+// reservedDuplicateStore holds the fingerprints of files being stored
+type reservedDuplicateStore struct {
+	mu      sync.RWMutex
+	sources map[string]string
+}
+
+func (s *reservedDuplicateStore) Lookup(fingerprint string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	source, found := s.sources[fingerprint]
+	return source, found, nil
+}
+
+func (s *reservedDuplicateStore) Record(source string, fingerprints ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, fp := range fingerprints {
+		if _, exists := s.sources[fp]; !exists {
+			s.sources[fp] = source
+		}
+	}
+	return nil
+}
+
+func (s *reservedDuplicateStore) release(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for fp, src := range s.sources {
+		if src == source {
+			delete(s.sources, fp)
+		}
+	}
+}
+
+// reservedLayer looks up fingerprints in base and then reserved, and records them in reserved only
+type reservedLayer struct {
+	base     ach.DuplicateStore
+	reserved *reservedDuplicateStore
+}
+
+func (s reservedLayer) Lookup(fingerprint string) (string, bool, error) {
+	source, found, err := s.base.Lookup(fingerprint)
+	if err != nil || found {
+		return source, found, err
+	}
+	return s.reserved.Lookup(fingerprint)
+}
+
+func (s reservedLayer) Record(source string, fingerprints ...string) error {
+	return s.reserved.Record(source, fingerprints...)
+}
+
+// ConfigureDuplicatesFromEnv reads ACH_DUPLICATES (flag or reject), ACH_DUPLICATES_PATH and ACH_DUPLICATES_RETENTION
+// and returns the DuplicateStore and mode to pass to WithDuplicates. Fingerprints are kept in memory unless
+// ACH_DUPLICATES_PATH is set and expire after ACH_DUPLICATES_RETENTION (ach.DefaultDuplicateRetention by default).
+// A nil store is returned when ACH_DUPLICATES is unset. An *ach.DiskDuplicateStore should be closed on shutdown.
+func ConfigureDuplicatesFromEnv() (ach.DuplicateStore, DuplicatesMode, error) {
+	mode := DuplicatesMode(strings.ToLower(strings.TrimSpace(os.Getenv("ACH_DUPLICATES"))))
+	switch mode {
+	case "":
+		return nil, "", nil
+	case DuplicatesFlag, DuplicatesReject:
+	default:
+		return nil, "", fmt.Errorf("unknown ACH_DUPLICATES mode %q", mode)
+	}
+
+	var retention time.Duration
+	if v := os.Getenv("ACH_DUPLICATES_RETENTION"); v != "" {
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing ACH_DUPLICATES_RETENTION: %w", err)
+		}
+		retention = dur
+	}
+
+	if path := os.Getenv("ACH_DUPLICATES_PATH"); path != "" {
+		disk, err := ach.NewDiskDuplicateStore(path, retention)
+		if err != nil {
+			return nil, "", err
+		}
+		return disk, mode, nil
+	}
+	return ach.NewMemoryDuplicateStore(retention), mode, nil
+}
@@ -0,0 +1,204 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func createNachaFile(t *testing.T, handler http.Handler, path string) (*httptest.ResponseRecorder, createFileResponse) {
+	t.Helper()
+
+	fd, err := os.Open(path)
+	require.NoError(t, err)
+	defer fd.Close()
+
+	req := httptest.NewRequest("POST", "/files/"+base.ID(), fd)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	w.Flush()
+
+	var resp createFileResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return w, resp
+}
+
+func TestFiles_CreateFileDuplicates(t *testing.T) {
+	path := filepath.Join("..", "test", "testdata", "ppd-debit.ach")
+
+	t.Run("flag", func(t *testing.T) {
+		repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+		svc := NewService(repo, WithDuplicates(ach.NewMemoryDuplicateStore(0), DuplicatesFlag))
+		handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+		w, resp := createNachaFile(t, handler, path)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Empty(t, resp.Duplicates)
+		first := resp.ID
+
+		w, resp = createNachaFile(t, handler, path)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, resp.Duplicates, 2)
+		require.Equal(t, first, resp.Duplicates[0].Source)
+
+		_, err := repo.FindFile(resp.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+		svc := NewService(repo, WithDuplicates(ach.NewMemoryDuplicateStore(0), DuplicatesReject))
+		handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+		w, _ := createNachaFile(t, handler, path)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		fd, err := os.Open(path)
+		require.NoError(t, err)
+		defer fd.Close()
+
+		fileID := base.ID()
+		req := httptest.NewRequest("POST", "/files/"+fileID, fd)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		w.Flush()
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), "duplicate found: file seen in")
+
+		_, err = repo.FindFile(fileID)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("concurrent reject", func(t *testing.T) {
+		repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+		svc := NewService(repo, WithDuplicates(ach.NewMemoryDuplicateStore(0), DuplicatesReject))
+		handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+		// only one of the files being stored at the same time is accepted
+		var wg sync.WaitGroup
+		codes := make(chan int, 10)
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				fd, err := os.Open(path)
+				if err != nil {
+					codes <- 0
+					return
+				}
+				defer fd.Close()
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest("POST", "/files/"+base.ID(), fd))
+				codes <- w.Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+
+		accepted := 0
+		for code := range codes {
+			if code == http.StatusOK {
+				accepted++
+			}
+		}
+		require.Equal(t, 1, accepted)
+		require.Len(t, repo.FindAllFiles(), 1)
+	})
+}
+
+type failingStoreRepository struct {
+	Repository
+	fail bool
+}
+
+func (r *failingStoreRepository) StoreFile(f *ach.File) error {
+	if r.fail {
+		return errors.New("store failed")
+	}
+	return r.Repository.StoreFile(f)
+}
+
+func TestFiles_CreateFileDuplicatesStoreError(t *testing.T) {
+	repo := &failingStoreRepository{Repository: NewRepositoryInMemory(testTTLDuration, log.NewNopLogger()), fail: true}
+	svc := NewService(repo, WithDuplicates(ach.NewMemoryDuplicateStore(0), DuplicatesReject))
+	handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+	path := filepath.Join("..", "test", "testdata", "ppd-debit.ach")
+
+	fd, err := os.Open(path)
+	require.NoError(t, err)
+	defer fd.Close()
+
+	req := httptest.NewRequest("POST", "/files/"+base.ID(), fd)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	w.Flush()
+	require.Contains(t, w.Body.String(), "store failed")
+
+	// The file was not stored, so it's not a duplicate when retried
+	repo.fail = false
+	w, resp := createNachaFile(t, handler, path)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, resp.Duplicates)
+}
+
+func TestConfigureDuplicatesFromEnv(t *testing.T) {
+	t.Setenv("ACH_DUPLICATES", "")
+	store, mode, err := ConfigureDuplicatesFromEnv()
+	require.NoError(t, err)
+	require.Empty(t, mode)
+	require.Nil(t, store)
+
+	t.Setenv("ACH_DUPLICATES", "other")
+	_, _, err = ConfigureDuplicatesFromEnv()
+	require.ErrorContains(t, err, "unknown ACH_DUPLICATES mode")
+
+	t.Setenv("ACH_DUPLICATES", "flag")
+	t.Setenv("ACH_DUPLICATES_RETENTION", "soon")
+	_, _, err = ConfigureDuplicatesFromEnv()
+	require.ErrorContains(t, err, "ACH_DUPLICATES_RETENTION")
+	t.Setenv("ACH_DUPLICATES_RETENTION", "720h")
+
+	t.Setenv("ACH_DUPLICATES", "Reject")
+	t.Setenv("ACH_DUPLICATES_PATH", filepath.Join(t.TempDir(), "duplicates.json"))
+	store, mode, err = ConfigureDuplicatesFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, DuplicatesReject, mode)
+	require.IsType(t, &ach.DiskDuplicateStore{}, store)
+	require.NoError(t, store.(*ach.DiskDuplicateStore).Close())
+
+	t.Setenv("ACH_DUPLICATES_PATH", "")
+	store, _, err = ConfigureDuplicatesFromEnv()
+	require.NoError(t, err)
+	require.IsType(t, &ach.MemoryDuplicateStore{}, store)
+}
@@ -0,0 +1,415 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/ach"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+)
+
+type searchEntriesRequest struct {
+	query EntryQuery
+
+	requestID string
+}
+
+type searchEntriesResponse struct {
+	Entries []EntryResult `json:"entries"`
+	Err     error         `json:"error"`
+}
+
+func (r searchEntriesResponse) count() int { return len(r.Entries) }
+
+func (r searchEntriesResponse) error() error { return r.Err }
+
+func decodeSearchEntriesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	params := r.URL.Query()
+
+	query := EntryQuery{
+		TraceNumber:          params.Get("traceNumber"),
+		AccountNumber:        params.Get("accountNumber"),
+		ImmediateOrigin:      params.Get("origin"),
+		ImmediateDestination: params.Get("destination"),
+	}
+	var err error
+	if query.Limit, err = readIntParam(params, "limit"); err != nil {
+		return nil, err
+	}
+	if query.CreatedFrom, err = readDateParam(params, "createdFrom"); err != nil {
+		return nil, err
+	}
+	if query.CreatedTo, err = readDateParam(params, "createdTo"); err != nil {
+		return nil, err
+	}
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	return searchEntriesRequest{
+		query:     query,
+		requestID: moovhttp.GetRequestID(r),
+	}, nil
+}
+
+func searchEntriesEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(searchEntriesRequest)
+		if !ok {
+			err := errors.New("invalid request")
+			return searchEntriesResponse{
+				Err: err,
+			}, err
+		}
+
+		entries, err := s.SearchEntries(req.query)
+
+		if logger != nil {
+			logger := logger.With(log.Fields{
+				"entries":   log.String("searchEntries"),
+				"requestID": log.String(req.requestID),
+			})
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Logf("found %d entries", len(entries))
+			}
+		}
+
+		return searchEntriesResponse{
+			Entries: entries,
+			Err:     err,
+		}, nil
+	}
+}
+
+// entryPath holds the path variables of /files/{fileID}/batches/{batchID}/entries[/{traceNumber}]
+type entryPath struct {
+	fileID      string
+	batchID     string
+	traceNumber string
+}
+
+func readEntryPath(r *http.Request, withTraceNumber bool) (entryPath, error) {
+	vars := mux.Vars(r)
+
+	var path entryPath
+	var ok bool
+	if path.fileID, ok = vars["fileID"]; !ok {
+		return path, ErrBadRouting
+	}
+	if path.batchID, ok = vars["batchID"]; !ok {
+		return path, ErrBadRouting
+	}
+	if withTraceNumber {
+		if path.traceNumber, ok = vars["traceNumber"]; !ok {
+			return path, ErrBadRouting
+		}
+	}
+	return path, nil
+}
+
+func readEntry(r *http.Request) (*ach.EntryDetail, error) {
+	bs, err := readBody(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var entry ach.EntryDetail
+	if err := json.Unmarshal(bs, &entry); err != nil {
+		return nil, fmt.Errorf("%w: problem reading EntryDetail: %v", ErrInvalidEntry, err)
+	}
+	return &entry, nil
+}
+
+func entryLogger(logger log.Logger, method string, path entryPath, requestID string) log.Logger {
+	return logger.With(log.Fields{
+		"entries":     log.String(method),
+		"file":        log.String(path.fileID),
+		"batch":       log.String(path.batchID),
+		"traceNumber": log.String(path.traceNumber),
+		"requestID":   log.String(requestID),
+	})
+}
+
+type getEntriesRequest struct {
+	path entryPath
+
+	requestID string
+}
+
+type getEntriesResponse struct {
+	Entries []*ach.EntryDetail `json:"entries"`
+	Err     error              `json:"error"`
+}
+
+func (r getEntriesResponse) count() int { return len(r.Entries) }
+
+func (r getEntriesResponse) error() error { return r.Err }
+
+func decodeGetEntriesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	path, err := readEntryPath(r, false)
+	if err != nil {
+		return nil, err
+	}
+	return getEntriesRequest{
+		path:      path,
+		requestID: moovhttp.GetRequestID(r),
+	}, nil
+}
+
+func getEntriesEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(getEntriesRequest)
+		if !ok {
+			err := errors.New("invalid request")
+			return getEntriesResponse{
+				Err: err,
+			}, err
+		}
+
+		entries, err := s.GetEntries(req.path.fileID, req.path.batchID)
+
+		if logger != nil {
+			logger := entryLogger(logger, "getEntries", req.path, req.requestID)
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Log("get entries")
+			}
+		}
+
+		return getEntriesResponse{
+			Entries: entries,
+			Err:     err,
+		}, nil
+	}
+}
+
+type getEntryRequest struct {
+	path entryPath
+
+	requestID string
+}
+
+type entryResponse struct {
+	Entry *ach.EntryDetail `json:"entry"`
+	Err   error            `json:"error"`
+}
+
+func (r entryResponse) error() error { return r.Err }
+
+func decodeGetEntryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	path, err := readEntryPath(r, true)
+	if err != nil {
+		return nil, err
+	}
+	return getEntryRequest{
+		path:      path,
+		requestID: moovhttp.GetRequestID(r),
+	}, nil
+}
+
+func getEntryEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(getEntryRequest)
+		if !ok {
+			err := errors.New("invalid request")
+			return entryResponse{
+				Err: err,
+			}, err
+		}
+
+		entry, err := s.GetEntry(req.path.fileID, req.path.batchID, req.path.traceNumber)
+
+		if logger != nil {
+			logger := entryLogger(logger, "getEntry", req.path, req.requestID)
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Log("get entry")
+			}
+		}
+
+		return entryResponse{
+			Entry: entry,
+			Err:   err,
+		}, nil
+	}
+}
+
+type createEntryRequest struct {
+	path  entryPath
+	entry *ach.EntryDetail
+
+	requestID string
+}
+
+func decodeCreateEntryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	path, err := readEntryPath(r, false)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := readEntry(r)
+	if err != nil {
+		return nil, err
+	}
+	return createEntryRequest{
+		path:      path,
+		entry:     entry,
+		requestID: moovhttp.GetRequestID(r),
+	}, nil
+}
+
+func createEntryEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(createEntryRequest)
+		if !ok {
+			err := errors.New("invalid request")
+			return entryResponse{
+				Err: err,
+			}, err
+		}
+
+		entry, err := s.CreateEntry(req.path.fileID, req.path.batchID, req.entry)
+
+		if logger != nil {
+			req.path.traceNumber = req.entry.TraceNumber
+			logger := entryLogger(logger, "createEntry", req.path, req.requestID)
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Log("create entry")
+			}
+		}
+
+		return entryResponse{
+			Entry: entry,
+			Err:   err,
+		}, nil
+	}
+}
+
+type updateEntryRequest struct {
+	path  entryPath
+	entry *ach.EntryDetail
+
+	requestID string
+}
+
+func decodeUpdateEntryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	path, err := readEntryPath(r, true)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := readEntry(r)
+	if err != nil {
+		return nil, err
+	}
+	return updateEntryRequest{
+		path:      path,
+		entry:     entry,
+		requestID: moovhttp.GetRequestID(r),
+	}, nil
+}
+
+func updateEntryEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(updateEntryRequest)
+		if !ok {
+			err := errors.New("invalid request")
+			return entryResponse{
+				Err: err,
+			}, err
+		}
+
+		entry, err := s.UpdateEntry(req.path.fileID, req.path.batchID, req.path.traceNumber, req.entry)
+
+		if logger != nil {
+			logger := entryLogger(logger, "updateEntry", req.path, req.requestID)
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Log("update entry")
+			}
+		}
+
+		return entryResponse{
+			Entry: entry,
+			Err:   err,
+		}, nil
+	}
+}
+
+type deleteEntryRequest struct {
+	path entryPath
+
+	requestID string
+}
+
+type deleteEntryResponse struct {
+	Err error `json:"error"`
+}
+
+func (r deleteEntryResponse) error() error { return r.Err }
+
+func decodeDeleteEntryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	path, err := readEntryPath(r, true)
+	if err != nil {
+		return nil, err
+	}
+	return deleteEntryRequest{
+		path:      path,
+		requestID: moovhttp.GetRequestID(r),
+	}, nil
+}
+
+func deleteEntryEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(deleteEntryRequest)
+		if !ok {
+			err := errors.New("invalid request")
+			return deleteEntryResponse{
+				Err: err,
+			}, err
+		}
+
+		err := s.DeleteEntry(req.path.fileID, req.path.batchID, req.path.traceNumber)
+
+		if logger != nil {
+			logger := entryLogger(logger, "deleteEntry", req.path, req.requestID)
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Log("delete entry")
+			}
+		}
+
+		return deleteEntryResponse{
+			Err: err,
+		}, nil
+	}
+}
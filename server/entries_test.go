@@ -0,0 +1,268 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntries__search(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	svc := NewService(repo)
+	handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	w, created := createNachaFile(t, handler, filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	search := func(t *testing.T, rawQuery string) (*httptest.ResponseRecorder, searchEntriesResponse) {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", "/entries?"+rawQuery, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		w.Flush()
+
+		var resp searchEntriesResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		}
+		return w, resp
+	}
+
+	w, resp := search(t, "traceNumber=121042880000001")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "1", w.Header().Get("X-Total-Count"))
+	require.Len(t, resp.Entries, 1)
+	require.Equal(t, created.ID, resp.Entries[0].FileID)
+	require.Equal(t, 100000000, resp.Entries[0].Entry.Amount)
+
+	w, resp = search(t, "accountNumber=12345678&limit=1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp.Entries, 1)
+
+	w, resp = search(t, "accountNumber=87654321")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Empty(t, resp.Entries)
+
+	w, _ = search(t, "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, resp = search(t, "traceNumber=121042880000001&origin=121042882&createdTo=2019-06-30")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp.Entries, 1)
+
+	w, resp = search(t, "traceNumber=121042880000001&destination=987654320")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Empty(t, resp.Entries)
+
+	w, _ = search(t, "traceNumber=121042880000001&limit=abc")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = search(t, "traceNumber=121042880000001&createdFrom=06-01-2019")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestEntries__CRUD(t *testing.T) {
+	for name, newRepo := range repositoryImplementations() {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			svc := NewService(repo)
+			handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+			file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+			require.NoError(t, err)
+			file.ID = "file-1"
+			file.Batches[0].SetID("batch-1")
+			file.Batches[0].GetHeader().ID = "batch-1"
+			require.NoError(t, repo.StoreFile(file))
+
+			entriesPath := "/files/file-1/batches/batch-1/entries"
+			do := func(t *testing.T, method, path string, body interface{}) *httptest.ResponseRecorder {
+				t.Helper()
+
+				var buf bytes.Buffer
+				if body != nil {
+					require.NoError(t, json.NewEncoder(&buf).Encode(body))
+				}
+				req := httptest.NewRequest(method, path, &buf)
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				w.Flush()
+				return w
+			}
+			readEntries := func(t *testing.T) []*ach.EntryDetail {
+				t.Helper()
+
+				w := do(t, "GET", entriesPath, nil)
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+				var resp getEntriesResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				return resp.Entries
+			}
+			storedControl := func(t *testing.T) (ach.BatchControl, ach.FileControl) {
+				t.Helper()
+
+				f, err := repo.FindFile("file-1")
+				require.NoError(t, err)
+				return *f.Batches[0].GetControl(), f.Control
+			}
+
+			entries := readEntries(t)
+			require.Len(t, entries, 1)
+			require.Equal(t, "121042880000001", entries[0].TraceNumber)
+
+			// Add an entry
+			entry := *entries[0]
+			entry.TraceNumber = "121042880000002"
+			entry.DFIAccountNumber = "87654321"
+			entry.Amount = 2500
+			w := do(t, "POST", entriesPath, entry)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.Len(t, readEntries(t), 2)
+
+			bc, fc := storedControl(t)
+			require.Equal(t, 2, bc.EntryAddendaCount)
+			require.Equal(t, 100002500, bc.TotalDebitEntryDollarAmount)
+			require.Equal(t, 2, fc.EntryAddendaCount)
+			require.Equal(t, 100002500, fc.TotalDebitEntryDollarAmountInFile)
+
+			// Trace numbers are unique within a batch
+			w = do(t, "POST", entriesPath, entry)
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+			// Read one entry
+			w = do(t, "GET", entriesPath+"/121042880000002", nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var resp entryResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			require.Equal(t, "87654321", resp.Entry.DFIAccountNumber)
+
+			// Update the entry with an addenda
+			entry.Amount = 7500
+			entry.AddendaRecordIndicator = 1
+			addenda05 := ach.NewAddenda05()
+			addenda05.PaymentRelatedInformation = "invoice 1234"
+			entry.AddAddenda05(addenda05)
+			w = do(t, "PUT", entriesPath+"/121042880000002", entry)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			entries = readEntries(t)
+			require.Len(t, entries, 2)
+			require.Equal(t, "121042880000002", entries[1].TraceNumber)
+			require.Len(t, entries[1].Addenda05, 1)
+
+			bc, fc = storedControl(t)
+			require.Equal(t, 3, bc.EntryAddendaCount)
+			require.Equal(t, 100007500, bc.TotalDebitEntryDollarAmount)
+			require.Equal(t, 100007500, fc.TotalDebitEntryDollarAmountInFile)
+
+			// Invalid entries are rejected without changing the file
+			invalid := entry
+			invalid.TransactionCode = 99
+			w = do(t, "PUT", entriesPath+"/121042880000002", invalid)
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			bc, _ = storedControl(t)
+			require.Equal(t, 100007500, bc.TotalDebitEntryDollarAmount)
+
+			// Delete the entry
+			w = do(t, "DELETE", entriesPath+"/121042880000002", nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.Len(t, readEntries(t), 1)
+
+			bc, fc = storedControl(t)
+			require.Equal(t, 1, bc.EntryAddendaCount)
+			require.Equal(t, 100000000, fc.TotalDebitEntryDollarAmountInFile)
+
+			// Missing files, batches and entries
+			for _, path := range []string{
+				entriesPath + "/121042880000002",
+				"/files/file-2/batches/batch-1/entries/121042880000001",
+				"/files/file-1/batches/batch-2/entries/121042880000001",
+			} {
+				require.Equal(t, http.StatusNotFound, do(t, "GET", path, nil).Code, path)
+				require.Equal(t, http.StatusNotFound, do(t, "PUT", path, entry).Code, path)
+				require.Equal(t, http.StatusNotFound, do(t, "DELETE", path, nil).Code, path)
+			}
+			require.Equal(t, http.StatusNotFound, do(t, "GET", "/files/file-1/batches/batch-2/entries", nil).Code)
+
+			w = do(t, "POST", entriesPath, "not an entry")
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+			noTrace := entry
+			noTrace.TraceNumber = ""
+			w = do(t, "POST", entriesPath, noTrace)
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestEntries__concurrentCreate(t *testing.T) {
+	for name, newRepo := range repositoryImplementations() {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			svc := NewService(repo)
+
+			file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+			require.NoError(t, err)
+			file.ID = "file-1"
+			file.Batches[0].SetID("batch-1")
+			file.Batches[0].GetHeader().ID = "batch-1"
+			file.SetValidation(&ach.ValidateOpts{CustomTraceNumbers: true}) // entries are added in any order
+			require.NoError(t, repo.StoreFile(file))
+
+			const n = 10
+			var wg sync.WaitGroup
+			errs := make(chan error, n)
+			for i := 0; i < n; i++ {
+				entry := *file.Batches[0].GetEntries()[0]
+				entry.TraceNumber = fmt.Sprintf("1210428800001%02d", i)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := svc.CreateEntry("file-1", "batch-1", &entry)
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				require.NoError(t, err)
+			}
+
+			// Every entry is kept
+			found, err := repo.FindFile("file-1")
+			require.NoError(t, err)
+			require.Len(t, found.Batches[0].GetEntries(), n+1)
+			require.Equal(t, n+1, found.Control.EntryAddendaCount)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+)
+
+// EventType describes the Service operation an Event was emitted for.
+type EventType string
+
+const (
+	EventFileCreated   EventType = "file.created"
+	EventFileBuilt     EventType = "file.built"
+	EventFileValidated EventType = "file.validated"
+	EventFilesMerged   EventType = "files.merged"
+	EventFileReversed  EventType = "file.reversed"
+	EventFileDeleted   EventType = "file.deleted"
+	EventFileExpired   EventType = "file.expired"
+)
+
+// Event is emitted after a Service operation completes.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// File summarizes the file the operation was performed on, or produced for EventFileReversed.
+	File *FileSummary `json:"file,omitempty"`
+
+	// Files summarizes the files produced by EventFilesMerged.
+	Files []FileSummary `json:"files,omitempty"`
+
+	// SourceFileIDs are the files merged together for EventFilesMerged or reversed for EventFileReversed.
+	SourceFileIDs []string `json:"sourceFileIDs,omitempty"`
+
+	// Error is set for EventFileValidated when the file failed validation.
+	Error string `json:"error,omitempty"`
+}
+
+// FileSummary describes a file in an Event without including every record.
+type FileSummary struct {
+	ID                   string `json:"id"`
+	ImmediateOrigin      string `json:"immediateOrigin"`
+	ImmediateDestination string `json:"immediateDestination"`
+	FileCreationDate     string `json:"fileCreationDate"`
+	BatchCount           int    `json:"batchCount"`
+	EntryCount           int    `json:"entryCount"`
+	TotalDebitAmount     int    `json:"totalDebitAmount"`
+	TotalCreditAmount    int    `json:"totalCreditAmount"`
+}
+
+// summarizeFile returns the FileSummary of file. Totals are computed from the entries as files in
+// the repository often do not have a FileControl until they are built.
+func summarizeFile(file *ach.File) *FileSummary {
+	if file == nil {
+		return nil
+	}
+	out := &FileSummary{
+		ID:                   file.ID,
+		ImmediateOrigin:      file.Header.ImmediateOrigin,
+		ImmediateDestination: file.Header.ImmediateDestination,
+		FileCreationDate:     file.Header.FileCreationDate,
+		BatchCount:           len(file.Batches) + len(file.IATBatches),
+	}
+	add := func(transactionCode, amount int) {
+		out.EntryCount++
+		switch (&ach.EntryDetail{TransactionCode: transactionCode}).CreditOrDebit() {
+		case "C":
+			out.TotalCreditAmount += amount
+		case "D":
+			out.TotalDebitAmount += amount
+		}
+	}
+	for _, b := range file.Batches {
+		for _, entry := range b.GetEntries() {
+			add(entry.TransactionCode, entry.Amount)
+		}
+	}
+	for _, b := range file.IATBatches {
+		for _, entry := range b.Entries {
+			add(entry.TransactionCode, entry.Amount)
+		}
+	}
+	return out
+}
+
+// Subscriber receives every Event emitted by the server.
+//
+// Notify is called from the goroutine performing the operation and should return quickly.
+// Slow work, such as network calls, should be done asynchronously.
+type Subscriber interface {
+	Notify(event Event)
+}
+
+// SubscriberFunc is a function which implements Subscriber.
+type SubscriberFunc func(event Event)
+
+func (fn SubscriberFunc) Notify(event Event) {
+	fn(event)
+}
+
+type subscription struct {
+	id  int
+	sub Subscriber
+}
+
+// Events delivers the Events of the Services created with it, and of their Repository, to its subscribers.
+// A nil *Events drops every Event.
+type Events struct {
+	mu sync.RWMutex
+
+	next int
+	subs []subscription
+}
+
+// NewEvents returns an Events without any subscribers. Pass it to NewServiceWithEvents.
+func NewEvents() *Events {
+	return &Events{}
+}
+
+// Subscribe registers sub to receive events. The returned function removes the subscription.
+func (e *Events) Subscribe(sub Subscriber) (unsubscribe func()) {
+	if e == nil || sub == nil {
+		return func() {}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	id := e.next
+	e.next++
+	e.subs = append(e.subs, subscription{id: id, sub: sub})
+
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		e.subs = slices.DeleteFunc(slices.Clone(e.subs), func(s subscription) bool {
+			return s.id == id
+		})
+	}
+}
+
+// publish sends event to every subscriber in the order they subscribed
+func (e *Events) publish(event Event) {
+	if e == nil {
+		return
+	}
+	e.mu.RLock()
+	subs := e.subs
+	e.mu.RUnlock()
+
+	if len(subs) == 0 {
+		return
+	}
+	if event.ID == "" {
+		event.ID = base.ID()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	for _, s := range subs {
+		s.sub.Notify(event)
+	}
+}
+
+// publishFile emits an event of eventType for file
+func (e *Events) publishFile(eventType EventType, file *ach.File) {
+	e.publish(Event{
+		Type: eventType,
+		File: summarizeFile(file),
+	})
+}
+
+// expiredEvents is embedded in repositories to emit an EventFileExpired for each file removed
+// by their TTL cleanup once a Service with Events uses them.
+type expiredEvents struct {
+	events atomic.Pointer[Events]
+}
+
+func (r *expiredEvents) setEvents(events *Events) {
+	r.events.Store(events)
+}
+
+func (r *expiredEvents) publishExpired(files []*ach.File) {
+	events := r.events.Load()
+	for _, file := range files {
+		events.publishFile(EventFileExpired, file)
+	}
+}
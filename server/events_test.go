@@ -0,0 +1,252 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder is a Subscriber which keeps every event it receives
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) Notify(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// take returns the events received so far and forgets them
+func (r *eventRecorder) take() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func recordEvents(t *testing.T, events *Events) *eventRecorder {
+	t.Helper()
+
+	rec := &eventRecorder{}
+	t.Cleanup(events.Subscribe(rec))
+	return rec
+}
+
+func TestEvents__Subscribe(t *testing.T) {
+	events := NewEvents()
+
+	var first, second []EventType
+	unsubscribeFirst := events.Subscribe(SubscriberFunc(func(event Event) {
+		first = append(first, event.Type)
+	}))
+	unsubscribeSecond := events.Subscribe(SubscriberFunc(func(event Event) {
+		second = append(second, event.Type)
+	}))
+	t.Cleanup(unsubscribeSecond)
+
+	events.publish(Event{Type: EventFileCreated})
+	unsubscribeFirst()
+	unsubscribeFirst() // no-op
+	events.publish(Event{Type: EventFileDeleted})
+
+	require.Equal(t, []EventType{EventFileCreated}, first)
+	require.Equal(t, []EventType{EventFileCreated, EventFileDeleted}, second)
+
+	// a nil Events drops everything
+	var none *Events
+	none.Subscribe(SubscriberFunc(func(event Event) {
+		t.Fatal("unexpected event")
+	}))()
+	none.publish(Event{Type: EventFileCreated})
+}
+
+func TestEvents__scopedToService(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = "source"
+
+	repoA := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	require.NoError(t, repoA.StoreFile(file))
+	eventsA := NewEvents()
+	recA := recordEvents(t, eventsA)
+	svcA := NewServiceWithEvents(repoA, eventsA)
+
+	repoB := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	require.NoError(t, repoB.StoreFile(file))
+	eventsB := NewEvents()
+	recB := recordEvents(t, eventsB)
+	svcB := NewServiceWithEvents(repoB, eventsB)
+
+	_, err = svcA.BuildFile(file.ID)
+	require.NoError(t, err)
+	require.Len(t, recA.take(), 1)
+	require.Empty(t, recB.take())
+
+	require.NoError(t, svcB.DeleteFile(file.ID))
+	require.Empty(t, recA.take())
+	require.Len(t, recB.take(), 1)
+
+	// services without Events don't publish to anyone
+	_, err = NewService(repoA).BuildFile(file.ID)
+	require.NoError(t, err)
+	require.Empty(t, recA.take())
+}
+
+func TestPublish__fillsEvent(t *testing.T) {
+	bus := NewEvents()
+	rec := recordEvents(t, bus)
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = "foo"
+	bus.publishFile(EventFileBuilt, file)
+
+	events := rec.take()
+	require.Len(t, events, 1)
+	require.NotEmpty(t, events[0].ID)
+	require.False(t, events[0].CreatedAt.IsZero())
+	require.Equal(t, EventFileBuilt, events[0].Type)
+
+	summary := events[0].File
+	require.NotNil(t, summary)
+	require.Equal(t, "foo", summary.ID)
+	require.Equal(t, file.Header.ImmediateOrigin, summary.ImmediateOrigin)
+	require.Equal(t, 1, summary.BatchCount)
+	require.Equal(t, 1, summary.EntryCount)
+	require.Equal(t, file.Control.TotalDebitEntryDollarAmountInFile, summary.TotalDebitAmount)
+}
+
+func TestService__Events(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	bus := NewEvents()
+	svc := NewServiceWithEvents(repo, bus)
+	rec := recordEvents(t, bus)
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = "source"
+	require.NoError(t, repo.StoreFile(file))
+
+	t.Run("build", func(t *testing.T) {
+		_, err := svc.BuildFile(file.ID)
+		require.NoError(t, err)
+
+		events := rec.take()
+		require.Len(t, events, 1)
+		require.Equal(t, EventFileBuilt, events[0].Type)
+		require.Equal(t, file.ID, events[0].File.ID)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		require.NoError(t, svc.ValidateFile(file.ID, nil))
+
+		invalid, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+		invalid.ID = "invalid"
+		invalid.Header.ImmediateOrigin = "123456789" // invalid routing number
+		require.NoError(t, repo.StoreFile(invalid))
+
+		err = svc.ValidateFile(invalid.ID, &ach.ValidateOpts{RequireABAOrigin: true})
+		require.Error(t, err)
+
+		events := rec.take()
+		require.Len(t, events, 2)
+		require.Equal(t, EventFileValidated, events[0].Type)
+		require.Empty(t, events[0].Error)
+		require.Equal(t, EventFileValidated, events[1].Type)
+		require.Equal(t, err.Error(), events[1].Error)
+
+		// missing files are not validated
+		require.Error(t, svc.ValidateFile("missing", nil))
+		require.Empty(t, rec.take())
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		merged, err := svc.MergeFiles([]string{file.ID}, nil, nil)
+		require.NoError(t, err)
+		require.Len(t, merged, 1)
+
+		events := rec.take()
+		require.Len(t, events, 1)
+		require.Equal(t, EventFilesMerged, events[0].Type)
+		require.Equal(t, []string{file.ID}, events[0].SourceFileIDs)
+		require.Len(t, events[0].Files, 1)
+		require.Equal(t, merged[0].ID, events[0].Files[0].ID)
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		reversal, err := svc.ReverseFile(file.ID, time.Now())
+		require.NoError(t, err)
+
+		events := rec.take()
+		require.Len(t, events, 1)
+		require.Equal(t, EventFileReversed, events[0].Type)
+		require.Equal(t, reversal.ID, events[0].File.ID)
+		require.Equal(t, []string{file.ID}, events[0].SourceFileIDs)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, svc.DeleteFile(file.ID))
+
+		events := rec.take()
+		require.Len(t, events, 1)
+		require.Equal(t, EventFileDeleted, events[0].Type)
+		require.Equal(t, file.ID, events[0].File.ID)
+
+		// deleting a missing file has nothing to describe
+		require.NoError(t, svc.DeleteFile(file.ID))
+		require.Empty(t, rec.take())
+	})
+}
+
+func TestFiles__CreateFileEvent(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	bus := NewEvents()
+	svc := NewServiceWithEvents(repo, bus)
+	handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+	rec := recordEvents(t, bus)
+
+	w, resp := createNachaFile(t, handler, filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	events := rec.take()
+	require.Len(t, events, 1)
+	require.Equal(t, EventFileCreated, events[0].Type)
+	require.Equal(t, resp.ID, events[0].File.ID)
+
+	_, err := svc.CreateFile(&ach.FileHeader{ID: "header"})
+	require.NoError(t, err)
+
+	events = rec.take()
+	require.Len(t, events, 1)
+	require.Equal(t, EventFileCreated, events[0].Type)
+	require.Equal(t, "header", events[0].File.ID)
+}
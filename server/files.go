@@ -26,7 +26,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -59,12 +61,18 @@ type createFileRequest struct {
 	parseError   error
 	requestID    string
 	validateOpts *ach.ValidateOpts
+
+	// sequence assigns the FileIDModifier and trace numbers from the Service's Sequencer
+	sequence bool
 }
 
 type createFileResponse struct {
 	ID   string    `json:"id"`
 	File *ach.File `json:"file"`
 
+	// Duplicates are returned when duplicate detection is enabled
+	Duplicates []ach.Duplicate `json:"duplicates,omitempty"`
+
 	Err error `json:"error"`
 }
 
@@ -91,7 +99,32 @@ func createFileEndpoint(s Service, r Repository, logger log.Logger) endpoint.End
 			req.File.SetValidation(req.validateOpts)
 		}
 
-		err := r.StoreFile(req.File)
+		svc := asService(s, r)
+		if req.sequence && svc.sequencer == nil {
+			return createFileResponse{
+				ID:  req.File.ID,
+				Err: ErrNoSequencer,
+			}, nil
+		}
+
+		dups, stored, err := svc.storeCreatedFile(req.File, req.sequence && req.parseError == nil)
+		if err != nil && !stored {
+			if logger != nil {
+				logger.With(log.Fields{
+					"files":     log.String("createFile"),
+					"requestID": log.String(req.requestID),
+				}).Error().LogError(err)
+			}
+			return createFileResponse{
+				ID:         req.File.ID,
+				Duplicates: dups,
+				Err:        err,
+			}, nil
+		}
+
+		if err == nil {
+			svc.events.publishFile(EventFileCreated, req.File)
+		}
 		if logger != nil {
 			logger := logger.With(log.Fields{
 				"files":     log.String("createFile"),
@@ -105,9 +138,10 @@ func createFileEndpoint(s Service, r Repository, logger log.Logger) endpoint.End
 		}
 
 		resp := createFileResponse{
-			ID:   req.File.ID,
-			File: req.File,
-			Err:  err,
+			ID:         req.File.ID,
+			File:       req.File,
+			Duplicates: dups,
+			Err:        err,
 		}
 		if req.parseError != nil {
 			resp.Err = req.parseError
@@ -130,6 +164,13 @@ func decodeCreateFileRequest(_ context.Context, request *http.Request) (interfac
 	}
 	req.validateOpts = validateOpts
 
+	if v := request.URL.Query().Get("sequence"); v != "" {
+		req.sequence, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("sequence is an invalid boolean: %v", err)
+		}
+	}
+
 	bs, err := readBody(body)
 	if err != nil {
 		return nil, err
@@ -238,33 +279,111 @@ func readBody(body io.ReadCloser) ([]byte, error) {
 }
 
 type getFilesRequest struct {
+	query FileQuery
+
 	requestID string
 }
 
 type getFilesResponse struct {
-	Files []*ach.File `json:"files"`
-	Err   error       `json:"error"`
+	Files      []*ach.File `json:"files"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+	Err        error       `json:"error"`
 }
 
-func (r getFilesResponse) count() int { return len(r.Files) }
+// count returns the number of files matching the query, which can exceed the files in one page
+func (r getFilesResponse) count() int {
+	if r.Total > 0 {
+		return r.Total
+	}
+	return len(r.Files)
+}
 
 func (r getFilesResponse) error() error { return r.Err }
 
 func getFilesEndpoint(s Service) endpoint.Endpoint {
-	return func(_ context.Context, _ interface{}) (interface{}, error) {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, _ := request.(getFilesRequest)
+
+		result, err := s.QueryFiles(req.query)
+		if err != nil {
+			return getFilesResponse{Err: err}, nil
+		}
 		return getFilesResponse{
-			Files: s.GetFiles(),
-			Err:   nil,
+			Files:      result.Files,
+			Total:      result.Total,
+			NextCursor: result.NextCursor,
+			Err:        nil,
 		}, nil
 	}
 }
 
 func decodeGetFilesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query, err := readFileQuery(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
 	return getFilesRequest{
+		query:     query,
 		requestID: moovhttp.GetRequestID(r),
 	}, nil
 }
 
+// readFileQuery parses the filters and pagination of GET /files from its query parameters
+func readFileQuery(params url.Values) (FileQuery, error) {
+	query := FileQuery{
+		Cursor:                 params.Get("cursor"),
+		ImmediateOrigin:        params.Get("origin"),
+		ImmediateDestination:   params.Get("destination"),
+		StandardEntryClassCode: params.Get("secCode"),
+		TraceNumber:            params.Get("traceNumber"),
+		AccountNumber:          params.Get("accountNumber"),
+	}
+
+	var err error
+	if query.Limit, err = readIntParam(params, "limit"); err != nil {
+		return query, err
+	}
+	if query.MinAmount, err = readIntParam(params, "minAmount"); err != nil {
+		return query, err
+	}
+	if query.MaxAmount, err = readIntParam(params, "maxAmount"); err != nil {
+		return query, err
+	}
+	if query.CreatedFrom, err = readDateParam(params, "createdFrom"); err != nil {
+		return query, err
+	}
+	if query.CreatedTo, err = readDateParam(params, "createdTo"); err != nil {
+		return query, err
+	}
+	return query, query.validate()
+}
+
+func readIntParam(params url.Values, name string) (int, error) {
+	v := params.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is an invalid integer: %v", ErrInvalidQuery, name, err)
+	}
+	return n, nil
+}
+
+// readDateParam parses a YYYY-MM-DD date
+func readDateParam(params url.Values, name string) (time.Time, error) {
+	v := params.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s is an invalid YYYY-MM-DD date: %v", ErrInvalidQuery, name, err)
+	}
+	return t, nil
+}
+
 type getFileRequest struct {
 	ID string
 
@@ -503,6 +622,22 @@ type validateFileResponse struct {
 
 func (v validateFileResponse) error() error { return v.Err }
 
+// encodeValidateFileResponse writes validation failures with each error in an "errors" array
+// alongside the combined "error" message returned by other endpoints. ValidateOpts.CollectAllErrors
+// needs to be set for more than one error to be returned.
+func encodeValidateFileResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp, ok := response.(validateFileResponse)
+	if !ok || resp.Err == nil {
+		return encodeResponse(ctx, w, response)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(codeFrom(resp.Err))
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  resp.Err.Error(),
+		"errors": ach.DescribeErrors(cmp.Or(errors.Unwrap(resp.Err), resp.Err)),
+	})
+}
+
 func validateFileEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
 	return func(_ context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(validateFileRequest)
@@ -523,7 +658,7 @@ func validateFileEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
 			}
 		}
 		if err != nil { // wrap err with context
-			err = fmt.Errorf("%v: %v", errInvalidFile, err)
+			err = fmt.Errorf("%v: %w", errInvalidFile, err)
 		}
 		return validateFileResponse{err}, nil
 	}
@@ -611,7 +746,9 @@ func decodeBalanceFileRequest(_ context.Context, r *http.Request) (interface{},
 	if err := json.NewDecoder(r.Body).Decode(&off); err != nil {
 		return nil, err
 	}
-	if off.RoutingNumber == "" || off.AccountNumber == "" || string(off.AccountType) == "" {
+	// Each offset record needs an account, which can be set separately for debits and credits
+	hasAccount := off.RoutingNumber != "" && off.AccountNumber != "" && string(off.AccountType) != ""
+	if !hasAccount && (off.DebitAccount == nil || off.CreditAccount == nil) {
 		return nil, errors.New("missing some offset json fields")
 	}
 	return balanceFileRequest{
@@ -635,9 +772,49 @@ type segmentedFilesResponse struct {
 	DebitFileID string    `json:"debitFileID"`
 	DebitFile   *ach.File `json:"debitFile"`
 
+	// Files are returned instead of the credit and debit files when segmentBy rules are provided
+	Files []ach.SegmentedFile `json:"files,omitempty"`
+
 	Err error `json:"error"`
 }
 
+// segmentsByRules returns true when the request asks for files split by SegmentBy rules
+func segmentsByRules(opts *ach.SegmentFileConfiguration) bool {
+	return opts != nil && len(opts.SegmentBy) > 0
+}
+
+func logSegmentFiles(logger log.Logger, name, requestID string, err error) {
+	if logger == nil {
+		return
+	}
+	logger = logger.With(log.Fields{
+		"files":     log.String(name),
+		"requestID": log.String(requestID),
+	})
+	if err != nil {
+		logger.Error().LogError(err)
+	} else {
+		logger.Info().Log("segment files")
+	}
+}
+
+func storeSegmentedFiles(r Repository, logger log.Logger, requestID string, files []ach.SegmentedFile) segmentedFilesResponse {
+	var resp segmentedFilesResponse
+	for i := range files {
+		if err := r.StoreFile(files[i].File); err != nil {
+			if logger != nil {
+				logger.With(log.Fields{
+					"files":     log.String("storeSegmentedFile"),
+					"requestID": log.String(requestID),
+				}).LogError(err)
+			}
+			resp.Err = err
+		}
+	}
+	resp.Files = files
+	return resp
+}
+
 func segmentFileIDEndpoint(s Service, r Repository, logger log.Logger) endpoint.Endpoint {
 	return func(_ context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(segmentFileIDRequest)
@@ -645,6 +822,15 @@ func segmentFileIDEndpoint(s Service, r Repository, logger log.Logger) endpoint.
 			return segmentedFilesResponse{Err: ErrFoundABug}, ErrFoundABug
 		}
 
+		if segmentsByRules(req.opts) {
+			files, err := s.SegmentFilesID(req.fileID, req.opts)
+			logSegmentFiles(logger, "segmentFileID", req.requestID, err)
+			if err != nil {
+				return segmentedFilesResponse{Err: err}, err
+			}
+			return storeSegmentedFiles(r, logger, req.requestID, files), nil
+		}
+
 		creditFile, debitFile, err := s.SegmentFileID(req.fileID, req.opts)
 
 		if logger != nil {
@@ -733,6 +919,15 @@ func segmentFileEndpoint(s Service, r Repository, logger log.Logger) endpoint.En
 			req.File.SetValidation(req.validateOpts)
 		}
 
+		if segmentsByRules(req.opts) {
+			files, err := s.SegmentFiles(req.File, req.opts)
+			logSegmentFiles(logger, "segmentFile", req.requestID, err)
+			if err != nil {
+				return segmentedFilesResponse{Err: err}, err
+			}
+			return storeSegmentedFiles(r, logger, req.requestID, files), nil
+		}
+
 		creditFile, debitFile, err := s.SegmentFile(req.File, req.opts)
 		if logger != nil {
 			logger.With(log.Fields{
@@ -908,12 +1103,16 @@ type mergeFilesRequest struct {
 
 	Conditions *ach.Conditions `json:"conditions"`
 
+	// Manifest requests a description of where each merged entry came from
+	Manifest bool `json:"manifest"`
+
 	RequestID string `json:"requestID"`
 }
 
 type mergeFilesResponse struct {
-	Files []*ach.File `json:"files"`
-	Err   error       `json:"error"`
+	Files    []*ach.File        `json:"files"`
+	Manifest *ach.MergeManifest `json:"manifest,omitempty"`
+	Err      error              `json:"error"`
 }
 
 func mergeFilesEndpoint(s Service, r Repository, logger log.Logger) endpoint.Endpoint {
@@ -923,7 +1122,14 @@ func mergeFilesEndpoint(s Service, r Repository, logger log.Logger) endpoint.End
 			return mergeFilesResponse{Err: ErrFoundABug}, ErrFoundABug
 		}
 
-		merged, err := s.MergeFiles(req.FileIDs, req.Files, req.Conditions)
+		var merged []*ach.File
+		var manifest *ach.MergeManifest
+		var err error
+		if req.Manifest {
+			merged, manifest, err = s.MergeFilesWithManifest(req.FileIDs, req.Files, req.Conditions)
+		} else {
+			merged, err = s.MergeFiles(req.FileIDs, req.Files, req.Conditions)
+		}
 		if logger != nil {
 			logger := logger.With(log.Fields{
 				"file_ids":  log.Strings(req.FileIDs),
@@ -955,7 +1161,8 @@ func mergeFilesEndpoint(s Service, r Repository, logger log.Logger) endpoint.End
 		}
 
 		return mergeFilesResponse{
-			Files: merged,
+			Files:    merged,
+			Manifest: manifest,
 		}, nil
 	}
 }
@@ -1054,3 +1261,250 @@ func decodeReverseFileRequest(_ context.Context, r *http.Request) (interface{},
 
 	return req, nil
 }
+
+type returnFileRequest struct {
+	fileID             string
+	effectiveEntryDate time.Time
+	entries            []ach.ReturnEntry
+	requestID          string
+}
+
+type returnFileResponse struct {
+	ID   string    `json:"id"`
+	File *ach.File `json:"file"`
+	Err  error     `json:"error"`
+}
+
+func (r returnFileResponse) error() error { return r.Err }
+
+func returnFileEndpoint(s Service, r Repository, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(returnFileRequest)
+		if !ok {
+			return returnFileResponse{Err: ErrFoundABug}, ErrFoundABug
+		}
+
+		returnedFile, err := s.ReturnFile(req.fileID, req.effectiveEntryDate, req.entries)
+		if logger != nil {
+			logger := logger.With(log.Fields{
+				"files":     log.String("ReturnFile"),
+				"requestID": log.String(req.requestID),
+			})
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Logf("return file with %d entries", len(req.entries))
+			}
+		}
+		if err != nil {
+			return returnFileResponse{Err: err}, err
+		}
+
+		if returnedFile.ID != "" {
+			err = r.StoreFile(returnedFile)
+			if logger != nil && err != nil {
+				logger.With(log.Fields{
+					"files":     log.String("storeReturnedFile"),
+					"requestID": log.String(req.requestID),
+				}).LogError(err)
+			}
+		}
+
+		return returnFileResponse{
+			ID:   returnedFile.ID,
+			File: returnedFile,
+			Err:  err,
+		}, nil
+	}
+}
+
+func decodeReturnFileRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	fileID, ok := vars["fileID"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	req := returnFileRequest{
+		fileID:    fileID,
+		requestID: moovhttp.GetRequestID(r),
+	}
+
+	var body struct {
+		EffectiveEntryDate base.Time         `json:"effectiveEntryDate"`
+		Entries            []ach.ReturnEntry `json:"entries"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("parsing return request: %w", err)
+	}
+	req.entries = body.Entries
+
+	if body.EffectiveEntryDate.IsZero() {
+		req.effectiveEntryDate = time.Now().In(time.UTC)
+	} else {
+		req.effectiveEntryDate = body.EffectiveEntryDate.Time
+	}
+
+	return req, nil
+}
+
+type correctionFileRequest struct {
+	fileID             string
+	effectiveEntryDate time.Time
+	entries            []ach.CorrectionEntry
+	refused            []ach.RefusedCorrectionEntry
+	requestID          string
+}
+
+type correctionFileResponse struct {
+	ID   string    `json:"id"`
+	File *ach.File `json:"file"`
+	Err  error     `json:"error"`
+}
+
+func (r correctionFileResponse) error() error { return r.Err }
+
+func correctionFileEndpoint(s Service, r Repository, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(correctionFileRequest)
+		if !ok {
+			return correctionFileResponse{Err: ErrFoundABug}, ErrFoundABug
+		}
+
+		var corFile *ach.File
+		var err error
+		if len(req.refused) > 0 {
+			corFile, err = s.RefusedCorrectionFile(req.fileID, req.effectiveEntryDate, req.refused)
+		} else {
+			corFile, err = s.CorrectionFile(req.fileID, req.effectiveEntryDate, req.entries)
+		}
+		if logger != nil {
+			logger := logger.With(log.Fields{
+				"files":     log.String("CorrectionFile"),
+				"requestID": log.String(req.requestID),
+			})
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Logf("correction file with %d entries", len(req.entries)+len(req.refused))
+			}
+		}
+		if err != nil {
+			return correctionFileResponse{Err: err}, err
+		}
+
+		if corFile.ID != "" {
+			err = r.StoreFile(corFile)
+			if logger != nil && err != nil {
+				logger.With(log.Fields{
+					"files":     log.String("storeCorrectionFile"),
+					"requestID": log.String(req.requestID),
+				}).LogError(err)
+			}
+		}
+
+		return correctionFileResponse{
+			ID:   corFile.ID,
+			File: corFile,
+			Err:  err,
+		}, nil
+	}
+}
+
+func decodeCorrectionFileRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	fileID, ok := vars["fileID"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	req := correctionFileRequest{
+		fileID:    fileID,
+		requestID: moovhttp.GetRequestID(r),
+	}
+
+	var body struct {
+		EffectiveEntryDate base.Time                    `json:"effectiveEntryDate"`
+		Entries            []ach.CorrectionEntry        `json:"entries"`
+		Refused            []ach.RefusedCorrectionEntry `json:"refused"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("parsing correction request: %w", err)
+	}
+	if len(body.Entries) > 0 && len(body.Refused) > 0 {
+		return nil, errors.New("only one of entries or refused can be provided")
+	}
+	req.entries = body.Entries
+	req.refused = body.Refused
+
+	if body.EffectiveEntryDate.IsZero() {
+		req.effectiveEntryDate = time.Now().In(time.UTC)
+	} else {
+		req.effectiveEntryDate = body.EffectiveEntryDate.Time
+	}
+
+	return req, nil
+}
+
+type checkLimitsRequest struct {
+	fileID    string
+	limits    *ach.Limits
+	requestID string
+}
+
+type checkLimitsResponse struct {
+	Violations []ach.LimitViolation `json:"violations"`
+	Err        error                `json:"error"`
+}
+
+func (r checkLimitsResponse) error() error { return r.Err }
+
+func checkLimitsEndpoint(s Service, logger log.Logger) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(checkLimitsRequest)
+		if !ok {
+			return checkLimitsResponse{Err: ErrFoundABug}, ErrFoundABug
+		}
+
+		violations, err := s.CheckLimits(req.fileID, req.limits)
+		if logger != nil {
+			logger := logger.With(log.Fields{
+				"files":     log.String("checkLimits"),
+				"requestID": log.String(req.requestID),
+			})
+			if err != nil {
+				logger.Error().LogError(err)
+			} else {
+				logger.Info().Logf("file has %d limit violations", len(violations))
+			}
+		}
+		if err != nil {
+			return checkLimitsResponse{Err: err}, err
+		}
+
+		if violations == nil {
+			violations = []ach.LimitViolation{}
+		}
+		return checkLimitsResponse{
+			Violations: violations,
+		}, nil
+	}
+}
+
+func decodeCheckLimitsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	fileID, ok := vars["fileID"]
+	if !ok {
+		return nil, ErrBadRouting
+	}
+
+	req := checkLimitsRequest{
+		fileID:    fileID,
+		requestID: moovhttp.GetRequestID(r),
+	}
+	// An empty body checks the file against the limits the server is configured with
+	if err := json.NewDecoder(r.Body).Decode(&req.limits); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing limits: %w", err)
+	}
+	return req, nil
+}
@@ -178,7 +178,7 @@ func TestFiles_CreateWithOffset(t *testing.T) {
 	require.Equal(t, "088888880123460", entries[1].TraceNumber)
 
 	offset := reflect.ValueOf(b).Elem().FieldByName("offset")
-	expected := `&ach.Offset{RoutingNumber:"987654320", AccountNumber:"123123123", AccountType:"checking", Description:"OFFSET"}`
+	expected := `&ach.Offset{RoutingNumber:"987654320", AccountNumber:"123123123", AccountType:"checking", Description:"OFFSET", DebitAccount:(*ach.OffsetAccount)(nil), CreditAccount:(*ach.OffsetAccount)(nil), Strategy:"", MatchIndividualName:false}`
 	require.Equal(t, expected, fmt.Sprintf("%#v", offset))
 }
 
@@ -361,6 +361,64 @@ func TestFiles__getFilesEndpoint(t *testing.T) {
 	}
 }
 
+func TestFiles__getFilesQuery(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	svc := NewService(repo)
+	handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	for _, name := range []string{"ppd-debit.ach", "20180713-IAT.ach"} {
+		w, _ := createNachaFile(t, handler, filepath.Join("..", "test", "testdata", name))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	getFiles := func(t *testing.T, rawQuery string) (*httptest.ResponseRecorder, getFilesResponse) {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", "/files?"+rawQuery, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		w.Flush()
+
+		var resp getFilesResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		}
+		return w, resp
+	}
+
+	w, resp := getFiles(t, "limit=1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "2", w.Header().Get("X-Total-Count"))
+	require.Equal(t, 2, resp.Total)
+	require.Len(t, resp.Files, 1)
+	require.NotEmpty(t, resp.NextCursor)
+
+	w, next := getFiles(t, "limit=1&cursor="+resp.NextCursor)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, next.Files, 1)
+	require.NotEqual(t, resp.Files[0].ID, next.Files[0].ID)
+	require.Empty(t, next.NextCursor)
+
+	w, resp = getFiles(t, "origin=121042882&destination=231380104&secCode=PPD&createdFrom=2019-06-01&createdTo=2019-06-30&minAmount=1&maxAmount=100000000")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp.Files, 1)
+	require.Equal(t, "121042882", resp.Files[0].Header.ImmediateOrigin)
+
+	w, resp = getFiles(t, "traceNumber=121042880000001&accountNumber=12345678")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp.Files, 1)
+
+	w, resp = getFiles(t, "secCode=CCD")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Empty(t, resp.Files)
+	require.Equal(t, "0", w.Header().Get("X-Total-Count"))
+
+	for _, rawQuery := range []string{"limit=abc", "limit=-1", "createdFrom=06/01/2019", "cursor=!!", "minAmount=10&maxAmount=5"} {
+		w, _ := getFiles(t, rawQuery)
+		require.Equal(t, http.StatusBadRequest, w.Code, rawQuery)
+	}
+}
+
 func TestFiles__getFileEndpoint(t *testing.T) {
 	repo := NewRepositoryInMemory(testTTLDuration, nil)
 	svc := NewService(repo)
@@ -590,6 +648,104 @@ func TestFiles__ValidateOpts(t *testing.T) {
 	}
 }
 
+func TestFiles__ValidateCollectAllErrors(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := NewRepositoryInMemory(testTTLDuration, logger)
+	svc := NewService(repo)
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = base.ID()
+	file.Header.FileIDModifier = "?"
+	file.Batches[0].GetEntries()[0].IndividualName = "testÑåṁe"
+	file.Control.EntryHash = 1
+	require.NoError(t, repo.StoreFile(file))
+
+	router := mux.NewRouter()
+	router.Methods("GET").Path("/files/{id}/validate").Handler(
+		httptransport.NewServer(validateFileEndpoint(svc, logger), decodeValidateFileRequest, encodeValidateFileResponse),
+	)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", fmt.Sprintf("/files/%s/validate?collectAllErrors=true", file.ID), nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp struct {
+		Error  string            `json:"error"`
+		Errors []ach.ErrorDetail `json:"errors"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Contains(t, resp.Error, "FileIDModifier")
+	require.Len(t, resp.Errors, 3)
+	require.Equal(t, ach.ErrorDetail{
+		Code:      ach.CodeFieldNotUppercase,
+		Record:    "FileHeader",
+		Line:      1,
+		FieldName: "FileIDModifier",
+		Value:     "?",
+		Rule:      ach.CodeFieldNotUppercase.Rule(),
+		Message:   "FileIDModifier ? is not uppercase A-Z or 0-9: ?",
+	}, resp.Errors[0])
+	require.Equal(t, 3, resp.Errors[1].Line)
+	require.Equal(t, "EntryDetail", resp.Errors[1].Record)
+	require.Equal(t, ach.CodeFieldNonAlphanumeric, resp.Errors[1].Code)
+	require.Equal(t, "IndividualName", resp.Errors[1].FieldName)
+	require.Equal(t, 5, resp.Errors[2].Line)
+	require.Equal(t, "FileControl", resp.Errors[2].Record)
+	require.Equal(t, ach.CodeFileControlTotals, resp.Errors[2].Code)
+
+	// Without the option only the first error is returned
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", fmt.Sprintf("/files/%s/validate", file.ID), nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Errors, 1)
+	require.Contains(t, resp.Errors[0].Message, "FileIDModifier")
+}
+
+func TestFiles__ValidateRules(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := NewRepositoryInMemory(testTTLDuration, logger)
+	svc := NewService(repo)
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = base.ID()
+	require.NoError(t, repo.StoreFile(file))
+
+	router := mux.NewRouter()
+	router.Methods("POST").Path("/files/{id}/validate").Handler(
+		httptransport.NewServer(validateFileEndpoint(svc, logger), decodeValidateFileRequest, encodeValidateFileResponse),
+	)
+
+	body := strings.NewReader(`{"rules": [{"name": "ppd-limit", "record": "EntryDetail", "secCodes": ["PPD"], "maxAmount": 2500000}]}`)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/files/%s/validate", file.ID), body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp struct {
+		Errors []ach.ErrorDetail `json:"errors"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Errors, 1)
+	require.Equal(t, ach.CodeRuleMaxAmount, resp.Errors[0].Code)
+	require.Equal(t, "Amount", resp.Errors[0].FieldName)
+
+	// Raising the limit passes
+	body = strings.NewReader(`{"rules": [{"name": "ppd-limit", "record": "EntryDetail", "secCodes": ["PPD"], "maxAmount": 250000000}]}`)
+	req = httptest.NewRequest("POST", fmt.Sprintf("/files/%s/validate", file.ID), body)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
 func TestFilesErr__balanceFileEndpoint(t *testing.T) {
 	repo := NewRepositoryInMemory(testTTLDuration, nil)
 	svc := NewService(repo)
@@ -647,6 +803,46 @@ func TestFiles__balanceFileEndpoint(t *testing.T) {
 	}
 }
 
+func TestFiles__balanceFileEndpointStrategy(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := NewRepositoryInMemory(testTTLDuration, logger)
+	svc := NewService(repo)
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	fd, err := os.Open(filepath.Join("..", "test", "testdata", "ppd-mixedDebitCredit-valid.json"))
+	require.NoError(t, err)
+	defer fd.Close()
+
+	bs, _ := io.ReadAll(fd)
+	file, err := ach.FileFromJSON(bs)
+	require.NoError(t, err)
+	repo.StoreFile(file)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"strategy": "file", "description": "OFFSET",
+"debitAccount": {"routingNumber": "987654320", "accountNumber": "216112", "accountType": "gl"},
+"creditAccount": {"routingNumber": "987654320", "accountNumber": "216113", "accountType": "loan"}}`)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/files/%s/balance", file.ID), body)
+	req.Header.Set("X-Request-ID", base.ID())
+
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp balanceFileResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.NotEmpty(t, resp.FileID)
+
+	balanced, err := repo.FindFile(resp.FileID)
+	require.NoError(t, err)
+	require.Len(t, balanced.Batches, len(file.Batches)+1)
+
+	offsets := balanced.Batches[len(balanced.Batches)-1]
+	require.Equal(t, "OFFSET", offsets.GetHeader().CompanyEntryDescription)
+	control := balanced.Control
+	require.Equal(t, control.TotalDebitEntryDollarAmountInFile, control.TotalCreditEntryDollarAmountInFile)
+}
+
 func TestFilesErr__balanceInvalidFile(t *testing.T) {
 	logger := log.NewNopLogger()
 	repo := NewRepositoryInMemory(testTTLDuration, logger)
@@ -823,6 +1019,85 @@ func TestFiles__segmentFileEndpoint(t *testing.T) {
 	require.NotNil(t, resp.DebitFile)
 }
 
+func TestFiles__segmentFileIDEndpointRules(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := NewRepositoryInMemory(testTTLDuration, logger)
+	svc := NewService(repo)
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-mixedDebitCredit.ach"))
+	require.NoError(t, err)
+	file.ID = base.ID()
+	require.NoError(t, repo.StoreFile(file))
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"segmentBy": ["standardEntryClass", "creditDebit"]}`)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/files/%s/segment", file.ID), body)
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp segmentedFilesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Empty(t, resp.CreditFileID)
+	require.Len(t, resp.Files, 2)
+	require.Equal(t, map[string]string{"standardEntryClass": "PPD", "creditDebit": "debit"}, resp.Files[0].Segment)
+	require.Equal(t, map[string]string{"standardEntryClass": "PPD", "creditDebit": "credit"}, resp.Files[1].Segment)
+
+	// segmented files are stored
+	for _, f := range resp.Files {
+		found, err := repo.FindFile(f.File.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+	}
+
+	// unknown rules are rejected
+	w = httptest.NewRecorder()
+	body = strings.NewReader(`{"segmentBy": ["color"]}`)
+	req = httptest.NewRequest("POST", fmt.Sprintf("/files/%s/segment", file.ID), body)
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFiles__segmentFileEndpointRules(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := NewRepositoryInMemory(testTTLDuration, logger)
+	svc := NewService(repo)
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-mixedDebitCredit.ach"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = json.NewEncoder(&buf).Encode(struct {
+		File *ach.File                     `json:"file"`
+		Opts *ach.SegmentFileConfiguration `json:"opts"`
+	}{
+		File: file,
+		Opts: &ach.SegmentFileConfiguration{
+			SegmentBy: []ach.SegmentRule{ach.SegmentByCompanyIdentification},
+		},
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/segment", &buf)
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp segmentedFilesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Files, 1)
+	require.Equal(t, map[string]string{"companyIdentification": "121042882"}, resp.Files[0].Segment)
+	require.Len(t, resp.Files[0].File.Batches[0].GetEntries(), 3)
+}
+
 func TestFiles__segmentFileEndpointJSON(t *testing.T) {
 	logger := log.NewNopLogger()
 	repo := NewRepositoryInMemory(testTTLDuration, logger)
@@ -1495,6 +1770,114 @@ func TestFiles_MergeFiles(t *testing.T) {
 	require.Equal(t, 200000, merged.Control.TotalCreditEntryDollarAmountInFile)
 }
 
+func TestFiles_MergeFilesManifest(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, nil)
+	svc := NewService(repo)
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	file, err := ach.ReadJSONFile(filepath.Join("..", "test", "testdata", "ppd-mixedDebitCredit-valid.json"))
+	require.NoError(t, err)
+	file.ID = base.ID()
+	require.NoError(t, repo.StoreFile(file))
+
+	file2, err := ach.ReadJSONFile(filepath.Join("..", "test", "testdata", "ppd-valid.json"))
+	require.NoError(t, err)
+	file2.ID = "inline"
+
+	var body bytes.Buffer
+	err = json.NewEncoder(&body).Encode(mergeFilesRequest{
+		FileIDs:  []string{file.ID},
+		Files:    []*ach.File{file2},
+		Manifest: true,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/merge", &body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp mergeFilesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Files, 1)
+	require.NotNil(t, resp.Manifest)
+	require.Len(t, resp.Manifest.Files, 1)
+	require.Equal(t, resp.Files[0].ID, resp.Manifest.Files[0].FileID)
+
+	sources := make(map[string]int)
+	for _, b := range resp.Manifest.Files[0].Batches {
+		for _, entry := range b.Entries {
+			require.Equal(t, entry.TraceNumber, entry.Source.TraceNumber)
+			sources[entry.Source.FileID]++
+		}
+	}
+	require.Equal(t, map[string]int{file.ID: 2, "inline": 1}, sources)
+}
+
+func TestFiles_MergeFilesLimits(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, nil)
+	svc := NewService(repo)
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	file, err := ach.ReadJSONFile(filepath.Join("..", "test", "testdata", "ppd-mixedDebitCredit-valid.json"))
+	require.NoError(t, err)
+	file2, err := ach.ReadJSONFile(filepath.Join("..", "test", "testdata", "ppd-valid.json"))
+	require.NoError(t, err)
+
+	var body bytes.Buffer
+	err = json.NewEncoder(&body).Encode(mergeFilesRequest{
+		Files: []*ach.File{file, file2},
+		Conditions: &ach.Conditions{
+			Limits: &ach.Limits{MaxFileCreditAmount: 150000},
+		},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/merge", &body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "maxFileCreditAmount: 200000 exceeds 150000")
+	require.Contains(t, w.Body.String(), string(ach.CodeLimitsExceeded))
+}
+
+func TestFiles__checkLimitsEndpoint(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, nil)
+	svc := NewService(repo)
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = base.ID()
+	require.NoError(t, repo.StoreFile(file))
+
+	body := strings.NewReader(`{"maxEntryAmount": 50000000, "companies": {"121042882": {"maxDailyDebitAmount": 200000000}}}`)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/files/%s/limits", file.ID), body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Violations []ach.LimitViolation `json:"violations"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Violations, 1)
+	require.Equal(t, "maxEntryAmount", resp.Violations[0].Limit)
+	require.Equal(t, "121042880000001", resp.Violations[0].TraceNumber)
+
+	// Missing file
+	req = httptest.NewRequest("POST", "/files/missing/limits", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
+
 func TestFiles__reverseFileEndpoint(t *testing.T) {
 	logger := log.NewNopLogger()
 	repo := NewRepositoryInMemory(testTTLDuration, logger)
@@ -1767,3 +2150,176 @@ func TestSetMaxBodySizeIgnoresNonPositive(t *testing.T) {
 	SetMaxBodySize(-1)
 	require.Equal(t, defaultMaxBodySize, MaxBodySize())
 }
+
+func TestFiles__returnFileEndpoint(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := NewRepositoryInMemory(testTTLDuration, logger)
+	svc := NewService(repo)
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	// write an ACH file into repository
+	fd, err := os.Open(filepath.Join("..", "test", "testdata", "ppd-mixedDebitCredit-valid.json"))
+	if fd == nil {
+		t.Fatalf("empty ACH file: %v", err)
+	}
+	defer fd.Close()
+	bs, _ := io.ReadAll(fd)
+	file, _ := ach.FileFromJSON(bs)
+	repo.StoreFile(file)
+
+	body := strings.NewReader(`{"effectiveEntryDate": "2025-06-15T10:00:00Z", "entries": [{"traceNumber": "121042880000002", "returnCode": "R03"}]}`)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", fmt.Sprintf("/files/%s/return", file.ID), body)
+	req.Header.Set("Origin", "https://moov.io")
+	req.Header.Set("X-Request-Id", "66666")
+
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp returnFileResponse
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.NotEqual(t, file.ID, resp.ID)
+	require.NotNil(t, resp.File)
+
+	// Verify the return was created
+	require.Len(t, resp.File.Batches, 1)
+	bh := resp.File.Batches[0].GetHeader()
+	require.Equal(t, "250615", bh.EffectiveEntryDate)
+
+	entries := resp.File.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.NotNil(t, entries[0].Addenda99)
+	require.Equal(t, "R03", entries[0].Addenda99.ReturnCode)
+	require.Equal(t, "121042880000002", entries[0].Addenda99.OriginalTrace)
+
+	// The returned file is stored
+	stored, err := repo.FindFile(resp.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+
+	// Unknown return codes are rejected
+	body = strings.NewReader(`{"entries": [{"traceNumber": "121042880000002", "returnCode": "R00"}]}`)
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", fmt.Sprintf("/files/%s/return", file.ID), body)
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFilesError__returnFileEndpoint(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, nil)
+	svc := NewService(repo)
+
+	resp, err := returnFileEndpoint(svc, repo, nil)(context.TODO(), nil)
+	r, ok := resp.(returnFileResponse)
+	require.True(t, ok)
+	require.Error(t, err)
+	require.Error(t, r.Err)
+}
+
+func TestFiles__decodeReturnFileRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/files/return", nil)
+	_, err := decodeReturnFileRequest(context.TODO(), req)
+	require.ErrorIs(t, err, ErrBadRouting)
+
+	body := strings.NewReader(`{"entries": [{"traceNumber": "121042880000001", "returnCode": "R14", "dateOfDeath": "2025-01-10T00:00:00Z"}]}`)
+	req = httptest.NewRequest("POST", "/files/test-file-id/return", body)
+	req = mux.SetURLVars(req, map[string]string{"fileID": "test-file-id"})
+
+	result, err := decodeReturnFileRequest(context.TODO(), req)
+	require.NoError(t, err)
+
+	r, ok := result.(returnFileRequest)
+	require.True(t, ok)
+	require.Equal(t, "test-file-id", r.fileID)
+	require.False(t, r.effectiveEntryDate.IsZero())
+	require.Len(t, r.entries, 1)
+	require.Equal(t, "R14", r.entries[0].ReturnCode)
+	require.Equal(t, 10, r.entries[0].DateOfDeath.Day())
+}
+
+func TestFiles__correctionFileEndpoint(t *testing.T) {
+	logger := log.NewNopLogger()
+	repo := NewRepositoryInMemory(testTTLDuration, logger)
+	svc := NewService(repo)
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	// write an ACH file into repository
+	fd, err := os.Open(filepath.Join("..", "test", "testdata", "ppd-mixedDebitCredit-valid.json"))
+	if fd == nil {
+		t.Fatalf("empty ACH file: %v", err)
+	}
+	defer fd.Close()
+	bs, _ := io.ReadAll(fd)
+	file, _ := ach.FileFromJSON(bs)
+	repo.StoreFile(file)
+
+	body := strings.NewReader(`{"entries": [{"traceNumber": "121042880000002", "changeCode": "C01", "correctedData": {"AccountNumber": "1918171614"}}]}`)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", fmt.Sprintf("/files/%s/correction", file.ID), body)
+	req.Header.Set("X-Request-Id", "77777")
+
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp correctionFileResponse
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	require.NotEqual(t, file.ID, resp.ID)
+	require.NotNil(t, resp.File)
+
+	require.Len(t, resp.File.Batches, 1)
+	require.Equal(t, ach.COR, resp.File.Batches[0].GetHeader().StandardEntryClassCode)
+
+	entries := resp.File.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.NotNil(t, entries[0].Addenda98)
+	require.Equal(t, "C01", entries[0].Addenda98.ChangeCode)
+	require.Equal(t, "1918171614", entries[0].Addenda98.CorrectedData)
+
+	// Refuse the NOC we just created
+	body = strings.NewReader(fmt.Sprintf(`{"refused": [{"traceNumber": "%s", "refusedChangeCode": "C61"}]}`, entries[0].TraceNumber))
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", fmt.Sprintf("/files/%s/correction", resp.ID), body)
+
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var refusedResp correctionFileResponse
+	err = json.NewDecoder(w.Body).Decode(&refusedResp)
+	require.NoError(t, err)
+
+	entries = refusedResp.File.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.NotNil(t, entries[0].Addenda98Refused)
+	require.Equal(t, "C61", entries[0].Addenda98Refused.RefusedChangeCode)
+
+	// Invalid change codes are rejected
+	body = strings.NewReader(`{"entries": [{"traceNumber": "121042880000002", "changeCode": "C00", "correctedData": {"AccountNumber": "1"}}]}`)
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", fmt.Sprintf("/files/%s/correction", file.ID), body)
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFiles__decodeCorrectionFileRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/files/correction", nil)
+	_, err := decodeCorrectionFileRequest(context.TODO(), req)
+	require.ErrorIs(t, err, ErrBadRouting)
+
+	body := strings.NewReader(`{"entries": [{"traceNumber": "1", "changeCode": "C01"}], "refused": [{"traceNumber": "2", "refusedChangeCode": "C61"}]}`)
+	req = httptest.NewRequest("POST", "/files/test-file-id/correction", body)
+	req = mux.SetURLVars(req, map[string]string{"fileID": "test-file-id"})
+	_, err = decodeCorrectionFileRequest(context.TODO(), req)
+	require.ErrorContains(t, err, "only one of entries or refused")
+}
@@ -0,0 +1,69 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/moov-io/ach"
+)
+
+// WithLimits rejects created and merged files, along with batches and entries added to stored files,
+// which exceed limits. Company and SEC code limits are checked against the totals of each request.
+func WithLimits(limits *ach.Limits) ServiceOption {
+	return func(s *service) {
+		s.limits = limits
+	}
+}
+
+// ConfigureLimitsFromEnv reads the JSON encoded ach.Limits from the file at ACH_LIMITS_PATH.
+// Nil Limits are returned when ACH_LIMITS_PATH is unset.
+func ConfigureLimitsFromEnv() (*ach.Limits, error) {
+	path := os.Getenv("ACH_LIMITS_PATH")
+	if path == "" {
+		return nil, nil
+	}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading ACH_LIMITS_PATH: %w", err)
+	}
+	var limits ach.Limits
+	if err := json.Unmarshal(bs, &limits); err != nil {
+		return nil, fmt.Errorf("parsing ACH_LIMITS_PATH: %w", err)
+	}
+	return &limits, nil
+}
+
+// checkBatchLimits returns a *ach.LimitsError when adding batch to the stored file exceeds the limits of s
+func (s *service) checkBatchLimits(fileID string, batch ach.Batcher) error {
+	if s.limits == nil {
+		return nil
+	}
+	file, err := s.store.FindFile(fileID)
+	if err != nil {
+		return err
+	}
+	return s.limits.Check(&ach.File{
+		ID:      file.ID,
+		Header:  file.Header,
+		Batches: append(slices.Clone(file.Batches), batch),
+	})
+}
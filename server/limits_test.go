@@ -0,0 +1,162 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles_CreateFileLimits(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	svc := NewService(repo, WithLimits(&ach.Limits{MaxEntryAmount: 50000000}))
+	handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	fd, err := os.Open(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	defer fd.Close()
+
+	req := httptest.NewRequest("POST", "/files/"+base.ID(), fd)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "maxEntryAmount")
+	require.Empty(t, svc.GetFiles())
+
+	w, resp := createNachaFile(t, handler, filepath.Join("..", "test", "testdata", "web-debit.ach"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEmpty(t, resp.ID)
+}
+
+func TestFiles_MergeFilesServerLimits(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	svc := NewService(repo, WithLimits(&ach.Limits{MaxFileCreditAmount: 150000}))
+	handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	file, err := ach.ReadJSONFile(filepath.Join("..", "test", "testdata", "ppd-mixedDebitCredit-valid.json"))
+	require.NoError(t, err)
+	file2, err := ach.ReadJSONFile(filepath.Join("..", "test", "testdata", "ppd-valid.json"))
+	require.NoError(t, err)
+
+	// each file is within the limits, but not once they're merged
+	var body bytes.Buffer
+	require.NoError(t, json.NewEncoder(&body).Encode(mergeFilesRequest{
+		Files: []*ach.File{file, file2},
+	}))
+	req := httptest.NewRequest("POST", "/merge", &body)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "maxFileCreditAmount: 200000 exceeds 150000")
+}
+
+func TestService_ModifyFileLimits(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	svc := NewService(repo, WithLimits(&ach.Limits{MaxFileDebitAmount: 150000000}))
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = base.ID()
+	require.NoError(t, repo.StoreFile(file))
+	batch := file.Batches[0]
+
+	// a second entry exceeds the file's debit limit
+	entry := *batch.GetEntries()[0]
+	entry.ID = ""
+	entry.TraceNumber = "121042880000002"
+	_, err = svc.CreateEntry(file.ID, batch.ID(), &entry)
+	require.ErrorIs(t, err, ach.ErrLimitsExceeded)
+
+	stored, err := svc.GetFile(file.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Batches[0].GetEntries(), 1)
+
+	// as does a second batch
+	bh := *batch.GetHeader()
+	bh.ID = ""
+	bh.BatchNumber = 2
+	second, err := ach.NewBatch(&bh)
+	require.NoError(t, err)
+	second.AddEntry(&entry)
+	require.NoError(t, second.Create())
+	_, err = svc.CreateBatch(file.ID, second)
+	require.ErrorIs(t, err, ach.ErrLimitsExceeded)
+	require.Len(t, svc.GetBatches(file.ID), 1)
+}
+
+func TestFiles__checkLimitsEndpointServerLimits(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	svc := NewService(repo, WithLimits(&ach.Limits{MaxEntryAmount: 50000000}))
+	router := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = base.ID()
+	require.NoError(t, repo.StoreFile(file))
+
+	// an empty body uses the server's limits
+	req := httptest.NewRequest("POST", fmt.Sprintf("/files/%s/limits", file.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp checkLimitsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Violations, 1)
+	require.Equal(t, "maxEntryAmount", resp.Violations[0].Limit)
+}
+
+func TestConfigureLimitsFromEnv(t *testing.T) {
+	limits, err := ConfigureLimitsFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, limits)
+
+	path := filepath.Join(t.TempDir(), "limits.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"maxEntryAmount": 2500000, "companies": {"*": {"maxDailyDebitAmount": 100}}}`), 0600))
+	t.Setenv("ACH_LIMITS_PATH", path)
+
+	limits, err = ConfigureLimitsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, 2500000, limits.MaxEntryAmount)
+	require.Equal(t, 100, limits.Companies["*"].MaxDailyDebitAmount)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0600))
+	_, err = ConfigureLimitsFromEnv()
+	require.ErrorContains(t, err, "parsing ACH_LIMITS_PATH")
+
+	t.Setenv("ACH_LIMITS_PATH", filepath.Join(t.TempDir(), "missing.json"))
+	_, err = ConfigureLimitsFromEnv()
+	require.ErrorContains(t, err, "reading ACH_LIMITS_PATH")
+}
@@ -32,14 +32,27 @@ type Repository interface {
 	StoreFile(file *ach.File) error
 	FindFile(id string) (*ach.File, error)
 	FindAllFiles() []*ach.File
+	// UpdateFile replaces a stored file with f, returning ErrNotFound if it has not been stored
+	UpdateFile(f *ach.File) error
+	// ModifyFile calls fn with a copy of the stored file and replaces the stored file with it, returning
+	// ErrNotFound if it has not been stored. No other change is made to the file until fn returns and
+	// the stored file is left as-is when fn returns an error.
+	ModifyFile(id string, fn func(f *ach.File) error) error
 	DeleteFile(id string) error
 	StoreBatch(fileID string, batch ach.Batcher) error
 	FindBatch(fileID string, batchID string) (ach.Batcher, error)
 	FindAllBatches(fileID string) []ach.Batcher
 	DeleteBatch(fileID string, batchID string) error
+
+	// QueryFiles returns a page of files matching query
+	QueryFiles(query FileQuery) (FileQueryResult, error)
+	// SearchEntries returns entries across every stored file matching query
+	SearchEntries(query EntryQuery) ([]EntryResult, error)
 }
 
 type repositoryInMemory struct {
+	expiredEvents
+
 	mtx   sync.RWMutex
 	files map[string]*ach.File
 
@@ -107,6 +120,49 @@ func (r *repositoryInMemory) FindAllFiles() []*ach.File {
 	return files
 }
 
+func (r *repositoryInMemory) UpdateFile(f *ach.File) error {
+	if f == nil {
+		return errors.New("nil ACH file provided")
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.files[f.ID]; !ok {
+		return ErrNotFound
+	}
+	r.files[f.ID] = f
+	return nil
+}
+
+func (r *repositoryInMemory) ModifyFile(id string, fn func(f *ach.File) error) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	original, ok := r.files[id]
+	if !ok || original == nil {
+		return ErrNotFound
+	}
+	file, err := cloneFile(original)
+	if err != nil {
+		return err
+	}
+	if err := fn(file); err != nil {
+		return err
+	}
+	r.files[id] = file
+	return nil
+}
+
+// QueryFiles returns a page of files from memory matching query
+func (r *repositoryInMemory) QueryFiles(query FileQuery) (FileQueryResult, error) {
+	return queryFiles(r.FindAllFiles(), query)
+}
+
+// SearchEntries returns entries of files in memory matching query
+func (r *repositoryInMemory) SearchEntries(query EntryQuery) ([]EntryResult, error) {
+	return searchEntries(r.FindAllFiles(), query)
+}
+
 func (r *repositoryInMemory) DeleteFile(id string) error {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
@@ -198,9 +254,8 @@ func (r *repositoryInMemory) DeleteBatch(fileID string, batchID string) error {
 // the environmental variable ACH_FILE_TTL (parsed as a time.Duration).
 func (r *repositoryInMemory) cleanupOldFiles() {
 	r.mtx.Lock()
-	defer r.mtx.Unlock()
 
-	removed := 0
+	var expired []*ach.File
 	tooOld := time.Now().Add(-1 * r.ttl)
 	tooOldStr := tooOld.Format("060102") // YYMMDD
 
@@ -210,12 +265,14 @@ func (r *repositoryInMemory) cleanupOldFiles() {
 			continue
 		}
 		if file.Header.FileCreationDate < tooOldStr {
-			removed++
+			expired = append(expired, file)
 			delete(r.files, i)
 		}
 	}
+	r.mtx.Unlock()
 
 	if r.logger != nil {
-		r.logger.Info().Logf("removed %d ACH files older than %v", removed, tooOld.Format(time.RFC3339))
+		r.logger.Info().Logf("removed %d ACH files older than %v", len(expired), tooOld.Format(time.RFC3339))
 	}
+	r.publishExpired(expired)
 }
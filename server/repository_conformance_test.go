@@ -0,0 +1,497 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// repositoryCleaner is implemented by repositories which remove files older than their TTL
+type repositoryCleaner interface {
+	cleanupOldFiles()
+}
+
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "ach.db"))
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// repositoryImplementations returns a constructor for each Repository which must pass the conformance suite
+func repositoryImplementations() map[string]func(t *testing.T) Repository {
+	return map[string]func(t *testing.T) Repository{
+		"memory": func(t *testing.T) Repository {
+			return NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+		},
+		"filesystem": func(t *testing.T) Repository {
+			repo, err := NewRepositoryFilesystem(t.TempDir(), testTTLDuration, log.NewNopLogger())
+			require.NoError(t, err)
+			return repo
+		},
+		"sqlite": func(t *testing.T) Repository {
+			repo, err := NewRepositorySQL(openTestSQLite(t), testTTLDuration, log.NewNopLogger())
+			require.NoError(t, err)
+			return repo
+		},
+	}
+}
+
+func TestRepositoryConformance(t *testing.T) {
+	for name, newRepo := range repositoryImplementations() {
+		t.Run(name, func(t *testing.T) {
+			testRepositoryConformance(t, newRepo)
+		})
+	}
+}
+
+func conformanceFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	file.ID = base.ID()
+	return file
+}
+
+func testRepositoryConformance(t *testing.T, newRepo func(t *testing.T) Repository) {
+	t.Run("files", func(t *testing.T) {
+		repo := newRepo(t)
+		require.Empty(t, repo.FindAllFiles())
+
+		file := conformanceFile(t)
+		require.NoError(t, repo.StoreFile(file))
+		require.ErrorIs(t, repo.StoreFile(file), ErrAlreadyExists)
+		require.Error(t, repo.StoreFile(nil))
+
+		found, err := repo.FindFile(file.ID)
+		require.NoError(t, err)
+		require.Equal(t, file.ID, found.ID)
+		require.Equal(t, file.Header.ImmediateOrigin, found.Header.ImmediateOrigin)
+		require.Equal(t, file.Header.FileCreationDate, found.Header.FileCreationDate)
+		require.Len(t, found.Batches, 1)
+		require.Len(t, found.Batches[0].GetEntries(), len(file.Batches[0].GetEntries()))
+		require.Equal(t, file.Control.TotalDebitEntryDollarAmountInFile, found.Control.TotalDebitEntryDollarAmountInFile)
+
+		other := conformanceFile(t)
+		require.NoError(t, repo.StoreFile(other))
+		require.Len(t, repo.FindAllFiles(), 2)
+
+		require.NoError(t, repo.DeleteFile(file.ID))
+		_, err = repo.FindFile(file.ID)
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Len(t, repo.FindAllFiles(), 1)
+
+		// deleting a missing file is not an error
+		require.NoError(t, repo.DeleteFile(file.ID))
+		require.NoError(t, repo.DeleteFile(base.ID()))
+	})
+
+	t.Run("update file", func(t *testing.T) {
+		repo := newRepo(t)
+
+		file := conformanceFile(t)
+		require.ErrorIs(t, repo.UpdateFile(file), ErrNotFound)
+		require.Error(t, repo.UpdateFile(nil))
+		require.NoError(t, repo.StoreFile(file))
+
+		updated := conformanceFile(t)
+		updated.ID = file.ID
+		updated.Header.ImmediateDestination = "987654320"
+		updated.Batches[0].GetEntries()[0].Amount = 500
+		require.NoError(t, updated.Batches[0].Create())
+		require.NoError(t, updated.Create())
+		require.NoError(t, repo.UpdateFile(updated))
+
+		found, err := repo.FindFile(file.ID)
+		require.NoError(t, err)
+		require.Equal(t, "987654320", found.Header.ImmediateDestination)
+		require.Equal(t, 500, found.Batches[0].GetEntries()[0].Amount)
+		require.Equal(t, 500, found.Control.TotalDebitEntryDollarAmountInFile)
+		require.Len(t, repo.FindAllFiles(), 1)
+
+		result, err := repo.QueryFiles(FileQuery{ImmediateDestination: "987654320"})
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Total)
+	})
+
+	t.Run("modify file", func(t *testing.T) {
+		repo := newRepo(t)
+
+		file := conformanceFile(t)
+		require.ErrorIs(t, repo.ModifyFile(file.ID, func(*ach.File) error { return nil }), ErrNotFound)
+		require.NoError(t, repo.StoreFile(file))
+
+		// The stored file is left as-is when fn fails
+		err := repo.ModifyFile(file.ID, func(f *ach.File) error {
+			f.Header.ImmediateDestination = "987654320"
+			return errors.New("bad change")
+		})
+		require.ErrorContains(t, err, "bad change")
+		found, err := repo.FindFile(file.ID)
+		require.NoError(t, err)
+		require.Equal(t, file.Header.ImmediateDestination, found.Header.ImmediateDestination)
+
+		require.NoError(t, repo.ModifyFile(file.ID, func(f *ach.File) error {
+			f.Header.ImmediateDestination = "987654320"
+			return nil
+		}))
+		found, err = repo.FindFile(file.ID)
+		require.NoError(t, err)
+		require.Equal(t, "987654320", found.Header.ImmediateDestination)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.FindFile(base.ID())
+		require.ErrorIs(t, err, ErrNotFound)
+
+		batch := mockBatchWEB(t)
+		require.ErrorIs(t, repo.StoreBatch(base.ID(), batch), ErrNotFound)
+		_, err = repo.FindBatch(base.ID(), batch.ID())
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Empty(t, repo.FindAllBatches(base.ID()))
+		require.ErrorContains(t, repo.DeleteBatch(base.ID(), batch.ID()), ErrNotFound.Error())
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		repo := newRepo(t)
+
+		file := &ach.File{
+			ID:     base.ID(),
+			Header: *mockFileHeader(),
+		}
+		require.NoError(t, repo.StoreFile(file))
+		require.Empty(t, repo.FindAllBatches(file.ID))
+
+		batch := mockBatchWEB(t)
+		_, err := repo.FindBatch(file.ID, batch.ID())
+		require.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, repo.StoreBatch(file.ID, batch))
+		require.ErrorIs(t, repo.StoreBatch(file.ID, batch), ErrAlreadyExists)
+		require.Len(t, repo.FindAllBatches(file.ID), 1)
+
+		found, err := repo.FindBatch(file.ID, batch.ID())
+		require.NoError(t, err)
+		require.Equal(t, batch.ID(), found.ID())
+		require.Equal(t, batch.GetHeader().CompanyName, found.GetHeader().CompanyName)
+		require.Len(t, found.GetEntries(), 1)
+
+		f, err := repo.FindFile(file.ID)
+		require.NoError(t, err)
+		require.Len(t, f.Batches, 1)
+
+		require.ErrorIs(t, repo.DeleteBatch(file.ID, base.ID()), ErrNotFound)
+		require.NoError(t, repo.DeleteBatch(file.ID, batch.ID()))
+		require.Empty(t, repo.FindAllBatches(file.ID))
+		_, err = repo.FindBatch(file.ID, batch.ID())
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("query files", func(t *testing.T) {
+		repo := newRepo(t)
+
+		first := conformanceFile(t)
+		first.ID = "file-1"
+		require.NoError(t, repo.StoreFile(first))
+
+		second := conformanceFile(t)
+		second.ID = "file-2"
+		second.Header.ImmediateDestination = "987654320"
+		second.Header.FileCreationDate = "190701"
+		entry := second.Batches[0].GetEntries()[0]
+		entry.Amount = 500
+		entry.TraceNumber = "121042880000002"
+		entry.DFIAccountNumber = "87654321"
+		require.NoError(t, repo.StoreFile(second))
+
+		iat, err := ach.ReadFile(filepath.Join("..", "test", "testdata", "20180713-IAT.ach"))
+		require.NoError(t, err)
+		iat.ID = "file-3"
+		require.NoError(t, repo.StoreFile(iat))
+
+		fileIDs := func(t *testing.T, query FileQuery) []string {
+			t.Helper()
+
+			result, err := repo.QueryFiles(query)
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(result.Files))
+			for _, f := range result.Files {
+				ids = append(ids, f.ID)
+			}
+			return ids
+		}
+
+		result, err := repo.QueryFiles(FileQuery{})
+		require.NoError(t, err)
+		require.Equal(t, 3, result.Total)
+		require.Empty(t, result.NextCursor)
+		require.Equal(t, []string{"file-1", "file-2", "file-3"}, fileIDs(t, FileQuery{}))
+
+		t.Run("pagination", func(t *testing.T) {
+			page, err := repo.QueryFiles(FileQuery{Limit: 2})
+			require.NoError(t, err)
+			require.Equal(t, 3, page.Total)
+			require.Len(t, page.Files, 2)
+			require.NotEmpty(t, page.NextCursor)
+
+			page, err = repo.QueryFiles(FileQuery{Limit: 2, Cursor: page.NextCursor})
+			require.NoError(t, err)
+			require.Equal(t, 3, page.Total)
+			require.Len(t, page.Files, 1)
+			require.Equal(t, "file-3", page.Files[0].ID)
+			require.Empty(t, page.NextCursor)
+		})
+
+		t.Run("filters", func(t *testing.T) {
+			require.Equal(t, []string{"file-1", "file-2"}, fileIDs(t, FileQuery{ImmediateOrigin: "121042882"}))
+			require.Equal(t, []string{"file-2"}, fileIDs(t, FileQuery{ImmediateDestination: "987654320"}))
+			require.Equal(t, []string{"file-3"}, fileIDs(t, FileQuery{StandardEntryClassCode: "IAT"}))
+			require.Equal(t, []string{"file-1", "file-2"}, fileIDs(t, FileQuery{StandardEntryClassCode: "ppd"}))
+
+			june30 := time.Date(2019, time.June, 30, 0, 0, 0, 0, time.UTC)
+			require.Equal(t, []string{"file-2"}, fileIDs(t, FileQuery{CreatedFrom: june30}))
+			require.Equal(t, []string{"file-1", "file-3"}, fileIDs(t, FileQuery{CreatedTo: june30}))
+
+			require.Equal(t, []string{"file-2"}, fileIDs(t, FileQuery{MaxAmount: 500}))
+			require.Equal(t, []string{"file-1"}, fileIDs(t, FileQuery{MinAmount: 100000000}))
+
+			require.Equal(t, []string{"file-1"}, fileIDs(t, FileQuery{TraceNumber: "121042880000001"}))
+			require.Equal(t, []string{"file-2"}, fileIDs(t, FileQuery{AccountNumber: "87654321"}))
+			require.Empty(t, fileIDs(t, FileQuery{TraceNumber: "121042880000001", AccountNumber: "87654321"}))
+
+			page, err := repo.QueryFiles(FileQuery{ImmediateOrigin: "121042882", Limit: 1})
+			require.NoError(t, err)
+			require.Equal(t, 2, page.Total)
+			require.NotEmpty(t, page.NextCursor)
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			_, err := repo.QueryFiles(FileQuery{Cursor: "!!"})
+			require.ErrorIs(t, err, ErrInvalidQuery)
+
+			_, err = repo.QueryFiles(FileQuery{Limit: -1})
+			require.ErrorIs(t, err, ErrInvalidQuery)
+
+			_, err = repo.QueryFiles(FileQuery{MinAmount: 10, MaxAmount: 5})
+			require.ErrorIs(t, err, ErrInvalidQuery)
+		})
+
+		t.Run("search entries", func(t *testing.T) {
+			entries, err := repo.SearchEntries(EntryQuery{TraceNumber: "121042880000001"})
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			require.Equal(t, "file-1", entries[0].FileID)
+			require.Equal(t, first.Batches[0].ID(), entries[0].BatchID)
+			require.Equal(t, "12345678", entries[0].Entry.DFIAccountNumber)
+
+			iatEntry := iat.IATBatches[0].Entries[0]
+			entries, err = repo.SearchEntries(EntryQuery{AccountNumber: iatEntry.DFIAccountNumber})
+			require.NoError(t, err)
+			require.NotEmpty(t, entries)
+			require.Equal(t, "file-3", entries[0].FileID)
+			require.NotNil(t, entries[0].IATEntry)
+			require.Nil(t, entries[0].Entry)
+
+			entries, err = repo.SearchEntries(EntryQuery{AccountNumber: "00000000"})
+			require.NoError(t, err)
+			require.Empty(t, entries)
+
+			// Header filters limit the files searched
+			june30 := time.Date(2019, time.June, 30, 0, 0, 0, 0, time.UTC)
+			entries, err = repo.SearchEntries(EntryQuery{AccountNumber: "87654321", CreatedTo: june30})
+			require.NoError(t, err)
+			require.Empty(t, entries)
+			entries, err = repo.SearchEntries(EntryQuery{AccountNumber: "87654321", ImmediateOrigin: "121042882", CreatedFrom: june30})
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			require.Equal(t, "file-2", entries[0].FileID)
+			entries, err = repo.SearchEntries(EntryQuery{TraceNumber: "121042880000001", ImmediateDestination: "987654320"})
+			require.NoError(t, err)
+			require.Empty(t, entries)
+
+			_, err = repo.SearchEntries(EntryQuery{})
+			require.ErrorIs(t, err, ErrInvalidQuery)
+			_, err = repo.SearchEntries(EntryQuery{TraceNumber: "121042880000001", CreatedFrom: june30, CreatedTo: june30.AddDate(0, 0, -1)})
+			require.ErrorIs(t, err, ErrInvalidQuery)
+		})
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		repo := newRepo(t)
+
+		cleaner, ok := repo.(repositoryCleaner)
+		require.True(t, ok, "%T does not cleanup old files", repo)
+
+		old := conformanceFile(t)
+		old.Header.FileCreationDate = time.Now().Add(-24 * time.Hour).Format("060102") // YYMMDD of 24hrs ago
+		require.NoError(t, repo.StoreFile(old))
+
+		current := conformanceFile(t)
+		current.Header.FileCreationDate = time.Now().Add(24 * time.Hour).Format("060102")
+		require.NoError(t, repo.StoreFile(current))
+
+		bus := NewEvents()
+		rec := recordEvents(t, bus)
+		NewServiceWithEvents(repo, bus)
+		cleaner.cleanupOldFiles()
+
+		files := repo.FindAllFiles()
+		require.Len(t, files, 1)
+		require.Equal(t, current.ID, files[0].ID)
+
+		events := rec.take()
+		require.Len(t, events, 1)
+		require.Equal(t, EventFileExpired, events[0].Type)
+		require.Equal(t, old.ID, events[0].File.ID)
+	})
+}
+
+func TestRepositoryFilesystem__persisted(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := NewRepositoryFilesystem(dir, testTTLDuration, log.NewNopLogger())
+	require.NoError(t, err)
+
+	file := conformanceFile(t)
+	require.NoError(t, repo.StoreFile(file))
+
+	// Nacha text is saved alongside the metadata
+	bs, err := os.ReadFile(filepath.Join(dir, file.ID+".ach"))
+	require.NoError(t, err)
+	require.Contains(t, string(bs), file.Header.ImmediateOriginName)
+
+	// No temporary files are left behind
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	require.NoError(t, err)
+	require.Empty(t, matches)
+
+	// Reopen the repository
+	repo, err = NewRepositoryFilesystem(dir, testTTLDuration, log.NewNopLogger())
+	require.NoError(t, err)
+
+	found, err := repo.FindFile(file.ID)
+	require.NoError(t, err)
+	require.Len(t, found.Batches, 1)
+
+	// The header index is rebuilt and kept up to date
+	result, err := repo.QueryFiles(FileQuery{ImmediateOrigin: file.Header.ImmediateOrigin})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+
+	found.Header.ImmediateOrigin = "987654320"
+	require.NoError(t, repo.UpdateFile(found))
+	result, err = repo.QueryFiles(FileQuery{ImmediateOrigin: file.Header.ImmediateOrigin})
+	require.NoError(t, err)
+	require.Empty(t, result.Files)
+	result, err = repo.QueryFiles(FileQuery{ImmediateOrigin: "987654320"})
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+
+	t.Run("invalid IDs", func(t *testing.T) {
+		for _, id := range []string{"", ".", "..", "../" + file.ID, "a/b", `a\b`} {
+			f := conformanceFile(t)
+			f.ID = id
+			require.ErrorIs(t, repo.StoreFile(f), ErrInvalidFileID)
+
+			_, err := repo.FindFile(id)
+			require.ErrorIs(t, err, ErrNotFound)
+			require.NoError(t, repo.DeleteFile(id))
+		}
+	})
+}
+
+func TestRepositorySQL__persisted(t *testing.T) {
+	db := openTestSQLite(t)
+
+	repo, err := NewRepositorySQL(db, testTTLDuration, log.NewNopLogger())
+	require.NoError(t, err)
+
+	file := conformanceFile(t)
+	require.NoError(t, repo.StoreFile(file))
+
+	var nacha string
+	require.NoError(t, db.QueryRow(`select nacha from ach_files where file_id = ?`, file.ID).Scan(&nacha))
+	require.Contains(t, nacha, file.Header.ImmediateOriginName)
+
+	// Reopening the repository keeps existing files
+	repo, err = NewRepositorySQL(db, testTTLDuration, log.NewNopLogger())
+	require.NoError(t, err)
+
+	found, err := repo.FindFile(file.ID)
+	require.NoError(t, err)
+	require.Len(t, found.Batches, 1)
+
+	_, err = NewRepositorySQL(nil, testTTLDuration, nil)
+	require.Error(t, err)
+}
+
+func TestRepositorySQL__cleanupOldFiles(t *testing.T) {
+	db := openTestSQLite(t)
+
+	repo, err := NewRepositorySQL(db, testTTLDuration, log.NewNopLogger())
+	require.NoError(t, err)
+
+	old := conformanceFile(t)
+	old.Header.FileCreationDate = time.Now().Add(-24 * time.Hour).Format("060102")
+	require.NoError(t, repo.StoreFile(old))
+
+	// Rows which can not be read are kept as no expired event could be published for them
+	_, err = db.Exec(`insert into ach_files(file_id, immediate_origin, immediate_destination, file_creation_date, stored_at, nacha, metadata) values (?, ?, ?, ?, ?, ?, ?)`,
+		"unreadable", "", "", old.Header.FileCreationDate, time.Now().UTC(), "", "{")
+	require.NoError(t, err)
+
+	bus := NewEvents()
+	rec := recordEvents(t, bus)
+	NewServiceWithEvents(repo, bus)
+	repo.(repositoryCleaner).cleanupOldFiles()
+
+	events := rec.take()
+	require.Len(t, events, 1)
+	require.Equal(t, old.ID, events[0].File.ID)
+
+	var ids []string
+	rows, err := db.Query(`select file_id from ach_files`)
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []string{"unreadable"}, ids)
+}
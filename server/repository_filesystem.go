@@ -0,0 +1,445 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+)
+
+// ErrInvalidFileID is returned when a file ID can not be used as a filename
+var ErrInvalidFileID = errors.New("invalid file ID")
+
+type repositoryFilesystem struct {
+	expiredEvents
+
+	mtx sync.RWMutex
+	dir string
+
+	// headers indexes the FileHeader of every stored file by its ID so queries
+	// only read the files they match
+	headers map[string]indexedHeader
+
+	ttl time.Duration
+
+	logger log.Logger
+}
+
+// indexedHeader holds the FileHeader fields a repositoryFilesystem filters files by
+type indexedHeader struct {
+	origin       string
+	destination  string
+	creationDate string
+}
+
+func indexHeader(f *ach.File) indexedHeader {
+	return indexedHeader{
+		origin:       f.Header.ImmediateOrigin,
+		destination:  f.Header.ImmediateDestination,
+		creationDate: f.Header.FileCreationDate,
+	}
+}
+
+// NewRepositoryFilesystem is an ach storage repository which saves each file in dir as Nacha text
+// (<id>.ach) along with JSON metadata (<id>.json). Files are kept across restarts.
+//
+// The FileHeader of each file is indexed in memory when the repository is created, so dir
+// must not be changed by anything else while the repository is in use.
+func NewRepositoryFilesystem(dir string, ttl time.Duration, logger log.Logger) (Repository, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating repository directory: %w", err)
+	}
+	repo := &repositoryFilesystem{
+		dir:     dir,
+		headers: make(map[string]indexedHeader),
+		ttl:     ttl,
+		logger:  logger,
+	}
+	if err := repo.buildIndex(); err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0*time.Second {
+		// Don't run the cleanup if we've disabled the TTL
+		return repo, nil
+	}
+
+	// Run our anon goroutine to cleanup old ACH files
+	go func() {
+		t := time.NewTicker(1 * time.Minute)
+		for range t.C {
+			repo.cleanupOldFiles()
+		}
+	}()
+
+	return repo, nil
+}
+
+func (r *repositoryFilesystem) paths(id string) (string, string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidFileID, id)
+	}
+	base := filepath.Join(r.dir, id)
+	return base + ".ach", base + ".json", nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// save writes the Nacha text first as the JSON metadata marks a file as stored
+func (r *repositoryFilesystem) save(f *ach.File, storedAt time.Time) error {
+	nachaPath, metadataPath, err := r.paths(f.ID)
+	if err != nil {
+		return err
+	}
+	nacha, metadata, err := encodeStoredFile(f, storedAt)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(nachaPath, nacha); err != nil {
+		return fmt.Errorf("saving file %s: %w", f.ID, err)
+	}
+	if err := writeFileAtomic(metadataPath, metadata); err != nil {
+		return fmt.Errorf("saving file %s: %w", f.ID, err)
+	}
+	r.headers[f.ID] = indexHeader(f)
+	return nil
+}
+
+func (r *repositoryFilesystem) load(id string) (*ach.File, time.Time, error) {
+	_, metadataPath, err := r.paths(id)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	bs, err := os.ReadFile(metadataPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, time.Time{}, ErrNotFound
+		}
+		return nil, time.Time{}, fmt.Errorf("reading file %s: %w", id, err)
+	}
+	return decodeStoredFile(bs)
+}
+
+func (r *repositoryFilesystem) remove(id string) error {
+	nachaPath, metadataPath, err := r.paths(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(metadataPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("deleting file %s: %w", id, err)
+	}
+	delete(r.headers, id)
+	if err := os.Remove(nachaPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("deleting file %s: %w", id, err)
+	}
+	return nil
+}
+
+// ids returns the ID of every stored file
+func (r *repositoryFilesystem) ids() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing files: %w", err)
+	}
+	var out []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, ".json") {
+			out = append(out, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	return out, nil
+}
+
+// buildIndex reads the FileHeader of every stored file into r.headers
+func (r *repositoryFilesystem) buildIndex() error {
+	ids, err := r.ids()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		f, _, err := r.load(id)
+		if err != nil {
+			r.logError(err)
+			continue
+		}
+		r.headers[id] = indexHeader(f)
+	}
+	return nil
+}
+
+// filterFiles reads the stored files whose indexed FileHeader matches filter
+func (r *repositoryFilesystem) filterFiles(filter headerFilter) []*ach.File {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var files []*ach.File
+	for id, h := range r.headers {
+		if !filter.matches(h.origin, h.destination, h.creationDate) {
+			continue
+		}
+		f, _, err := r.load(id)
+		if err != nil {
+			r.logError(err)
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+func (r *repositoryFilesystem) StoreFile(f *ach.File) error {
+	if f == nil {
+		return errors.New("nil ACH file provided")
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	_, metadataPath, err := r.paths(f.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(metadataPath); err == nil {
+		return ErrAlreadyExists
+	}
+	return r.save(f, time.Now())
+}
+
+// FindFile retrieves a ach.File based on the supplied ID
+func (r *repositoryFilesystem) FindFile(id string) (*ach.File, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	f, _, err := r.load(id)
+	if errors.Is(err, ErrInvalidFileID) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// FindAllFiles returns all files that have been saved in dir
+func (r *repositoryFilesystem) FindAllFiles() []*ach.File {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	ids, err := r.ids()
+	if err != nil {
+		r.logError(err)
+		return nil
+	}
+	files := make([]*ach.File, 0, len(ids))
+	for _, id := range ids {
+		f, _, err := r.load(id)
+		if err != nil {
+			r.logError(err)
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+func (r *repositoryFilesystem) UpdateFile(f *ach.File) error {
+	if f == nil {
+		return errors.New("nil ACH file provided")
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	_, storedAt, err := r.load(f.ID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFileID) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return r.save(f, storedAt)
+}
+
+func (r *repositoryFilesystem) ModifyFile(id string, fn func(f *ach.File) error) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	file, storedAt, err := r.load(id)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFileID) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if err := fn(file); err != nil {
+		return err
+	}
+	return r.save(file, storedAt)
+}
+
+// QueryFiles returns a page of files saved in dir matching query.
+// Only files whose indexed origin, destination and creation date match are read.
+func (r *repositoryFilesystem) QueryFiles(query FileQuery) (FileQueryResult, error) {
+	if err := query.validate(); err != nil {
+		return FileQueryResult{}, err
+	}
+	return queryFiles(r.filterFiles(query.headers()), query)
+}
+
+// SearchEntries returns entries of files saved in dir matching query.
+// Only files whose indexed origin, destination and creation date match are read.
+func (r *repositoryFilesystem) SearchEntries(query EntryQuery) ([]EntryResult, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	return searchEntries(r.filterFiles(query.headers()), query)
+}
+
+func (r *repositoryFilesystem) DeleteFile(id string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	err := r.remove(id)
+	if errors.Is(err, ErrInvalidFileID) {
+		return nil // nothing could have been stored
+	}
+	return err
+}
+
+func (r *repositoryFilesystem) StoreBatch(fileID string, batch ach.Batcher) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	file, storedAt, err := r.load(fileID)
+	if err != nil {
+		return ErrNotFound
+	}
+	if hasBatch(file, batch.ID()) {
+		return ErrAlreadyExists
+	}
+	file.AddBatch(batch)
+	return r.save(file, storedAt)
+}
+
+// FindBatch retrieves a ach.Batcher based on the supplied ID
+func (r *repositoryFilesystem) FindBatch(fileID string, batchID string) (ach.Batcher, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	file, _, err := r.load(fileID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	for _, val := range file.Batches {
+		if val.ID() == batchID {
+			return val, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// FindAllBatches
+func (r *repositoryFilesystem) FindAllBatches(fileID string) []ach.Batcher {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	file, _, err := r.load(fileID)
+	if err != nil {
+		return nil
+	}
+	return file.Batches
+}
+
+func (r *repositoryFilesystem) DeleteBatch(fileID string, batchID string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	file, storedAt, err := r.load(fileID)
+	if err != nil {
+		return fmt.Errorf("%v: no file %s with batch %s found", ErrNotFound, fileID, batchID)
+	}
+	if !removeBatch(file, batchID) {
+		return ErrNotFound
+	}
+	return r.save(file, storedAt)
+}
+
+// cleanupOldFiles will delete files from dir which are older than the environmental
+// variable ACH_FILE_TTL (parsed as a time.Duration).
+func (r *repositoryFilesystem) cleanupOldFiles() {
+	r.mtx.Lock()
+
+	var expired []*ach.File
+	tooOld, tooOldStr := expiredFileCreationDate(r.ttl)
+
+	var ids []string
+	for id, h := range r.headers {
+		if h.creationDate < tooOldStr {
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		file, _, err := r.load(id)
+		if err != nil {
+			r.logError(err)
+			continue
+		}
+		if err := r.remove(id); err != nil {
+			r.logError(err)
+			continue
+		}
+		expired = append(expired, file)
+	}
+	r.mtx.Unlock()
+
+	if r.logger != nil {
+		r.logger.Info().Logf("removed %d ACH files older than %v", len(expired), tooOld.Format(time.RFC3339))
+	}
+	r.publishExpired(expired)
+}
+
+func (r *repositoryFilesystem) logError(err error) {
+	if r.logger != nil {
+		r.logger.Error().LogError(err)
+	}
+}
@@ -0,0 +1,360 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+)
+
+// ErrInvalidQuery is returned when a file or entry query can not be used
+var ErrInvalidQuery = errors.New("invalid query")
+
+// FileQuery filters and paginates the files returned by a Repository.
+// Fields left as their zero value do not filter files.
+type FileQuery struct {
+	// Limit is the maximum number of files returned. Zero returns every matching file.
+	Limit int
+	// Cursor continues a previous query from its NextCursor
+	Cursor string
+
+	ImmediateOrigin      string
+	ImmediateDestination string
+
+	// CreatedFrom and CreatedTo match files with a FileCreationDate on or between them
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	// StandardEntryClassCode matches files containing a batch of the SEC code
+	StandardEntryClassCode string
+
+	// MinAmount and MaxAmount bound the sum of entry amounts (in cents) in a file
+	MinAmount int
+	MaxAmount int
+
+	// TraceNumber and AccountNumber match files containing an entry with them
+	TraceNumber   string
+	AccountNumber string
+}
+
+// FileQueryResult is one page of files matching a FileQuery
+type FileQueryResult struct {
+	Files []*ach.File
+
+	// Total is the count of matching files across every page
+	Total int
+
+	// NextCursor is set when more files follow and is passed as FileQuery.Cursor to read them
+	NextCursor string
+}
+
+// EntryQuery searches the entries of every file in a Repository.
+// At least one of TraceNumber or AccountNumber is required.
+type EntryQuery struct {
+	// Limit is the maximum number of entries returned. Zero returns every matching entry.
+	Limit int
+
+	TraceNumber   string
+	AccountNumber string
+
+	// ImmediateOrigin, ImmediateDestination, CreatedFrom and CreatedTo limit the files searched
+	// as they do for a FileQuery.
+	ImmediateOrigin      string
+	ImmediateDestination string
+	CreatedFrom          time.Time
+	CreatedTo            time.Time
+}
+
+// headerFilter holds the FileHeader fields of a query. Repositories index these fields
+// so files can be filtered without reading them.
+type headerFilter struct {
+	ImmediateOrigin      string
+	ImmediateDestination string
+
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+func (f headerFilter) validate() error {
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && f.CreatedFrom.After(f.CreatedTo) {
+		return fmt.Errorf("%w: createdFrom is after createdTo", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// matches returns if a file with the origin, destination and FileCreationDate satisfies the filter
+func (f headerFilter) matches(origin, destination, creationDate string) bool {
+	if v := strings.TrimSpace(f.ImmediateOrigin); v != "" && strings.TrimSpace(origin) != v {
+		return false
+	}
+	if v := strings.TrimSpace(f.ImmediateDestination); v != "" && strings.TrimSpace(destination) != v {
+		return false
+	}
+	if !f.CreatedFrom.IsZero() && creationDate < fileCreationDate(f.CreatedFrom) {
+		return false
+	}
+	if !f.CreatedTo.IsZero() && creationDate > fileCreationDate(f.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+func (f headerFilter) matchesFile(file *ach.File) bool {
+	return f.matches(file.Header.ImmediateOrigin, file.Header.ImmediateDestination, file.Header.FileCreationDate)
+}
+
+// EntryResult is an entry matching an EntryQuery along with the file and batch containing it
+type EntryResult struct {
+	FileID  string `json:"fileID"`
+	BatchID string `json:"batchID"`
+
+	Entry    *ach.EntryDetail    `json:"entryDetail,omitempty"`
+	IATEntry *ach.IATEntryDetail `json:"iatEntryDetail,omitempty"`
+}
+
+// fileCreationDate formats t as a FileCreationDate (YYMMDD)
+func fileCreationDate(t time.Time) string {
+	return t.Format("060102")
+}
+
+func (q FileQuery) headers() headerFilter {
+	return headerFilter{
+		ImmediateOrigin:      q.ImmediateOrigin,
+		ImmediateDestination: q.ImmediateDestination,
+		CreatedFrom:          q.CreatedFrom,
+		CreatedTo:            q.CreatedTo,
+	}
+}
+
+func (q FileQuery) validate() error {
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: negative limit %d", ErrInvalidQuery, q.Limit)
+	}
+	if q.MinAmount < 0 || q.MaxAmount < 0 {
+		return fmt.Errorf("%w: negative amount", ErrInvalidQuery)
+	}
+	if q.MaxAmount > 0 && q.MinAmount > q.MaxAmount {
+		return fmt.Errorf("%w: minAmount %d is greater than maxAmount %d", ErrInvalidQuery, q.MinAmount, q.MaxAmount)
+	}
+	if err := q.headers().validate(); err != nil {
+		return err
+	}
+	_, err := decodeCursor(q.Cursor)
+	return err
+}
+
+// matches returns if file satisfies every filter of the query
+func (q FileQuery) matches(file *ach.File) bool {
+	if file == nil {
+		return false
+	}
+	if !q.headers().matchesFile(file) {
+		return false
+	}
+	if q.StandardEntryClassCode != "" && !fileHasSECCode(file, q.StandardEntryClassCode) {
+		return false
+	}
+	if q.MinAmount > 0 || q.MaxAmount > 0 {
+		total := fileEntryAmount(file)
+		if total < q.MinAmount || (q.MaxAmount > 0 && total > q.MaxAmount) {
+			return false
+		}
+	}
+	if q.TraceNumber != "" || q.AccountNumber != "" {
+		found := false
+		eq := EntryQuery{TraceNumber: q.TraceNumber, AccountNumber: q.AccountNumber}
+		eq.each(file, func(_ EntryResult) bool {
+			found = true
+			return false
+		})
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func fileHasSECCode(file *ach.File, code string) bool {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for _, b := range file.Batches {
+		if b.GetHeader().StandardEntryClassCode == code {
+			return true
+		}
+	}
+	for _, b := range file.IATBatches {
+		if b.Header != nil && b.Header.StandardEntryClassCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// fileEntryAmount returns the sum of every entry amount in file
+func fileEntryAmount(file *ach.File) int {
+	total := 0
+	for _, b := range file.Batches {
+		for _, entry := range b.GetEntries() {
+			total += entry.Amount
+		}
+		for _, entry := range b.GetADVEntries() {
+			total += entry.Amount
+		}
+	}
+	for _, b := range file.IATBatches {
+		for _, entry := range b.Entries {
+			total += entry.Amount
+		}
+	}
+	return total
+}
+
+// encodeCursor returns an opaque cursor for the file ID which ended a page
+func encodeCursor(lastFileID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastFileID))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	bs, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(bs) == 0 {
+		return "", fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return string(bs), nil
+}
+
+// queryFiles applies the filters and pagination of query to files. Repositories which can not
+// filter files themselves return their results through queryFiles.
+//
+// Files are ordered by ID so cursors are stable as files are added and removed.
+func queryFiles(files []*ach.File, query FileQuery) (FileQueryResult, error) {
+	if err := query.validate(); err != nil {
+		return FileQueryResult{}, err
+	}
+	after, _ := decodeCursor(query.Cursor)
+
+	matched := make([]*ach.File, 0, len(files))
+	for _, file := range files {
+		if query.matches(file) {
+			matched = append(matched, file)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID < matched[j].ID
+	})
+
+	result := FileQueryResult{
+		Files: make([]*ach.File, 0),
+		Total: len(matched),
+	}
+	start := sort.Search(len(matched), func(i int) bool {
+		return matched[i].ID > after
+	})
+	page := matched[start:]
+	if query.Limit > 0 && len(page) > query.Limit {
+		page = page[:query.Limit]
+		result.NextCursor = encodeCursor(page[len(page)-1].ID)
+	}
+	result.Files = append(result.Files, page...)
+	return result, nil
+}
+
+func (q EntryQuery) headers() headerFilter {
+	return headerFilter{
+		ImmediateOrigin:      q.ImmediateOrigin,
+		ImmediateDestination: q.ImmediateDestination,
+		CreatedFrom:          q.CreatedFrom,
+		CreatedTo:            q.CreatedTo,
+	}
+}
+
+func (q EntryQuery) validate() error {
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: negative limit %d", ErrInvalidQuery, q.Limit)
+	}
+	if strings.TrimSpace(q.TraceNumber) == "" && strings.TrimSpace(q.AccountNumber) == "" {
+		return fmt.Errorf("%w: traceNumber or accountNumber is required", ErrInvalidQuery)
+	}
+	return q.headers().validate()
+}
+
+func (q EntryQuery) matchesEntry(traceNumber, accountNumber string) bool {
+	if q.TraceNumber != "" && strings.TrimSpace(traceNumber) != strings.TrimSpace(q.TraceNumber) {
+		return false
+	}
+	if q.AccountNumber != "" && strings.TrimSpace(accountNumber) != strings.TrimSpace(q.AccountNumber) {
+		return false
+	}
+	return true
+}
+
+// each calls fn with every entry of file matching the query until fn returns false
+func (q EntryQuery) each(file *ach.File, fn func(EntryResult) bool) bool {
+	for _, b := range file.Batches {
+		for _, entry := range b.GetEntries() {
+			if entry != nil && q.matchesEntry(entry.TraceNumber, entry.DFIAccountNumber) {
+				if !fn(EntryResult{FileID: file.ID, BatchID: b.ID(), Entry: entry}) {
+					return false
+				}
+			}
+		}
+	}
+	for _, b := range file.IATBatches {
+		for _, entry := range b.Entries {
+			if entry != nil && q.matchesEntry(entry.TraceNumber, entry.DFIAccountNumber) {
+				if !fn(EntryResult{FileID: file.ID, BatchID: b.ID, IATEntry: entry}) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// searchEntries returns the entries of files matching query. Files are searched in order of their ID.
+func searchEntries(files []*ach.File, query EntryQuery) ([]EntryResult, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ID < files[j].ID
+	})
+
+	filter := query.headers()
+	out := make([]EntryResult, 0)
+	for _, file := range files {
+		if file == nil || !filter.matchesFile(file) {
+			continue
+		}
+		more := query.each(file, func(result EntryResult) bool {
+			out = append(out, result)
+			return query.Limit <= 0 || len(out) < query.Limit
+		})
+		if !more {
+			break
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,377 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+)
+
+type repositorySQL struct {
+	expiredEvents
+
+	db *sql.DB
+
+	ttl time.Duration
+
+	logger log.Logger
+}
+
+const sqlRepositorySchema = `create table if not exists ach_files(
+	file_id text primary key,
+	immediate_origin text not null,
+	immediate_destination text not null,
+	file_creation_date text not null,
+	stored_at timestamp not null,
+	nacha text not null,
+	metadata text not null
+);`
+
+// NewRepositorySQL is an ach storage repository which saves files into the ach_files table of db.
+// Queries use ? placeholders and are compatible with SQLite. The table is created if it does not exist.
+func NewRepositorySQL(db *sql.DB, ttl time.Duration, logger log.Logger) (Repository, error) {
+	if db == nil {
+		return nil, errors.New("nil database provided")
+	}
+	if _, err := db.Exec(sqlRepositorySchema); err != nil {
+		return nil, fmt.Errorf("creating ach_files table: %w", err)
+	}
+	repo := &repositorySQL{
+		db:     db,
+		ttl:    ttl,
+		logger: logger,
+	}
+
+	if ttl <= 0*time.Second {
+		// Don't run the cleanup if we've disabled the TTL
+		return repo, nil
+	}
+
+	// Run our anon goroutine to cleanup old ACH files
+	go func() {
+		t := time.NewTicker(1 * time.Minute)
+		for range t.C {
+			repo.cleanupOldFiles()
+		}
+	}()
+
+	return repo, nil
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (r *repositorySQL) load(q queryer, id string) (*ach.File, time.Time, error) {
+	var metadata string
+	err := q.QueryRow(`select metadata from ach_files where file_id = ?`, id).Scan(&metadata)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, time.Time{}, ErrNotFound
+		}
+		return nil, time.Time{}, fmt.Errorf("reading file %s: %w", id, err)
+	}
+	return decodeStoredFile([]byte(metadata))
+}
+
+func (r *repositorySQL) update(q queryer, f *ach.File, storedAt time.Time) error {
+	nacha, metadata, err := encodeStoredFile(f, storedAt)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`update ach_files set immediate_origin = ?, immediate_destination = ?, file_creation_date = ?, nacha = ?, metadata = ? where file_id = ?`,
+		strings.TrimSpace(f.Header.ImmediateOrigin), strings.TrimSpace(f.Header.ImmediateDestination), f.Header.FileCreationDate, string(nacha), string(metadata), f.ID)
+	if err != nil {
+		return fmt.Errorf("saving file %s: %w", f.ID, err)
+	}
+	return nil
+}
+
+// ModifyFile loads a file, applies fn and saves the result in one transaction
+func (r *repositorySQL) ModifyFile(fileID string, fn func(file *ach.File) error) error {
+	tx, err := r.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	file, storedAt, err := r.load(tx, fileID)
+	if err != nil {
+		return err
+	}
+	if err := fn(file); err != nil {
+		return err
+	}
+	if err := r.update(tx, file, storedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repositorySQL) StoreFile(f *ach.File) error {
+	if f == nil {
+		return errors.New("nil ACH file provided")
+	}
+	nacha, metadata, err := encodeStoredFile(f, time.Now())
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRow(`select count(*) from ach_files where file_id = ?`, f.ID).Scan(&n); err != nil {
+		return fmt.Errorf("saving file %s: %w", f.ID, err)
+	}
+	if n > 0 {
+		return ErrAlreadyExists
+	}
+	_, err = tx.Exec(`insert into ach_files(file_id, immediate_origin, immediate_destination, file_creation_date, stored_at, nacha, metadata) values (?, ?, ?, ?, ?, ?, ?)`,
+		f.ID, strings.TrimSpace(f.Header.ImmediateOrigin), strings.TrimSpace(f.Header.ImmediateDestination), f.Header.FileCreationDate, time.Now().UTC(), string(nacha), string(metadata))
+	if err != nil {
+		return fmt.Errorf("saving file %s: %w", f.ID, err)
+	}
+	return tx.Commit()
+}
+
+func (r *repositorySQL) UpdateFile(f *ach.File) error {
+	if f == nil {
+		return errors.New("nil ACH file provided")
+	}
+
+	tx, err := r.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, storedAt, err := r.load(tx, f.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.update(tx, f, storedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindFile retrieves a ach.File based on the supplied ID
+func (r *repositorySQL) FindFile(id string) (*ach.File, error) {
+	f, _, err := r.load(r.db, id)
+	return f, err
+}
+
+// FindAllFiles returns all files stored in the ach_files table
+func (r *repositorySQL) FindAllFiles() []*ach.File {
+	files, err := r.findFiles(r.db, `select metadata from ach_files order by stored_at, file_id`)
+	if err != nil {
+		r.logError(err)
+	}
+	return files
+}
+
+// findFiles returns the files of a query selecting the metadata column. Files which can not be read are logged and skipped.
+func (r *repositorySQL) findFiles(q queryer, query string, args ...interface{}) ([]*ach.File, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*ach.File
+	for rows.Next() {
+		var metadata string
+		if err := rows.Scan(&metadata); err != nil {
+			r.logError(err)
+			continue
+		}
+		f, _, err := decodeStoredFile([]byte(metadata))
+		if err != nil {
+			r.logError(err)
+			continue
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// headerWhere returns the where clause and arguments selecting rows of ach_files matching filter
+func headerWhere(filter headerFilter) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	if v := strings.TrimSpace(filter.ImmediateOrigin); v != "" {
+		where, args = append(where, "immediate_origin = ?"), append(args, v)
+	}
+	if v := strings.TrimSpace(filter.ImmediateDestination); v != "" {
+		where, args = append(where, "immediate_destination = ?"), append(args, v)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		where, args = append(where, "file_creation_date >= ?"), append(args, fileCreationDate(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		where, args = append(where, "file_creation_date <= ?"), append(args, fileCreationDate(filter.CreatedTo))
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return ` where ` + strings.Join(where, " and "), args
+}
+
+// QueryFiles returns a page of files from the ach_files table matching query.
+// The origin, destination and creation date are filtered by the database.
+func (r *repositorySQL) QueryFiles(query FileQuery) (FileQueryResult, error) {
+	if err := query.validate(); err != nil {
+		return FileQueryResult{}, err
+	}
+	where, args := headerWhere(query.headers())
+	files, err := r.findFiles(r.db, `select metadata from ach_files`+where+` order by file_id`, args...)
+	if err != nil {
+		return FileQueryResult{}, fmt.Errorf("querying files: %w", err)
+	}
+	return queryFiles(files, query)
+}
+
+// SearchEntries returns entries of files in the ach_files table matching query.
+// The origin, destination and creation date are filtered by the database.
+func (r *repositorySQL) SearchEntries(query EntryQuery) ([]EntryResult, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	where, args := headerWhere(query.headers())
+	files, err := r.findFiles(r.db, `select metadata from ach_files`+where+` order by file_id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching entries: %w", err)
+	}
+	return searchEntries(files, query)
+}
+
+func (r *repositorySQL) DeleteFile(id string) error {
+	if _, err := r.db.Exec(`delete from ach_files where file_id = ?`, id); err != nil {
+		return fmt.Errorf("deleting file %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *repositorySQL) StoreBatch(fileID string, batch ach.Batcher) error {
+	return r.ModifyFile(fileID, func(file *ach.File) error {
+		if hasBatch(file, batch.ID()) {
+			return ErrAlreadyExists
+		}
+		file.AddBatch(batch)
+		return nil
+	})
+}
+
+// FindBatch retrieves a ach.Batcher based on the supplied ID
+func (r *repositorySQL) FindBatch(fileID string, batchID string) (ach.Batcher, error) {
+	file, _, err := r.load(r.db, fileID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	for _, val := range file.Batches {
+		if val.ID() == batchID {
+			return val, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// FindAllBatches
+func (r *repositorySQL) FindAllBatches(fileID string) []ach.Batcher {
+	file, _, err := r.load(r.db, fileID)
+	if err != nil {
+		return nil
+	}
+	return file.Batches
+}
+
+func (r *repositorySQL) DeleteBatch(fileID string, batchID string) error {
+	fileFound := false
+	err := r.ModifyFile(fileID, func(file *ach.File) error {
+		fileFound = true
+		if !removeBatch(file, batchID) {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if !fileFound && errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%v: no file %s with batch %s found", ErrNotFound, fileID, batchID)
+	}
+	return err
+}
+
+// cleanupOldFiles will delete rows from ach_files which are older than the environmental
+// variable ACH_FILE_TTL (parsed as a time.Duration).
+func (r *repositorySQL) cleanupOldFiles() {
+	tooOld, tooOldStr := expiredFileCreationDate(r.ttl)
+
+	expired, err := r.removeOldFiles(tooOldStr)
+	if err != nil {
+		r.logError(fmt.Errorf("removing old ACH files: %w", err))
+		return
+	}
+
+	if r.logger != nil {
+		r.logger.Info().Logf("removed %d ACH files older than %v", len(expired), tooOld.Format(time.RFC3339))
+	}
+	r.publishExpired(expired)
+}
+
+// removeOldFiles deletes the files created before fileCreationDate and returns them. Files are
+// read and deleted by their ID in one transaction so only the files returned are removed.
+func (r *repositorySQL) removeOldFiles(fileCreationDate string) ([]*ach.File, error) {
+	tx, err := r.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	expired, err := r.findFiles(tx, `select metadata from ach_files where file_creation_date < ? order by stored_at, file_id`, fileCreationDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range expired {
+		if _, err := tx.Exec(`delete from ach_files where file_id = ?`, file.ID); err != nil {
+			return nil, fmt.Errorf("deleting file %s: %w", file.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+func (r *repositorySQL) logError(err error) {
+	if r.logger != nil {
+		r.logger.Error().LogError(err)
+	}
+}
@@ -0,0 +1,116 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+)
+
+// storedFile is the JSON metadata persistent repositories save alongside the Nacha text of each file.
+//
+// Files in the repository are often incomplete (e.g. batches without controls) until they are built,
+// so File holds the JSON form which is read back. Nacha is written as-is for operators and other tools.
+type storedFile struct {
+	ID       string    `json:"id"`
+	StoredAt time.Time `json:"storedAt"`
+
+	ValidateOpts *ach.ValidateOpts `json:"validateOpts,omitempty"`
+	File         json.RawMessage   `json:"file"`
+}
+
+// encodeStoredFile returns the Nacha text and JSON metadata of file
+func encodeStoredFile(file *ach.File, storedAt time.Time) ([]byte, []byte, error) {
+	if file == nil {
+		return nil, nil, errors.New("nil ACH file provided")
+	}
+
+	var nacha bytes.Buffer
+	w := ach.NewWriter(&nacha)
+	w.BypassValidation = true
+	if err := w.Write(file); err != nil {
+		return nil, nil, fmt.Errorf("writing file %s: %w", file.ID, err)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, nil, fmt.Errorf("writing file %s: %w", file.ID, err)
+	}
+
+	bs, err := json.Marshal(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding file %s: %w", file.ID, err)
+	}
+	metadata, err := json.Marshal(storedFile{
+		ID:           file.ID,
+		StoredAt:     storedAt.UTC(),
+		ValidateOpts: file.GetValidation(),
+		File:         bs,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding file %s: %w", file.ID, err)
+	}
+	return nacha.Bytes(), metadata, nil
+}
+
+// decodeStoredFile reads the file from its JSON metadata
+func decodeStoredFile(metadata []byte) (*ach.File, time.Time, error) {
+	var stored storedFile
+	if err := json.Unmarshal(metadata, &stored); err != nil {
+		return nil, time.Time{}, fmt.Errorf("decoding stored file: %w", err)
+	}
+
+	// Files are read without validation as they were accepted when stored
+	file, err := ach.FileFromJSONWith(stored.File, &ach.ValidateOpts{SkipAll: true})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("decoding stored file %s: %w", stored.ID, err)
+	}
+	file.ID = stored.ID
+	file.SetValidation(stored.ValidateOpts)
+	return file, stored.StoredAt, nil
+}
+
+// removeBatch deletes the batch from file and returns if it was found
+func removeBatch(file *ach.File, batchID string) bool {
+	for i := len(file.Batches) - 1; i >= 0; i-- {
+		if file.Batches[i].ID() == batchID {
+			file.Batches = append(file.Batches[:i], file.Batches[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// hasBatch returns if file contains a batch with batchID
+func hasBatch(file *ach.File, batchID string) bool {
+	for _, val := range file.Batches {
+		if val.ID() == batchID {
+			return true
+		}
+	}
+	return false
+}
+
+// expiredFileCreationDate returns the FileCreationDate (YYMMDD) files created before are removed by the TTL
+func expiredFileCreationDate(ttl time.Duration) (time.Time, string) {
+	tooOld := time.Now().Add(-1 * ttl)
+	return tooOld, tooOld.Format("060102")
+}
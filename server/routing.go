@@ -28,6 +28,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 	moovhttp "github.com/moov-io/base/http"
 	"github.com/moov-io/base/log"
@@ -121,6 +122,12 @@ func MakeHTTPHandler(s Service, repo Repository, kitlog gokitlog.Logger) http.Ha
 		encodeResponse,
 		options...,
 	))
+	r.Methods("GET").Path("/entries").Handler(httptransport.NewServer(
+		searchEntriesEndpoint(s, logger),
+		decodeSearchEntriesRequest,
+		encodeResponse,
+		options...,
+	))
 	r.Methods("GET").Path("/files/{id}/build").Handler(httptransport.NewServer(
 		buildFileEndpoint(s, repo, logger),
 		decodeBuildFileRequest,
@@ -154,13 +161,13 @@ func MakeHTTPHandler(s Service, repo Repository, kitlog gokitlog.Logger) http.Ha
 	r.Methods("GET").Path("/files/{id}/validate").Handler(httptransport.NewServer(
 		validateFileEndpoint(s, logger),
 		decodeValidateFileRequest,
-		encodeResponse,
+		encodeValidateFileResponse,
 		options...,
 	))
 	r.Methods("POST").Path("/files/{id}/validate").Handler(httptransport.NewServer(
 		validateFileEndpoint(s, logger),
 		decodeValidateFileRequest,
-		encodeResponse,
+		encodeValidateFileResponse,
 		options...,
 	))
 	r.Methods("DELETE").Path("/files/{id}").Handler(httptransport.NewServer(
@@ -187,6 +194,36 @@ func MakeHTTPHandler(s Service, repo Repository, kitlog gokitlog.Logger) http.Ha
 		encodeResponse,
 		options...,
 	))
+	r.Methods("GET").Path("/files/{fileID}/batches/{batchID}/entries").Handler(httptransport.NewServer(
+		getEntriesEndpoint(s, logger),
+		decodeGetEntriesRequest,
+		encodeResponse,
+		options...,
+	))
+	r.Methods("POST").Path("/files/{fileID}/batches/{batchID}/entries").Handler(httptransport.NewServer(
+		createEntryEndpoint(s, logger),
+		decodeCreateEntryRequest,
+		encodeResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/files/{fileID}/batches/{batchID}/entries/{traceNumber}").Handler(httptransport.NewServer(
+		getEntryEndpoint(s, logger),
+		decodeGetEntryRequest,
+		encodeResponse,
+		options...,
+	))
+	r.Methods("PUT").Path("/files/{fileID}/batches/{batchID}/entries/{traceNumber}").Handler(httptransport.NewServer(
+		updateEntryEndpoint(s, logger),
+		decodeUpdateEntryRequest,
+		encodeResponse,
+		options...,
+	))
+	r.Methods("DELETE").Path("/files/{fileID}/batches/{batchID}/entries/{traceNumber}").Handler(httptransport.NewServer(
+		deleteEntryEndpoint(s, logger),
+		decodeDeleteEntryRequest,
+		encodeResponse,
+		options...,
+	))
 	r.Methods("DELETE").Path("/files/{fileID}/batches/{batchID}").Handler(httptransport.NewServer(
 		deleteBatchEndpoint(s, logger),
 		decodeDeleteBatchRequest,
@@ -223,6 +260,24 @@ func MakeHTTPHandler(s Service, repo Repository, kitlog gokitlog.Logger) http.Ha
 		encodeResponse,
 		options...,
 	))
+	r.Methods("POST").Path("/files/{fileID}/return").Handler(httptransport.NewServer(
+		returnFileEndpoint(s, repo, logger),
+		decodeReturnFileRequest,
+		encodeResponse,
+		options...,
+	))
+	r.Methods("POST").Path("/files/{fileID}/correction").Handler(httptransport.NewServer(
+		correctionFileEndpoint(s, repo, logger),
+		decodeCorrectionFileRequest,
+		encodeResponse,
+		options...,
+	))
+	r.Methods("POST").Path("/files/{fileID}/limits").Handler(httptransport.NewServer(
+		checkLimitsEndpoint(s, logger),
+		decodeCheckLimitsRequest,
+		encodeResponse,
+		options...,
+	))
 	r.Methods("POST").Path("/merge").Handler(httptransport.NewServer(
 		mergeFilesEndpoint(s, repo, logger),
 		decodeMergeFilesRequest,
@@ -265,6 +320,9 @@ func marshalStructWithError(in interface{}, w http.ResponseWriter) error {
 		}
 		if err, ok := value.(error); ok {
 			out["error"] = err.Error()
+			if details := errorDetails(err); len(details) > 0 {
+				out["errors"] = details
+			}
 		} else {
 			out[name] = value
 		}
@@ -318,14 +376,29 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(codeFrom(err))
-	err = json.NewEncoder(w).Encode(map[string]interface{}{
+	response := map[string]interface{}{
 		"error": err.Error(),
-	})
+	}
+	if details := errorDetails(err); len(details) > 0 {
+		response["errors"] = details
+	}
+	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
 		w.Write([]byte(fmt.Sprintf("problem rendering json: %v", err)))
 	}
 }
 
+// errorDetails returns the machine readable details of err when it contains ACH validation errors.
+func errorDetails(err error) []ach.ErrorDetail {
+	details := ach.DescribeErrors(err)
+	for i := range details {
+		if details[i].Code != ach.CodeInvalid {
+			return details
+		}
+	}
+	return nil
+}
+
 func codeFrom(err error) int {
 	if err == nil {
 		return http.StatusOK
@@ -334,6 +407,23 @@ func codeFrom(err error) int {
 	if errors.Is(err, ErrRequestBodyTooLarge) {
 		return http.StatusRequestEntityTooLarge
 	}
+	switch {
+	case
+		errors.Is(err, ach.ErrAddenda99ReturnCode),
+		errors.Is(err, ach.ErrReturnNoEntries),
+		errors.Is(err, ach.ErrReturnDateOfDeath),
+		errors.Is(err, ach.ErrAddenda98ChangeCode),
+		errors.Is(err, ach.ErrAddenda98RefusedChangeCode),
+		errors.Is(err, ach.ErrCorrectionNoEntries),
+		errors.Is(err, ach.ErrCorrectionCorrectedData),
+		errors.Is(err, ach.ErrLimitsExceeded),
+		errors.Is(err, ach.ErrSegmentRule),
+		errors.Is(err, ach.ErrDuplicate),
+		errors.Is(err, ErrInvalidQuery),
+		errors.Is(err, ErrInvalidEntry),
+		errors.Is(err, ErrNoSequencer):
+		return http.StatusBadRequest
+	}
 
 	errString := fmt.Sprintf("%#v", err)
 	if el, ok := err.(base.ErrorList); ok {
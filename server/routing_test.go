@@ -117,6 +117,36 @@ func TestEncodeResponse(t *testing.T) {
 	}
 }
 
+func TestEncodeResponse__errorDetails(t *testing.T) {
+	ctx := context.TODO()
+	w := httptest.NewRecorder()
+
+	fileErr := ach.NewErrFileCalculatedControlEquality("EntryHash", 10, 1)
+	if err := encodeResponse(ctx, w, validateFileResponse{Err: fileErr}); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+
+	var resp struct {
+		Error  string            `json:"error"`
+		Errors []ach.ErrorDetail `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != fileErr.Error() {
+		t.Errorf("error: %q", resp.Error)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Code != ach.CodeFileControlTotals {
+		t.Errorf("errors: %#v", resp.Errors)
+	}
+
+	// Errors without a code are left out
+	if details := errorDetails(errors.New("other")); len(details) != 0 {
+		t.Errorf("unexpected details: %#v", details)
+	}
+}
+
 func TestEncodeTextResponse(t *testing.T) {
 	ctx := context.TODO()
 	w := httptest.NewRecorder()
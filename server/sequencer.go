@@ -0,0 +1,60 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/moov-io/ach"
+)
+
+// WithSequencer assigns the FileIDModifier of merged files from seq, along with the FileIDModifier and
+// trace numbers of created files when the request asks for it with sequence=true.
+func WithSequencer(seq ach.Sequencer) ServiceOption {
+	return func(s *service) {
+		s.sequencer = seq
+	}
+}
+
+// ConfigureSequencerFromEnv reads ACH_SEQUENCER (memory or file) and ACH_SEQUENCER_PATH and returns the
+// configured Sequencer and its kind. ACH_SEQUENCER_PATH is required for the file sequencer.
+// A nil Sequencer is returned when ACH_SEQUENCER is unset.
+func ConfigureSequencerFromEnv() (ach.Sequencer, string, error) {
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv("ACH_SEQUENCER")))
+	switch kind {
+	case "":
+		return nil, "", nil
+	case "memory":
+		return ach.NewMemorySequencer(), kind, nil
+	case "file":
+		path := os.Getenv("ACH_SEQUENCER_PATH")
+		if path == "" {
+			return nil, "", errors.New("missing ACH_SEQUENCER_PATH")
+		}
+		seq, err := ach.NewFileSequencer(path)
+		if err != nil {
+			return nil, "", err
+		}
+		return seq, kind, nil
+	default:
+		return nil, "", fmt.Errorf("unknown ACH_SEQUENCER %q", kind)
+	}
+}
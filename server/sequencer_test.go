@@ -0,0 +1,160 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+// createSequencedNachaFile posts the file at path with sequence=true
+func createSequencedNachaFile(t *testing.T, handler http.Handler, path string) (*httptest.ResponseRecorder, createFileResponse) {
+	t.Helper()
+
+	fd, err := os.Open(path)
+	require.NoError(t, err)
+	defer fd.Close()
+
+	req := httptest.NewRequest("POST", "/files/"+base.ID()+"?sequence=true", fd)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	w.Flush()
+
+	var resp createFileResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return w, resp
+}
+
+func TestFiles_Sequencer(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	svc := NewService(repo, WithSequencer(ach.NewMemorySequencer()))
+	handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+	path := filepath.Join("..", "test", "testdata", "ppd-debit.ach")
+
+	// files are only sequenced when asked to
+	w, posted := createNachaFile(t, handler, path)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "A", posted.File.Header.FileIDModifier)
+	require.Equal(t, "121042880000001", posted.File.Batches[0].GetEntries()[0].TraceNumber)
+
+	w, first := createSequencedNachaFile(t, handler, path)
+	require.Equal(t, http.StatusOK, w.Code)
+	w, second := createSequencedNachaFile(t, handler, path)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Equal(t, "A", first.File.Header.FileIDModifier)
+	require.Equal(t, "B", second.File.Header.FileIDModifier)
+	require.Equal(t, "121042880000001", first.File.Batches[0].GetEntries()[0].TraceNumber)
+	require.Equal(t, "121042880000002", second.File.Batches[0].GetEntries()[0].TraceNumber)
+
+	var body bytes.Buffer
+	require.NoError(t, json.NewEncoder(&body).Encode(mergeFilesRequest{
+		FileIDs: []string{first.ID, second.ID},
+	}))
+	req := httptest.NewRequest("POST", "/merge", &body)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp mergeFilesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Files, 1)
+	require.Equal(t, "C", resp.Files[0].Header.FileIDModifier)
+}
+
+func TestFiles_SequencerNotConfigured(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	handler := MakeHTTPHandler(NewService(repo), repo, kitlog.NewNopLogger())
+
+	fd, err := os.Open(filepath.Join("..", "test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	defer fd.Close()
+
+	req := httptest.NewRequest("POST", "/files/"+base.ID()+"?sequence=true", fd)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "no sequencer is configured")
+	require.Empty(t, repo.FindAllFiles())
+}
+
+func TestFiles_SequencerDuplicates(t *testing.T) {
+	repo := NewRepositoryInMemory(testTTLDuration, log.NewNopLogger())
+	svc := NewService(repo,
+		WithSequencer(ach.NewMemorySequencer()),
+		WithDuplicates(ach.NewMemoryDuplicateStore(0), DuplicatesFlag),
+	)
+	handler := MakeHTTPHandler(svc, repo, kitlog.NewNopLogger())
+	path := filepath.Join("..", "test", "testdata", "ppd-debit.ach")
+
+	w, first := createSequencedNachaFile(t, handler, path)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, first.Duplicates)
+
+	// Re-posting the same file is found before sequencing changes its FileIDModifier and trace numbers
+	w, second := createSequencedNachaFile(t, handler, path)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, []ach.Duplicate{
+		{Kind: ach.DuplicateFile, Source: first.ID},
+		{Kind: ach.DuplicateEntry, TraceNumber: "121042880000001", Source: first.ID},
+	}, second.Duplicates)
+	require.Equal(t, "B", second.File.Header.FileIDModifier)
+}
+
+func TestConfigureSequencerFromEnv(t *testing.T) {
+	t.Setenv("ACH_SEQUENCER", "")
+	seq, kind, err := ConfigureSequencerFromEnv()
+	require.NoError(t, err)
+	require.Empty(t, kind)
+	require.Nil(t, seq)
+
+	t.Setenv("ACH_SEQUENCER", "other")
+	_, _, err = ConfigureSequencerFromEnv()
+	require.ErrorContains(t, err, "unknown ACH_SEQUENCER")
+
+	t.Setenv("ACH_SEQUENCER", "file")
+	_, _, err = ConfigureSequencerFromEnv()
+	require.ErrorContains(t, err, "missing ACH_SEQUENCER_PATH")
+
+	t.Setenv("ACH_SEQUENCER_PATH", filepath.Join(t.TempDir(), "sequences.json"))
+	seq, kind, err = ConfigureSequencerFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "file", kind)
+	require.IsType(t, &ach.FileSequencer{}, seq)
+
+	t.Setenv("ACH_SEQUENCER", "memory")
+	seq, kind, err = ConfigureSequencerFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "memory", kind)
+	require.IsType(t, &ach.MemorySequencer{}, seq)
+}
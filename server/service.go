@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/moov-io/ach"
@@ -34,6 +35,10 @@ import (
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrAlreadyExists = errors.New("already exists")
+	ErrInvalidEntry  = errors.New("invalid entry")
+
+	// ErrNoSequencer is returned when sequencing is requested from a Service without a Sequencer
+	ErrNoSequencer = errors.New("sequence requested but no sequencer is configured")
 )
 
 // Service is a REST interface for interacting with ACH file structures
@@ -45,6 +50,10 @@ type Service interface {
 	GetFile(id string) (*ach.File, error)
 	// GetFiles retrieves all files accessible from the client.
 	GetFiles() []*ach.File
+	// QueryFiles retrieves a page of files matching the query
+	QueryFiles(query FileQuery) (FileQueryResult, error)
+	// SearchEntries finds entries across all files by trace number or account number
+	SearchEntries(query EntryQuery) ([]EntryResult, error)
 	// BuildFile tabulates file values according to the Nacha spec
 	BuildFile(id string) (*ach.File, error)
 	// DeleteFile takes a file resource ID and deletes it from the store
@@ -53,12 +62,17 @@ type Service interface {
 	GetFileContents(id string, opts *ach.WriteOpts) (io.Reader, error)
 	// ValidateFile
 	ValidateFile(id string, opts *ach.ValidateOpts) error
-	// BalanceFile will apply a given offset record to the file
+	// BalanceFile will apply a given offset record to each batch of the file, or the entire file
+	// depending on the offset's Strategy
 	BalanceFile(fileID string, off *ach.Offset) (*ach.File, error)
 	// SegmentFileID segments an ach file
 	SegmentFileID(id string, opts *ach.SegmentFileConfiguration) (*ach.File, *ach.File, error)
 	// SegmentFile segments an ach file
 	SegmentFile(file *ach.File, opts *ach.SegmentFileConfiguration) (*ach.File, *ach.File, error)
+	// SegmentFilesID splits an ach file into files according to the SegmentBy rules of opts
+	SegmentFilesID(id string, opts *ach.SegmentFileConfiguration) ([]ach.SegmentedFile, error)
+	// SegmentFiles splits an ach file into files according to the SegmentBy rules of opts
+	SegmentFiles(file *ach.File, opts *ach.SegmentFileConfiguration) ([]ach.SegmentedFile, error)
 	// FlattenBatches will minimize the ach.Batch objects in a file by consolidating EntryDetails under distinct batch headers
 	FlattenBatches(id string) (*ach.File, error)
 	// CreateBatch creates a new batch within and ach file and returns its resource ID
@@ -69,22 +83,106 @@ type Service interface {
 	GetBatches(fileID string) []ach.Batcher
 	// DeleteBatch takes a fileID and BatchID and removes the batch from the file
 	DeleteBatch(fileID string, batchID string) error
+	// GetEntries retrieves the entries of a batch
+	GetEntries(fileID string, batchID string) ([]*ach.EntryDetail, error)
+	// GetEntry retrieves an entry of a batch based on its trace number
+	GetEntry(fileID string, batchID string, traceNumber string) (*ach.EntryDetail, error)
+	// CreateEntry appends an entry to a batch and recomputes the BatchControl and FileControl
+	CreateEntry(fileID string, batchID string, entry *ach.EntryDetail) (*ach.EntryDetail, error)
+	// UpdateEntry replaces an entry of a batch and recomputes the BatchControl and FileControl
+	UpdateEntry(fileID string, batchID string, traceNumber string, entry *ach.EntryDetail) (*ach.EntryDetail, error)
+	// DeleteEntry removes an entry from a batch and recomputes the BatchControl and FileControl
+	DeleteEntry(fileID string, batchID string, traceNumber string) error
 	// MergeFiles will combine all the given files together
 	MergeFiles(fileIDs []string, files []*ach.File, conditions *ach.Conditions) ([]*ach.File, error)
+	// MergeFilesWithManifest will combine all the given files together and describe where each merged entry came from
+	MergeFilesWithManifest(fileIDs []string, files []*ach.File, conditions *ach.Conditions) ([]*ach.File, *ach.MergeManifest, error)
 	// ReverseFile creates a NACHA compliant reversal of the ACH file
 	ReverseFile(fileID string, effectiveEntryDate time.Time) (*ach.File, error)
+	// ReturnFile creates a NACHA compliant return file for the given entries of the ACH file
+	ReturnFile(fileID string, effectiveEntryDate time.Time, entries []ach.ReturnEntry) (*ach.File, error)
+	// CorrectionFile creates a Notification of Change (COR) file for the given entries of the ACH file
+	CorrectionFile(fileID string, effectiveEntryDate time.Time, entries []ach.CorrectionEntry) (*ach.File, error)
+	// RefusedCorrectionFile creates a refused Notification of Change (COR) file for the given entries of the ACH file
+	RefusedCorrectionFile(fileID string, effectiveEntryDate time.Time, entries []ach.RefusedCorrectionEntry) (*ach.File, error)
+	// CheckLimits evaluates the ach file against origination limits, or those of the Service when nil,
+	// and returns each limit exceeded
+	CheckLimits(fileID string, limits *ach.Limits) ([]ach.LimitViolation, error)
 }
 
 // service a concrete implementation of the service.
 type service struct {
-	store Repository
+	store      Repository
+	events     *Events
+	sequencer  ach.Sequencer
+	duplicates *duplicateDetection
+	limits     *ach.Limits
 }
 
+// ServiceOption configures a Service created by NewService
+type ServiceOption func(s *service)
+
 // NewService creates a new concrete service
-func NewService(r Repository) Service {
-	return &service{
+func NewService(r Repository, opts ...ServiceOption) Service {
+	s := &service{
 		store: r,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if repo, ok := r.(interface{ setEvents(*Events) }); ok && s.events != nil {
+		repo.setEvents(s.events)
+	}
+	return s
+}
+
+// NewServiceWithEvents creates a new concrete service which emits an Event to the subscribers of events
+// after each operation. Files removed by the TTL cleanup of r are emitted as EventFileExpired.
+func NewServiceWithEvents(r Repository, events *Events, opts ...ServiceOption) Service {
+	return NewService(r, append([]ServiceOption{WithEvents(events)}, opts...)...)
+}
+
+// WithEvents emits an Event to the subscribers of events after each operation of the Service
+func WithEvents(events *Events) ServiceOption {
+	return func(s *service) {
+		s.events = events
+	}
+}
+
+// storeCreatedFile checks file against limits and for duplicates, sequences it when asked to and stores it. Files are checked
+// as they were posted and recorded under their ID both as checked and as stored, but only once stored.
+// stored reports if the file was saved, as duplicates can fail to be recorded afterwards.
+// A *ach.LimitsError or *ach.DuplicatesError is returned when rejecting file.
+func (s *service) storeCreatedFile(file *ach.File, sequence bool) (dups []ach.Duplicate, stored bool, err error) {
+	if err := s.limits.Check(file); err != nil {
+		return nil, false, err
+	}
+	checked, dups, err := s.duplicates.reserve(file)
+	if err != nil {
+		return dups, false, err
+	}
+	if sequence {
+		if err := file.Sequence(s.sequencer); err != nil {
+			s.duplicates.release(file.ID)
+			return dups, false, err
+		}
+	}
+	if err := s.store.StoreFile(file); err != nil {
+		s.duplicates.release(file.ID)
+		return dups, false, err
+	}
+	if checked != nil {
+		return dups, true, s.duplicates.record(file.ID, checked, file)
+	}
+	return dups, true, nil
+}
+
+// asService returns the concrete service behind s, or one using r when s is another implementation
+func asService(s Service, r Repository) *service {
+	if svc, ok := s.(*service); ok {
+		return svc
+	}
+	return &service{store: r}
 }
 
 // CreateFile add a file to storage
@@ -107,6 +205,7 @@ func (s *service) CreateFile(fh *ach.FileHeader) (string, error) {
 	if err := s.store.StoreFile(f); err != nil {
 		return "", err
 	}
+	s.events.publishFile(EventFileCreated, f)
 	return f.ID, nil
 }
 
@@ -123,6 +222,14 @@ func (s *service) GetFiles() []*ach.File {
 	return s.store.FindAllFiles()
 }
 
+func (s *service) QueryFiles(query FileQuery) (FileQueryResult, error) {
+	return s.store.QueryFiles(query)
+}
+
+func (s *service) SearchEntries(query EntryQuery) ([]EntryResult, error) {
+	return s.store.SearchEntries(query)
+}
+
 // BuildFile tabulates file values according to the Nacha spec
 func (s *service) BuildFile(id string) (*ach.File, error) {
 	original, err := s.GetFile(id)
@@ -137,11 +244,23 @@ func (s *service) BuildFile(id string) (*ach.File, error) {
 	}
 
 	err = file.Create()
+	if err == nil {
+		s.events.publishFile(EventFileBuilt, file)
+	}
 	return file, err
 }
 
 func (s *service) DeleteFile(id string) error {
-	return s.store.DeleteFile(id)
+	// Read the file first so the event can describe it
+	file, _ := s.store.FindFile(id)
+
+	if err := s.store.DeleteFile(id); err != nil {
+		return err
+	}
+	if file != nil {
+		s.events.publishFile(EventFileDeleted, file)
+	}
+	return nil
 }
 
 func (s *service) GetFileContents(id string, opts *ach.WriteOpts) (io.Reader, error) {
@@ -181,7 +300,19 @@ func (s *service) ValidateFile(id string, opts *ach.ValidateOpts) error {
 	if err != nil {
 		return fmt.Errorf("problem reading file %s: %w", id, err)
 	}
-	return f.ValidateWith(opts)
+
+	err = f.ValidateWith(opts)
+
+	event := Event{
+		Type: EventFileValidated,
+		File: summarizeFile(f),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	s.events.publish(event)
+
+	return err
 }
 
 func (s *service) CreateBatch(fileID string, batch ach.Batcher) (string, error) {
@@ -197,6 +328,9 @@ func (s *service) CreateBatch(fileID string, batch ach.Batcher) (string, error)
 		batch.SetID(batch.GetHeader().ID)
 		batch.GetControl().ID = batch.GetHeader().ID
 	}
+	if err := s.checkBatchLimits(fileID, batch); err != nil {
+		return "", err
+	}
 	if err := s.store.StoreBatch(fileID, batch); err != nil {
 		return "", err
 	}
@@ -219,6 +353,116 @@ func (s *service) DeleteBatch(fileID string, batchID string) error {
 	return s.store.DeleteBatch(fileID, batchID)
 }
 
+func (s *service) GetEntries(fileID string, batchID string) ([]*ach.EntryDetail, error) {
+	b, err := s.GetBatch(fileID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetEntries(), nil
+}
+
+func (s *service) GetEntry(fileID string, batchID string, traceNumber string) (*ach.EntryDetail, error) {
+	b, err := s.GetBatch(fileID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if entry := ach.GetEntry(b, traceNumber); entry != nil {
+		return entry, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *service) CreateEntry(fileID string, batchID string, entry *ach.EntryDetail) (*ach.EntryDetail, error) {
+	if entry == nil {
+		return nil, fmt.Errorf("%w: no entry provided", ErrInvalidEntry)
+	}
+	if strings.TrimSpace(entry.TraceNumber) == "" {
+		return nil, fmt.Errorf("%w: TraceNumber is required", ErrInvalidEntry)
+	}
+	err := s.modifyBatch(fileID, batchID, func(b ach.Batcher) error {
+		if ach.GetEntry(b, entry.TraceNumber) != nil {
+			return ErrAlreadyExists
+		}
+		b.AddEntry(entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *service) UpdateEntry(fileID string, batchID string, traceNumber string, entry *ach.EntryDetail) (*ach.EntryDetail, error) {
+	if entry == nil {
+		return nil, fmt.Errorf("%w: no entry provided", ErrInvalidEntry)
+	}
+	// Keep the trace number of the replaced entry unless it's renumbered
+	traceNumber = strings.TrimSpace(traceNumber)
+	if strings.TrimSpace(entry.TraceNumber) == "" {
+		entry.TraceNumber = traceNumber
+	}
+	err := s.modifyBatch(fileID, batchID, func(b ach.Batcher) error {
+		if ach.GetEntry(b, traceNumber) == nil {
+			return ErrNotFound
+		}
+		if strings.TrimSpace(entry.TraceNumber) != traceNumber && ach.GetEntry(b, entry.TraceNumber) != nil {
+			return ErrAlreadyExists
+		}
+		return ach.UpdateEntry(b, traceNumber, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *service) DeleteEntry(fileID string, batchID string, traceNumber string) error {
+	return s.modifyBatch(fileID, batchID, func(b ach.Batcher) error {
+		if ach.GetEntry(b, traceNumber) == nil {
+			return ErrNotFound
+		}
+		trace := strings.TrimSpace(traceNumber)
+		b.DeleteEntries(func(e *ach.EntryDetail) bool {
+			return e != nil && strings.TrimSpace(e.TraceNumber) == trace
+		})
+		return nil
+	})
+}
+
+// modifyBatch applies fn to a batch of a copy of the stored file, recomputes the BatchControl and FileControl
+// and replaces the stored file. Concurrent modifications of the file are applied one after another and
+// the stored file is left as-is if any step fails.
+func (s *service) modifyBatch(fileID string, batchID string, fn func(b ach.Batcher) error) error {
+	return s.store.ModifyFile(fileID, func(file *ach.File) error {
+		var batch ach.Batcher
+		for _, b := range file.Batches {
+			if b.ID() == batchID {
+				batch = b
+				break
+			}
+		}
+		if batch == nil {
+			return ErrNotFound
+		}
+		// Batches are validated with the file's options rather than those used for reading
+		batch.SetValidation(file.GetValidation())
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if err := batch.Create(); err != nil {
+			return err
+		}
+		if err := batch.Validate(); err != nil {
+			return err
+		}
+		if err := file.Create(); err != nil {
+			return err
+		}
+		return s.limits.Check(file)
+	})
+}
+
 func (s *service) BalanceFile(fileID string, off *ach.Offset) (*ach.File, error) {
 	original, err := s.GetFile(fileID)
 	if err != nil {
@@ -234,12 +478,9 @@ func (s *service) BalanceFile(fileID string, off *ach.Offset) (*ach.File, error)
 	if err := f.Create(); err != nil {
 		return nil, err
 	}
-	// Apply the Offset to each Batch and then re-create (to tabulate new EntryDetail records)
-	for i := range f.Batches {
-		f.Batches[i].WithOffset(off)
-		if err := f.Batches[i].Create(); err != nil {
-			return nil, err
-		}
+	// Apply the Offset to each Batch, or the entire File, and then re-create (to tabulate new EntryDetail records)
+	if err := f.Balance(off); err != nil {
+		return nil, err
 	}
 	f.ID = base.ID() // overwrite the ID so it's new and unique
 	if err := f.Create(); err != nil {
@@ -282,6 +523,31 @@ func (s *service) SegmentFile(file *ach.File, opts *ach.SegmentFileConfiguration
 	return creditFile, debitFile, nil
 }
 
+// SegmentFilesID splits an ach file into files according to the SegmentBy rules of opts
+func (s *service) SegmentFilesID(fileID string, opts *ach.SegmentFileConfiguration) ([]ach.SegmentedFile, error) {
+	original, err := s.GetFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clone the file to avoid mutating the original in the repository
+	f, err := cloneFile(original)
+	if err != nil {
+		return nil, fmt.Errorf("cloning file: %w", err)
+	}
+
+	return s.SegmentFiles(f, opts)
+}
+
+// SegmentFiles splits an ach file into files according to the SegmentBy rules of opts
+func (s *service) SegmentFiles(file *ach.File, opts *ach.SegmentFileConfiguration) ([]ach.SegmentedFile, error) {
+	// Build/tabulate file in the case it is malformed.
+	if err := file.Create(); err != nil {
+		return nil, err
+	}
+	return file.SegmentFiles(opts)
+}
+
 // FlattenBatches consolidates batches that have the same BatchHeader
 func (s *service) FlattenBatches(fileID string) (*ach.File, error) {
 	original, err := s.GetFile(fileID)
@@ -306,38 +572,80 @@ func (s *service) FlattenBatches(fileID string) (*ach.File, error) {
 	return ff, err
 }
 
+// MergeFiles will combine all the given files together
 func (s *service) MergeFiles(fileIDs []string, files []*ach.File, conditions *ach.Conditions) ([]*ach.File, error) {
+	merged, _, err := s.mergeFiles(fileIDs, files, conditions, false)
+	return merged, err
+}
+
+// MergeFilesWithManifest will combine all the given files together and describe where each merged entry came from
+func (s *service) MergeFilesWithManifest(fileIDs []string, files []*ach.File, conditions *ach.Conditions) ([]*ach.File, *ach.MergeManifest, error) {
+	return s.mergeFiles(fileIDs, files, conditions, true)
+}
+
+func (s *service) mergeFiles(fileIDs []string, files []*ach.File, conditions *ach.Conditions, withManifest bool) ([]*ach.File, *ach.MergeManifest, error) {
 	for idx := range fileIDs {
 		file, err := s.store.FindFile(fileIDs[idx])
 		if err != nil {
-			return nil, fmt.Errorf("file not found: %v", fileIDs[idx])
+			return nil, nil, fmt.Errorf("file not found: %v", fileIDs[idx])
 		}
 
 		files = append(files, file)
 	}
 
+	if conditions == nil {
+		conditions = &ach.Conditions{
+			MaxLines: ach.NACHAFileLineLimit,
+		}
+	}
+	if seq := s.sequencer; seq != nil {
+		c := *conditions
+		c.Sequencer = seq
+		conditions = &c
+	}
+
 	var merged []*ach.File
+	var manifest *ach.MergeManifest
 	var err error
-	if conditions != nil {
-		merged, err = ach.MergeFilesWith(files, *conditions)
+	if withManifest {
+		merged, manifest, err = ach.MergeFilesWithManifest(files, *conditions)
 	} else {
-		merged, err = ach.MergeFiles(files)
+		merged, err = ach.MergeFilesWith(files, *conditions)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("merging files: %w", err)
+		return nil, nil, fmt.Errorf("merging files: %w", err)
+	}
+	if err := s.limits.Check(merged...); err != nil {
+		return nil, nil, fmt.Errorf("merging files: %w", err)
 	}
 
 	for idx := range merged {
 		var buf bytes.Buffer
 		err := ach.NewWriter(&buf).Write(merged[idx])
 		if err != nil {
-			return nil, fmt.Errorf("problem hashing merged file: %w", err)
+			return nil, nil, fmt.Errorf("problem hashing merged file: %w", err)
 		}
 
 		merged[idx].ID = hash(buf.Bytes())
+		if manifest != nil {
+			manifest.Files[idx].FileID = merged[idx].ID
+		}
 	}
 
-	return merged, nil
+	event := Event{
+		Type: EventFilesMerged,
+	}
+	for _, file := range files {
+		if file != nil && file.ID != "" {
+			event.SourceFileIDs = append(event.SourceFileIDs, file.ID)
+		}
+	}
+	for _, file := range merged {
+		event.Files = append(event.Files, *summarizeFile(file))
+	}
+	s.events.publish(event)
+
+	return merged, manifest, nil
 }
 
 func hash(data []byte) string {
@@ -367,9 +675,74 @@ func (s *service) ReverseFile(fileID string, effectiveEntryDate time.Time) (*ach
 		return nil, err
 	}
 	cloned.ID = base.ID() // new ID for reversed file
+
+	s.events.publish(Event{
+		Type:          EventFileReversed,
+		File:          summarizeFile(&cloned),
+		SourceFileIDs: []string{fileID},
+	})
+
 	return &cloned, nil
 }
 
+// ReturnFile creates a NACHA compliant return file for the given entries of the ACH file
+func (s *service) ReturnFile(fileID string, effectiveEntryDate time.Time, entries []ach.ReturnEntry) (*ach.File, error) {
+	f, err := s.GetFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	returned, err := f.Return(effectiveEntryDate, entries)
+	if err != nil {
+		return nil, err
+	}
+	returned.ID = base.ID() // new ID for returned file
+	return returned, nil
+}
+
+// CorrectionFile creates a Notification of Change (COR) file for the given entries of the ACH file
+func (s *service) CorrectionFile(fileID string, effectiveEntryDate time.Time, entries []ach.CorrectionEntry) (*ach.File, error) {
+	f, err := s.GetFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	cor, err := f.Correction(effectiveEntryDate, entries)
+	if err != nil {
+		return nil, err
+	}
+	cor.ID = base.ID() // new ID for NOC file
+	return cor, nil
+}
+
+// CheckLimits evaluates the ach file against origination limits and returns each limit exceeded.
+// The limits of the service are used when limits is nil.
+func (s *service) CheckLimits(fileID string, limits *ach.Limits) ([]ach.LimitViolation, error) {
+	f, err := s.GetFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if limits == nil {
+		limits = s.limits
+	}
+	return limits.Violations(f), nil
+}
+
+// RefusedCorrectionFile creates a refused Notification of Change (COR) file for the given entries of the ACH file
+func (s *service) RefusedCorrectionFile(fileID string, effectiveEntryDate time.Time, entries []ach.RefusedCorrectionEntry) (*ach.File, error) {
+	f, err := s.GetFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	refused, err := f.RefusedCorrection(effectiveEntryDate, entries)
+	if err != nil {
+		return nil, err
+	}
+	refused.ID = base.ID() // new ID for refused NOC file
+	return refused, nil
+}
+
 // cloneFile creates a deep copy of the file via JSON serialization.
 // This prevents mutations to the returned file from affecting the original in the repository.
 // JSON is used instead of ACH Writer/Reader because it can handle files that haven't been built yet.
@@ -54,6 +54,10 @@ const (
 	bypassBatchValidation            = "bypassBatchValidation"
 	skipFileCreationValidation       = "skipFileCreationValidation"
 	skipBatchHeaderCompanyValidation = "skipBatchHeaderCompanyValidation"
+	collectAllErrors                 = "collectAllErrors"
+
+	requireBankingDayEffectiveEntryDate = "requireBankingDayEffectiveEntryDate"
+	validateTXP                         = "validateTXP"
 )
 
 // readValidateOpts parses ValidateOpts from the URL query parameters and from the request body.
@@ -87,6 +91,9 @@ func readValidateOpts(request *http.Request) (io.ReadCloser, *ach.ValidateOpts,
 		bypassBatchValidation,
 		skipFileCreationValidation,
 		skipBatchHeaderCompanyValidation,
+		collectAllErrors,
+		requireBankingDayEffectiveEntryDate,
+		validateTXP,
 	}
 
 	bs, err := readBody(request.Body)
@@ -156,6 +163,12 @@ func readValidateOpts(request *http.Request) (io.ReadCloser, *ach.ValidateOpts,
 			opts.SkipFileCreationValidation = yes
 		case skipBatchHeaderCompanyValidation:
 			opts.SkipBatchHeaderCompanyValidation = yes
+		case collectAllErrors:
+			opts.CollectAllErrors = yes
+		case requireBankingDayEffectiveEntryDate:
+			opts.RequireBankingDayEffectiveEntryDate = yes
+		case validateTXP:
+			opts.ValidateTXP = yes
 		}
 	}
 
@@ -29,9 +29,10 @@ func TestReadValidateOpts(t *testing.T) {
 	body := strings.NewReader(`{
   "bypassOriginValidation":true,
   "bypassDestinationValidation":true,
-  "allowUnorderedBatchNumbers":true
+  "allowUnorderedBatchNumbers":true,
+  "maxEffectiveEntryDateDays":30
 }`)
-	req, err := http.NewRequest("POST", "/files/f1/validate?bypassDestination=false&allowInvalidCheckDigit=true&skipBatchHeaderCompanyValidation=true", body)
+	req, err := http.NewRequest("POST", "/files/f1/validate?bypassDestination=false&allowInvalidCheckDigit=true&skipBatchHeaderCompanyValidation=true&requireBankingDayEffectiveEntryDate=true&validateTXP=true", body)
 	require.NoError(t, err)
 
 	_, opts, err := readValidateOpts(req)
@@ -42,4 +43,7 @@ func TestReadValidateOpts(t *testing.T) {
 	require.True(t, opts.AllowUnorderedBatchNumbers)
 	require.True(t, opts.AllowInvalidCheckDigit)
 	require.True(t, opts.SkipBatchHeaderCompanyValidation)
+	require.True(t, opts.RequireBankingDayEffectiveEntryDate)
+	require.True(t, opts.ValidateTXP)
+	require.Equal(t, 30, opts.MaxEffectiveEntryDateDays)
 }
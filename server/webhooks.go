@@ -0,0 +1,288 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base/log"
+)
+
+const (
+	// WebhookSignatureHeader contains the hex encoded HMAC-SHA256 of the timestamp, a period and the request body.
+	WebhookSignatureHeader = "X-ACH-Signature"
+	// WebhookTimestampHeader contains the Unix time a delivery attempt was signed at.
+	WebhookTimestampHeader = "X-ACH-Timestamp"
+	// WebhookEventHeader contains the EventType of the delivered Event.
+	WebhookEventHeader = "X-ACH-Event"
+)
+
+// WebhookConfig configures the delivery of events to HTTP endpoints.
+type WebhookConfig struct {
+	// URLs each receive a POST with the JSON encoded Event
+	URLs []string
+
+	// Secret signs each delivery. Deliveries are unsigned when empty.
+	Secret string
+
+	// MaxRetries is how many times a failed delivery is retried. Defaults to 5 when nil,
+	// a pointer to zero disables retries.
+	MaxRetries *int
+
+	// Backoff is the delay before the first retry, which doubles after each attempt
+	// up to MaxBackoff. Defaults to 1s and 1m.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// QueueSize is how many events can wait for delivery to each URL. Defaults to 1000.
+	QueueSize int
+
+	// Client sends each delivery. Defaults to a client with a 10s timeout.
+	Client *http.Client
+}
+
+// WebhookSubscriber is a Subscriber which POSTs events to each configured URL.
+//
+// Events are delivered to each URL in order from a background goroutine. Events are
+// dropped (and logged) when the queue for a URL is full or all retries have failed.
+type WebhookSubscriber struct {
+	config WebhookConfig
+	logger log.Logger
+
+	mu     sync.RWMutex // held while queueing so queues are not closed underneath Notify
+	queues []chan Event
+	wg     sync.WaitGroup
+
+	closed bool
+	done   chan struct{}
+}
+
+// NewWebhookSubscriber starts delivering events to config.URLs. Close should be called to stop delivery.
+func NewWebhookSubscriber(config WebhookConfig, logger log.Logger) (*WebhookSubscriber, error) {
+	if len(config.URLs) == 0 {
+		return nil, errors.New("no webhook URLs provided")
+	}
+	for _, u := range config.URLs {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return nil, fmt.Errorf("invalid webhook URL %q", u)
+		}
+	}
+	if config.MaxRetries == nil {
+		retries := 5
+		config.MaxRetries = &retries
+	}
+	if *config.MaxRetries < 0 {
+		return nil, fmt.Errorf("negative webhook MaxRetries %d", *config.MaxRetries)
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1000
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	w := &WebhookSubscriber{
+		config: config,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	for _, u := range config.URLs {
+		queue := make(chan Event, config.QueueSize)
+		w.queues = append(w.queues, queue)
+
+		w.wg.Add(1)
+		go w.deliverAll(u, queue)
+	}
+	return w, nil
+}
+
+// Notify queues event for delivery to each URL
+func (w *WebhookSubscriber) Notify(event Event) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return
+	}
+	for i, queue := range w.queues {
+		select {
+		case queue <- event:
+		default:
+			w.logError(fmt.Errorf("webhook queue for %s is full, dropping %s event %s", w.config.URLs[i], event.Type, event.ID))
+		}
+	}
+}
+
+// Close stops delivery once queued events have been attempted, or waiting retries are interrupted.
+func (w *WebhookSubscriber) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.done)
+		for _, queue := range w.queues {
+			close(queue)
+		}
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return nil
+}
+
+func (w *WebhookSubscriber) deliverAll(url string, queue chan Event) {
+	defer w.wg.Done()
+
+	for event := range queue {
+		if err := w.deliver(url, event); err != nil {
+			w.logError(err)
+		}
+	}
+}
+
+// deliver sends event to url, retrying with exponential backoff
+func (w *WebhookSubscriber) deliver(url string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding %s event %s: %w", event.Type, event.ID, err)
+	}
+
+	backoff := w.config.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.send(url, event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= *w.config.MaxRetries {
+			return fmt.Errorf("delivering %s event %s to %s after %d attempts: %w", event.Type, event.ID, url, attempt+1, err)
+		}
+
+		select {
+		case <-w.done:
+			// keep attempting queued events on Close, but without waiting
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, w.config.MaxBackoff)
+	}
+}
+
+// send makes one delivery attempt and returns if a failure can be retried
+func (w *WebhookSubscriber) send(url string, event Event, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(event.Type))
+	if w.config.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(w.config.Secret, timestamp, body))
+	}
+
+	resp, err := w.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+	return false, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+}
+
+func (w *WebhookSubscriber) logError(err error) {
+	if w.logger != nil {
+		w.logger.Error().LogError(err)
+	}
+}
+
+// SignWebhook returns the hex encoded HMAC-SHA256 of timestamp, a period and body using secret.
+// Receivers compare it to the X-ACH-Signature header with VerifyWebhook.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook returns if signature was created by SignWebhook with secret for timestamp and body.
+// Receivers should also reject timestamps which are too old to prevent replays.
+func VerifyWebhook(secret, timestamp string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(SignWebhook(secret, timestamp, body))
+	if err != nil {
+		return false
+	}
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, actual)
+}
+
+// ConfigureWebhooksFromEnv reads ACH_WEBHOOK_URLS (comma separated), ACH_WEBHOOK_SECRET and
+// ACH_WEBHOOK_MAX_RETRIES to deliver the events published to events to webhooks. The returned
+// WebhookSubscriber should be closed on shutdown and is nil when no URLs are configured.
+func ConfigureWebhooksFromEnv(events *Events, logger log.Logger) (*WebhookSubscriber, error) {
+	var config WebhookConfig
+	for _, u := range strings.Split(os.Getenv("ACH_WEBHOOK_URLS"), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			config.URLs = append(config.URLs, u)
+		}
+	}
+	if len(config.URLs) == 0 {
+		return nil, nil
+	}
+	config.Secret = os.Getenv("ACH_WEBHOOK_SECRET")
+	if v := os.Getenv("ACH_WEBHOOK_MAX_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ACH_WEBHOOK_MAX_RETRIES: %w", err)
+		}
+		config.MaxRetries = &n
+	}
+
+	sub, err := NewWebhookSubscriber(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	events.Subscribe(sub)
+	return sub, nil
+}
@@ -0,0 +1,201 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"file.created"}`)
+	signature := SignWebhook("secret", "1700000000", body)
+
+	require.True(t, VerifyWebhook("secret", "1700000000", body, signature))
+	require.False(t, VerifyWebhook("other", "1700000000", body, signature))
+	require.False(t, VerifyWebhook("secret", "1700000001", body, signature))
+	require.False(t, VerifyWebhook("secret", "1700000000", []byte(`{}`), signature))
+	require.False(t, VerifyWebhook("secret", "1700000000", body, "not-hex"))
+}
+
+func TestNewWebhookSubscriber__errors(t *testing.T) {
+	_, err := NewWebhookSubscriber(WebhookConfig{}, log.NewNopLogger())
+	require.Error(t, err)
+
+	_, err = NewWebhookSubscriber(WebhookConfig{URLs: []string{"ftp://example.com"}}, log.NewNopLogger())
+	require.Error(t, err)
+
+	negative := -1
+	_, err = NewWebhookSubscriber(WebhookConfig{URLs: []string{"http://example.com"}, MaxRetries: &negative}, log.NewNopLogger())
+	require.Error(t, err)
+}
+
+func TestWebhookSubscriber__deliver(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		timestamp := r.Header.Get(WebhookTimestampHeader)
+		if !VerifyWebhook("secret", timestamp, body, r.Header.Get(WebhookSignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, string(EventFileCreated), r.Header.Get(WebhookEventHeader))
+
+		var event Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		received <- event
+	}))
+	defer server.Close()
+
+	sub, err := NewWebhookSubscriber(WebhookConfig{
+		URLs:   []string{server.URL},
+		Secret: "secret",
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	sub.Notify(Event{ID: "foo", Type: EventFileCreated, File: &FileSummary{ID: "bar"}})
+	require.NoError(t, sub.Close())
+
+	select {
+	case event := <-received:
+		require.Equal(t, "foo", event.ID)
+		require.Equal(t, "bar", event.File.ID)
+	default:
+		t.Fatal("expected event to be delivered")
+	}
+
+	// events after Close are dropped
+	sub.Notify(Event{ID: "dropped", Type: EventFileCreated})
+	require.NoError(t, sub.Close())
+}
+
+func TestWebhookSubscriber__retries(t *testing.T) {
+	cases := []struct {
+		name       string
+		status     int
+		maxRetries int
+		attempts   int32
+	}{
+		{"server error", http.StatusInternalServerError, 2, 3},
+		{"too many requests", http.StatusTooManyRequests, 2, 3},
+		{"bad request", http.StatusBadRequest, 2, 1},
+		{"retries disabled", http.StatusInternalServerError, 0, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			sub, err := NewWebhookSubscriber(WebhookConfig{
+				URLs:       []string{server.URL},
+				MaxRetries: &tc.maxRetries,
+				Backoff:    time.Millisecond,
+			}, log.NewNopLogger())
+			require.NoError(t, err)
+
+			sub.Notify(Event{ID: "foo", Type: EventFileDeleted})
+			require.Eventually(t, func() bool {
+				return attempts.Load() >= tc.attempts
+			}, time.Second, time.Millisecond)
+			require.NoError(t, sub.Close())
+
+			require.Equal(t, tc.attempts, attempts.Load())
+		})
+	}
+
+	t.Run("recovers", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+
+		sub, err := NewWebhookSubscriber(WebhookConfig{
+			URLs:    []string{server.URL},
+			Backoff: time.Millisecond,
+		}, log.NewNopLogger())
+		require.NoError(t, err)
+
+		sub.Notify(Event{ID: "foo", Type: EventFileDeleted})
+		require.Eventually(t, func() bool {
+			return attempts.Load() == 2
+		}, time.Second, time.Millisecond)
+		require.NoError(t, sub.Close())
+
+		require.Equal(t, int32(2), attempts.Load())
+	})
+}
+
+func TestConfigureWebhooksFromEnv(t *testing.T) {
+	t.Run("unset", func(t *testing.T) {
+		t.Setenv("ACH_WEBHOOK_URLS", "")
+
+		sub, err := ConfigureWebhooksFromEnv(NewEvents(), log.NewNopLogger())
+		require.NoError(t, err)
+		require.Nil(t, sub)
+	})
+
+	t.Run("invalid retries", func(t *testing.T) {
+		t.Setenv("ACH_WEBHOOK_URLS", "http://localhost:9999/hook")
+		t.Setenv("ACH_WEBHOOK_MAX_RETRIES", "many")
+
+		_, err := ConfigureWebhooksFromEnv(NewEvents(), log.NewNopLogger())
+		require.ErrorContains(t, err, "ACH_WEBHOOK_MAX_RETRIES")
+	})
+
+	t.Run("subscribes", func(t *testing.T) {
+		received := make(chan string, 2)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received <- r.URL.Path
+		}))
+		defer server.Close()
+
+		t.Setenv("ACH_WEBHOOK_URLS", server.URL+"/one, "+server.URL+"/two")
+		t.Setenv("ACH_WEBHOOK_SECRET", "secret")
+		t.Setenv("ACH_WEBHOOK_MAX_RETRIES", "1")
+
+		events := NewEvents()
+		sub, err := ConfigureWebhooksFromEnv(events, log.NewNopLogger())
+		require.NoError(t, err)
+		require.NotNil(t, sub)
+		require.Equal(t, []string{server.URL + "/one", server.URL + "/two"}, sub.config.URLs)
+		require.Equal(t, 1, *sub.config.MaxRetries)
+
+		events.publish(Event{Type: EventFileCreated})
+		require.NoError(t, sub.Close())
+
+		require.ElementsMatch(t, []string{"/one", "/two"}, []string{<-received, <-received})
+	})
+}
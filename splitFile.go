@@ -0,0 +1,173 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moov-io/base"
+)
+
+var (
+	// ErrSplitRule is returned when a SplitFileConfiguration has an unknown SplitRule or no way to split a File
+	ErrSplitRule = errors.New("unknown split rule")
+
+	// ErrSplitTooManyFiles is returned when File.SplitFiles produces more Files than there are FileIDModifiers
+	ErrSplitTooManyFiles = errors.New("too many split files for unique FileIDModifiers")
+)
+
+// SplitRule is a way of grouping batches into separate Files
+type SplitRule string
+
+const (
+	// SplitByCompanyIdentification separates batches by their CompanyIdentification (or OriginatorIdentification for IAT).
+	SplitByCompanyIdentification SplitRule = "companyIdentification"
+	// SplitByODFI separates batches by their ODFIIdentification.
+	SplitByODFI SplitRule = "odfi"
+)
+
+// SplitFileConfiguration controls how File.SplitFiles separates the batches of a File.
+type SplitFileConfiguration struct {
+	// SplitBy is the rule used to group batches into Files.
+	SplitBy SplitRule `json:"splitBy,omitempty"`
+
+	// KeyFunc is an optional caller-supplied rule used instead of SplitBy. Batches which return different
+	// keys are placed in different Files. Only the BatchHeader or IATBatchHeader field is set on each record.
+	KeyFunc func(record *IteratorRecord) string `json:"-"`
+
+	// Conditions are applied to the Files of each key the same as MergeFilesWith, except
+	// PreserveBatches is always set unless MergeBatches is true.
+	Conditions Conditions `json:"conditions"`
+
+	// MergeBatches consolidates batches with equal headers within each File instead of keeping
+	// every batch of the original File separate.
+	MergeBatches bool `json:"mergeBatches,omitempty"`
+}
+
+// SplitFile is one of the Files returned by File.SplitFiles
+type SplitFile struct {
+	// Key is the value of SplitBy or KeyFunc shared by every batch in File
+	Key string `json:"key"`
+
+	File *File `json:"file"`
+}
+
+// fileIDModifiers are assigned in order to the Files returned by File.SplitFiles
+const fileIDModifiers = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// SplitFiles is the inverse of MergeFilesWith. Batches are grouped by SplitBy (or KeyFunc) and each group is
+// merged into Files with their own FileHeader, FileControl, batch numbers and totals. Each batch is kept
+// as-is unless MergeBatches is set.
+//
+// Files are returned in the order each key is first found. Each key can produce multiple Files when
+// Conditions (e.g. MaxLines) are exceeded. FileIDModifier is assigned from A-Z then 0-9 so files with
+// the same FileCreationDate can be told apart, unless Conditions.Sequencer is set. ErrSplitTooManyFiles
+// is returned when that would produce more than 36 Files.
+func (f *File) SplitFiles(config *SplitFileConfiguration) ([]SplitFile, error) {
+	if config == nil {
+		return nil, fmt.Errorf("%w: missing SplitFileConfiguration", ErrSplitRule)
+	}
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		switch config.SplitBy {
+		case SplitByCompanyIdentification:
+			keyFunc = splitByCompanyIdentification
+		case SplitByODFI:
+			keyFunc = splitByODFI
+		default:
+			return nil, fmt.Errorf("%w %q", ErrSplitRule, config.SplitBy)
+		}
+	}
+
+	// Group batches by their key into partial Files which are then merged
+	var keys []string
+	groups := make(map[string]*File)
+	groupFor := func(key string) *File {
+		if group, exists := groups[key]; exists {
+			return group
+		}
+		group := &File{Header: f.Header}
+		group.SetValidation(f.GetValidation())
+		groups[key] = group
+		keys = append(keys, key)
+		return group
+	}
+	for _, batch := range f.Batches {
+		bh := batch.GetHeader()
+		if bh == nil {
+			return nil, fmt.Errorf("batch %s has nil BatchHeader", batch.ID())
+		}
+		group := groupFor(keyFunc(&IteratorRecord{BatchHeader: bh}))
+		group.Batches = append(group.Batches, batch)
+	}
+	for _, batch := range f.IATBatches {
+		if batch.Header == nil {
+			return nil, fmt.Errorf("IAT batch %s has nil IATBatchHeader", batch.ID)
+		}
+		group := groupFor(keyFunc(&IteratorRecord{IATBatchHeader: batch.Header}))
+		group.IATBatches = append(group.IATBatches, batch)
+	}
+
+	conditions := config.Conditions
+	if !config.MergeBatches {
+		conditions.PreserveBatches = true
+	}
+
+	var out []SplitFile
+	for _, key := range keys {
+		group := groups[key]
+		sorted := &outFile{
+			header:       group.Header,
+			validateOpts: group.GetValidation(),
+		}
+		if err := sorted.add(group, conditions); err != nil {
+			return nil, fmt.Errorf("splitting %s: %w", key, err)
+		}
+		files, err := convertToFiles(sorted, conditions)
+		if err != nil {
+			return nil, fmt.Errorf("splitting %s: %w", key, err)
+		}
+		for _, file := range files {
+			file.ID = base.ID()
+			file.Header.ID = base.ID()
+			if conditions.Sequencer == nil {
+				if len(out) >= len(fileIDModifiers) {
+					return nil, fmt.Errorf("%w: more than %d files", ErrSplitTooManyFiles, len(fileIDModifiers))
+				}
+				file.Header.FileIDModifier = string(fileIDModifiers[len(out)%len(fileIDModifiers)])
+			}
+			out = append(out, SplitFile{Key: key, File: file})
+		}
+	}
+	return out, nil
+}
+
+func splitByCompanyIdentification(record *IteratorRecord) string {
+	if record.IATBatchHeader != nil {
+		return record.IATBatchHeader.OriginatorIdentification
+	}
+	return record.BatchHeader.CompanyIdentification
+}
+
+func splitByODFI(record *IteratorRecord) string {
+	if record.IATBatchHeader != nil {
+		return record.IATBatchHeader.ODFIIdentification
+	}
+	return record.BatchHeader.ODFIIdentification
+}
@@ -0,0 +1,172 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mockSplitFile(t *testing.T) *File {
+	t.Helper()
+
+	file := NewFile()
+	file.SetHeader(mockFileHeader())
+	for i, company := range []string{"121042882", "231380104", "121042882"} {
+		bh := mockBatchPPDHeader()
+		bh.CompanyIdentification = company
+		bh.CompanyName = "Company " + company
+		if i == 1 {
+			bh.ODFIIdentification = "23138010"
+		}
+		batch := NewBatchPPD(bh)
+		for n := 1; n <= 3; n++ {
+			ed := mockPPDEntryDetail()
+			ed.SetTraceNumber(bh.ODFIIdentification, i*10+n)
+			batch.AddEntry(ed)
+		}
+		require.NoError(t, batch.Create())
+		file.AddBatch(batch)
+	}
+	require.NoError(t, file.Create())
+	return file
+}
+
+func TestFile_SplitFiles(t *testing.T) {
+	file := mockSplitFile(t)
+
+	t.Run("companyIdentification", func(t *testing.T) {
+		out, err := file.SplitFiles(&SplitFileConfiguration{
+			SplitBy: SplitByCompanyIdentification,
+		})
+		require.NoError(t, err)
+		require.Len(t, out, 2)
+
+		require.Equal(t, "121042882", out[0].Key)
+		require.Len(t, out[0].File.Batches, 2) // batches are kept as-is
+		require.Equal(t, 2, out[0].File.Control.BatchCount)
+		require.Equal(t, 2, out[0].File.Batches[1].GetHeader().BatchNumber)
+		require.Equal(t, 6, out[0].File.Control.EntryAddendaCount)
+		require.Equal(t, "A", out[0].File.Header.FileIDModifier)
+
+		require.Equal(t, "231380104", out[1].Key)
+		require.Len(t, out[1].File.Batches, 1)
+		require.Equal(t, 3, out[1].File.Control.EntryAddendaCount)
+		require.Equal(t, 1, out[1].File.Batches[0].GetHeader().BatchNumber)
+		require.Equal(t, "B", out[1].File.Header.FileIDModifier)
+
+		var total int
+		for i := range out {
+			require.NotEmpty(t, out[i].File.ID)
+			require.NoError(t, out[i].File.Validate())
+			total += out[i].File.Control.TotalCreditEntryDollarAmountInFile
+		}
+		require.Equal(t, file.Control.TotalCreditEntryDollarAmountInFile, total)
+	})
+
+	t.Run("MergeBatches", func(t *testing.T) {
+		out, err := file.SplitFiles(&SplitFileConfiguration{
+			SplitBy:      SplitByCompanyIdentification,
+			MergeBatches: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, out, 2)
+		require.Len(t, out[0].File.Batches, 1) // equal headers are merged
+		require.Equal(t, 6, out[0].File.Control.EntryAddendaCount)
+	})
+
+	t.Run("odfi", func(t *testing.T) {
+		out, err := file.SplitFiles(&SplitFileConfiguration{
+			SplitBy: SplitByODFI,
+		})
+		require.NoError(t, err)
+		require.Len(t, out, 2)
+		require.Equal(t, "12104288", out[0].Key)
+		require.Equal(t, "23138010", out[1].Key)
+	})
+
+	t.Run("KeyFunc with conditions", func(t *testing.T) {
+		out, err := file.SplitFiles(&SplitFileConfiguration{
+			KeyFunc: func(record *IteratorRecord) string {
+				return record.BatchHeader.StandardEntryClassCode
+			},
+			Conditions: Conditions{
+				MaxLines: 8,
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, out, 3)
+		for i := range out {
+			require.Equal(t, PPD, out[i].Key)
+			require.LessOrEqual(t, lineCount(out[i].File), 8)
+		}
+		require.Equal(t, "C", out[2].File.Header.FileIDModifier)
+	})
+
+	t.Run("IAT", func(t *testing.T) {
+		iat, err := readACHFilepath(filepath.Join("test", "testdata", "20180713-IAT.ach"))
+		require.NoError(t, err)
+
+		out, err := iat.SplitFiles(&SplitFileConfiguration{
+			SplitBy: SplitByCompanyIdentification,
+		})
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Equal(t, iat.IATBatches[0].Header.OriginatorIdentification, out[0].Key)
+		require.Len(t, out[0].File.IATBatches, len(iat.IATBatches))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := file.SplitFiles(nil)
+		require.ErrorIs(t, err, ErrSplitRule)
+
+		_, err = file.SplitFiles(&SplitFileConfiguration{SplitBy: "other"})
+		require.ErrorIs(t, err, ErrSplitRule)
+
+		// each entry needs its own file, which runs out of FileIDModifiers
+		large := NewFile()
+		large.SetHeader(mockFileHeader())
+		batch := NewBatchPPD(mockBatchPPDHeader())
+		for n := 1; n <= len(fileIDModifiers)+1; n++ {
+			ed := mockPPDEntryDetail()
+			ed.SetTraceNumber(batch.Header.ODFIIdentification, n)
+			batch.AddEntry(ed)
+		}
+		require.NoError(t, batch.Create())
+		large.AddBatch(batch)
+		require.NoError(t, large.Create())
+
+		config := &SplitFileConfiguration{
+			SplitBy:    SplitByCompanyIdentification,
+			Conditions: Conditions{MaxEntriesPerBatch: 1, MaxBatchesPerFile: 1},
+		}
+		_, err = large.SplitFiles(config)
+		require.ErrorIs(t, err, ErrSplitTooManyFiles)
+
+		first := batch.Entries[0].TraceNumber
+		batch.DeleteEntries(func(ed *EntryDetail) bool { return ed.TraceNumber == first })
+		require.NoError(t, batch.Create())
+		require.NoError(t, large.Create())
+		out, err := large.SplitFiles(config)
+		require.NoError(t, err)
+		require.Len(t, out, len(fileIDModifiers))
+		require.Equal(t, "9", out[len(out)-1].File.Header.FileIDModifier)
+	})
+}
@@ -0,0 +1,274 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrStreamWriterState is given when StreamWriter methods are called out of order
+	ErrStreamWriterState = errors.New("stream writer")
+)
+
+// StreamWriter writes a File one record at a time without holding every Batch and EntryDetail
+// in memory. It is the write-side counterpart to Iterator.
+//
+// Records are written in order: WriteHeader, then OpenBatch, WriteEntry (for each entry) and
+// CloseBatch for each batch, and finally Close. BatchControl and FileControl records are
+// calculated as entries are written and the final block is padded on Close.
+//
+// Each entry is validated by the Batch type for its StandardEntryClassCode, the same as
+// Batch.Create, unless BypassValidation is set. ADV and IAT batches are not supported.
+type StreamWriter struct {
+	converters
+
+	w *Writer
+
+	// BypassValidation can be set to skip validation and will allow non-compliant Nacha files to be written.
+	BypassValidation bool
+
+	validateOpts *ValidateOpts
+
+	header      *FileHeader
+	batch       Batcher // validates each entry of the open batch
+	batchTotals streamTotals
+	lastTrace   string
+	entrySeq    int
+
+	batchCount      int
+	lastBatchNumber int
+	fileTotals      streamTotals
+	closed          bool
+}
+
+type streamTotals struct {
+	entryAddendaCount int
+	entryHash         int
+	credit, debit     int
+}
+
+// NewStreamWriter returns a StreamWriter that writes records to w.
+func NewStreamWriter(w io.Writer, opts *WriteOpts) *StreamWriter {
+	return &StreamWriter{
+		w: NewWriterWithOpts(w, opts),
+	}
+}
+
+// SetValidation stores ValidateOpts which are applied to every record written.
+func (sw *StreamWriter) SetValidation(opts *ValidateOpts) {
+	sw.validateOpts = opts
+}
+
+func (sw *StreamWriter) opts() *ValidateOpts {
+	if sw.validateOpts == nil {
+		return &ValidateOpts{}
+	}
+	return sw.validateOpts
+}
+
+func (sw *StreamWriter) validate() bool {
+	return !sw.BypassValidation && !sw.opts().SkipAll
+}
+
+// WriteHeader writes the FileHeader, which must be the first record written.
+func (sw *StreamWriter) WriteHeader(fh FileHeader) error {
+	if sw.header != nil || sw.closed {
+		return fmt.Errorf("%w: FileHeader already written", ErrStreamWriterState)
+	}
+	if sw.validate() && !sw.opts().AllowMissingFileHeader {
+		if err := fh.ValidateWith(sw.opts()); err != nil {
+			return err
+		}
+	}
+	if err := sw.w.writeLine(&fh); err != nil {
+		return err
+	}
+	sw.header = &fh
+	return nil
+}
+
+// OpenBatch writes a BatchHeader and starts a batch which entries are written into.
+// BatchNumber is assigned when it is not set, the same as File.Create.
+func (sw *StreamWriter) OpenBatch(bh *BatchHeader) error {
+	switch {
+	case sw.header == nil || sw.closed:
+		return fmt.Errorf("%w: FileHeader must be written before a batch", ErrStreamWriterState)
+	case sw.batch != nil:
+		return fmt.Errorf("%w: batch %d is still open", ErrStreamWriterState, sw.batch.GetHeader().BatchNumber)
+	case bh == nil:
+		return errors.New("nil BatchHeader provided")
+	case bh.StandardEntryClassCode == ADV:
+		return fmt.Errorf("%w: ADV batches are not supported", ErrStreamWriterState)
+	}
+
+	if bh.BatchNumber <= 1 {
+		bh.BatchNumber = sw.lastBatchNumber + 1
+	}
+	if sw.validate() && !sw.opts().AllowUnorderedBatchNumbers && bh.BatchNumber <= sw.lastBatchNumber {
+		return NewErrFileBatchNumberAscending(sw.lastBatchNumber, bh.BatchNumber)
+	}
+
+	batch, err := NewBatch(bh)
+	if err != nil {
+		return err
+	}
+	batch.SetValidation(sw.validateOpts)
+	if sw.validate() {
+		if err := bh.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := sw.w.writeLine(bh); err != nil {
+		return err
+	}
+
+	sw.batch = batch
+	sw.batchTotals = streamTotals{}
+	sw.lastTrace = ""
+	sw.entrySeq = 0
+	sw.lastBatchNumber = bh.BatchNumber
+	return nil
+}
+
+// WriteEntry writes an EntryDetail and its addenda records into the open batch.
+//
+// TraceNumbers and Addenda05 sequence numbers are assigned the same as Batch.Create.
+func (sw *StreamWriter) WriteEntry(ed *EntryDetail) error {
+	if sw.batch == nil {
+		return fmt.Errorf("%w: no open batch", ErrStreamWriterState)
+	}
+	if ed == nil {
+		return errors.New("nil EntryDetail provided")
+	}
+	bh := sw.batch.GetHeader()
+	opts := sw.opts()
+
+	sw.entrySeq++
+	if ed.TraceNumberField()[:8] != bh.ODFIIdentificationField()[:8] {
+		if !opts.BypassOriginValidation && !opts.CustomTraceNumbers {
+			ed.SetTraceNumber(bh.ODFIIdentification, sw.entrySeq)
+		}
+	}
+	for i, a := range ed.Addenda05 {
+		a.SequenceNumber = i + 1
+		a.EntryDetailSequenceNumber = sw.parseNumField(ed.TraceNumberField()[8:])
+	}
+
+	if sw.validate() {
+		if !opts.CustomTraceNumbers && ed.TraceNumber <= sw.lastTrace {
+			return sw.batch.Error("TraceNumber", NewErrBatchAscending(sw.lastTrace, ed.TraceNumber))
+		}
+
+		// Validate the entry as the only one in its batch
+		sw.batch.DeleteEntries(func(*EntryDetail) bool { return true })
+		sw.batch.AddEntry(ed)
+		if err := sw.batch.Create(); err != nil {
+			return err
+		}
+	}
+	if err := sw.w.writeEntry(ed); err != nil {
+		return err
+	}
+	sw.lastTrace = ed.TraceNumber
+
+	single := &Batch{Header: bh, Entries: []*EntryDetail{ed}}
+	credit, debit := single.calculateBatchAmounts()
+	sw.batchTotals.entryAddendaCount += 1 + ed.addendaCount()
+	sw.batchTotals.entryHash += single.calculateEntryHash()
+	sw.batchTotals.credit += credit
+	sw.batchTotals.debit += debit
+	return nil
+}
+
+// CloseBatch writes the BatchControl of the open batch.
+func (sw *StreamWriter) CloseBatch() error {
+	if sw.batch == nil {
+		return fmt.Errorf("%w: no open batch", ErrStreamWriterState)
+	}
+	bh := sw.batch.GetHeader()
+	if sw.validate() && sw.entrySeq == 0 {
+		return sw.batch.Error("entries", ErrBatchNoEntries)
+	}
+
+	bc := NewBatchControl()
+	bc.ServiceClassCode = bh.ServiceClassCode
+	bc.CompanyIdentification = bh.CompanyIdentification
+	bc.ODFIIdentification = bh.ODFIIdentification
+	bc.BatchNumber = bh.BatchNumber
+	bc.EntryAddendaCount = sw.batchTotals.entryAddendaCount
+	bc.EntryHash = sw.leastSignificantDigits(sw.batchTotals.entryHash, 10)
+	bc.TotalCreditEntryDollarAmount = sw.batchTotals.credit
+	bc.TotalDebitEntryDollarAmount = sw.batchTotals.debit
+	if sw.validate() {
+		if err := bc.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := sw.w.writeLine(bc); err != nil {
+		return err
+	}
+
+	sw.batchCount++
+	sw.fileTotals.entryAddendaCount += bc.EntryAddendaCount
+	sw.fileTotals.entryHash += bc.EntryHash
+	sw.fileTotals.credit += bc.TotalCreditEntryDollarAmount
+	sw.fileTotals.debit += bc.TotalDebitEntryDollarAmount
+	sw.batch = nil
+	return nil
+}
+
+// Close writes the FileControl, pads the final block and flushes all records to the underlying io.Writer.
+// The FileControl written is returned.
+func (sw *StreamWriter) Close() (*FileControl, error) {
+	switch {
+	case sw.closed:
+		return nil, fmt.Errorf("%w: already closed", ErrStreamWriterState)
+	case sw.header == nil:
+		return nil, fmt.Errorf("%w: FileHeader must be written before closing", ErrStreamWriterState)
+	case sw.batch != nil:
+		return nil, fmt.Errorf("%w: batch %d is still open", ErrStreamWriterState, sw.batch.GetHeader().BatchNumber)
+	}
+	if sw.validate() && sw.batchCount == 0 && !sw.opts().AllowZeroBatches {
+		return nil, ErrFileNoBatches
+	}
+
+	totalRecordsInFile := sw.w.lineNum + 1 // FileControl
+	fc := NewFileControl()
+	fc.BatchCount = sw.batchCount
+	fc.BlockCount = (totalRecordsInFile + 9) / 10
+	fc.EntryAddendaCount = sw.fileTotals.entryAddendaCount
+	fc.EntryHash = sw.leastSignificantDigits(sw.fileTotals.entryHash, 10)
+	fc.TotalCreditEntryDollarAmountInFile = sw.fileTotals.credit
+	fc.TotalDebitEntryDollarAmountInFile = sw.fileTotals.debit
+	if sw.validate() && !sw.opts().AllowMissingFileControl {
+		if err := fc.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := sw.w.writeLine(&fc); err != nil {
+		return nil, err
+	}
+	if err := sw.w.writePadding(); err != nil {
+		return nil, err
+	}
+	sw.closed = true
+	return &fc, sw.w.Flush()
+}
@@ -0,0 +1,187 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf, nil)
+	require.NoError(t, sw.WriteHeader(staticFileHeader()))
+
+	for b := 0; b < 3; b++ {
+		bh := mockBatchPPDHeader2()
+		bh.EffectiveEntryDate = "230422"
+		require.NoError(t, sw.OpenBatch(bh))
+
+		for i := 0; i < 15; i++ {
+			ed := mockPPDEntryDetail2()
+			ed.TraceNumber = "" // assigned by the StreamWriter
+			if i%2 == 0 {
+				ed.TransactionCode = CheckingDebit
+				ed.AddendaRecordIndicator = 1
+				ed.AddAddenda05(mockAddenda05())
+			}
+			require.NoError(t, sw.WriteEntry(ed))
+		}
+		require.NoError(t, sw.CloseBatch())
+	}
+
+	fc, err := sw.Close()
+	require.NoError(t, err)
+	require.Equal(t, 3, fc.BatchCount)
+	require.Equal(t, 3*(15+8), fc.EntryAddendaCount)
+	require.Equal(t, 3*8*100000, fc.TotalDebitEntryDollarAmountInFile)
+	require.Equal(t, 3*7*100000, fc.TotalCreditEntryDollarAmountInFile)
+
+	// Read the file back and compare it against what Writer produces
+	file, err := NewReader(bytes.NewReader(buf.Bytes())).Read()
+	require.NoError(t, err)
+	require.Len(t, file.Batches, 3)
+	require.Equal(t, "121042880000015", file.Batches[2].GetEntries()[14].TraceNumber)
+	file.Control.LineNumber = 0
+	require.Equal(t, *fc, file.Control)
+
+	var expected bytes.Buffer
+	require.NoError(t, NewWriter(&expected).Write(&file))
+	require.Equal(t, expected.String(), buf.String())
+	require.Equal(t, 0, strings.Count(buf.String(), "\n")%10)
+}
+
+func TestStreamWriter__Iterator(t *testing.T) {
+	fd, err := os.Open(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	t.Cleanup(func() { fd.Close() })
+
+	iter := NewIterator(fd)
+
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf, nil)
+
+	var currentBatch *BatchHeader
+	for {
+		bh, ed, err := iter.NextEntry()
+		require.NoError(t, err)
+		if ed == nil {
+			break
+		}
+		if currentBatch == nil {
+			require.NoError(t, sw.WriteHeader(*iter.GetHeader()))
+		}
+		if currentBatch != bh {
+			if currentBatch != nil {
+				require.NoError(t, sw.CloseBatch())
+			}
+			require.NoError(t, sw.OpenBatch(bh))
+			currentBatch = bh
+		}
+		require.NoError(t, sw.WriteEntry(ed))
+	}
+	require.NoError(t, sw.CloseBatch())
+	_, err = sw.Close()
+	require.NoError(t, err)
+
+	expected, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	var want bytes.Buffer
+	require.NoError(t, NewWriter(&want).Write(expected))
+	require.Equal(t, want.String(), buf.String())
+}
+
+func TestStreamWriter__Errors(t *testing.T) {
+	sw := NewStreamWriter(&bytes.Buffer{}, nil)
+
+	// Records out of order
+	require.ErrorIs(t, sw.OpenBatch(mockBatchPPDHeader()), ErrStreamWriterState)
+	require.ErrorIs(t, sw.WriteEntry(mockPPDEntryDetail()), ErrStreamWriterState)
+	require.ErrorIs(t, sw.CloseBatch(), ErrStreamWriterState)
+	_, err := sw.Close()
+	require.ErrorIs(t, err, ErrStreamWriterState)
+
+	require.NoError(t, sw.WriteHeader(staticFileHeader()))
+	require.ErrorIs(t, sw.WriteHeader(staticFileHeader()), ErrStreamWriterState)
+
+	_, err = sw.Close()
+	require.ErrorIs(t, err, ErrFileNoBatches)
+
+	// Batches require entries
+	require.NoError(t, sw.OpenBatch(mockBatchPPDHeader()))
+	require.ErrorIs(t, sw.OpenBatch(mockBatchPPDHeader()), ErrStreamWriterState)
+	require.ErrorIs(t, sw.CloseBatch(), ErrBatchNoEntries)
+
+	// Debits are not allowed in a credit only batch
+	ed := mockPPDEntryDetail()
+	ed.TransactionCode = CheckingDebit
+	var tranCodeErr ErrBatchServiceClassTranCode
+	require.ErrorAs(t, sw.WriteEntry(ed), &tranCodeErr)
+
+	// Trace numbers must ascend
+	ed = mockPPDEntryDetail()
+	ed.SetTraceNumber("12104288", 5)
+	require.NoError(t, sw.WriteEntry(ed))
+
+	ed = mockPPDEntryDetail()
+	ed.SetTraceNumber("12104288", 4)
+	require.ErrorContains(t, sw.WriteEntry(ed), "must be in ascending order")
+
+	_, err = sw.Close()
+	require.ErrorIs(t, err, ErrStreamWriterState)
+	require.NoError(t, sw.CloseBatch())
+
+	// Batch numbers must ascend
+	bh := mockBatchPPDHeader()
+	bh.BatchNumber = 10
+	require.NoError(t, sw.OpenBatch(bh))
+	require.NoError(t, sw.WriteEntry(mockPPDEntryDetail()))
+	require.NoError(t, sw.CloseBatch())
+
+	bh = mockBatchPPDHeader()
+	bh.BatchNumber = 9
+	require.ErrorContains(t, sw.OpenBatch(bh), "must be in ascending order")
+
+	_, err = sw.Close()
+	require.NoError(t, err)
+}
+
+func TestStreamWriter__BypassValidation(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf, nil)
+	sw.BypassValidation = true
+
+	fh := staticFileHeader()
+	fh.ImmediateOrigin = ""
+	require.NoError(t, sw.WriteHeader(fh))
+	require.NoError(t, sw.OpenBatch(mockBatchPPDHeader()))
+
+	ed := mockPPDEntryDetail()
+	ed.TransactionCode = CheckingDebit
+	require.NoError(t, sw.WriteEntry(ed))
+	require.NoError(t, sw.CloseBatch())
+
+	fc, err := sw.Close()
+	require.NoError(t, err)
+	require.Equal(t, ed.Amount, fc.TotalDebitEntryDollarAmountInFile)
+}
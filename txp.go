@@ -0,0 +1,112 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moov-io/ach/addenda"
+)
+
+var (
+	// ErrTXPAmount is given when the amounts of TXP addenda records don't add up to the entry's Amount.
+	ErrTXPAmount = errors.New("TXP tax amounts do not equal the entry amount")
+)
+
+// isTXP returns if the PaymentRelatedInformation of an Addenda05 record is meant to be a TXP addenda
+func isTXP(paymentInfo string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(paymentInfo)), "TXP")
+}
+
+// txpAmount returns the sum of the tax amounts in txp
+func txpAmount(txp *addenda.TXP) (int, error) {
+	var total int
+	for _, amt := range txp.TaxAmounts {
+		n, err := strconv.Atoi(amt.AmountCents)
+		if err != nil {
+			return 0, fmt.Errorf("%w: amount %s", addenda.ErrInvalidTXPFormat, amt.AmountCents)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// SetTXP replaces the Addenda05 records of a CCD tax payment with one TXP addenda record.
+// An error is returned when txp is invalid or its tax amounts do not add up to the entry's Amount.
+func (ed *EntryDetail) SetTXP(txp *addenda.TXP) error {
+	if txp == nil {
+		return addenda.ErrInvalidTXPFormat
+	}
+	paymentInfo := txp.String()
+	parsed, err := addenda.ParseTXP(paymentInfo)
+	if err != nil {
+		return fieldError("PaymentRelatedInformation", err, paymentInfo)
+	}
+	if total, err := txpAmount(parsed); err != nil {
+		return fieldError("PaymentRelatedInformation", err, paymentInfo)
+	} else if total != ed.Amount {
+		return fieldError("Amount", ErrTXPAmount, ed.Amount)
+	}
+
+	addenda05 := NewAddenda05()
+	addenda05.PaymentRelatedInformation = paymentInfo
+	addenda05.SequenceNumber = 1
+	addenda05.EntryDetailSequenceNumber = ed.parseNumField(ed.TraceNumberField()[8:])
+
+	ed.Addenda05 = []*Addenda05{addenda05}
+	ed.AddendaRecordIndicator = 1
+	return nil
+}
+
+// validateTXP checks the TXP addenda records of CCD and CTX entries when ValidateTXP is set. Each
+// Addenda05 record starting with TXP must be a valid TXP addenda and their tax amounts must add up
+// to the entry's Amount.
+func (v *ValidateOpts) validateTXP(bh *BatchHeader, ed *EntryDetail) error {
+	if v == nil || !v.ValidateTXP || bh == nil || ed == nil {
+		return nil
+	}
+	if bh.StandardEntryClassCode != CCD && bh.StandardEntryClassCode != CTX {
+		return nil
+	}
+
+	var found bool
+	var total int
+	for _, addenda05 := range ed.Addenda05 {
+		if addenda05 == nil || !isTXP(addenda05.PaymentRelatedInformation) {
+			continue
+		}
+		found = true
+
+		txp, err := addenda.ParseTXP(addenda05.PaymentRelatedInformation)
+		if err != nil {
+			return fieldError("PaymentRelatedInformation", err, addenda05.PaymentRelatedInformation)
+		}
+		amount, err := txpAmount(txp)
+		if err != nil {
+			return fieldError("PaymentRelatedInformation", err, addenda05.PaymentRelatedInformation)
+		}
+		total += amount
+	}
+	if found && total != ed.Amount {
+		return fieldError("Amount", fmt.Errorf("%w: TXP total is %d", ErrTXPAmount, total), ed.Amount)
+	}
+	return nil
+}
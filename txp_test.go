@@ -0,0 +1,137 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach/addenda"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mockTXP() *addenda.TXP {
+	return &addenda.TXP{
+		TaxIdentificationNumber: "123456789",
+		TaxPaymentTypeCode:      "94105",
+		Date:                    "250930",
+		TaxAmounts: []addenda.TaxAmount{
+			{AmountType: "1", AmountCents: "10000"},
+			{AmountType: "2", AmountCents: "2345"},
+		},
+	}
+}
+
+func TestEntryDetail__SetTXP(t *testing.T) {
+	ed := mockCCDEntryDetail()
+	ed.Amount = 12345
+	require.NoError(t, ed.SetTXP(mockTXP()))
+
+	require.Len(t, ed.Addenda05, 1)
+	require.Equal(t, 1, ed.AddendaRecordIndicator)
+	require.Equal(t, `TXP*123456789*94105*250930*1*10000*2*2345\`, ed.Addenda05[0].PaymentRelatedInformation)
+	require.Equal(t, 1, ed.Addenda05[0].SequenceNumber)
+	require.Equal(t, ed.TraceNumberField()[8:], ed.Addenda05[0].EntryDetailSequenceNumberField())
+
+	// replaces existing addenda records
+	require.NoError(t, ed.SetTXP(mockTXP()))
+	require.Len(t, ed.Addenda05, 1)
+
+	t.Run("amount", func(t *testing.T) {
+		ed := mockCCDEntryDetail()
+		ed.Amount = 100
+		require.ErrorIs(t, ed.SetTXP(mockTXP()), ErrTXPAmount)
+		require.Empty(t, ed.Addenda05)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		ed := mockCCDEntryDetail()
+		ed.Amount = 12345
+
+		txp := mockTXP()
+		txp.Date = "2025"
+		require.ErrorIs(t, ed.SetTXP(txp), addenda.ErrInvalidTXPFormat)
+		require.ErrorIs(t, ed.SetTXP(nil), addenda.ErrInvalidTXPFormat)
+	})
+}
+
+func TestValidateOpts__ValidateTXP(t *testing.T) {
+	file, err := ReadFile(filepath.Join("test", "testdata", "txp-credit.ach"))
+	require.NoError(t, err)
+
+	// opt-in
+	require.NoError(t, file.Validate())
+
+	// 12345 + 1000 + 500 does not equal the entry's 12345
+	err = file.ValidateWith(&ValidateOpts{ValidateTXP: true})
+	require.ErrorIs(t, err, ErrTXPAmount)
+
+	ed := file.Batches[0].GetEntries()[0]
+	ed.Addenda05[0].PaymentRelatedInformation = `TXP*123456789*941*250901*941*12345*VER\`
+	require.NoError(t, file.ValidateWith(&ValidateOpts{ValidateTXP: true}))
+
+	ed.Addenda05[0].PaymentRelatedInformation = `TXP*123456789*941*25*941*12345\`
+	require.NoError(t, file.Validate())
+	err = file.ValidateWith(&ValidateOpts{ValidateTXP: true})
+	require.ErrorIs(t, err, addenda.ErrInvalidTXPFormat)
+
+	// BypassBatchValidation skips Nacha's batch rules, but not TXP checks which were asked for
+	require.ErrorIs(t, file.ValidateWith(&ValidateOpts{ValidateTXP: true, BypassBatchValidation: true}), addenda.ErrInvalidTXPFormat)
+	require.NoError(t, file.ValidateWith(&ValidateOpts{ValidateTXP: true, SkipAll: true}))
+
+	// Addenda05 records which aren't TXP are skipped
+	ed.Addenda05[0].PaymentRelatedInformation = "INVOICE 12345"
+	require.NoError(t, file.ValidateWith(&ValidateOpts{ValidateTXP: true}))
+}
+
+func TestValidateOpts__ValidateTXPCTX(t *testing.T) {
+	bh := mockBatchCTXHeader()
+	opts := &ValidateOpts{ValidateTXP: true}
+
+	ed := mockCTXEntryDetail()
+	ed.Amount = 15000
+	ed.Addenda05 = []*Addenda05{NewAddenda05(), NewAddenda05()}
+	ed.Addenda05[0].PaymentRelatedInformation = `TXP*123456789*941*250901*1*10000\`
+	ed.Addenda05[1].PaymentRelatedInformation = `TXP*123456789*941*250901*2*5000\`
+	require.NoError(t, opts.validateTXP(bh, ed))
+
+	ed.Amount = 10000
+	require.ErrorIs(t, opts.validateTXP(bh, ed), ErrTXPAmount)
+
+	// other SEC codes are skipped
+	bh.StandardEntryClassCode = PPD
+	require.NoError(t, opts.validateTXP(bh, ed))
+
+	// nil options are skipped
+	var none *ValidateOpts
+	require.NoError(t, none.validateTXP(mockBatchCTXHeader(), ed))
+}
+
+func TestValidateOpts__ValidateTXPReader(t *testing.T) {
+	fd, err := os.Open(filepath.Join("test", "testdata", "txp-debit.ach"))
+	require.NoError(t, err)
+	t.Cleanup(func() { fd.Close() })
+
+	r := NewReader(fd)
+	r.SetValidation(&ValidateOpts{ValidateTXP: true})
+
+	_, err = r.Read()
+	require.ErrorContains(t, err, ErrTXPAmount.Error())
+}
@@ -0,0 +1,310 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrRulePattern is given when a field does not match the pattern of a ValidationRule
+	ErrRulePattern = errors.New("does not match the required pattern")
+	// ErrRuleAllowedValues is given when a field is not one of the allowed values of a ValidationRule
+	ErrRuleAllowedValues = errors.New("is not an allowed value")
+	// ErrRuleBlockedValues is given when a field is one of the blocked values of a ValidationRule
+	ErrRuleBlockedValues = errors.New("is a blocked value")
+	// ErrRuleMaxAmount is given when an entry's Amount exceeds the maximum of a ValidationRule
+	ErrRuleMaxAmount = errors.New("exceeds the maximum amount")
+)
+
+// CustomRules holds validation functions which run after the Nacha rules in File.ValidateWith,
+// File.ValidateAll and Reader.Read. Each function is called for every record of its type and a
+// non-nil error fails validation.
+//
+// Functions cannot be serialized into/from JSON, see ValidationRule for checks which can be.
+type CustomRules struct {
+	FileHeader  []func(fh *FileHeader) error
+	BatchHeader []func(bh *BatchHeader) error
+	EntryDetail []func(bh *BatchHeader, ed *EntryDetail) error
+	Addenda05   []func(bh *BatchHeader, ed *EntryDetail, addenda05 *Addenda05) error
+
+	IATBatchHeader []func(bh *IATBatchHeader) error
+	IATEntryDetail []func(bh *IATBatchHeader, ed *IATEntryDetail) error
+}
+
+func (r *CustomRules) merge(other *CustomRules) *CustomRules {
+	if r == nil {
+		return other
+	}
+	if other == nil {
+		return r
+	}
+	return &CustomRules{
+		FileHeader:     slices.Concat(r.FileHeader, other.FileHeader),
+		BatchHeader:    slices.Concat(r.BatchHeader, other.BatchHeader),
+		EntryDetail:    slices.Concat(r.EntryDetail, other.EntryDetail),
+		Addenda05:      slices.Concat(r.Addenda05, other.Addenda05),
+		IATBatchHeader: slices.Concat(r.IATBatchHeader, other.IATBatchHeader),
+		IATEntryDetail: slices.Concat(r.IATEntryDetail, other.IATEntryDetail),
+	}
+}
+
+// ValidationRule is a declarative check of a record's field which can be read from JSON.
+//
+// Record is one of FileHeader, BatchHeader, EntryDetail, Addenda05, IATBatchHeader or IATEntryDetail
+// and Field is the name of a field on that record (e.g. CompanyEntryDescription or RDFIIdentification).
+//
+// Example:
+//
+//	{"name": "approved-descriptions", "record": "BatchHeader", "field": "CompanyEntryDescription", "allowedValues": ["PAYROLL", "REFUND"]}
+//	{"name": "ppd-limit", "record": "EntryDetail", "secCodes": ["PPD"], "maxAmount": 2500000}
+type ValidationRule struct {
+	// Name is included in errors to identify the rule
+	Name string `json:"name"`
+
+	Record string `json:"record"`
+	Field  string `json:"field"`
+
+	// SECCodes limits the rule to batches with one of the StandardEntryClassCodes.
+	// FileHeader rules ignore this.
+	SECCodes []string `json:"secCodes,omitempty"`
+
+	// Pattern is a regular expression the field's value must match
+	Pattern string `json:"pattern,omitempty"`
+
+	// AllowedValues lists the only values permitted for the field
+	AllowedValues []string `json:"allowedValues,omitempty"`
+
+	// BlockedValues lists values which are not permitted for the field (e.g. RDFIs which are blocked)
+	BlockedValues []string `json:"blockedValues,omitempty"`
+
+	// MaxAmount is the largest Amount, in cents, permitted on EntryDetail or IATEntryDetail records.
+	// Field defaults to Amount when MaxAmount is set.
+	MaxAmount int `json:"maxAmount,omitempty"`
+}
+
+// check returns an error if value fails the rule.
+func (rule ValidationRule) check(field string, value reflect.Value, pattern *regexp.Regexp) error {
+	str := strings.TrimSpace(fmt.Sprintf("%v", value.Interface()))
+
+	var err error
+	switch {
+	case pattern != nil && !pattern.MatchString(str):
+		err = ErrRulePattern
+	case len(rule.AllowedValues) > 0 && !slices.Contains(rule.AllowedValues, str):
+		err = ErrRuleAllowedValues
+	case slices.Contains(rule.BlockedValues, str):
+		err = ErrRuleBlockedValues
+	case rule.MaxAmount > 0 && value.CanInt() && value.Int() > int64(rule.MaxAmount):
+		err = ErrRuleMaxAmount
+	}
+	if err == nil {
+		return nil
+	}
+	if rule.Name != "" {
+		err = fmt.Errorf("%w (rule %s)", err, rule.Name)
+	}
+	return fieldError(field, err, value.Interface())
+}
+
+func (rule ValidationRule) appliesTo(sec string) bool {
+	return len(rule.SECCodes) == 0 || slices.Contains(rule.SECCodes, sec)
+}
+
+// compile returns a checking function for the rule's record, which is expected to be a
+// pointer to the struct named by rule.Record.
+func (rule ValidationRule) compile() (func(record interface{}, sec string) error, error) {
+	field := rule.Field
+	if field == "" && rule.MaxAmount > 0 {
+		field = "Amount"
+	}
+
+	var zero interface{}
+	switch rule.Record {
+	case "FileHeader":
+		zero = FileHeader{}
+	case "BatchHeader":
+		zero = BatchHeader{}
+	case "EntryDetail":
+		zero = EntryDetail{}
+	case "Addenda05":
+		zero = Addenda05{}
+	case "IATBatchHeader":
+		zero = IATBatchHeader{}
+	case "IATEntryDetail":
+		zero = IATEntryDetail{}
+	default:
+		return nil, fmt.Errorf("validation rule %s: unknown record %q", rule.Name, rule.Record)
+	}
+	sf, exists := reflect.TypeOf(zero).FieldByName(field)
+	if !exists || field == "" || !sf.IsExported() || len(sf.Index) != 1 {
+		return nil, fmt.Errorf("validation rule %s: unknown %s field %q", rule.Name, rule.Record, field)
+	}
+	if !isScalarKind(sf.Type.Kind()) {
+		return nil, fmt.Errorf("validation rule %s: %s field %q is not a string or number", rule.Name, rule.Record, field)
+	}
+
+	var pattern *regexp.Regexp
+	if rule.Pattern != "" {
+		p, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("validation rule %s: invalid pattern: %w", rule.Name, err)
+		}
+		pattern = p
+	}
+
+	return func(record interface{}, sec string) error {
+		if !rule.appliesTo(sec) {
+			return nil
+		}
+		value := reflect.ValueOf(record).Elem().FieldByName(field)
+		return rule.check(field, value, pattern)
+	}, nil
+}
+
+// isScalarKind reports if a field of kind k can be checked by a ValidationRule.
+func isScalarKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// customRules combines CustomRules with the compiled ValidationRules from opts.
+func (v *ValidateOpts) customRules() (*CustomRules, error) {
+	if v == nil || v.SkipAll || (v.CustomRules == nil && len(v.Rules) == 0) {
+		return nil, nil
+	}
+
+	out := v.CustomRules.merge(&CustomRules{})
+	for _, rule := range v.Rules {
+		fn, err := rule.compile()
+		if err != nil {
+			return nil, err
+		}
+		switch rule.Record {
+		case "FileHeader":
+			out.FileHeader = append(out.FileHeader, func(fh *FileHeader) error {
+				return fn(fh, "")
+			})
+		case "BatchHeader":
+			out.BatchHeader = append(out.BatchHeader, func(bh *BatchHeader) error {
+				return fn(bh, bh.StandardEntryClassCode)
+			})
+		case "EntryDetail":
+			out.EntryDetail = append(out.EntryDetail, func(bh *BatchHeader, ed *EntryDetail) error {
+				return fn(ed, bh.StandardEntryClassCode)
+			})
+		case "Addenda05":
+			out.Addenda05 = append(out.Addenda05, func(bh *BatchHeader, _ *EntryDetail, addenda05 *Addenda05) error {
+				return fn(addenda05, bh.StandardEntryClassCode)
+			})
+		case "IATBatchHeader":
+			out.IATBatchHeader = append(out.IATBatchHeader, func(bh *IATBatchHeader) error {
+				return fn(bh, IAT)
+			})
+		case "IATEntryDetail":
+			out.IATEntryDetail = append(out.IATEntryDetail, func(bh *IATBatchHeader, ed *IATEntryDetail) error {
+				return fn(ed, IAT)
+			})
+		}
+	}
+	return out, nil
+}
+
+// checkCustomRules runs CustomRules and ValidationRules from opts against each record in f and calls
+// onFailure for every failure. Batch records are skipped with BypassBatchValidation. An error is returned
+// when the rules are misconfigured.
+func (v *ValidateOpts) checkCustomRules(f *File, onFailure func(line int, record string, err error)) error {
+	rules, err := v.customRules()
+	if err != nil || f == nil || rules == nil {
+		return err
+	}
+	report := func(line int, record string, err error) {
+		if err != nil {
+			onFailure(line, record, err)
+		}
+	}
+
+	if !v.AllowMissingFileHeader {
+		for _, fn := range rules.FileHeader {
+			report(f.Header.LineNumber, "FileHeader", fn(&f.Header))
+		}
+	}
+	if v.BypassBatchValidation {
+		return nil
+	}
+
+	for _, b := range f.Batches {
+		bh := b.GetHeader()
+		if bh == nil {
+			continue
+		}
+		for _, fn := range rules.BatchHeader {
+			report(bh.LineNumber, "BatchHeader", fn(bh))
+		}
+		for _, ed := range b.GetEntries() {
+			for _, fn := range rules.EntryDetail {
+				report(ed.LineNumber, "EntryDetail", fn(bh, ed))
+			}
+			for _, addenda05 := range ed.Addenda05 {
+				for _, fn := range rules.Addenda05 {
+					report(addenda05.LineNumber, "Addenda", fn(bh, ed, addenda05))
+				}
+			}
+		}
+	}
+	for i := range f.IATBatches {
+		bh := f.IATBatches[i].GetHeader()
+		if bh == nil {
+			continue
+		}
+		for _, fn := range rules.IATBatchHeader {
+			report(bh.LineNumber, "BatchHeader", fn(bh))
+		}
+		for _, ed := range f.IATBatches[i].GetEntries() {
+			for _, fn := range rules.IATEntryDetail {
+				report(ed.LineNumber, "EntryDetail", fn(bh, ed))
+			}
+		}
+	}
+	return nil
+}
+
+// validateRules returns the first failure of the optional and custom rules in opts.
+func (f *File) validateRules(opts *ValidateOpts) error {
+	var first error
+	onFailure := func(_ int, _ string, err error) {
+		if first == nil {
+			first = err
+		}
+	}
+	opts.checkOptionalRules(f, onFailure)
+	if err := opts.checkCustomRules(f, onFailure); err != nil {
+		return err
+	}
+	return first
+}
@@ -0,0 +1,199 @@
+// Licensed to The Moov Authors under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. The Moov Authors licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ach
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/base"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOpts_CustomRules(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	errUnapproved := errors.New("unapproved description")
+	opts := &ValidateOpts{
+		CustomRules: &CustomRules{
+			BatchHeader: []func(bh *BatchHeader) error{
+				func(bh *BatchHeader) error {
+					if bh.CompanyEntryDescription != "PAYROLL" {
+						return errUnapproved
+					}
+					return nil
+				},
+			},
+		},
+	}
+	err = file.ValidateWith(opts)
+	require.ErrorIs(t, err, errUnapproved)
+
+	// Nacha rules are checked first
+	file.Control.EntryHash = 1
+	err = file.ValidateWith(opts)
+	require.ErrorAs(t, err, &ErrFileCalculatedControlEquality{})
+	file.Control.EntryHash = 23138010
+
+	// SkipAll and BypassBatchValidation skip the rules
+	opts.SkipAll = true
+	require.NoError(t, file.ValidateWith(opts))
+	opts.SkipAll = false
+	opts.BypassBatchValidation = true
+	require.NoError(t, file.ValidateWith(opts))
+}
+
+func TestValidateOpts_Rules(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	opts := &ValidateOpts{
+		Rules: []ValidationRule{
+			{
+				Name:          "approved-descriptions",
+				Record:        "BatchHeader",
+				Field:         "CompanyEntryDescription",
+				AllowedValues: []string{"PAYROLL", "REFUND"},
+			},
+			{
+				Name:          "blocked-rdfis",
+				Record:        "EntryDetail",
+				Field:         "RDFIIdentification",
+				BlockedValues: []string{"23138010"},
+			},
+			{
+				Name:      "ppd-limit",
+				Record:    "EntryDetail",
+				SECCodes:  []string{PPD},
+				MaxAmount: 2500000,
+			},
+			{
+				Name:      "ccd-limit",
+				Record:    "EntryDetail",
+				SECCodes:  []string{CCD},
+				MaxAmount: 1,
+			},
+			{
+				Name:    "origin-name",
+				Record:  "FileHeader",
+				Field:   "ImmediateOriginName",
+				Pattern: "^[A-Z ]+$",
+			},
+		},
+	}
+
+	err = file.ValidateWith(opts)
+	require.ErrorIs(t, err, ErrRulePattern)
+	require.Contains(t, err.Error(), "ImmediateOriginName My Bank Name does not match the required pattern (rule origin-name)")
+
+	err = file.ValidateAll(opts)
+	var el base.ErrorList
+	require.ErrorAs(t, err, &el)
+	require.Len(t, el, 4)
+
+	details := DescribeErrors(err)
+	require.Equal(t, CodeRulePattern, details[0].Code)
+	require.Equal(t, 1, details[0].Line)
+
+	require.Equal(t, CodeRuleAllowedValues, details[1].Code)
+	require.Equal(t, "BatchHeader", details[1].Record)
+	require.Equal(t, "CompanyEntryDescription", details[1].FieldName)
+
+	require.Equal(t, CodeRuleBlockedValues, details[2].Code)
+	require.Equal(t, 3, details[2].Line)
+	require.Equal(t, "RDFIIdentification", details[2].FieldName)
+
+	require.Equal(t, CodeRuleMaxAmount, details[3].Code)
+	require.Equal(t, "Amount", details[3].FieldName)
+	require.Equal(t, 100000000, details[3].Value)
+}
+
+func TestValidateOpts_RulesJSON(t *testing.T) {
+	var opts ValidateOpts
+	err := json.Unmarshal([]byte(`{"rules": [{"name": "blocked", "record": "EntryDetail", "field": "RDFIIdentification", "blockedValues": ["23138010"]}]}`), &opts)
+	require.NoError(t, err)
+	require.Len(t, opts.Rules, 1)
+
+	fd, err := os.Open(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	defer fd.Close()
+
+	r := NewReader(fd)
+	r.SetValidation(&opts)
+	_, err = r.Read()
+	require.True(t, base.Has(err, ErrRuleBlockedValues))
+
+	var el base.ErrorList
+	require.ErrorAs(t, err, &el)
+	require.Len(t, el, 1)
+
+	var pe *base.ParseError
+	require.ErrorAs(t, el[0], &pe)
+	require.Equal(t, 3, pe.Line)
+	require.Equal(t, "EntryDetail", pe.Record)
+}
+
+func TestValidateOpts_RulesInvalid(t *testing.T) {
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	cases := map[string]ValidationRule{
+		"unknown record":   {Name: "a", Record: "Other", Field: "Amount"},
+		"unknown field":    {Name: "b", Record: "EntryDetail", Field: "Other"},
+		"missing field":    {Name: "c", Record: "EntryDetail"},
+		"invalid pattern":  {Name: "d", Record: "EntryDetail", Field: "IndividualName", Pattern: "["},
+		"unexported field": {Name: "e", Record: "EntryDetail", Field: "validateOpts", BlockedValues: []string{"x"}},
+		"non-scalar field": {Name: "f", Record: "EntryDetail", Field: "Addenda05", BlockedValues: []string{"x"}},
+	}
+	for name, rule := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := file.ValidateWith(&ValidateOpts{Rules: []ValidationRule{rule}})
+			require.ErrorContains(t, err, "validation rule "+rule.Name)
+		})
+	}
+}
+
+func TestValidateOpts_mergeRules(t *testing.T) {
+	calls := 0
+	rule := func(fh *FileHeader) error {
+		calls++
+		return nil
+	}
+	a := &ValidateOpts{
+		CustomRules: &CustomRules{FileHeader: []func(*FileHeader) error{rule}},
+		Rules:       []ValidationRule{{Name: "a"}},
+	}
+	b := &ValidateOpts{
+		CustomRules: &CustomRules{FileHeader: []func(*FileHeader) error{rule}},
+		Rules:       []ValidationRule{{Name: "b"}},
+	}
+	out := a.merge(b)
+	require.Len(t, out.CustomRules.FileHeader, 2)
+	require.Len(t, out.Rules, 2)
+
+	out.Rules = nil
+	file, err := readACHFilepath(filepath.Join("test", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	require.NoError(t, file.ValidateWith(out))
+	require.Equal(t, 2, calls)
+}
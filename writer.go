@@ -96,7 +96,14 @@ func (w *Writer) Write(file *File) error {
 		}
 	}
 
-	// pad the final block
+	if err := w.writePadding(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// writePadding fills the final block of a file with lines of 9's
+func (w *Writer) writePadding() error {
 	for i := 0; i < (10-(w.lineNum%10)) && w.lineNum%10 != 0; i++ {
 		_, err := w.w.WriteString(paddingLine)
 		if err != nil {
@@ -107,8 +114,7 @@ func (w *Writer) Write(file *File) error {
 			return err
 		}
 	}
-
-	return w.w.Flush()
+	return nil
 }
 
 // Flush writes any buffered data to the underlying io.Writer.
@@ -126,51 +132,9 @@ func (w *Writer) writeBatch(file *File, isADV bool) error {
 		}
 		if !isADV {
 			for _, entry := range batch.GetEntries() {
-				if err := w.writeLine(entry); err != nil {
+				if err := w.writeEntry(entry); err != nil {
 					return err
 				}
-				if entry.Addenda02 != nil {
-					if err := w.writeLine(entry.Addenda02); err != nil {
-						return err
-					}
-				}
-
-				for _, addenda05 := range entry.Addenda05 {
-					if addenda05 != nil {
-						if err := w.writeLine(addenda05); err != nil {
-							return err
-						}
-					}
-				}
-				if entry.Addenda98 != nil {
-					if err := w.writeLine(entry.Addenda98); err != nil {
-						return err
-					}
-				}
-
-				if entry.Addenda98Refused != nil {
-					if err := w.writeLine(entry.Addenda98Refused); err != nil {
-						return err
-					}
-				}
-
-				if entry.Addenda99 != nil {
-					if err := w.writeLine(entry.Addenda99); err != nil {
-						return err
-					}
-				}
-
-				if entry.Addenda99Dishonored != nil {
-					if err := w.writeLine(entry.Addenda99Dishonored); err != nil {
-						return err
-					}
-				}
-
-				if entry.Addenda99Contested != nil {
-					if err := w.writeLine(entry.Addenda99Contested); err != nil {
-						return err
-					}
-				}
 			}
 		} else {
 			for _, entry := range batch.GetADVEntries() {
@@ -198,6 +162,56 @@ func (w *Writer) writeBatch(file *File, isADV bool) error {
 	return nil
 }
 
+// writeEntry writes an EntryDetail and its addenda records
+func (w *Writer) writeEntry(entry *EntryDetail) error {
+	if err := w.writeLine(entry); err != nil {
+		return err
+	}
+	if entry.Addenda02 != nil {
+		if err := w.writeLine(entry.Addenda02); err != nil {
+			return err
+		}
+	}
+
+	for _, addenda05 := range entry.Addenda05 {
+		if addenda05 != nil {
+			if err := w.writeLine(addenda05); err != nil {
+				return err
+			}
+		}
+	}
+	if entry.Addenda98 != nil {
+		if err := w.writeLine(entry.Addenda98); err != nil {
+			return err
+		}
+	}
+
+	if entry.Addenda98Refused != nil {
+		if err := w.writeLine(entry.Addenda98Refused); err != nil {
+			return err
+		}
+	}
+
+	if entry.Addenda99 != nil {
+		if err := w.writeLine(entry.Addenda99); err != nil {
+			return err
+		}
+	}
+
+	if entry.Addenda99Dishonored != nil {
+		if err := w.writeLine(entry.Addenda99Dishonored); err != nil {
+			return err
+		}
+	}
+
+	if entry.Addenda99Contested != nil {
+		if err := w.writeLine(entry.Addenda99Contested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (w *Writer) writeIATBatch(file *File) error {
 	for _, iatBatch := range file.IATBatches {
 		if err := w.writeLine(iatBatch.GetHeader()); err != nil {